@@ -36,6 +36,8 @@ type ChatCommand struct {
 	Stop          []string `name:"stop" help:"Stop sequences"`
 	PrefixCache   *bool    `name:"prefix-cache" help:"Enable prefix caching"`
 	Stream        bool     `name:"stream" help:"Stream output tokens" default:"true"`
+	Collection    string   `name:"collection" help:"Ground responses in the documents of a collection"`
+	Documents     int      `name:"documents" help:"Number of documents from the collection (default: 4)"`
 }
 
 ///////////////////////////////////////////////////////////////////////////////
@@ -117,6 +119,9 @@ func (cmd *ChatCommand) Run(ctx *Globals) (err error) {
 	if result.FinishReason != "" {
 		fmt.Printf("[finish_reason=%s]\n", result.FinishReason)
 	}
+	if len(result.Citations) > 0 {
+		fmt.Printf("[sources=%s]\n", strings.Join(result.Citations, ","))
+	}
 
 	return nil
 }
@@ -204,6 +209,9 @@ func runInteractiveChat(parentCtx context.Context, ctx *Globals, client *httpcli
 		if result.FinishReason != "" {
 			fmt.Printf("[finish_reason=%s]\n", result.FinishReason)
 		}
+		if len(result.Citations) > 0 {
+			fmt.Printf("[sources=%s]\n", strings.Join(result.Citations, ","))
+		}
 
 		if assistant.Len() > 0 {
 			messages = append(messages, schema.ChatMessage{Role: "assistant", Content: assistant.String()})
@@ -340,6 +348,12 @@ func buildChatOpts(cmd *ChatCommand, system string) ([]httpclient.Opt, error) {
 	if cmd.PrefixCache != nil {
 		opts = append(opts, httpclient.WithPrefixCache(*cmd.PrefixCache))
 	}
+	if cmd.Collection != "" {
+		opts = append(opts, httpclient.WithRetrieval(schema.ChatRetrieval{
+			Collection: cmd.Collection,
+			TopK:       cmd.Documents,
+		}))
+	}
 
 	return opts, nil
 }
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	// Packages
+	otel "github.com/mutablelogic/go-client/pkg/otel"
+	httpclient "github.com/mutablelogic/go-llama/pkg/llamacpp/httpclient"
+	schema "github.com/mutablelogic/go-llama/pkg/llamacpp/schema"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+type CollectionCommands struct {
+	ListCollections  ListCollectionsCommand  `cmd:"" name:"collections" help:"List collections." group:"COLLECTION"`
+	GetCollection    GetCollectionCommand    `cmd:"" name:"collection" help:"Get collection." group:"COLLECTION"`
+	AddDocuments     AddDocumentsCommand     `cmd:"" name:"add-documents" help:"Embed documents and store them in a collection." group:"COLLECTION"`
+	QueryCollection  QueryCollectionCommand  `cmd:"" name:"query" help:"Find the documents in a collection most similar to a query." group:"COLLECTION"`
+	DeleteCollection DeleteCollectionCommand `cmd:"" name:"delete-collection" help:"Delete a collection and its documents." group:"COLLECTION"`
+}
+
+type ListCollectionsCommand struct{}
+
+type GetCollectionCommand struct {
+	Name string `arg:"" name:"name" help:"Collection name"`
+}
+
+type DeleteCollectionCommand struct {
+	Name string `arg:"" name:"name" help:"Collection name"`
+}
+
+type AddDocumentsCommand struct {
+	Name     string   `arg:"" name:"name" help:"Collection name"`
+	Texts    []string `arg:"" name:"text" help:"Document text"`
+	Model    string   `name:"model" help:"Model name or path, required when the collection is created"`
+	Index    string   `name:"index" help:"Search index, when the collection is created" enum:"flat,hnsw" default:"flat"`
+	ID       []string `name:"id" help:"Document identifiers, one per text (default: from the checksum of the text)"`
+	Metadata []string `name:"metadata" help:"Metadata of every document, as key=value where the value may be JSON"`
+}
+
+type QueryCollectionCommand struct {
+	Name     string   `arg:"" name:"name" help:"Collection name"`
+	Query    string   `arg:"" name:"query" help:"Text to search for"`
+	TopK     int32    `name:"top-k" help:"Number of results (default: 10)"`
+	Filter   []string `name:"filter" help:"Metadata filter, as key=value where the value may be JSON, and a key can be repeated to match any of the values"`
+	MinScore float64  `name:"min-score" help:"Lowest similarity of a result"`
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// COMMANDS
+
+func (cmd *ListCollectionsCommand) Run(ctx *Globals) (err error) {
+	client, err := ctx.Client()
+	if err != nil {
+		return err
+	}
+
+	// OTEL
+	parent, endSpan := otel.StartSpan(ctx.tracer, ctx.ctx, "ListCollectionsCommand")
+	defer func() { endSpan(err) }()
+
+	collections, err := client.ListCollections(parent)
+	if err != nil {
+		return err
+	}
+
+	// Print
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tMODEL\tINDEX\tDIMENSION\tDOCUMENTS")
+	for _, c := range collections {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%d\n", c.Name, c.Model, c.Index, c.Dimension, c.Documents)
+	}
+	return w.Flush()
+}
+
+func (cmd *GetCollectionCommand) Run(ctx *Globals) (err error) {
+	client, err := ctx.Client()
+	if err != nil {
+		return err
+	}
+
+	// OTEL
+	parent, endSpan := otel.StartSpan(ctx.tracer, ctx.ctx, "GetCollectionCommand")
+	defer func() { endSpan(err) }()
+
+	collection, err := client.GetCollection(parent, cmd.Name)
+	if err != nil {
+		return err
+	}
+
+	// Print
+	fmt.Println(collection)
+	return nil
+}
+
+func (cmd *DeleteCollectionCommand) Run(ctx *Globals) (err error) {
+	client, err := ctx.Client()
+	if err != nil {
+		return err
+	}
+
+	// OTEL
+	parent, endSpan := otel.StartSpan(ctx.tracer, ctx.ctx, "DeleteCollectionCommand")
+	defer func() { endSpan(err) }()
+
+	if err := client.DeleteCollection(parent, cmd.Name); err != nil {
+		return err
+	}
+
+	// Print
+	fmt.Printf("Deleted collection %q\n", cmd.Name)
+	return nil
+}
+
+func (cmd *AddDocumentsCommand) Run(ctx *Globals) (err error) {
+	client, err := ctx.Client()
+	if err != nil {
+		return err
+	}
+
+	// OTEL
+	parent, endSpan := otel.StartSpan(ctx.tracer, ctx.ctx, "AddDocumentsCommand")
+	defer func() { endSpan(err) }()
+
+	// Build documents
+	if len(cmd.ID) > 0 && len(cmd.ID) != len(cmd.Texts) {
+		return fmt.Errorf("%d identifiers for %d documents", len(cmd.ID), len(cmd.Texts))
+	}
+	metadata, err := parseKeyValues(cmd.Metadata)
+	if err != nil {
+		return err
+	}
+	documents := make([]schema.CollectionDocument, len(cmd.Texts))
+	for i, text := range cmd.Texts {
+		documents[i] = schema.CollectionDocument{Text: text, Metadata: metadata}
+		if len(cmd.ID) > 0 {
+			documents[i].ID = cmd.ID[i]
+		}
+	}
+
+	// Add documents
+	result, err := client.AddDocuments(parent, cmd.Name, cmd.Model, documents, httpclient.WithIndex(schema.CollectionIndex(cmd.Index)))
+	if err != nil {
+		return err
+	}
+
+	// Print
+	for _, id := range result.IDs {
+		fmt.Println(id)
+	}
+	fmt.Fprintf(os.Stderr, "Collection %q has %d documents\n", result.Collection.Name, result.Collection.Documents)
+	return nil
+}
+
+func (cmd *QueryCollectionCommand) Run(ctx *Globals) (err error) {
+	client, err := ctx.Client()
+	if err != nil {
+		return err
+	}
+
+	// OTEL
+	parent, endSpan := otel.StartSpan(ctx.tracer, ctx.ctx, "QueryCollectionCommand")
+	defer func() { endSpan(err) }()
+
+	// Build options, where a repeated key matches any of its values
+	opts := []httpclient.Opt{}
+	if cmd.TopK > 0 {
+		opts = append(opts, httpclient.WithTopK(cmd.TopK))
+	}
+	if cmd.MinScore != 0 {
+		opts = append(opts, httpclient.WithMinScore(cmd.MinScore))
+	}
+	values := make(map[string][]any)
+	keys := []string{}
+	for _, filter := range cmd.Filter {
+		kv, err := parseKeyValues([]string{filter})
+		if err != nil {
+			return err
+		}
+		for key, value := range kv {
+			if _, exists := values[key]; !exists {
+				keys = append(keys, key)
+			}
+			values[key] = append(values[key], value)
+		}
+	}
+	for _, key := range keys {
+		opts = append(opts, httpclient.WithFilter(key, values[key]...))
+	}
+
+	// Search
+	result, err := client.SearchCollection(parent, cmd.Name, cmd.Query, opts...)
+	if err != nil {
+		return err
+	}
+
+	// Print results, most similar first
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tSCORE\tTEXT")
+	for _, r := range result.Results {
+		fmt.Fprintf(w, "%s\t%.4f\t%s\n", r.ID, r.Score, strings.ReplaceAll(r.Text, "\n", " "))
+	}
+	return w.Flush()
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// parseKeyValues parses key=value pairs, where a value which is valid JSON
+// is decoded and any other value is a string
+func parseKeyValues(pairs []string) (map[string]any, error) {
+	if len(pairs) == 0 {
+		return nil, nil
+	}
+	result := make(map[string]any, len(pairs))
+	for _, pair := range pairs {
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("expected key=value, got %q", pair)
+		}
+		var v any
+		if err := json.Unmarshal([]byte(value), &v); err != nil {
+			v = value
+		}
+		result[key] = v
+	}
+	return result, nil
+}
@@ -1,11 +1,21 @@
 package main
 
 import (
+	"bufio"
+	"context"
+	"encoding/json"
 	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 
 	// Packages
 	otel "github.com/mutablelogic/go-client/pkg/otel"
 	httpclient "github.com/mutablelogic/go-llama/pkg/llamacpp/httpclient"
+	schema "github.com/mutablelogic/go-llama/pkg/llamacpp/schema"
+	vectorfile "github.com/mutablelogic/go-llama/pkg/llamacpp/vectorfile"
 )
 
 ///////////////////////////////////////////////////////////////////////////////
@@ -16,9 +26,20 @@ type EmbedCommands struct {
 }
 
 type EmbedCommand struct {
-	Model     string   `arg:"" name:"model" help:"Model name or path"`
-	Input     []string `arg:"" name:"input" help:"Text(s) to embed"`
-	Normalize *bool    `name:"normalize" help:"L2-normalize embeddings"`
+	Model      string   `arg:"" name:"model" help:"Model name or path"`
+	Texts      []string `arg:"" name:"text" help:"Text(s) to embed" optional:""`
+	Input      string   `name:"input" short:"i" help:"Embed the rows of a file with a JSON object on each line, or - to read from standard input"`
+	Field      string   `name:"field" help:"Field of each row with the text to embed" default:"text"`
+	IDField    string   `name:"id-field" help:"Field of each row with its identifier" default:"id"`
+	Output     string   `name:"output" short:"o" help:"Write the embeddings of the rows to a .jsonl, .npy or .parquet file (default: JSON lines to standard output)"`
+	Normalize  *bool    `name:"normalize" help:"L2-normalize embeddings"`
+	Pooling    string   `name:"pooling" enum:"default,none,mean,cls,last" default:"default" help:"Pooling type, or none for per-token embeddings"`
+	Dimensions int      `name:"dimensions" help:"Truncate embeddings to this many dimensions"`
+	Encoding   string   `name:"encoding" enum:"float32,base64,float16,int8,binary" default:"float32" help:"Encoding of embeddings (${enum})"`
+	Overflow   string   `name:"overflow" enum:"error,truncate,chunk" default:"error" help:"Handling of inputs which are too long (${enum})"`
+	ChunkSize  int      `name:"chunk-size" help:"Most tokens in a chunk (default: most the model can embed at once)"`
+	Overlap    int      `name:"chunk-overlap" help:"Tokens repeated between chunks"`
+	Aggregate  string   `name:"aggregate" enum:"mean,none" default:"mean" help:"Combine chunks with a weighted mean, or return each chunk"`
 }
 
 ///////////////////////////////////////////////////////////////////////////////
@@ -39,9 +60,37 @@ func (cmd *EmbedCommand) Run(ctx *Globals) (err error) {
 	if cmd.Normalize != nil {
 		opts = append(opts, httpclient.WithNormalize(*cmd.Normalize))
 	}
+	if cmd.Pooling != string(schema.PoolingDefault) {
+		opts = append(opts, httpclient.WithPooling(schema.Pooling(cmd.Pooling)))
+	}
+	if cmd.Dimensions > 0 {
+		opts = append(opts, httpclient.WithDimensions(cmd.Dimensions))
+	}
+	if cmd.Encoding != string(schema.EncodingFloat32) {
+		opts = append(opts, httpclient.WithEncoding(schema.EmbeddingEncoding(cmd.Encoding)))
+	}
+	if cmd.Overflow != string(schema.OverflowError) {
+		opts = append(opts, httpclient.WithOverflow(schema.EmbeddingOverflow(cmd.Overflow)))
+	}
+	if cmd.ChunkSize > 0 || cmd.Overlap > 0 {
+		opts = append(opts, httpclient.WithChunkSize(cmd.ChunkSize, cmd.Overlap))
+	}
+	if cmd.Aggregate != string(schema.AggregateMean) {
+		opts = append(opts, httpclient.WithAggregate(schema.EmbeddingAggregate(cmd.Aggregate)))
+	}
+
+	// Embed the rows of a file
+	if cmd.Input != "" {
+		if len(cmd.Texts) > 0 {
+			return fmt.Errorf("texts cannot be used with --input")
+		}
+		return cmd.stream(parent, client, opts)
+	} else if len(cmd.Texts) == 0 {
+		return fmt.Errorf("no texts to embed")
+	}
 
 	// Embed
-	result, err := client.Embed(parent, cmd.Model, cmd.Input, opts...)
+	result, err := client.Embed(parent, cmd.Model, cmd.Texts, opts...)
 	if err != nil {
 		return err
 	}
@@ -50,3 +99,139 @@ func (cmd *EmbedCommand) Run(ctx *Globals) (err error) {
 	fmt.Println(result)
 	return nil
 }
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// stream embeds the rows of the input file, and writes the embeddings to the
+// output file in the format of its extension. Rows which are not embedded
+// are written with their error as JSON lines, and otherwise are reported
+// and skipped.
+func (cmd *EmbedCommand) stream(ctx context.Context, client *httpclient.Client, opts []httpclient.Opt) (err error) {
+	if cmd.Aggregate != string(schema.AggregateMean) {
+		return fmt.Errorf("--aggregate %s cannot be used with --input", cmd.Aggregate)
+	}
+	if cmd.Field != schema.DefaultEmbedStreamField || cmd.IDField != schema.DefaultEmbedStreamIDField {
+		opts = append(opts, httpclient.WithFields(cmd.Field, cmd.IDField))
+	}
+
+	// Check the output format, which is JSON lines unless the file is an array
+	// or a table of numbers
+	ext := strings.ToLower(filepath.Ext(cmd.Output))
+	switch ext {
+	case "", ".jsonl", ".ndjson":
+		// Rows are written as they are received
+	case ".npy", ".parquet":
+		if cmd.Encoding != string(schema.EncodingFloat32) {
+			return fmt.Errorf("%s output requires --encoding float32", ext)
+		}
+	default:
+		return fmt.Errorf("unsupported output format %q (use .jsonl, .npy or .parquet)", ext)
+	}
+
+	// Open the input
+	var r io.Reader = os.Stdin
+	if cmd.Input != "-" {
+		f, err := os.Open(cmd.Input)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	// Create the output
+	var w io.Writer = os.Stdout
+	if cmd.Output != "" && cmd.Output != "-" {
+		f, createErr := os.Create(cmd.Output)
+		if createErr != nil {
+			return createErr
+		}
+		defer func() {
+			if closeErr := f.Close(); err == nil {
+				err = closeErr
+			}
+		}()
+		w = f
+	}
+	buf := bufio.NewWriter(w)
+	var out vectorfile.Writer
+	switch ext {
+	case ".npy":
+		f, ok := w.(*os.File)
+		if !ok {
+			return fmt.Errorf(".npy output must be a file")
+		}
+
+		// The identifiers are written alongside the array
+		ids, createErr := os.Create(strings.TrimSuffix(cmd.Output, filepath.Ext(cmd.Output)) + ".ids.jsonl")
+		if createErr != nil {
+			return createErr
+		}
+		defer func() {
+			if closeErr := ids.Close(); err == nil {
+				err = closeErr
+			}
+		}()
+		if out, err = vectorfile.NewNPY(f, ids); err != nil {
+			return err
+		}
+	case ".parquet":
+		if out, err = vectorfile.NewParquet(buf); err != nil {
+			return err
+		}
+	}
+
+	// Embed the rows, and write the embeddings
+	enc := json.NewEncoder(buf)
+	var rows, failed int
+	if err := client.EmbedStream(ctx, cmd.Model, r, func(row *schema.EmbedStreamRow) error {
+		if row.Error != "" {
+			failed++
+		} else {
+			rows++
+		}
+		switch {
+		case out == nil:
+			return enc.Encode(row)
+		case row.Error != "":
+			fmt.Fprintf(os.Stderr, "line %d: %s\n", row.Line, row.Error)
+			return nil
+		default:
+			return out.Write(streamRowID(row), row.Embedding)
+		}
+	}, opts...); err != nil {
+		return err
+	}
+	if out != nil {
+		if err := out.Close(); err != nil {
+			return err
+		}
+	}
+	if err := buf.Flush(); err != nil {
+		return err
+	}
+
+	// Report the rows written to a file
+	if w != os.Stdout {
+		fmt.Fprintf(os.Stderr, "%d rows embedded to %s", rows, cmd.Output)
+		if failed > 0 {
+			fmt.Fprintf(os.Stderr, ", %d failed", failed)
+		}
+		fmt.Fprintln(os.Stderr)
+	}
+	return nil
+}
+
+// streamRowID returns the identifier of a row as text, or its line when it
+// has no identifier
+func streamRowID(row *schema.EmbedStreamRow) string {
+	if len(row.ID) == 0 {
+		return strconv.Itoa(row.Line)
+	}
+	var id string
+	if err := json.Unmarshal(row.ID, &id); err == nil {
+		return id
+	}
+	return string(row.ID)
+}
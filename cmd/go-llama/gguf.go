@@ -0,0 +1,242 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	// Packages
+	otel "github.com/mutablelogic/go-client/pkg/otel"
+	schema "github.com/mutablelogic/go-llama/pkg/llamacpp/schema"
+	gguf "github.com/mutablelogic/go-llama/sys/gguf"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+type GGUFCommands struct {
+	GGUF GGUFCommand `cmd:"" name:"gguf" help:"Inspect a local GGUF file." group:"GGUF"`
+}
+
+type GGUFCommand struct {
+	Show GGUFShowCommand `cmd:"" name:"show" default:"withargs" help:"Show GGUF metadata and tensors."`
+	Set  GGUFSetCommand  `cmd:"" name:"set" help:"Set or remove GGUF metadata keys."`
+}
+
+type GGUFShowCommand struct {
+	Path    string `arg:"" name:"file" help:"Path to GGUF file" type:"existingfile"`
+	Tensors bool   `name:"tensors" help:"Show tensor types and layout"`
+	Limit   int    `name:"limit" help:"Maximum number of array elements to show" default:"8"`
+}
+
+type GGUFSetCommand struct {
+	Path   string   `arg:"" name:"file" help:"Path to GGUF file" type:"existingfile"`
+	Values []string `arg:"" optional:"" name:"key=value" help:"Metadata to set, as key=value or key:type=value (types u8..f64, bool, str, arr[type] with a JSON array value). Use key=@path to read a string from a file."`
+	Remove []string `name:"remove" help:"Metadata keys to remove"`
+	Output string   `name:"output" short:"o" help:"Output file (default is to modify the file in place)"`
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// COMMANDS
+
+func (cmd *GGUFShowCommand) Run(ctx *Globals) (err error) {
+	// OTEL
+	_, endSpan := otel.StartSpan(ctx.tracer, ctx.ctx, "GGUFShowCommand")
+	defer func() { endSpan(err) }()
+
+	// Decode the file header
+	file, err := gguf.ReadFile(cmd.Path)
+	if err != nil {
+		return err
+	}
+
+	// Tensor layout
+	var tensors *schema.ModelTensors
+	if cmd.Tensors {
+		gctx, err := gguf.Open(cmd.Path)
+		if err != nil {
+			return err
+		}
+		defer gctx.Close()
+		if tensors, err = schema.NewModelTensorsFromGGUF(gctx, true); err != nil {
+			return err
+		}
+	}
+
+	// Print as JSON in debug mode
+	if ctx.Debug {
+		meta := make(map[string]any, len(file.KV))
+		for _, kv := range file.KV {
+			meta[kv.Key] = kv.Value
+		}
+		if b, err := json.MarshalIndent(map[string]any{"version": file.Version, "meta": meta, "tensors": tensors}, "", "  "); err == nil {
+			fmt.Fprintln(os.Stderr, string(b))
+		}
+		return nil
+	}
+
+	// Print metadata
+	fmt.Printf("GGUF v%d, %d metadata keys, %d tensors\n\n", file.Version, len(file.KV), len(file.Tensors))
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "KEY\tTYPE\tVALUE")
+	for _, kv := range file.KV {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", kv.Key, formatGGUFType(kv), formatGGUFValue(kv.Value, cmd.Limit))
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	// Print tensors
+	if tensors != nil {
+		fmt.Println()
+		printTensors(tensors)
+	}
+
+	return nil
+}
+
+func (cmd *GGUFSetCommand) Run(ctx *Globals) (err error) {
+	// OTEL
+	_, endSpan := otel.StartSpan(ctx.tracer, ctx.ctx, "GGUFSetCommand")
+	defer func() { endSpan(err) }()
+
+	if len(cmd.Values) == 0 && len(cmd.Remove) == 0 {
+		return fmt.Errorf("nothing to set or remove")
+	}
+
+	// Write to the output file, or in place
+	dest := cmd.Output
+	if dest == "" {
+		dest = cmd.Path
+	}
+
+	// Rewrite the file
+	return gguf.Rewrite(cmd.Path, dest, func(f *gguf.File) error {
+		for _, key := range cmd.Remove {
+			if !f.Remove(key) {
+				return fmt.Errorf("metadata key not found: %q", key)
+			}
+		}
+		for _, value := range cmd.Values {
+			kv, err := parseGGUFKV(f, value)
+			if err != nil {
+				return err
+			}
+			if err := f.SetKV(kv); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// parseGGUFKV parses key=value or key:type=value. Without a type, the type of
+// an existing key is used, or str for a new key.
+func parseGGUFKV(f *gguf.File, expr string) (gguf.KV, error) {
+	key, value, ok := strings.Cut(expr, "=")
+	if !ok || key == "" {
+		return gguf.KV{}, fmt.Errorf("expected key=value, got %q", expr)
+	}
+
+	// Determine the type
+	kv := gguf.KV{Type: gguf.TypeString}
+	if name, typ, ok := strings.Cut(key, ":"); ok {
+		t, elemType, err := gguf.ParseType(typ)
+		if err != nil {
+			return kv, err
+		}
+		key, kv.Type, kv.ElemType = name, t, elemType
+	} else if i := f.Find(key); i >= 0 {
+		kv.Type, kv.ElemType = f.KV[i].Type, f.KV[i].ElemType
+	}
+	kv.Key = key
+
+	// Read string values from a file
+	if path, ok := strings.CutPrefix(value, "@"); ok && kv.Type == gguf.TypeString {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return kv, err
+		}
+		value = string(data)
+	}
+
+	// Parse the value
+	v, err := gguf.ParseValue(kv.Type, kv.ElemType, value)
+	if err != nil {
+		return kv, err
+	}
+	kv.Value = v
+
+	return kv, nil
+}
+
+func formatGGUFType(kv gguf.KV) string {
+	if kv.Type == gguf.TypeArray {
+		return fmt.Sprintf("%s[%s]", kv.Type, kv.ElemType)
+	}
+	return kv.Type.String()
+}
+
+// formatGGUFValue formats a metadata value on a single line, truncating
+// long strings and arrays with more than limit elements
+func formatGGUFValue(value any, limit int) string {
+	const maxString = 60
+	switch v := value.(type) {
+	case string:
+		r := []rune(strconv.Quote(v))
+		if len(r) > maxString {
+			return string(r[:maxString]) + "…"
+		}
+		return string(r)
+	case []string:
+		return formatGGUFArray(v, limit, func(s string) string { return strconv.Quote(s) })
+	case []any:
+		return formatGGUFArray(v, limit, func(v any) string { return formatGGUFValue(v, limit) })
+	case []uint8:
+		return formatGGUFArray(v, limit, formatAny)
+	case []int8:
+		return formatGGUFArray(v, limit, formatAny)
+	case []uint16:
+		return formatGGUFArray(v, limit, formatAny)
+	case []int16:
+		return formatGGUFArray(v, limit, formatAny)
+	case []uint32:
+		return formatGGUFArray(v, limit, formatAny)
+	case []int32:
+		return formatGGUFArray(v, limit, formatAny)
+	case []uint64:
+		return formatGGUFArray(v, limit, formatAny)
+	case []int64:
+		return formatGGUFArray(v, limit, formatAny)
+	case []float32:
+		return formatGGUFArray(v, limit, formatAny)
+	case []float64:
+		return formatGGUFArray(v, limit, formatAny)
+	case []bool:
+		return formatGGUFArray(v, limit, formatAny)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func formatGGUFArray[T any](values []T, limit int, fn func(T) string) string {
+	parts := make([]string, 0, min(len(values), limit)+1)
+	for i, v := range values {
+		if limit > 0 && i >= limit {
+			parts = append(parts, fmt.Sprintf("… (%d more)", len(values)-limit))
+			break
+		}
+		parts = append(parts, fn(v))
+	}
+	return "[" + strings.Join(parts, ", ") + "]"
+}
+
+func formatAny[T any](v T) string {
+	return fmt.Sprint(v)
+}
@@ -60,7 +60,11 @@ type CLI struct {
 	CompletionCommands
 	ChatCommands
 	EmbedCommands
+	RerankCommands
+	SimilarityCommands
+	CollectionCommands
 	TokenizerCommands
+	GGUFCommands
 	ServerCommands
 }
 
@@ -4,11 +4,15 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 	"text/tabwriter"
+	"time"
 
 	// Packages
 	otel "github.com/mutablelogic/go-client/pkg/otel"
 	httpclient "github.com/mutablelogic/go-llama/pkg/llamacpp/httpclient"
+	schema "github.com/mutablelogic/go-llama/pkg/llamacpp/schema"
 )
 
 ///////////////////////////////////////////////////////////////////////////////
@@ -18,20 +22,53 @@ type ModelCommands struct {
 	ListModels  ListModelsCommand  `cmd:"" name:"models" help:"List models." group:"MODEL"`
 	GetModel    GetModelCommand    `cmd:"" name:"model" help:"Get model." group:"MODEL"`
 	PullModel   PullModelCommand   `cmd:"" name:"pull" help:"Download a model from URL." group:"MODEL"`
+	ListPulls   ListPullsCommand   `cmd:"" name:"pulls" help:"List background model pulls." group:"MODEL"`
+	ImportModel ImportModelCommand `cmd:"" name:"import" help:"Import a model file into the store." group:"MODEL"`
+	CancelPull  CancelPullCommand  `cmd:"" name:"cancel" help:"Cancel a background model pull." group:"MODEL"`
+	Search      SearchCommand      `cmd:"" name:"search" help:"List models in a Hugging Face repository." group:"MODEL"`
 	LoadModel   LoadModelCommand   `cmd:"" name:"load" help:"Load model into memory." group:"MODEL"`
 	UnloadModel UnloadModelCommand `cmd:"" name:"unload" help:"Unload model from memory." group:"MODEL"`
 	DeleteModel DeleteModelCommand `cmd:"" name:"delete" help:"Delete model from disk." group:"MODEL"`
+	PinModel    PinModelCommand    `cmd:"" name:"pin" help:"Pin a model, so it is not evicted when the store is over quota." group:"MODEL"`
+	LabelModel  LabelModelCommand  `cmd:"" name:"label" help:"Set the labels and notes of a model." group:"MODEL"`
+	GC          GCCommand          `cmd:"" name:"gc" help:"Remove temporary files, and evict models when the store is over quota." group:"MODEL"`
 }
 
-type ListModelsCommand struct{}
+type ListModelsCommand struct {
+	Label      []string `name:"label" help:"List only models with this label (can be repeated)"`
+	Arch       string   `name:"arch" help:"List only models with this architecture"`
+	Capability []string `name:"capability" help:"List only models with this capability, e.g. chat or embedding (can be repeated)"`
+	MinSize    uint64   `name:"min-size" help:"List only models of at least this size in bytes"`
+	MaxSize    uint64   `name:"max-size" help:"List only models of at most this size in bytes"`
+}
 
 type GetModelCommand struct {
-	ID string `arg:"" name:"id" help:"Model ID or path"`
+	ID      string `arg:"" name:"id" help:"Model ID or path"`
+	Tensors bool   `name:"tensors" help:"Show tensor types and layout"`
 }
 
 type PullModelCommand struct {
-	URL      string `arg:"" name:"url" help:"Model URL (supports hf:// and https://)"`
+	URL      string `arg:"" name:"url" help:"Model URL (supports hf://, https://, ollama:// and oci://, or hf://org/repo[:quant] to choose from a repository)"`
 	Progress bool   `name:"progress" help:"Show download progress" default:"true"`
+	Token    string `name:"token" help:"Hugging Face token for gated and private repositories (default is the server token)"`
+	Detach   bool   `name:"detach" help:"Pull in the background on the server, and print the job"`
+}
+
+type ListPullsCommand struct{}
+
+type CancelPullCommand struct {
+	ID string `arg:"" name:"id" help:"Pull job ID"`
+}
+
+type ImportModelCommand struct {
+	Path   string `arg:"" name:"path" help:"Path of the GGUF file on the server, or on this machine with --upload"`
+	Dest   string `name:"dest" help:"Path of the model in the store (default is the filename)"`
+	Mode   string `name:"mode" enum:"copy,hardlink,symlink" default:"copy" help:"Copy, hardlink or symlink the file into the store (${enum})"`
+	Upload bool   `name:"upload" help:"Upload the file from this machine rather than importing a file on the server"`
+}
+
+type SearchCommand struct {
+	Repo string `arg:"" name:"repo" help:"Hugging Face repository (org/repo[@branch])"`
 }
 
 type LoadModelCommand struct {
@@ -50,6 +87,22 @@ type DeleteModelCommand struct {
 	ID string `arg:"" name:"id" help:"Model ID or path"`
 }
 
+type PinModelCommand struct {
+	ID    string `arg:"" name:"id" help:"Model ID or path"`
+	Unpin bool   `name:"unpin" help:"Unpin the model"`
+}
+
+type LabelModelCommand struct {
+	ID     string   `arg:"" name:"id" help:"Model ID or path"`
+	Labels []string `arg:"" optional:"" name:"label" help:"Labels of the model, replacing any existing labels"`
+	Clear  bool     `name:"clear" help:"Remove all labels from the model"`
+	Notes  *string  `name:"notes" help:"Notes for the model, replacing any existing notes"`
+}
+
+type GCCommand struct {
+	DryRun bool `name:"dry-run" help:"List the files which would be removed, without removing them"`
+}
+
 ///////////////////////////////////////////////////////////////////////////////
 // COMMANDS
 
@@ -63,8 +116,26 @@ func (cmd *ListModelsCommand) Run(ctx *Globals) (err error) {
 	parent, endSpan := otel.StartSpan(ctx.tracer, ctx.ctx, "ListModelsCommand")
 	defer func() { endSpan(err) }()
 
+	// Set filters
+	opts := []httpclient.Opt{}
+	if len(cmd.Label) > 0 {
+		opts = append(opts, httpclient.WithLabel(cmd.Label...))
+	}
+	if cmd.Arch != "" {
+		opts = append(opts, httpclient.WithArch(cmd.Arch))
+	}
+	for _, capability := range cmd.Capability {
+		opts = append(opts, httpclient.WithCapability(schema.Capability(capability)))
+	}
+	if cmd.MinSize > 0 {
+		opts = append(opts, httpclient.WithMinSize(cmd.MinSize))
+	}
+	if cmd.MaxSize > 0 {
+		opts = append(opts, httpclient.WithMaxSize(cmd.MaxSize))
+	}
+
 	// List models
-	models, err := client.ListModels(parent)
+	models, err := client.ListModels(parent, opts...)
 	if err != nil {
 		return err
 	}
@@ -78,7 +149,7 @@ func (cmd *ListModelsCommand) Run(ctx *Globals) (err error) {
 	}
 
 	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
-	fmt.Fprintln(w, "PATH\tNAME\tLOADED\tPARAMS\tSIZE\tCTX_TRAIN")
+	fmt.Fprintln(w, "PATH\tNAME\tLOADED\tPARAMS\tSIZE\tCTX_TRAIN\tSTORE\tCAPABILITIES\tLABELS")
 	for _, model := range models {
 		loaded := "no"
 		params := "-"
@@ -98,7 +169,26 @@ func (cmd *ListModelsCommand) Run(ctx *Globals) (err error) {
 				}
 			}
 		}
-		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", model.Path, model.Name, loaded, params, size, ctxTrain)
+		if size == "-" && model.Size > 0 {
+			size = formatBytes(model.Size)
+		}
+		store := "local"
+		if model.ReadOnly {
+			store = "read-only"
+		}
+		capabilities := "-"
+		if len(model.Capabilities) > 0 {
+			names := make([]string, 0, len(model.Capabilities))
+			for _, capability := range model.Capabilities {
+				names = append(names, string(capability))
+			}
+			capabilities = strings.Join(names, ",")
+		}
+		labels := "-"
+		if len(model.Labels) > 0 {
+			labels = strings.Join(model.Labels, ",")
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", model.Path, model.Name, loaded, params, size, ctxTrain, store, capabilities, labels)
 	}
 	_ = w.Flush()
 	return nil
@@ -150,6 +240,20 @@ func (cmd *PullModelCommand) Run(ctx *Globals) (err error) {
 
 	// Build options
 	opts := []httpclient.Opt{}
+	if cmd.Token != "" {
+		opts = append(opts, httpclient.WithToken(cmd.Token))
+	}
+
+	// Start a background pull
+	if cmd.Detach {
+		job, err := client.StartPullJob(parent, cmd.URL, opts...)
+		if err != nil {
+			return fmt.Errorf("failed to pull model from %q: %w", cmd.URL, err)
+		}
+		fmt.Println(job)
+		return nil
+	}
+
 	if cmd.Progress {
 		opts = append(opts, httpclient.WithProgressCallback(func(filename string, received, total uint64) error {
 			if total > 0 {
@@ -177,6 +281,151 @@ func (cmd *PullModelCommand) Run(ctx *Globals) (err error) {
 	return nil
 }
 
+func (cmd *ListPullsCommand) Run(ctx *Globals) (err error) {
+	client, err := ctx.Client()
+	if err != nil {
+		return err
+	}
+
+	// OTEL
+	parent, endSpan := otel.StartSpan(ctx.tracer, ctx.ctx, "ListPullsCommand")
+	defer func() { endSpan(err) }()
+
+	// List pull jobs
+	jobs, err := client.ListPullJobs(parent)
+	if err != nil {
+		return err
+	}
+
+	// Print
+	if ctx.Debug {
+		if b, err := json.MarshalIndent(jobs, "", "  "); err == nil {
+			fmt.Fprintln(os.Stderr, string(b))
+		}
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tSTATUS\tPROGRESS\tRATE\tETA\tURL")
+	for _, job := range jobs {
+		progress, rate, eta := "-", "-", "-"
+		if job.TotalBytes > 0 {
+			progress = fmt.Sprintf("%.1f%%", job.Percentage)
+		} else if job.BytesReceived > 0 {
+			progress = formatBytes(job.BytesReceived)
+		}
+		if job.BytesPerSecond > 0 {
+			rate = formatBytes(job.BytesPerSecond) + "/s"
+		}
+		if job.ETA > 0 {
+			eta = (time.Duration(job.ETA) * time.Second).String()
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", job.ID, job.Status, progress, rate, eta, job.URL)
+	}
+	_ = w.Flush()
+	return nil
+}
+
+func (cmd *CancelPullCommand) Run(ctx *Globals) (err error) {
+	client, err := ctx.Client()
+	if err != nil {
+		return err
+	}
+
+	// OTEL
+	parent, endSpan := otel.StartSpan(ctx.tracer, ctx.ctx, "CancelPullCommand")
+	defer func() { endSpan(err) }()
+
+	// Cancel pull job
+	job, err := client.CancelPullJob(parent, cmd.ID)
+	if err != nil {
+		return err
+	}
+
+	// Print
+	fmt.Println(job)
+	return nil
+}
+
+func (cmd *ImportModelCommand) Run(ctx *Globals) (err error) {
+	client, err := ctx.Client()
+	if err != nil {
+		return err
+	}
+
+	// OTEL
+	parent, endSpan := otel.StartSpan(ctx.tracer, ctx.ctx, "ImportModelCommand")
+	defer func() { endSpan(err) }()
+
+	// Upload a local file, or import a file on the server
+	var model *schema.CachedModel
+	if cmd.Upload {
+		f, err := os.Open(cmd.Path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		dest := cmd.Dest
+		if dest == "" {
+			dest = filepath.Base(cmd.Path)
+		}
+		model, err = client.UploadModel(parent, dest, f)
+		if err != nil {
+			return fmt.Errorf("failed to upload model %q: %w", cmd.Path, err)
+		}
+	} else {
+		model, err = client.ImportModel(parent, cmd.Path, httpclient.WithDest(cmd.Dest), httpclient.WithImportMode(schema.ImportMode(cmd.Mode)))
+		if err != nil {
+			return fmt.Errorf("failed to import model %q: %w", cmd.Path, err)
+		}
+	}
+
+	// Print
+	fmt.Println(model)
+	return nil
+}
+
+func (cmd *SearchCommand) Run(ctx *Globals) (err error) {
+	client, err := ctx.Client()
+	if err != nil {
+		return err
+	}
+
+	// OTEL
+	parent, endSpan := otel.StartSpan(ctx.tracer, ctx.ctx, "SearchCommand")
+	defer func() { endSpan(err) }()
+
+	// List models in the repository
+	models, err := client.ListRemoteModels(parent, strings.TrimPrefix(cmd.Repo, "hf://"))
+	if err != nil {
+		return err
+	}
+
+	// Print
+	if ctx.Debug {
+		if b, err := json.MarshalIndent(models, "", "  "); err == nil {
+			fmt.Fprintln(os.Stderr, string(b))
+		}
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "PATH\tQUANT\tSIZE\tFILES\tURL")
+	for _, model := range models {
+		quant := "-"
+		if model.Quant != "" {
+			quant = model.Quant
+		}
+		files := 1
+		if model.Shards > 0 {
+			files = model.Shards
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\n", model.Path, quant, formatBytes(model.Size), files, model.URL)
+	}
+	_ = w.Flush()
+	return nil
+}
+
 func (cmd *GetModelCommand) Run(ctx *Globals) (err error) {
 	client, err := ctx.Client()
 	if err != nil {
@@ -188,16 +437,48 @@ func (cmd *GetModelCommand) Run(ctx *Globals) (err error) {
 	defer func() { endSpan(err) }()
 
 	// Get model
-	model, err := client.GetModel(parent, cmd.ID)
+	model, err := client.GetModel(parent, cmd.ID, httpclient.WithTensors(cmd.Tensors))
 	if err != nil {
 		return err
 	}
 
+	// Print tensor layout
+	if cmd.Tensors && model.Tensors != nil && !ctx.Debug {
+		printTensors(model.Tensors)
+		return nil
+	}
+
 	// Print
 	fmt.Println(model)
 	return nil
 }
 
+func printTensors(tensors *schema.ModelTensors) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TYPE\tTENSORS\tPARAMS\tSIZE\tSHARE")
+	for _, t := range tensors.Types {
+		share := "-"
+		if tensors.Size > 0 {
+			share = fmt.Sprintf("%.1f%%", float64(t.Size)*100.0/float64(tensors.Size))
+		}
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\n", t.Type, t.Count, formatParams(t.Params), formatBytes(t.Size), share)
+	}
+	fmt.Fprintf(w, "TOTAL\t%d\t%s\t%s\t\n", tensors.Count, formatParams(tensors.Params), formatBytes(tensors.Size))
+	_ = w.Flush()
+
+	fmt.Println()
+	w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tTYPE\tSHAPE\tSIZE")
+	for _, t := range tensors.Tensors {
+		shape := make([]string, len(t.Shape))
+		for i, d := range t.Shape {
+			shape[i] = fmt.Sprint(d)
+		}
+		fmt.Fprintf(w, "%s\t%s\t[%s]\t%s\n", t.Name, t.Type, strings.Join(shape, ", "), formatBytes(t.Size))
+	}
+	_ = w.Flush()
+}
+
 func (cmd *LoadModelCommand) Run(ctx *Globals) (err error) {
 	client, err := ctx.Client()
 	if err != nil {
@@ -274,3 +555,110 @@ func (cmd *DeleteModelCommand) Run(ctx *Globals) (err error) {
 	fmt.Printf("Model %s deleted successfully\n", cmd.ID)
 	return nil
 }
+
+func (cmd *PinModelCommand) Run(ctx *Globals) (err error) {
+	client, err := ctx.Client()
+	if err != nil {
+		return err
+	}
+
+	// OTEL
+	parent, endSpan := otel.StartSpan(ctx.tracer, ctx.ctx, "PinModelCommand")
+	defer func() { endSpan(err) }()
+
+	// Pin or unpin model
+	model, err := client.PinModel(parent, cmd.ID, !cmd.Unpin)
+	if err != nil {
+		return err
+	}
+
+	// Print result
+	fmt.Println(model)
+	return nil
+}
+
+func (cmd *LabelModelCommand) Run(ctx *Globals) (err error) {
+	var req schema.UpdateModelRequest
+	if len(cmd.Labels) > 0 && cmd.Clear {
+		return fmt.Errorf("cannot set and clear labels at the same time")
+	} else if len(cmd.Labels) > 0 || cmd.Clear {
+		labels := cmd.Labels
+		if labels == nil {
+			labels = []string{}
+		}
+		req.Labels = &labels
+	}
+	if cmd.Notes != nil {
+		req.Notes = cmd.Notes
+	}
+	if req.Labels == nil && req.Notes == nil {
+		return fmt.Errorf("nothing to update, set labels, --clear or --notes")
+	}
+
+	client, err := ctx.Client()
+	if err != nil {
+		return err
+	}
+
+	// OTEL
+	parent, endSpan := otel.StartSpan(ctx.tracer, ctx.ctx, "LabelModelCommand")
+	defer func() { endSpan(err) }()
+
+	// Update model
+	model, err := client.UpdateModel(parent, cmd.ID, req)
+	if err != nil {
+		return err
+	}
+
+	// Print result
+	fmt.Println(model)
+	return nil
+}
+
+func (cmd *GCCommand) Run(ctx *Globals) (err error) {
+	client, err := ctx.Client()
+	if err != nil {
+		return err
+	}
+
+	// OTEL
+	parent, endSpan := otel.StartSpan(ctx.tracer, ctx.ctx, "GCCommand")
+	defer func() { endSpan(err) }()
+
+	// Garbage collect
+	result, err := client.GarbageCollect(parent, cmd.DryRun)
+	if err != nil {
+		return err
+	}
+
+	// Print
+	if ctx.Debug {
+		if b, err := json.MarshalIndent(result, "", "  "); err == nil {
+			fmt.Fprintln(os.Stderr, string(b))
+		}
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "PATH\tREASON\tSIZE\tLAST_LOADED")
+	for _, file := range result.Removed {
+		lastLoaded := "-"
+		if !file.LastLoaded.IsZero() {
+			lastLoaded = file.LastLoaded.Local().Format(time.DateTime)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", file.Path, file.Reason, formatBytes(file.Size), lastLoaded)
+	}
+	_ = w.Flush()
+
+	// Print summary
+	verb := "Removed"
+	if result.DryRun {
+		verb = "Would remove"
+	}
+	quota := "no quota"
+	if result.Quota > 0 {
+		quota = "quota " + formatBytes(result.Quota)
+	}
+	fmt.Printf("%s %d files (%s), store uses %s (%s)\n", verb, len(result.Removed), formatBytes(result.Freed), formatBytes(result.Usage), quota)
+	return nil
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	// Packages
+	otel "github.com/mutablelogic/go-client/pkg/otel"
+	httpclient "github.com/mutablelogic/go-llama/pkg/llamacpp/httpclient"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+type RerankCommands struct {
+	Rerank RerankCommand `cmd:"" name:"rerank" help:"Sort documents by relevance to a query." group:"EMBEDDING"`
+}
+
+type RerankCommand struct {
+	Model     string   `arg:"" name:"model" help:"Model name or path"`
+	Query     string   `arg:"" name:"query" help:"Query to score documents against"`
+	Documents []string `arg:"" name:"documents" help:"Documents to score"`
+	TopN      int      `name:"top-n" help:"Number of documents to return (default: all)"`
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// COMMANDS
+
+func (cmd *RerankCommand) Run(ctx *Globals) (err error) {
+	client, err := ctx.Client()
+	if err != nil {
+		return err
+	}
+
+	// OTEL
+	parent, endSpan := otel.StartSpan(ctx.tracer, ctx.ctx, "RerankCommand")
+	defer func() { endSpan(err) }()
+
+	// Build options
+	opts := []httpclient.Opt{httpclient.WithReturnDocuments()}
+	if cmd.TopN > 0 {
+		opts = append(opts, httpclient.WithTopN(cmd.TopN))
+	}
+
+	// Rerank
+	result, err := client.Rerank(parent, cmd.Model, cmd.Query, cmd.Documents, opts...)
+	if err != nil {
+		return err
+	}
+
+	// Print results, most relevant first
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "INDEX\tSCORE\tDOCUMENT")
+	for _, r := range result.Results {
+		fmt.Fprintf(w, "%d\t%.4f\t%s\n", r.Index, r.Score, strings.ReplaceAll(r.Document, "\n", " "))
+	}
+	return w.Flush()
+}
@@ -12,11 +12,13 @@ import (
 	"os"
 	"path/filepath"
 	"sync"
+	"time"
 
 	// Packages
 	otel "github.com/mutablelogic/go-client/pkg/otel"
 	pkg "github.com/mutablelogic/go-llama/pkg/llamacpp"
 	httphandler "github.com/mutablelogic/go-llama/pkg/llamacpp/httphandler"
+	store "github.com/mutablelogic/go-llama/pkg/llamacpp/store"
 	version "github.com/mutablelogic/go-llama/pkg/version"
 	"github.com/mutablelogic/go-server/pkg/httpresponse"
 	httpserver "github.com/mutablelogic/go-server/pkg/httpserver"
@@ -31,7 +33,20 @@ type ServerCommands struct {
 }
 
 type RunServer struct {
-	Models string `name:"models" env:"GOLLAMA_DIR" help:"Models directory path" default:""`
+	Models      string        `name:"models" env:"GOLLAMA_DIR" help:"Models directory path" default:""`
+	Watch       time.Duration `name:"watch" env:"GOLLAMA_WATCH" help:"Interval for polling the models directory for changes (0 scans on every lookup)" default:"0"`
+	ReadOnly    []string      `name:"readonly-models" env:"GOLLAMA_READONLY_MODELS" help:"Read-only model directories, listed and loaded alongside the models directory"`
+	Quota       uint64        `name:"quota" env:"GOLLAMA_QUOTA" help:"Maximum size in bytes of the models directory, enforced by garbage collection (0 for no quota)" default:"0"`
+	UploadLimit int64         `name:"upload-limit" env:"GOLLAMA_UPLOAD_LIMIT" help:"Maximum size in bytes of an uploaded model file (0 for no limit)" default:"0"`
+
+	// Credentials for pulling models from private mirrors. Hugging Face
+	// tokens are read from HF_TOKEN or the Hugging Face CLI token file.
+	Credentials []string `name:"credential" env:"GOLLAMA_CREDENTIALS" help:"Credentials for pulling models, as host=token or host=user:password (host may be a pattern such as *.example.com)"`
+
+	// Embedding cache, in memory and optionally in a directory
+	EmbedCache      int    `name:"embed-cache" env:"GOLLAMA_EMBED_CACHE" help:"Number of embeddings cached in memory (0 for none)" default:"0"`
+	EmbedCacheDir   string `name:"embed-cache-dir" env:"GOLLAMA_EMBED_CACHE_DIR" help:"Directory for a persistent embedding cache" default:""`
+	EmbedCacheLimit uint64 `name:"embed-cache-limit" env:"GOLLAMA_EMBED_CACHE_LIMIT" help:"Maximum size in bytes of the embedding cache directory, removing the least recently used embeddings (0 for no limit)" default:"0"`
 
 	// TLS server options
 	TLS struct {
@@ -69,6 +84,28 @@ func (cmd *RunServer) Run(ctx *Globals) error {
 	if ctx.tracer != nil {
 		managerOpts = append(managerOpts, pkg.WithTracer(ctx.tracer))
 	}
+	if len(cmd.ReadOnly) > 0 {
+		managerOpts = append(managerOpts, pkg.WithReadOnlyStores(cmd.ReadOnly...))
+	}
+	if cmd.Quota > 0 {
+		managerOpts = append(managerOpts, pkg.WithQuota(cmd.Quota))
+	}
+	if cmd.UploadLimit > 0 {
+		managerOpts = append(managerOpts, pkg.WithUploadLimit(cmd.UploadLimit))
+	}
+	if cmd.EmbedCache != 0 || cmd.EmbedCacheDir != "" {
+		managerOpts = append(managerOpts, pkg.WithEmbeddingCache(cmd.EmbedCache, cmd.EmbedCacheDir))
+	}
+	if cmd.EmbedCacheLimit > 0 {
+		managerOpts = append(managerOpts, pkg.WithEmbeddingCacheLimit(cmd.EmbedCacheLimit))
+	}
+	for _, value := range cmd.Credentials {
+		credential, err := store.ParseCredential(value)
+		if err != nil {
+			return err
+		}
+		managerOpts = append(managerOpts, pkg.WithCredentials(credential))
+	}
 
 	// Create the whisper manager
 	manager, err := pkg.New(modelsPath, managerOpts...)
@@ -161,8 +198,23 @@ func (cmd *RunServer) Run(ctx *Globals) error {
 		}
 	}()
 
-	// Wait for goroutine to finish
+	// Watch the models directory
+	var watchErr error
+	if cmd.Watch > 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx.logger.With("interval", cmd.Watch.String()).Print(ctx.ctx, "watching models directory")
+			if err := manager.Watch(ctx.ctx, cmd.Watch); err != nil {
+				watchErr = fmt.Errorf("models watcher error: %w", err)
+				ctx.cancel()
+			}
+		}()
+	}
+
+	// Wait for goroutines to finish
 	wg.Wait()
+	result = errors.Join(result, watchErr)
 
 	// Terminated message
 	if result == nil {
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	// Packages
+	otel "github.com/mutablelogic/go-client/pkg/otel"
+	httpclient "github.com/mutablelogic/go-llama/pkg/llamacpp/httpclient"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+type SimilarityCommands struct {
+	Similarity SimilarityCommand `cmd:"" name:"similarity" help:"Compare, rank or cluster texts by the similarity of their embeddings." group:"EMBEDDING"`
+}
+
+type SimilarityCommand struct {
+	Model           string   `arg:"" name:"model" help:"Model name or path"`
+	Texts           []string `arg:"" name:"text" help:"Texts to compare" optional:""`
+	File            string   `name:"file" short:"f" help:"Read texts from a file, one per line, or - to read from standard input"`
+	Query           string   `name:"query" help:"Rank the texts by similarity to a query"`
+	TopK            int32    `name:"top-k" help:"Number of ranked texts (default: all)"`
+	Cluster         string   `name:"cluster" help:"Group the texts into clusters" enum:"none,kmeans,agglomerative" default:"none"`
+	Clusters        int      `name:"clusters" help:"Number of clusters"`
+	Threshold       *float64 `name:"threshold" help:"Least average similarity of merged clusters, for agglomerative clustering"`
+	Representatives bool     `name:"representatives" help:"Print only the text which represents each cluster, to remove near-duplicates"`
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// COMMANDS
+
+func (cmd *SimilarityCommand) Run(ctx *Globals) (err error) {
+	client, err := ctx.Client()
+	if err != nil {
+		return err
+	}
+
+	// OTEL
+	parent, endSpan := otel.StartSpan(ctx.tracer, ctx.ctx, "SimilarityCommand")
+	defer func() { endSpan(err) }()
+
+	// Read the texts
+	texts := cmd.Texts
+	if cmd.File != "" {
+		lines, err := readLines(cmd.File)
+		if err != nil {
+			return err
+		}
+		texts = append(texts, lines...)
+	}
+	if len(texts) == 0 {
+		return fmt.Errorf("no texts to compare")
+	}
+
+	// Build options
+	opts := []httpclient.Opt{}
+	if cmd.Query != "" {
+		opts = append(opts, httpclient.WithQuery(cmd.Query))
+	}
+	if cmd.TopK > 0 {
+		opts = append(opts, httpclient.WithTopK(cmd.TopK))
+	}
+	switch cmd.Cluster {
+	case "kmeans":
+		opts = append(opts, httpclient.WithKMeans(cmd.Clusters))
+	case "agglomerative":
+		opts = append(opts, httpclient.WithAgglomerative(cmd.Clusters))
+		if cmd.Threshold != nil {
+			opts = append(opts, httpclient.WithThreshold(*cmd.Threshold))
+		}
+	}
+
+	// Compare
+	result, err := client.Similarity(parent, cmd.Model, texts, opts...)
+	if err != nil {
+		return err
+	}
+
+	// Print the representative of each cluster, largest first
+	if cmd.Representatives {
+		if len(result.Clusters) == 0 {
+			return fmt.Errorf("--representatives requires --cluster")
+		}
+		for _, cluster := range result.Clusters {
+			fmt.Println(texts[cluster.Representative])
+		}
+		return nil
+	}
+
+	// Print results
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	if len(result.Results) > 0 {
+		fmt.Fprintln(w, "INDEX\tSCORE\tTEXT")
+		for _, r := range result.Results {
+			fmt.Fprintf(w, "%d\t%.4f\t%s\n", r.Index, r.Score, oneLine(texts[r.Index]))
+		}
+		if len(result.Clusters) > 0 {
+			fmt.Fprintln(w)
+		}
+	}
+	if len(result.Clusters) > 0 {
+		fmt.Fprintln(w, "CLUSTER\tCOHESION\tINDEX\tTEXT")
+		for i, cluster := range result.Clusters {
+			for _, index := range cluster.Members {
+				marker := ""
+				if index == cluster.Representative {
+					marker = "*"
+				}
+				fmt.Fprintf(w, "%d\t%.4f\t%d%s\t%s\n", i, cluster.Cohesion, index, marker, oneLine(texts[index]))
+			}
+		}
+	}
+	if len(result.Matrix) > 0 {
+		fmt.Fprint(w, "INDEX")
+		for j := range result.Matrix {
+			fmt.Fprintf(w, "\t%d", j)
+		}
+		fmt.Fprintln(w, "\tTEXT")
+		for i, row := range result.Matrix {
+			fmt.Fprintf(w, "%d", i)
+			for _, score := range row {
+				fmt.Fprintf(w, "\t%.3f", score)
+			}
+			fmt.Fprintf(w, "\t%s\n", oneLine(texts[i]))
+		}
+	}
+	return w.Flush()
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// readLines returns the lines of a file, or of standard input for -, which
+// are not blank
+func readLines(path string) ([]string, error) {
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1024*1024)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines, scanner.Err()
+}
+
+// oneLine returns text with line breaks replaced by spaces
+func oneLine(text string) string {
+	return strings.ReplaceAll(text, "\n", " ")
+}
@@ -2,6 +2,10 @@ package main
 
 import (
 	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
 
 	// Packages
 	otel "github.com/mutablelogic/go-client/pkg/otel"
@@ -17,6 +21,7 @@ type Token = int32
 type TokenizerCommands struct {
 	Tokenize   TokenizeCommand   `cmd:"" name:"tokenize" help:"Convert text to tokens." group:"TOKENIZER"`
 	Detokenize DetokenizeCommand `cmd:"" name:"detokenize" help:"Convert tokens to text." group:"TOKENIZER"`
+	Chunk      ChunkCommand      `cmd:"" name:"chunk" help:"Split text into chunks of tokens." group:"TOKENIZER"`
 }
 
 type TokenizeCommand struct {
@@ -33,6 +38,13 @@ type DetokenizeCommand struct {
 	UnparseSpecial *bool   `name:"unparse-special" help:"Render special tokens as text"`
 }
 
+type ChunkCommand struct {
+	Model   string `arg:"" name:"model" help:"Model name or path"`
+	Text    string `arg:"" name:"text" help:"Text to split, or - to read from standard input"`
+	Size    int    `name:"size" help:"Most tokens in a chunk (default: 512)"`
+	Overlap int    `name:"overlap" help:"Most tokens repeated from the end of the previous chunk"`
+}
+
 ///////////////////////////////////////////////////////////////////////////////
 // COMMANDS
 
@@ -95,3 +107,38 @@ func (cmd *DetokenizeCommand) Run(ctx *Globals) (err error) {
 	fmt.Println(result.Text)
 	return nil
 }
+
+func (cmd *ChunkCommand) Run(ctx *Globals) (err error) {
+	client, err := ctx.Client()
+	if err != nil {
+		return err
+	}
+
+	// OTEL
+	parent, endSpan := otel.StartSpan(ctx.tracer, ctx.ctx, "ChunkCommand")
+	defer func() { endSpan(err) }()
+
+	// Read the text
+	text := cmd.Text
+	if text == "-" {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return err
+		}
+		text = string(data)
+	}
+
+	// Chunk
+	result, err := client.Chunk(parent, cmd.Model, text, httpclient.WithChunkSize(cmd.Size, cmd.Overlap))
+	if err != nil {
+		return err
+	}
+
+	// Print result
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "INDEX\tSTART\tEND\tTOKENS\tTEXT")
+	for _, chunk := range result.Chunks {
+		fmt.Fprintf(w, "%d\t%d\t%d\t%d\t%s\n", chunk.Index, chunk.Start, chunk.End, chunk.Tokens, strings.ReplaceAll(chunk.Text, "\n", " "))
+	}
+	return w.Flush()
+}
@@ -27,6 +27,9 @@ const (
 	ErrNotFound
 	ErrModelNotLoaded
 	ErrNotEmbeddingModel
+	ErrTooLarge
+	ErrReadOnly
+	ErrNotSupported
 )
 
 ///////////////////////////////////////////////////////////////////////////////
@@ -64,6 +67,12 @@ func (e Error) String() string {
 		return "model not loaded"
 	case ErrNotEmbeddingModel:
 		return "model does not support embeddings"
+	case ErrTooLarge:
+		return "too large"
+	case ErrReadOnly:
+		return "read-only"
+	case ErrNotSupported:
+		return "not supported by model"
 	default:
 		return fmt.Sprintf("error(%d)", int(e))
 	}
@@ -9,6 +9,7 @@ import (
 
 	// Packages
 	otel "github.com/mutablelogic/go-client/pkg/otel"
+	llama "github.com/mutablelogic/go-llama"
 	schema "github.com/mutablelogic/go-llama/pkg/llamacpp/schema"
 	llamacpp "github.com/mutablelogic/go-llama/sys/llamacpp"
 	attribute "go.opentelemetry.io/otel/attribute"
@@ -30,6 +31,26 @@ func (l *Llama) Chat(ctx context.Context, req schema.ChatRequest, onChunk func(s
 		req.Stop = defaultStopSequences
 	}
 
+	if req.Retrieval != nil && req.Retrieval.Collection == "" {
+		return nil, llama.ErrInvalidArgument.With("retrieval collection is required")
+	}
+	if req.Retrieval != nil && req.Retrieval.TopK < 0 {
+		return nil, llama.ErrInvalidArgument.With("retrieval top_k cannot be negative")
+	}
+
+	// Refuse models without a chat template
+	if err := l.requireCapability(ctx, req.Model, schema.CapChat, llama.ErrNotSupported.Withf("model %q does not support chat", req.Model)); err != nil {
+		return nil, err
+	}
+
+	// Add the documents most similar to the last user message
+	var sources []schema.ChatSource
+	if req.Retrieval != nil {
+		if req.Messages, sources, err = l.retrieve(ctx, *req.Retrieval, req.Messages); err != nil {
+			return nil, err
+		}
+	}
+
 	// Create a context, and run the chat completion
 	err = l.WithContext(ctx, schema.ContextRequest{
 		LoadModelRequest: schema.LoadModelRequest{
@@ -111,6 +132,8 @@ func (l *Llama) Chat(ctx context.Context, req schema.ChatRequest, onChunk func(s
 				Role:    "assistant",
 				Content: cleanText,
 			},
+			Sources:      sources,
+			Citations:    citations(cleanText, sources),
 			Usage:        usage,
 			FinishReason: finishReason,
 		}
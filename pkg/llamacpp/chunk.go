@@ -0,0 +1,347 @@
+package llamacpp
+
+import (
+	"context"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	// Packages
+	otel "github.com/mutablelogic/go-client/pkg/otel"
+	llama "github.com/mutablelogic/go-llama"
+	schema "github.com/mutablelogic/go-llama/pkg/llamacpp/schema"
+	llamacpp "github.com/mutablelogic/go-llama/sys/llamacpp"
+	attribute "go.opentelemetry.io/otel/attribute"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// chunker splits a text into spans of at most size tokens
+type chunker struct {
+	text  string
+	size  int
+	count func(string) (int, error)
+}
+
+// textSpan is a span of the text, without leading and trailing whitespace,
+// with byte offsets
+type textSpan struct {
+	start, end int
+	tokens     int
+}
+
+// runeCounter converts increasing byte offsets of a text into character
+// offsets
+type runeCounter struct {
+	text  string
+	bytes int
+	runes int
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+var (
+	reParagraph = regexp.MustCompile(`\n[ \t]*\n\s*`)
+	reLine      = regexp.MustCompile(`\n`)
+	reSentence  = regexp.MustCompile(`(?:[.!?…]+["'”’)\]]*\s+|[。！？]+)`)
+	reWord      = regexp.MustCompile(`\s+`)
+	reHeading   = regexp.MustCompile(`^ {0,3}#{1,6}(?:[ \t]|$)`)
+	reFence     = regexp.MustCompile("^ {0,3}(?:```|~~~)")
+
+	// chunkBoundaries return the offsets where a text can be split, with the
+	// most preferred boundaries first
+	chunkBoundaries = []func(string) []int{
+		headingBoundaries,
+		func(s string) []int { return boundaries(reParagraph, s) },
+		func(s string) []int { return boundaries(reLine, s) },
+		func(s string) []int { return boundaries(reSentence, s) },
+		func(s string) []int { return boundaries(reWord, s) },
+	}
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Chunk splits text into chunks of at most a number of tokens of a model,
+// at markdown headings, paragraphs, sentences or words where possible.
+// Loads the model if not already cached.
+func (l *Llama) Chunk(ctx context.Context, req schema.ChunkRequest) (result *schema.ChunkResponse, err error) {
+	ctx, endSpan := otel.StartSpan(l.tracer, ctx, schema.SpanName("Chunk"),
+		attribute.String("request", req.String()),
+	)
+	defer func() { endSpan(err) }()
+
+	// Check the request
+	size := req.Size
+	if size == 0 {
+		size = schema.DefaultChunkSize
+	}
+	if size < 0 || req.Overlap < 0 {
+		return nil, llama.ErrInvalidArgument.With("chunk size and overlap cannot be negative")
+	} else if req.Overlap >= size {
+		return nil, llama.ErrInvalidArgument.Withf("chunk overlap %d must be less than the chunk size %d", req.Overlap, size)
+	}
+
+	err = l.WithModel(ctx, schema.LoadModelRequest{Name: req.Model}, func(ctx context.Context, task *Task) error {
+		// Lock the model - tokenization is not thread-safe
+		task.CachedModel().Lock()
+		defer task.CachedModel().Unlock()
+
+		// Count tokens without special tokens
+		opts := llamacpp.DefaultTokenizeOptions()
+		opts.AddSpecial = false
+		count := func(text string) (int, error) {
+			if err := ctx.Err(); err != nil {
+				return 0, err
+			}
+			tokens, err := task.Model().Tokenize(text, opts)
+			return len(tokens), err
+		}
+
+		// Split the text
+		chunks, err := chunkText(req.Text, size, req.Overlap, count)
+		if err != nil {
+			return err
+		}
+		tokens, err := count(req.Text)
+		if err != nil {
+			return err
+		}
+
+		result = &schema.ChunkResponse{
+			Model:  req.Model,
+			Chunks: chunks,
+			Usage:  schema.Usage{InputTokens: tokens},
+		}
+		return nil
+	})
+	return
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// chunkText splits text into chunks of at most size tokens, as counted by
+// the count function. Each chunk after the first starts with at most overlap
+// tokens from the end of the previous chunk, at a word boundary.
+func chunkText(text string, size, overlap int, count func(string) (int, error)) ([]schema.TextChunk, error) {
+	c := &chunker{text: text, size: size - overlap, count: count}
+	spans, err := c.split(0, len(text), 0)
+	if err != nil {
+		return nil, err
+	}
+
+	// Extend each chunk back into the previous chunk
+	if overlap > 0 {
+		c.size = size
+		for i := len(spans) - 1; i > 0; i-- {
+			if spans[i], err = c.overlap(spans[i-1], spans[i], overlap); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// Return chunks with character offsets
+	result := make([]schema.TextChunk, len(spans))
+	starts, ends := runeCounter{text: text}, runeCounter{text: text}
+	for i, span := range spans {
+		result[i] = schema.TextChunk{
+			Index:  i,
+			Start:  starts.offset(span.start),
+			End:    ends.offset(span.end),
+			Tokens: span.tokens,
+			Text:   text[span.start:span.end],
+		}
+	}
+	return result, nil
+}
+
+// span returns the span between byte offsets, without leading and trailing
+// whitespace, and its tokens
+func (c *chunker) span(start, end int) (textSpan, error) {
+	s := c.text[start:end]
+	trimmed := strings.TrimLeftFunc(s, unicode.IsSpace)
+	start += len(s) - len(trimmed)
+	end = start + len(strings.TrimRightFunc(trimmed, unicode.IsSpace))
+	if start == end {
+		return textSpan{start: start, end: end}, nil
+	}
+	tokens, err := c.count(c.text[start:end])
+	if err != nil {
+		return textSpan{}, err
+	}
+	return textSpan{start: start, end: end, tokens: tokens}, nil
+}
+
+// split returns the spans between byte offsets, which are split at the
+// boundaries of a level when there are too many tokens, and then merged
+// while they fit
+func (c *chunker) split(start, end, level int) ([]textSpan, error) {
+	span, err := c.span(start, end)
+	if err != nil {
+		return nil, err
+	} else if span.start == span.end {
+		return nil, nil
+	} else if span.tokens <= c.size {
+		return []textSpan{span}, nil
+	} else if level == len(chunkBoundaries) {
+		return c.splitRunes(span)
+	}
+
+	// Split at the boundaries of this level, or try the next level
+	cuts := chunkBoundaries[level](c.text[span.start:span.end])
+	if len(cuts) == 0 {
+		return c.split(span.start, span.end, level+1)
+	}
+	var spans []textSpan
+	prev := span.start
+	for _, cut := range append(cuts, span.end-span.start) {
+		parts, err := c.split(prev, span.start+cut, level+1)
+		if err != nil {
+			return nil, err
+		}
+		spans = append(spans, parts...)
+		prev = span.start + cut
+	}
+	return c.merge(spans)
+}
+
+// merge joins adjacent spans while the tokens fit
+func (c *chunker) merge(spans []textSpan) ([]textSpan, error) {
+	var result []textSpan
+	for _, span := range spans {
+		if n := len(result); n > 0 && result[n-1].tokens+span.tokens <= c.size {
+			merged, err := c.span(result[n-1].start, span.end)
+			if err != nil {
+				return nil, err
+			}
+			if merged.tokens <= c.size {
+				result[n-1] = merged
+				continue
+			}
+		}
+		result = append(result, span)
+	}
+	return result, nil
+}
+
+// splitRunes splits a span without boundaries into the longest runs of
+// characters which fit. Each run has at least one character.
+func (c *chunker) splitRunes(span textSpan) ([]textSpan, error) {
+	var result []textSpan
+	for start := span.start; start < span.end; {
+		// Find the offset of each character after the first
+		var offsets []int
+		for i := range c.text[start:span.end] {
+			if i > 0 {
+				offsets = append(offsets, start+i)
+			}
+		}
+		offsets = append(offsets, span.end)
+
+		// Find the most characters which fit
+		var err error
+		n := sort.Search(len(offsets), func(i int) bool {
+			if err != nil {
+				return true
+			}
+			var part textSpan
+			part, err = c.span(start, offsets[i])
+			return part.tokens > c.size
+		})
+		if err != nil {
+			return nil, err
+		}
+		end := offsets[max(n-1, 0)]
+		part, err := c.span(start, end)
+		if err != nil {
+			return nil, err
+		}
+		if part.start < part.end {
+			result = append(result, part)
+		}
+		start = end
+	}
+	return result, nil
+}
+
+// overlap returns a span which starts at the earliest word in the previous
+// span, with at most overlap tokens from the previous span, and at most
+// size tokens in all
+func (c *chunker) overlap(prev, span textSpan, overlap int) (textSpan, error) {
+	// Find the start of each word in the previous span
+	var words []int
+	for _, cut := range append([]int{0}, boundaries(reWord, c.text[prev.start:prev.end])...) {
+		words = append(words, prev.start+cut)
+	}
+
+	// Find the earliest word with few enough tokens to the end of the span
+	var err error
+	i := sort.Search(len(words), func(i int) bool {
+		if err != nil {
+			return true
+		}
+		var part textSpan
+		part, err = c.span(words[i], span.start)
+		return part.tokens <= overlap
+	})
+	if err != nil {
+		return textSpan{}, err
+	}
+
+	// Move forward a word at a time until the chunk fits
+	for ; i < len(words); i++ {
+		extended, err := c.span(words[i], span.end)
+		if err != nil {
+			return textSpan{}, err
+		}
+		if extended.tokens <= c.size {
+			return extended, nil
+		}
+	}
+	return span, nil
+}
+
+// boundaries returns the offsets after each match of a regular expression,
+// except at the start and end of the text
+func boundaries(re *regexp.Regexp, s string) []int {
+	var result []int
+	for _, match := range re.FindAllStringIndex(s, -1) {
+		if match[1] > 0 && match[1] < len(s) {
+			result = append(result, match[1])
+		}
+	}
+	return result
+}
+
+// headingBoundaries returns the offsets of markdown headings, except for
+// lines in fenced code blocks and at the start of the text
+func headingBoundaries(s string) []int {
+	var result []int
+	fenced := false
+	for offset := 0; offset < len(s); {
+		line, _, _ := strings.Cut(s[offset:], "\n")
+		if reFence.MatchString(line) {
+			fenced = !fenced
+		} else if !fenced && offset > 0 && reHeading.MatchString(line) {
+			result = append(result, offset)
+		}
+		offset += len(line) + 1
+	}
+	return result
+}
+
+// offset returns the character offset of a byte offset, which is counted
+// from the previous offset when it is not earlier
+func (r *runeCounter) offset(b int) int {
+	if b < r.bytes {
+		r.bytes, r.runes = 0, 0
+	}
+	r.runes += utf8.RuneCountInString(r.text[r.bytes:b])
+	r.bytes = b
+	return r.runes
+}
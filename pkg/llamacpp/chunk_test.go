@@ -0,0 +1,103 @@
+package llamacpp
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countWords counts each word as a token
+func countWords(text string) (int, error) {
+	return len(strings.Fields(text)), nil
+}
+
+// countRunes counts each character as a token
+func countRunes(text string) (int, error) {
+	return utf8.RuneCountInString(text), nil
+}
+
+func chunkTexts(t *testing.T, text string, size, overlap int, count func(string) (int, error)) []string {
+	t.Helper()
+	chunks, err := chunkText(text, size, overlap, count)
+	require.NoError(t, err)
+	result := make([]string, len(chunks))
+	for i, chunk := range chunks {
+		assert.Equal(t, i, chunk.Index)
+		assert.LessOrEqual(t, chunk.Tokens, size, chunk.Text)
+		assert.Equal(t, chunk.Text, string([]rune(text)[chunk.Start:chunk.End]))
+		result[i] = chunk.Text
+	}
+	return result
+}
+
+func TestChunkText_Short(t *testing.T) {
+	assert := assert.New(t)
+	chunks, err := chunkText("  Hello, world!\n", 10, 0, countWords)
+	assert.NoError(err)
+	if assert.Len(chunks, 1) {
+		assert.Equal(2, chunks[0].Start)
+		assert.Equal(15, chunks[0].End)
+		assert.Equal(2, chunks[0].Tokens)
+		assert.Equal("Hello, world!", chunks[0].Text)
+	}
+
+	chunks, err = chunkText(" \n ", 10, 0, countWords)
+	assert.NoError(err)
+	assert.Empty(chunks)
+}
+
+func TestChunkText_Boundaries(t *testing.T) {
+	assert := assert.New(t)
+
+	// Paragraphs are kept together while they fit
+	assert.Equal([]string{
+		"one two three\n\nfour five six",
+		"seven eight nine",
+	}, chunkTexts(t, "one two three\n\nfour five six\n\nseven eight nine", 6, 0, countWords))
+
+	// Sections start at headings
+	assert.Equal([]string{
+		"# A\nx y z",
+		"## B\nu v w",
+	}, chunkTexts(t, "# A\nx y z\n## B\nu v w", 5, 0, countWords))
+
+	// Long paragraphs are split at sentences, then words
+	assert.Equal([]string{
+		"One two. Three four.",
+		"Five six seven eight",
+		"nine.",
+	}, chunkTexts(t, "One two. Three four. Five six seven eight nine.", 4, 0, countWords))
+
+	// Words which are too long are split at characters
+	assert.Equal([]string{"abc", "def", "g"}, chunkTexts(t, "abcdefg", 3, 0, countRunes))
+	assert.Equal([]string{"héllo", "wörld"}, chunkTexts(t, "héllo wörld", 5, 0, countRunes))
+}
+
+func TestChunkText_Overlap(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal([]string{
+		"a b",
+		"a b c d",
+		"c d e f",
+		"e f g h",
+	}, chunkTexts(t, "a b c d e f g h", 4, 2, countWords))
+}
+
+func TestChunkText_Error(t *testing.T) {
+	assert := assert.New(t)
+	err := errors.New("tokenize")
+	_, got := chunkText("one two three", 2, 0, func(string) (int, error) {
+		return 0, err
+	})
+	assert.ErrorIs(got, err)
+}
+
+func TestHeadingBoundaries(t *testing.T) {
+	assert := assert.New(t)
+	text := "# Title\ntext\n```\n# comment\n```\n## Section\n#hashtag\n"
+	assert.Equal([]int{strings.Index(text, "## Section")}, headingBoundaries(text))
+}
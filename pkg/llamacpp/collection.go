@@ -0,0 +1,174 @@
+package llamacpp
+
+import (
+	"context"
+	"errors"
+
+	// Packages
+	otel "github.com/mutablelogic/go-client/pkg/otel"
+	llama "github.com/mutablelogic/go-llama"
+	schema "github.com/mutablelogic/go-llama/pkg/llamacpp/schema"
+	attribute "go.opentelemetry.io/otel/attribute"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	// collectionsDir is the directory of collections in the models directory.
+	// It is hidden, so it is not scanned for models.
+	collectionsDir = ".collections"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// ListCollections returns all collections, sorted by name.
+func (l *Llama) ListCollections(ctx context.Context) (result []schema.Collection, err error) {
+	_, endSpan := otel.StartSpan(l.tracer, ctx, schema.SpanName("ListCollections"))
+	defer func() { endSpan(err) }()
+
+	return l.collections.List()
+}
+
+// GetCollection returns a collection by name.
+func (l *Llama) GetCollection(ctx context.Context, name string) (result *schema.Collection, err error) {
+	_, endSpan := otel.StartSpan(l.tracer, ctx, schema.SpanName("GetCollection"),
+		attribute.String("name", name),
+	)
+	defer func() { endSpan(err) }()
+
+	c, err := l.collections.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	meta := c.Meta()
+	return &meta, nil
+}
+
+// DeleteCollection deletes a collection and all its documents.
+func (l *Llama) DeleteCollection(ctx context.Context, name string) (err error) {
+	_, endSpan := otel.StartSpan(l.tracer, ctx, schema.SpanName("DeleteCollection"),
+		attribute.String("name", name),
+	)
+	defer func() { endSpan(err) }()
+
+	return l.collections.Delete(name)
+}
+
+// AddDocuments embeds documents and stores them in a collection, which is
+// created with the model in the request if it does not exist. Documents
+// replace stored documents with the same identifier.
+func (l *Llama) AddDocuments(ctx context.Context, name string, req schema.CollectionAddRequest) (result *schema.CollectionAddResponse, err error) {
+	ctx, endSpan := otel.StartSpan(l.tracer, ctx, schema.SpanName("AddDocuments"),
+		attribute.String("name", name),
+		attribute.String("request", req.String()),
+	)
+	defer func() { endSpan(err) }()
+
+	if len(req.Documents) == 0 {
+		return nil, llama.ErrInvalidArgument.With("documents are required")
+	}
+	if !req.Index.Valid() {
+		return nil, llama.ErrInvalidArgument.Withf("invalid index %q", req.Index)
+	}
+	texts := make([]string, len(req.Documents))
+	for i, doc := range req.Documents {
+		if doc.Text == "" {
+			return nil, llama.ErrInvalidArgument.Withf("document %d has no text", i)
+		}
+		texts[i] = doc.Text
+	}
+
+	// Embed with the model of the collection, or the model in the request
+	// when the collection is created
+	c, err := l.collections.Get(name)
+	model := req.Model
+	if errors.Is(err, llama.ErrNotFound) {
+		if model == "" {
+			return nil, llama.ErrInvalidArgument.Withf("model is required to create collection %q", name)
+		}
+	} else if err != nil {
+		return nil, err
+	} else if model == "" {
+		model = c.Meta().Model
+	} else if model != c.Meta().Model {
+		return nil, llama.ErrInvalidArgument.Withf("collection %q uses model %q", name, c.Meta().Model)
+	}
+	embeddings, err := l.Embed(ctx, schema.EmbedRequest{
+		Model: model,
+		Input: texts,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if c == nil {
+		if c, err = l.collections.GetOrCreate(name, model, embeddings.Dimension, req.Index); err != nil {
+			return nil, err
+		}
+
+		// The collection may have been created by another request meanwhile,
+		// with a different model
+		if model != c.Meta().Model {
+			return nil, llama.ErrInvalidArgument.Withf("collection %q uses model %q", name, c.Meta().Model)
+		}
+	}
+
+	// Store the documents
+	ids, err := c.Add(req.Documents, embeddings.Embeddings)
+	if err != nil {
+		return nil, err
+	}
+	return &schema.CollectionAddResponse{
+		Collection: c.Meta(),
+		IDs:        ids,
+		Usage:      embeddings.Usage,
+	}, nil
+}
+
+// SearchCollection returns the documents in a collection which are most
+// similar to the query, and which have the metadata values in the filter.
+func (l *Llama) SearchCollection(ctx context.Context, name string, req schema.CollectionSearchRequest) (result *schema.CollectionSearchResponse, err error) {
+	ctx, endSpan := otel.StartSpan(l.tracer, ctx, schema.SpanName("SearchCollection"),
+		attribute.String("name", name),
+		attribute.String("request", req.String()),
+	)
+	defer func() { endSpan(err) }()
+
+	if req.Query == "" {
+		return nil, llama.ErrInvalidArgument.With("query is required")
+	}
+	if req.TopK < 0 {
+		return nil, llama.ErrInvalidArgument.With("top_k cannot be negative")
+	}
+	topK := req.TopK
+	if topK == 0 {
+		topK = schema.DefaultCollectionTopK
+	}
+
+	// Embed the query with the model of the collection
+	c, err := l.collections.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	meta := c.Meta()
+	embeddings, err := l.Embed(ctx, schema.EmbedRequest{
+		Model: meta.Model,
+		Input: []string{req.Query},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Search the collection
+	results, err := c.Search(embeddings.Embeddings[0], topK, req.Filter, req.MinScore)
+	if err != nil {
+		return nil, err
+	}
+	return &schema.CollectionSearchResponse{
+		Collection: meta.Name,
+		Model:      meta.Model,
+		Results:    results,
+		Usage:      embeddings.Usage,
+	}, nil
+}
@@ -0,0 +1,360 @@
+package collection
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	// Packages
+	llama "github.com/mutablelogic/go-llama"
+	schema "github.com/mutablelogic/go-llama/pkg/llamacpp/schema"
+	llamacpp "github.com/mutablelogic/go-llama/sys/llamacpp"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// Collection is a named set of documents and their embeddings, which are
+// appended to a file in the directory of the collection
+type Collection struct {
+	sync.RWMutex
+	path  string            // Directory of the collection
+	meta  schema.Collection // Collection metadata
+	docs  []*document       // Documents, including replaced documents
+	ids   map[string]int    // Index in docs of the current document with each identifier
+	index *hnsw             // Search index, or nil to compare with every document
+}
+
+// document is a stored document and its embedding
+type document struct {
+	schema.CollectionDocument
+	embedding []float32
+	deleted   bool
+}
+
+// record is a document as written to the documents file
+type record struct {
+	schema.CollectionDocument
+	Embedding string `json:"embedding"` // Little-endian float32 values, base64 encoded
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	metaFilename      = "collection.json"
+	documentsFilename = "documents.jsonl"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// LIFECYCLE
+
+// create makes the directory and metadata file of a new collection
+func create(path string, meta schema.Collection) (*Collection, error) {
+	if err := os.MkdirAll(path, 0755); err != nil {
+		return nil, err
+	}
+	c := &Collection{
+		path: path,
+		meta: meta,
+		ids:  make(map[string]int),
+	}
+	if meta.Index == schema.IndexHNSW {
+		c.index = newHNSW()
+	}
+	if err := c.saveMeta(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// load reads a collection from its directory, and builds the search index.
+// When most of the stored records were replaced, the documents file is
+// written again without them.
+func load(path string) (*Collection, error) {
+	c := &Collection{
+		path: path,
+		ids:  make(map[string]int),
+	}
+	if data, err := os.ReadFile(filepath.Join(path, metaFilename)); errors.Is(err, fs.ErrNotExist) {
+		return nil, llama.ErrNotFound.Withf("collection %q", filepath.Base(path))
+	} else if err != nil {
+		return nil, err
+	} else if err := json.Unmarshal(data, &c.meta); err != nil {
+		return nil, err
+	}
+	if c.meta.Index == schema.IndexHNSW {
+		c.index = newHNSW()
+	}
+
+	// Read the records, where a later record replaces an earlier one
+	var records []record
+	f, err := os.Open(filepath.Join(path, documentsFilename))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	} else if err == nil {
+		defer f.Close()
+		scanner := bufio.NewScanner(f)
+		scanner.Buffer(nil, 64<<20)
+		for scanner.Scan() {
+			var r record
+			if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+				return nil, llama.ErrInvalidArgument.Withf("collection %q: %v", c.meta.Name, err)
+			}
+			records = append(records, r)
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+	}
+	latest := make(map[string]int, len(records))
+	for i, r := range records {
+		latest[r.ID] = i
+	}
+
+	// Add the current documents
+	for i, r := range records {
+		if latest[r.ID] != i {
+			continue
+		}
+		vectors, err := schema.DecodeEmbeddings(r.Embedding, schema.EncodingBase64, nil, 1, c.meta.Dimension)
+		if err != nil {
+			return nil, llama.ErrInvalidArgument.Withf("collection %q: document %q: %v", c.meta.Name, r.ID, err)
+		}
+		c.add(r.CollectionDocument, vectors[0])
+	}
+
+	// Compact the documents file
+	if len(records) > 2*len(c.ids) {
+		if err := c.compact(); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Meta returns the collection metadata
+func (c *Collection) Meta() schema.Collection {
+	c.RLock()
+	defer c.RUnlock()
+	meta := c.meta
+	meta.Documents = len(c.ids)
+	return meta
+}
+
+// Add stores documents with their embeddings, which must have the dimension
+// of the collection, and returns the identifier of each document. Documents
+// without an identifier are identified by the checksum of their text, and
+// documents replace any stored document with the same identifier.
+func (c *Collection) Add(docs []schema.CollectionDocument, embeddings [][]float32) ([]string, error) {
+	if len(docs) != len(embeddings) {
+		return nil, llama.ErrInvalidArgument.Withf("%d documents but %d embeddings", len(docs), len(embeddings))
+	}
+
+	// Make the records
+	docs = slices.Clone(docs)
+	ids := make([]string, len(docs))
+	records := make([]byte, 0, len(docs)*c.meta.Dimension*6)
+	for i, doc := range docs {
+		if len(embeddings[i]) != c.meta.Dimension {
+			return nil, llama.ErrInvalidArgument.Withf("embedding has dimension %d, but collection %q has dimension %d", len(embeddings[i]), c.meta.Name, c.meta.Dimension)
+		}
+		if doc.ID == "" {
+			sum := sha256.Sum256([]byte(doc.Text))
+			doc.ID = hex.EncodeToString(sum[:8])
+		}
+		metadata, err := normalize(doc.Metadata)
+		if err != nil {
+			return nil, llama.ErrInvalidArgument.Withf("document %q: %v", doc.ID, err)
+		}
+		doc.Metadata = metadata
+		docs[i], ids[i] = doc, doc.ID
+
+		data, _, err := schema.EncodeEmbeddings([][]float32{embeddings[i]}, schema.EncodingBase64)
+		if err != nil {
+			return nil, err
+		}
+		line, err := json.Marshal(record{CollectionDocument: doc, Embedding: data})
+		if err != nil {
+			return nil, err
+		}
+		records = append(append(records, line...), '\n')
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	// Append the records, then add the documents
+	f, err := os.OpenFile(filepath.Join(c.path, documentsFilename), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := f.Write(records); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if err := f.Close(); err != nil {
+		return nil, err
+	}
+	for i, doc := range docs {
+		c.add(doc, embeddings[i])
+	}
+
+	// Update the metadata
+	c.meta.Documents = len(c.ids)
+	c.meta.Modified = time.Now()
+	if err := c.saveMeta(); err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// Search returns up to k documents which are most similar to the query and
+// have the metadata values in the filter, most similar first. Collections
+// with an index are searched approximately, unless there is a filter, in
+// which case every matching document is compared with the query.
+func (c *Collection) Search(query []float32, k int, filter map[string]any, minScore float64) ([]schema.CollectionResult, error) {
+	if len(query) != c.meta.Dimension {
+		return nil, llama.ErrInvalidArgument.Withf("query has dimension %d, but collection %q has dimension %d", len(query), c.meta.Name, c.meta.Dimension)
+	}
+	filter, err := normalize(filter)
+	if err != nil {
+		return nil, llama.ErrInvalidArgument.Withf("filter: %v", err)
+	}
+
+	c.RLock()
+	defer c.RUnlock()
+
+	// Find the most similar documents
+	var found []scored
+	if c.index != nil && len(filter) == 0 {
+		found = c.index.search(query, k)
+	} else {
+		found = c.scan(query, k, filter)
+	}
+
+	// Return documents with at least the minimum score
+	results := make([]schema.CollectionResult, 0, len(found))
+	for _, node := range found {
+		if node.score < minScore {
+			break
+		}
+		results = append(results, schema.CollectionResult{
+			CollectionDocument: c.docs[node.id].CollectionDocument,
+			Score:              node.score,
+		})
+	}
+	return results, nil
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// add adds a document in memory, replacing any document with the same
+// identifier
+func (c *Collection) add(doc schema.CollectionDocument, embedding []float32) {
+	if i, exists := c.ids[doc.ID]; exists {
+		c.docs[i].deleted = true
+		if c.index != nil {
+			c.index.delete(i)
+		}
+	}
+	c.ids[doc.ID] = len(c.docs)
+	c.docs = append(c.docs, &document{CollectionDocument: doc, embedding: embedding})
+	if c.index != nil {
+		c.index.insert(embedding)
+	}
+}
+
+// scan compares the query with every document which matches the filter, and
+// returns the k most similar
+func (c *Collection) scan(query []float32, k int, filter map[string]any) []scored {
+	matches := make([]int, 0, len(c.ids))
+	batch := llamacpp.BatchEmbeddings{
+		Embeddings: [][]float32{query},
+		Dimension:  c.meta.Dimension,
+	}
+	for i, doc := range c.docs {
+		if doc.deleted || !match(doc.Metadata, filter) {
+			continue
+		}
+		matches = append(matches, i)
+		batch.Embeddings = append(batch.Embeddings, doc.embedding)
+	}
+
+	// The query is the first embedding in the batch
+	similar := batch.MostSimilar(0, k)
+	result := make([]scored, len(similar))
+	for i, j := range similar {
+		result[i] = scored{matches[j-1], llamacpp.CosineSimilarity(query, batch.Embeddings[j])}
+	}
+	return result
+}
+
+// compact writes the documents file again, with only the current documents
+func (c *Collection) compact() error {
+	tmp, err := os.CreateTemp(c.path, "."+documentsFilename+"-*.tmp")
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(tmp)
+	for _, doc := range c.docs {
+		if doc.deleted {
+			continue
+		}
+		data, _, err := schema.EncodeEmbeddings([][]float32{doc.embedding}, schema.EncodingBase64)
+		if err == nil {
+			var line []byte
+			if line, err = json.Marshal(record{CollectionDocument: doc.CollectionDocument, Embedding: data}); err == nil {
+				_, err = w.Write(append(line, '\n'))
+			}
+		}
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+			return err
+		}
+	}
+	err = errors.Join(w.Flush(), tmp.Close())
+	if err == nil {
+		err = os.Rename(tmp.Name(), filepath.Join(c.path, documentsFilename))
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
+
+// saveMeta writes the metadata file. The file is written to a temporary file
+// and renamed, so a partially written file is never read.
+func (c *Collection) saveMeta() error {
+	data, err := json.MarshalIndent(c.meta, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(c.path, "."+metaFilename+"-*.tmp")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	if err = errors.Join(err, tmp.Close()); err == nil {
+		err = os.Rename(tmp.Name(), filepath.Join(c.path, metaFilename))
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
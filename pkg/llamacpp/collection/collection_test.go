@@ -0,0 +1,200 @@
+package collection
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	llama "github.com/mutablelogic/go-llama"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mutablelogic/go-llama/pkg/llamacpp/schema"
+)
+
+func TestCollections_GetOrCreate(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	collections := New(filepath.Join(t.TempDir(), ".collections"))
+
+	// Collections which do not exist are not found
+	list, err := collections.List()
+	require.NoError(err)
+	assert.Empty(list)
+	_, err = collections.Get("docs")
+	assert.ErrorIs(err, llama.ErrNotFound)
+
+	// Names are checked
+	for _, name := range []string{"", ".docs", "a/b", "..", strings.Repeat("a", 65)} {
+		_, err = collections.GetOrCreate(name, "model", 3, "")
+		assert.ErrorIs(err, llama.ErrInvalidArgument, name)
+	}
+	_, err = collections.GetOrCreate("docs", "model", 3, "lsh")
+	assert.ErrorIs(err, llama.ErrInvalidArgument)
+
+	// A created collection is returned again
+	c, err := collections.GetOrCreate("docs", "model", 3, "")
+	require.NoError(err)
+	assert.Equal(schema.IndexFlat, c.Meta().Index)
+	other, err := collections.GetOrCreate("docs", "other", 4, schema.IndexHNSW)
+	require.NoError(err)
+	assert.Same(c, other)
+
+	list, err = collections.List()
+	require.NoError(err)
+	require.Len(list, 1)
+	assert.Equal("docs", list[0].Name)
+	assert.Equal("model", list[0].Model)
+	assert.Equal(3, list[0].Dimension)
+
+	// Deleted collections are removed
+	require.NoError(collections.Delete("docs"))
+	_, err = collections.Get("docs")
+	assert.ErrorIs(err, llama.ErrNotFound)
+	assert.ErrorIs(collections.Delete("docs"), llama.ErrNotFound)
+}
+
+func TestCollection_AddSearch(t *testing.T) {
+	for _, index := range []schema.CollectionIndex{schema.IndexFlat, schema.IndexHNSW} {
+		t.Run(string(index), func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+			collections := New(t.TempDir())
+
+			c, err := collections.GetOrCreate("docs", "model", 2, index)
+			require.NoError(err)
+			ids, err := c.Add([]schema.CollectionDocument{
+				{ID: "east", Text: "east", Metadata: map[string]any{"kind": "point", "n": 1}},
+				{ID: "north", Text: "north", Metadata: map[string]any{"kind": "point", "n": 2}},
+				{Text: "north east", Metadata: map[string]any{"kind": "line"}},
+			}, [][]float32{{1, 0}, {0, 1}, {0.7071, 0.7071}})
+			require.NoError(err)
+			require.Len(ids, 3)
+			assert.Equal([]string{"east", "north"}, ids[:2])
+			assert.Len(ids[2], 16)
+			assert.Equal(3, c.Meta().Documents)
+
+			// Results are the most similar first
+			results, err := c.Search([]float32{1, 0.1}, 2, nil, 0)
+			require.NoError(err)
+			require.Len(results, 2)
+			assert.Equal("east", results[0].ID)
+			assert.Equal(ids[2], results[1].ID)
+			assert.InDelta(0.995, results[0].Score, 0.001)
+
+			// Filters match values, and any value in a list
+			results, err = c.Search([]float32{1, 0.1}, 10, map[string]any{"kind": "point"}, 0)
+			require.NoError(err)
+			require.Len(results, 2)
+			assert.Equal("east", results[0].ID)
+			assert.Equal("north", results[1].ID)
+			results, err = c.Search([]float32{1, 0.1}, 10, map[string]any{"n": []int{2, 3}}, 0)
+			require.NoError(err)
+			require.Len(results, 1)
+			assert.Equal("north", results[0].ID)
+			results, err = c.Search([]float32{1, 0.1}, 10, map[string]any{"missing": true}, 0)
+			require.NoError(err)
+			assert.Empty(results)
+
+			// Results below the minimum score are not returned
+			results, err = c.Search([]float32{1, 0.1}, 10, nil, 0.5)
+			require.NoError(err)
+			assert.Len(results, 2)
+
+			// Queries must have the dimension of the collection
+			_, err = c.Search([]float32{1, 0, 0}, 10, nil, 0)
+			assert.ErrorIs(err, llama.ErrInvalidArgument)
+			_, err = c.Add([]schema.CollectionDocument{{Text: "up"}}, [][]float32{{1, 0, 0}})
+			assert.ErrorIs(err, llama.ErrInvalidArgument)
+		})
+	}
+}
+
+func TestCollection_Persisted(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	path := t.TempDir()
+
+	c, err := New(path).GetOrCreate("docs", "model", 2, schema.IndexHNSW)
+	require.NoError(err)
+	_, err = c.Add([]schema.CollectionDocument{
+		{ID: "a", Text: "east", Metadata: map[string]any{"n": 1}},
+		{ID: "b", Text: "north"},
+	}, [][]float32{{1, 0}, {0, 1}})
+	require.NoError(err)
+
+	// A document replaces the stored document with the same identifier
+	_, err = c.Add([]schema.CollectionDocument{
+		{ID: "a", Text: "west"},
+	}, [][]float32{{-1, 0}})
+	require.NoError(err)
+	assert.Equal(2, c.Meta().Documents)
+
+	results, err := c.Search([]float32{1, 0}, 1, nil, 0)
+	require.NoError(err)
+	require.Len(results, 1)
+	assert.Equal("b", results[0].ID)
+
+	// The collection is read again from its files
+	collections := New(path)
+	list, err := collections.List()
+	require.NoError(err)
+	require.Len(list, 1)
+	assert.Equal(2, list[0].Documents)
+
+	c, err = collections.Get("docs")
+	require.NoError(err)
+	assert.Equal(schema.IndexHNSW, c.Meta().Index)
+	assert.Equal(2, c.Meta().Documents)
+	results, err = c.Search([]float32{-1, 0}, 2, nil, 0)
+	require.NoError(err)
+	require.Len(results, 2)
+	assert.Equal("a", results[0].ID)
+	assert.Equal("west", results[0].Text)
+	assert.InDelta(1, results[0].Score, 0.0001)
+}
+
+func TestCollection_Compact(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	path := t.TempDir()
+
+	c, err := New(path).GetOrCreate("docs", "model", 2, "")
+	require.NoError(err)
+	for range 5 {
+		_, err = c.Add([]schema.CollectionDocument{{ID: "a", Text: "east"}}, [][]float32{{1, 0}})
+		require.NoError(err)
+	}
+	data, err := os.ReadFile(filepath.Join(path, "docs", documentsFilename))
+	require.NoError(err)
+	assert.Equal(5, strings.Count(string(data), "\n"))
+
+	// Replaced records are removed when the collection is read
+	c, err = New(path).Get("docs")
+	require.NoError(err)
+	assert.Equal(1, c.Meta().Documents)
+	data, err = os.ReadFile(filepath.Join(path, "docs", documentsFilename))
+	require.NoError(err)
+	assert.Equal(1, strings.Count(string(data), "\n"))
+}
+
+func TestMatch(t *testing.T) {
+	assert := assert.New(t)
+
+	metadata, err := normalize(map[string]any{"n": 1, "tags": []string{"a"}, "ok": true})
+	assert.NoError(err)
+	filter := func(f map[string]any) map[string]any {
+		f, err := normalize(f)
+		assert.NoError(err)
+		return f
+	}
+	assert.True(match(metadata, nil))
+	assert.True(match(metadata, filter(map[string]any{"n": 1.0})))
+	assert.True(match(metadata, filter(map[string]any{"n": 1, "ok": true})))
+	assert.True(match(metadata, filter(map[string]any{"n": []any{3, 1}})))
+	assert.False(match(metadata, filter(map[string]any{"n": 2})))
+	assert.False(match(metadata, filter(map[string]any{"n": "1"})))
+	assert.False(match(metadata, filter(map[string]any{"missing": nil})))
+	assert.False(match(metadata, filter(map[string]any{"n": []any{}})))
+}
@@ -0,0 +1,176 @@
+package collection
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	// Packages
+	llama "github.com/mutablelogic/go-llama"
+	schema "github.com/mutablelogic/go-llama/pkg/llamacpp/schema"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// Collections are the named collections in a directory, which are read when
+// they are first used
+type Collections struct {
+	sync.Mutex
+	path        string
+	collections map[string]*Collection
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+var (
+	// reName matches valid collection names
+	reName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]{0,63}$`)
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// LIFECYCLE
+
+// New returns the collections in a directory, which is created when the
+// first collection is
+func New(path string) *Collections {
+	return &Collections{
+		path:        path,
+		collections: make(map[string]*Collection),
+	}
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// List returns the metadata of all collections, sorted by name
+func (c *Collections) List() ([]schema.Collection, error) {
+	c.Lock()
+	defer c.Unlock()
+
+	entries, err := os.ReadDir(c.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return []schema.Collection{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	result := make([]schema.Collection, 0, len(entries))
+	for _, entry := range entries {
+		if !entry.IsDir() || !reName.MatchString(entry.Name()) {
+			continue
+		}
+		if collection, exists := c.collections[entry.Name()]; exists {
+			result = append(result, collection.Meta())
+			continue
+		}
+
+		// Read the metadata of collections which are not loaded
+		var meta schema.Collection
+		if data, err := os.ReadFile(filepath.Join(c.path, entry.Name(), metaFilename)); errors.Is(err, fs.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return nil, err
+		} else if err := json.Unmarshal(data, &meta); err != nil {
+			return nil, err
+		}
+		result = append(result, meta)
+	}
+	slices.SortFunc(result, func(a, b schema.Collection) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return result, nil
+}
+
+// Get returns a collection, or ErrNotFound if it does not exist
+func (c *Collections) Get(name string) (*Collection, error) {
+	if !reName.MatchString(name) {
+		return nil, llama.ErrInvalidArgument.Withf("invalid collection name %q", name)
+	}
+
+	c.Lock()
+	defer c.Unlock()
+	return c.get(name)
+}
+
+// GetOrCreate returns a collection, which is created for the model, dimension
+// and index if it does not exist
+func (c *Collections) GetOrCreate(name, model string, dimension int, index schema.CollectionIndex) (*Collection, error) {
+	if !reName.MatchString(name) {
+		return nil, llama.ErrInvalidArgument.Withf("invalid collection name %q", name)
+	} else if !index.Valid() {
+		return nil, llama.ErrInvalidArgument.Withf("invalid index %q", index)
+	} else if dimension <= 0 {
+		return nil, llama.ErrInvalidArgument.Withf("invalid dimension %d", dimension)
+	}
+	if index == "" {
+		index = schema.IndexFlat
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	if collection, err := c.get(name); err == nil {
+		return collection, nil
+	} else if !errors.Is(err, llama.ErrNotFound) {
+		return nil, err
+	}
+	collection, err := create(filepath.Join(c.path, name), schema.Collection{
+		Name:      name,
+		Model:     model,
+		Dimension: dimension,
+		Index:     index,
+		Created:   time.Now(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	c.collections[name] = collection
+	return collection, nil
+}
+
+// Delete removes a collection and its files
+func (c *Collections) Delete(name string) error {
+	if !reName.MatchString(name) {
+		return llama.ErrInvalidArgument.Withf("invalid collection name %q", name)
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	path := filepath.Join(c.path, name)
+	if _, err := os.Stat(filepath.Join(path, metaFilename)); errors.Is(err, fs.ErrNotExist) {
+		return llama.ErrNotFound.Withf("collection %q", name)
+	} else if err != nil {
+		return err
+	}
+	if err := os.RemoveAll(path); err != nil {
+		return err
+	}
+	delete(c.collections, name)
+	return nil
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// get returns a loaded collection, or loads it, with the lock held
+func (c *Collections) get(name string) (*Collection, error) {
+	if collection, exists := c.collections[name]; exists {
+		return collection, nil
+	}
+	collection, err := load(filepath.Join(c.path, name))
+	if err != nil {
+		return nil, err
+	}
+	c.collections[name] = collection
+	return collection, nil
+}
@@ -0,0 +1,48 @@
+package collection
+
+import (
+	"encoding/json"
+	"reflect"
+	"slices"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// normalize returns metadata with the values it has after encoding as JSON,
+// so that numbers are float64 and values can be compared with a filter
+func normalize(metadata map[string]any) (map[string]any, error) {
+	if len(metadata) == 0 {
+		return nil, nil
+	}
+	data, err := json.Marshal(metadata)
+	if err != nil {
+		return nil, err
+	}
+	var result map[string]any
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// match returns true if metadata has each value in the filter. A list in the
+// filter matches metadata with any of the values in the list.
+func match(metadata, filter map[string]any) bool {
+	for key, want := range filter {
+		value, exists := metadata[key]
+		if !exists {
+			return false
+		}
+		if list, ok := want.([]any); ok {
+			if !slices.ContainsFunc(list, func(want any) bool {
+				return reflect.DeepEqual(value, want)
+			}) {
+				return false
+			}
+		} else if !reflect.DeepEqual(value, want) {
+			return false
+		}
+	}
+	return true
+}
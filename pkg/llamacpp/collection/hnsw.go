@@ -0,0 +1,240 @@
+package collection
+
+import (
+	"cmp"
+	"container/heap"
+	"math"
+	"math/rand/v2"
+	"slices"
+
+	// Packages
+	llamacpp "github.com/mutablelogic/go-llama/sys/llamacpp"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// hnsw is a hierarchical navigable small world graph, for approximate search
+// of the vectors most similar to a query. Nodes are never removed, so that
+// the graph stays connected, but deleted nodes are not returned.
+type hnsw struct {
+	m              int        // Most neighbours of a node above level zero
+	m0             int        // Most neighbours of a node at level zero
+	efConstruction int        // Candidates considered when inserting
+	efSearch       int        // Candidates considered when searching
+	levelMult      float64    // Normalization of the random level of a node
+	rand           *rand.Rand // Source of levels, which is seeded so graphs are rebuilt the same
+	nodes          []*hnswNode
+	entry          int // Entry point, or -1 if the graph is empty
+	maxLevel       int
+}
+
+// hnswNode is a vector and its neighbours on each level
+type hnswNode struct {
+	vector    []float32
+	neighbors [][]int
+	deleted   bool
+}
+
+// scored is a node and its similarity to a query
+type scored struct {
+	id    int
+	score float64
+}
+
+// candidates is a heap of nodes, most similar first
+type candidates []scored
+
+///////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	hnswM              = 16
+	hnswEfConstruction = 200
+	hnswEfSearch       = 64
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// LIFECYCLE
+
+// newHNSW returns an empty graph
+func newHNSW() *hnsw {
+	return &hnsw{
+		m:              hnswM,
+		m0:             2 * hnswM,
+		efConstruction: hnswEfConstruction,
+		efSearch:       hnswEfSearch,
+		levelMult:      1 / math.Log(hnswM),
+		rand:           rand.New(rand.NewPCG(1, 2)),
+		entry:          -1,
+	}
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// insert adds a vector to the graph, and returns its node
+func (h *hnsw) insert(vector []float32) int {
+	id := len(h.nodes)
+	level := int(math.Floor(-math.Log(1-h.rand.Float64()) * h.levelMult))
+	node := &hnswNode{vector: vector, neighbors: make([][]int, level+1)}
+	h.nodes = append(h.nodes, node)
+
+	// The first node is the entry point
+	if h.entry < 0 {
+		h.entry, h.maxLevel = id, level
+		return id
+	}
+
+	// Descend to the level of the node, following the most similar neighbour
+	entry := h.entry
+	for l := h.maxLevel; l > level; l-- {
+		entry = h.greedy(vector, entry, l)
+	}
+
+	// Connect the node to the most similar nodes on each of its levels
+	for l := min(level, h.maxLevel); l >= 0; l-- {
+		found := h.searchLevel(vector, entry, h.efConstruction, l)
+		node.neighbors[l] = h.closest(found, h.m)
+		for _, neighbor := range node.neighbors[l] {
+			h.connect(neighbor, id, l)
+		}
+		entry = found[0].id
+	}
+
+	// A node above the top level is the new entry point
+	if level > h.maxLevel {
+		h.entry, h.maxLevel = id, level
+	}
+	return id
+}
+
+// delete marks a node as deleted, so it is not returned by searches
+func (h *hnsw) delete(id int) {
+	h.nodes[id].deleted = true
+}
+
+// search returns up to k nodes which are not deleted, most similar first
+func (h *hnsw) search(query []float32, k int) []scored {
+	if h.entry < 0 || k <= 0 {
+		return nil
+	}
+	entry := h.entry
+	for l := h.maxLevel; l > 0; l-- {
+		entry = h.greedy(query, entry, l)
+	}
+	found := h.searchLevel(query, entry, max(h.efSearch, k), 0)
+
+	result := make([]scored, 0, k)
+	for _, node := range found {
+		if !h.nodes[node.id].deleted {
+			result = append(result, node)
+		}
+		if len(result) == k {
+			break
+		}
+	}
+	return result
+}
+
+// greedy returns the node on a level which is most similar to the query,
+// moving from the entry point to more similar neighbours
+func (h *hnsw) greedy(query []float32, entry, level int) int {
+	best := h.similarity(query, entry)
+	for changed := true; changed; {
+		changed = false
+		for _, neighbor := range h.nodes[entry].neighbors[level] {
+			if score := h.similarity(query, neighbor); score > best {
+				entry, best, changed = neighbor, score, true
+			}
+		}
+	}
+	return entry
+}
+
+// searchLevel returns up to ef nodes on a level which are most similar to
+// the query, most similar first
+func (h *hnsw) searchLevel(query []float32, entry, ef, level int) []scored {
+	visited := map[int]bool{entry: true}
+	first := scored{entry, h.similarity(query, entry)}
+	queue := candidates{first}
+	found := []scored{first}
+
+	for len(queue) > 0 {
+		current := heap.Pop(&queue).(scored)
+		if len(found) >= ef && current.score < found[len(found)-1].score {
+			break
+		}
+		for _, neighbor := range h.nodes[current.id].neighbors[level] {
+			if visited[neighbor] {
+				continue
+			}
+			visited[neighbor] = true
+			next := scored{neighbor, h.similarity(query, neighbor)}
+			if len(found) >= ef && next.score <= found[len(found)-1].score {
+				continue
+			}
+			heap.Push(&queue, next)
+			i, _ := slices.BinarySearchFunc(found, next, byScore)
+			found = slices.Insert(found, i, next)
+			if len(found) > ef {
+				found = found[:ef]
+			}
+		}
+	}
+	return found
+}
+
+// closest returns the first n nodes, which are sorted most similar first
+func (h *hnsw) closest(found []scored, n int) []int {
+	result := make([]int, 0, min(n, len(found)))
+	for _, node := range found[:min(n, len(found))] {
+		result = append(result, node.id)
+	}
+	return result
+}
+
+// connect adds a neighbour to a node on a level, keeping the most similar
+// neighbours when there are too many
+func (h *hnsw) connect(id, neighbor, level int) {
+	node := h.nodes[id]
+	node.neighbors[level] = append(node.neighbors[level], neighbor)
+	limit := h.m
+	if level == 0 {
+		limit = h.m0
+	}
+	if len(node.neighbors[level]) <= limit {
+		return
+	}
+	neighbors := make([]scored, len(node.neighbors[level]))
+	for i, other := range node.neighbors[level] {
+		neighbors[i] = scored{other, llamacpp.CosineSimilarity(node.vector, h.nodes[other].vector)}
+	}
+	slices.SortStableFunc(neighbors, byScore)
+	node.neighbors[level] = h.closest(neighbors, limit)
+}
+
+// similarity returns the cosine similarity of the query and a node
+func (h *hnsw) similarity(query []float32, id int) float64 {
+	return llamacpp.CosineSimilarity(query, h.nodes[id].vector)
+}
+
+// byScore orders nodes most similar first
+func byScore(a, b scored) int {
+	return cmp.Compare(b.score, a.score)
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// HEAP
+
+func (c candidates) Len() int           { return len(c) }
+func (c candidates) Less(i, j int) bool { return c[i].score > c[j].score }
+func (c candidates) Swap(i, j int)      { c[i], c[j] = c[j], c[i] }
+func (c *candidates) Push(x any)        { *c = append(*c, x.(scored)) }
+func (c *candidates) Pop() any {
+	old := *c
+	n := len(old)
+	x := old[n-1]
+	*c = old[:n-1]
+	return x
+}
@@ -0,0 +1,72 @@
+package collection
+
+import (
+	"math/rand/v2"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	llamacpp "github.com/mutablelogic/go-llama/sys/llamacpp"
+)
+
+func TestHNSW_Empty(t *testing.T) {
+	assert := assert.New(t)
+	h := newHNSW()
+	assert.Empty(h.search([]float32{1, 0}, 10))
+}
+
+func TestHNSW_Recall(t *testing.T) {
+	assert := assert.New(t)
+	r := rand.New(rand.NewPCG(3, 4))
+	vector := func() []float32 {
+		v := make([]float32, 16)
+		for i := range v {
+			v[i] = float32(r.NormFloat64())
+		}
+		return v
+	}
+
+	h := newHNSW()
+	vectors := make([][]float32, 1000)
+	for i := range vectors {
+		vectors[i] = vector()
+		assert.Equal(i, h.insert(vectors[i]))
+	}
+
+	// Compare the results with an exact search
+	const k = 10
+	found := 0
+	for range 20 {
+		query := vector()
+		exact := make([]scored, len(vectors))
+		for i, v := range vectors {
+			exact[i] = scored{i, llamacpp.CosineSimilarity(query, v)}
+		}
+		slices.SortFunc(exact, byScore)
+
+		results := h.search(query, k)
+		assert.Len(results, k)
+		assert.True(slices.IsSortedFunc(results, byScore))
+		for _, want := range exact[:k] {
+			if slices.ContainsFunc(results, func(s scored) bool { return s.id == want.id }) {
+				found++
+			}
+		}
+	}
+	assert.GreaterOrEqual(float64(found)/(20*k), 0.9)
+}
+
+func TestHNSW_Delete(t *testing.T) {
+	assert := assert.New(t)
+	h := newHNSW()
+	h.insert([]float32{1, 0})
+	h.insert([]float32{0, 1})
+	h.insert([]float32{0.7, 0.7})
+
+	h.delete(0)
+	results := h.search([]float32{1, 0}, 3)
+	assert.Len(results, 2)
+	assert.Equal(2, results[0].id)
+	assert.Equal(1, results[1].id)
+}
@@ -5,6 +5,7 @@ import (
 
 	// Packages
 	otel "github.com/mutablelogic/go-client/pkg/otel"
+	llama "github.com/mutablelogic/go-llama"
 	schema "github.com/mutablelogic/go-llama/pkg/llamacpp/schema"
 	llamacpp "github.com/mutablelogic/go-llama/sys/llamacpp"
 	attribute "go.opentelemetry.io/otel/attribute"
@@ -22,6 +23,11 @@ func (l *Llama) Complete(ctx context.Context, req schema.CompletionRequest, onCh
 	)
 	defer func() { endSpan(err) }()
 
+	// Refuse models which cannot generate text
+	if err := l.requireCapability(ctx, req.Model, schema.CapCompletion, llama.ErrNotSupported.Withf("model %q does not support completion", req.Model)); err != nil {
+		return nil, err
+	}
+
 	// Create a context, and run the completion
 	err = l.WithContext(ctx, schema.ContextRequest{
 		LoadModelRequest: schema.LoadModelRequest{
@@ -2,6 +2,7 @@ package llamacpp
 
 import (
 	"context"
+	"unicode/utf8"
 
 	// Packages
 	otel "github.com/mutablelogic/go-client/pkg/otel"
@@ -9,6 +10,7 @@ import (
 	schema "github.com/mutablelogic/go-llama/pkg/llamacpp/schema"
 	llamacpp "github.com/mutablelogic/go-llama/sys/llamacpp"
 	attribute "go.opentelemetry.io/otel/attribute"
+	trace "go.opentelemetry.io/otel/trace"
 )
 
 ///////////////////////////////////////////////////////////////////////////////
@@ -16,65 +18,400 @@ import (
 
 // Embed generates embeddings for one or more texts.
 // Loads the model if not already cached, creates a context with embeddings enabled,
-// and computes embeddings for all input texts in a single batch.
+// and computes embeddings for all input texts in a single batch. With pooling
+// set to none, the embedding of every token of each text is returned instead.
 func (l *Llama) Embed(ctx context.Context, req schema.EmbedRequest) (result *schema.EmbedResponse, err error) {
 	ctx, endSpan := otel.StartSpan(l.tracer, ctx, schema.SpanName("Embed"),
 		attribute.String("request", req.String()),
 	)
 	defer func() { endSpan(err) }()
 
+	if !req.Pooling.Valid() {
+		return nil, llama.ErrInvalidArgument.Withf("invalid pooling %q", req.Pooling)
+	}
+	if !req.Encoding.Valid() {
+		return nil, llama.ErrInvalidArgument.Withf("invalid encoding %q", req.Encoding)
+	}
+	if !req.Overflow.Valid() {
+		return nil, llama.ErrInvalidArgument.Withf("invalid overflow %q", req.Overflow)
+	}
+	if !req.Aggregate.Valid() {
+		return nil, llama.ErrInvalidArgument.Withf("invalid aggregate %q", req.Aggregate)
+	}
+	if req.ChunkSize < 0 || req.ChunkOverlap < 0 {
+		return nil, llama.ErrInvalidArgument.With("chunk_size and chunk_overlap cannot be negative")
+	}
+	if req.Dimensions < 0 {
+		return nil, llama.ErrInvalidArgument.With("dimensions cannot be negative")
+	}
+
+	// Refuse models without pooling, unless a pooling type is requested, which
+	// is checked again on the context
+	pooling := poolingType(req.Pooling)
+	if pooling == llamacpp.PoolingNone && req.Overflow != "" && req.Overflow != schema.OverflowError {
+		return nil, llama.ErrInvalidArgument.Withf("overflow %q is not supported with pooling %q", req.Overflow, req.Pooling)
+	}
+	if pooling == llamacpp.PoolingUnspecified {
+		if err := l.requireCapability(ctx, req.Model, schema.CapEmbedding, llama.ErrNotEmbeddingModel.Withf("model %q", req.Model)); err != nil {
+			return nil, err
+		}
+	}
+
 	// Build context request for embedding models:
 	// - Embeddings enabled: required to extract embeddings
+	// - Pooling type: from the model, unless requested
+	normalize := req.Normalize == nil || *req.Normalize
 	embeddings := true
+	contextPooling := int32(pooling)
 	contextReq := schema.ContextRequest{
 		LoadModelRequest: schema.LoadModelRequest{
 			Name: req.Model,
 		},
-		Embeddings: &embeddings,
+		Embeddings:  &embeddings,
+		PoolingType: &contextPooling,
+	}
+
+	// Compute the embeddings of the inputs, returning the dimensions they are
+	// truncated to
+	compute := func(req schema.EmbedRequest) (result *schema.EmbedResponse, dimensions int, err error) {
+		dimensions = req.Dimensions
+		err = l.WithContext(ctx, contextReq, func(ctx context.Context, task *Task) error {
+			// Lock the model - embedding computation is not thread-safe
+			task.CachedModel().Lock()
+			defer task.CachedModel().Unlock()
+
+			if pooling == llamacpp.PoolingUnspecified && task.Context().PoolingType() == llamacpp.PoolingNone {
+				return llama.ErrNotEmbeddingModel
+			}
+
+			// Build embedding options
+			opts := llamacpp.DefaultEmbeddingOptions()
+			opts.Normalize = normalize
+
+			// Embeddings can be truncated to fewer dimensions
+			if nembd := int(task.Context().NEmbd()); dimensions > nembd {
+				return llama.ErrInvalidArgument.Withf("dimensions %d is more than the model's %d", dimensions, nembd)
+			} else if dimensions == 0 {
+				dimensions = nembd
+			}
+
+			// Compute the embedding of each token
+			if pooling == llamacpp.PoolingNone {
+				result, err = tokenEmbeddings(task, req, opts, dimensions)
+				return err
+			}
+
+			// Compute embeddings for all inputs, in chunks if they are too long
+			result, err = chunkEmbeddings(task, req, opts)
+			return err
+		})
+		return
 	}
 
-	err = l.WithContext(ctx, contextReq, func(ctx context.Context, task *Task) error {
-		// Lock the model - embedding computation is not thread-safe
-		task.CachedModel().Lock()
-		defer task.CachedModel().Unlock()
+	// The embedding of every token is encoded as it is computed
+	if pooling == llamacpp.PoolingNone {
+		result, _, err = compute(req)
+		return
+	}
 
-		if task.Context().PoolingType() == llamacpp.PoolingNone {
-			return llama.ErrNotEmbeddingModel
+	// Look up pooled embeddings in the cache, so that only the inputs which
+	// are not cached are computed, and the model is not loaded when every
+	// input is cached
+	cache := req.Aggregate != schema.AggregateNone
+	if result, err = l.embedCached(ctx, req, normalize, cache, compute); err != nil {
+		return nil, err
+	}
+	if cache && l.embedCache != nil {
+		trace.SpanFromContext(ctx).SetAttributes(
+			attribute.Int("cache.hits", result.Usage.CacheHits),
+			attribute.Int("cache.misses", result.Usage.CacheMisses),
+		)
+	}
+	return
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// embedCached returns the pooled embedding of each input, truncated and
+// encoded. When cache is true and the embedding cache is enabled, the
+// embeddings are looked up in the cache, and compute is called only with the
+// inputs which are not cached, if any. Otherwise compute is called with all
+// inputs. compute returns the embeddings and the dimensions to truncate them
+// to, and the computed embeddings are cached.
+func (l *Llama) embedCached(ctx context.Context, req schema.EmbedRequest, normalize, cache bool, compute func(schema.EmbedRequest) (*schema.EmbedResponse, int, error)) (*schema.EmbedResponse, error) {
+	computeReq := req
+	var keys []string
+	var cached [][]float32
+	var misses []int
+	if cache && l.embedCache != nil {
+		var err error
+		if keys, cached, err = l.embedLookup(ctx, req, normalize); err != nil {
+			return nil, err
 		}
+		computeReq.Input = nil
+		for i, embedding := range cached {
+			if embedding == nil {
+				misses = append(misses, i)
+				computeReq.Input = append(computeReq.Input, req.Input[i])
+			}
+		}
+	}
 
-		// Build embedding options
-		opts := llamacpp.DefaultEmbeddingOptions()
-		if req.Normalize != nil {
-			opts.Normalize = *req.Normalize
+	// Return cached embeddings without computing any
+	if keys != nil && len(misses) == 0 && len(cached) > 0 {
+		result := &schema.EmbedResponse{
+			Model:      req.Model,
+			Embeddings: cached,
+			Dimension:  len(cached[0]),
 		}
+		result.Usage.CacheHits = len(cached)
+		return result, cachedEmbeddings(result, req.Dimensions, req.Encoding, normalize)
+	}
 
-		// Compute embeddings for all inputs
-		batch, err := task.Context().ComputeEmbeddings(task.Model(), req.Input, opts)
-		if err != nil {
-			return err
+	// Compute the embeddings which are not cached
+	result, dimensions, err := compute(computeReq)
+	if err != nil {
+		return nil, err
+	}
+
+	// Cache the computed embeddings, and merge them with the cached ones. The
+	// cache is best-effort, so an embedding which cannot be written is still
+	// returned, and the first error is recorded on the span.
+	if keys != nil {
+		var cacheErr error
+		for i, input := range misses {
+			if err := l.embedCache.put(keys[input], result.Embeddings[i]); err != nil && cacheErr == nil {
+				cacheErr = err
+			}
+			cached[input] = result.Embeddings[i]
+		}
+		if cacheErr != nil {
+			trace.SpanFromContext(ctx).RecordError(cacheErr)
 		}
+		result.Embeddings = cached
+		result.Usage.CacheHits = len(cached) - len(misses)
+		result.Usage.CacheMisses = len(misses)
+	}
+
+	// Truncate and encode the embeddings
+	if err := encodeEmbeddings(result, dimensions, req.Encoding, normalize); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// poolingType returns the context pooling type for a requested pooling
+func poolingType(pooling schema.Pooling) llamacpp.PoolingType {
+	switch pooling {
+	case schema.PoolingNone:
+		return llamacpp.PoolingNone
+	case schema.PoolingMean:
+		return llamacpp.PoolingMean
+	case schema.PoolingCLS:
+		return llamacpp.PoolingCLS
+	case schema.PoolingLast:
+		return llamacpp.PoolingLast
+	default:
+		return llamacpp.PoolingUnspecified
+	}
+}
 
-		// Count total input tokens
-		var inputTokens int
-		for _, text := range req.Input {
-			tokOpts := llamacpp.DefaultTokenizeOptions()
-			tokens, err := task.Model().Tokenize(text, tokOpts)
+// chunkEmbeddings computes the embedding of each input, which is refused,
+// truncated or split into chunks when it is too long for the context, and
+// returns one embedding per input or, if not aggregated, per chunk
+func chunkEmbeddings(task *Task, req schema.EmbedRequest, opts llamacpp.EmbeddingOptions) (*schema.EmbedResponse, error) {
+	chunkOpts := llamacpp.ChunkOptions{
+		Size:    req.ChunkSize,
+		Overlap: req.ChunkOverlap,
+	}
+	switch req.Overflow {
+	case schema.OverflowTruncate:
+		chunkOpts.MaxChunks = 1
+	case schema.OverflowChunk:
+		// Unlimited chunks
+	default:
+		// Refuse inputs which need more than one chunk
+		maxTokens := task.Context().MaxEmbeddingTokens()
+		if req.ChunkSize > 0 {
+			maxTokens = min(maxTokens, req.ChunkSize)
+		}
+		for i, text := range req.Input {
+			tokens, err := task.Model().Tokenize(text, llamacpp.DefaultTokenizeOptions())
 			if err != nil {
-				return err
+				return nil, err
+			}
+			if len(tokens) > maxTokens {
+				return nil, llama.ErrTooLarge.Withf("input %d has %d tokens, more than %d (set overflow to %q or %q)", i, len(tokens), maxTokens, schema.OverflowTruncate, schema.OverflowChunk)
 			}
-			inputTokens += len(tokens)
 		}
+	}
 
-		result = &schema.EmbedResponse{
-			Model:      req.Model,
-			Embeddings: batch.Embeddings,
-			Dimension:  batch.Dimension,
-			Usage: schema.Usage{
-				InputTokens:  inputTokens,
-				OutputTokens: 0,
-			},
+	chunks, err := task.Context().ComputeChunkEmbeddings(task.Model(), req.Input, opts, chunkOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &schema.EmbedResponse{
+		Model:     req.Model,
+		Dimension: int(task.Context().NEmbd()),
+	}
+	for _, chunk := range chunks {
+		result.Usage.InputTokens += chunk.Tokens
+	}
+
+	// Return each chunk, with character offsets
+	if req.Aggregate == schema.AggregateNone {
+		result.Chunks = make([]schema.EmbeddingChunk, len(chunks))
+		for i, chunk := range chunks {
+			text := req.Input[chunk.Input]
+			start := utf8.RuneCountInString(text[:chunk.Start])
+			result.Chunks[i] = schema.EmbeddingChunk{
+				Index:     chunk.Input,
+				Start:     start,
+				End:       start + utf8.RuneCountInString(text[chunk.Start:chunk.End]),
+				Tokens:    chunk.Tokens,
+				Embedding: chunk.Embedding,
+			}
+		}
+		return result, nil
+	}
+
+	// Otherwise return the mean of the chunks of each input, weighted by tokens
+	result.Embeddings = make([][]float32, len(req.Input))
+	weights := make([]int, len(req.Input))
+	for _, chunk := range chunks {
+		if result.Embeddings[chunk.Input] == nil {
+			result.Embeddings[chunk.Input] = make([]float32, result.Dimension)
+		}
+		for j, v := range chunk.Embedding {
+			result.Embeddings[chunk.Input][j] += v * float32(chunk.Tokens)
+		}
+		weights[chunk.Input] += chunk.Tokens
+	}
+	for i, embedding := range result.Embeddings {
+		if embedding == nil {
+			result.Embeddings[i] = make([]float32, result.Dimension)
+			continue
+		}
+		for j := range embedding {
+			embedding[j] /= float32(max(weights[i], 1))
+		}
+		if opts.Normalize {
+			llamacpp.NormalizeEmbeddings(embedding)
 		}
+	}
+	return result, nil
+}
+
+// encodeEmbeddings truncates the pooled embeddings in the response to the
+// dimensions, normalizing them again, and packs them in the encoding
+func encodeEmbeddings(result *schema.EmbedResponse, dimensions int, encoding schema.EmbeddingEncoding, normalize bool) error {
+	if dimensions < result.Dimension {
+		for i := range result.Embeddings {
+			result.Embeddings[i] = truncateEmbedding(result.Embeddings[i], dimensions, normalize)
+		}
+		for i := range result.Chunks {
+			result.Chunks[i].Embedding = truncateEmbedding(result.Chunks[i].Embedding, dimensions, normalize)
+		}
+		result.Dimension = dimensions
+	}
+
+	// Embeddings are returned as numbers by default
+	if encoding == "" || encoding == schema.EncodingFloat32 {
 		return nil
-	})
-	return
+	}
+
+	// Pack each embedding
+	result.Encoding = encoding
+	if result.Chunks == nil {
+		result.Data = make([]string, len(result.Embeddings))
+		for i, embedding := range result.Embeddings {
+			data, scales, err := schema.EncodeEmbeddings([][]float32{embedding}, encoding)
+			if err != nil {
+				return err
+			}
+			result.Data[i] = data
+			result.Scales = append(result.Scales, scales...)
+		}
+		result.Embeddings = nil
+	}
+	for i, chunk := range result.Chunks {
+		data, scales, err := schema.EncodeEmbeddings([][]float32{chunk.Embedding}, encoding)
+		if err != nil {
+			return err
+		}
+		result.Chunks[i].Data = data
+		if len(scales) > 0 {
+			result.Chunks[i].Scale = scales[0]
+		}
+		result.Chunks[i].Embedding = nil
+	}
+	return nil
+}
+
+// cachedEmbeddings truncates and encodes embeddings from the cache, which
+// have the dimensions of the model
+func cachedEmbeddings(result *schema.EmbedResponse, dimensions int, encoding schema.EmbeddingEncoding, normalize bool) error {
+	if dimensions > result.Dimension {
+		return llama.ErrInvalidArgument.Withf("dimensions %d is more than the model's %d", dimensions, result.Dimension)
+	} else if dimensions == 0 {
+		dimensions = result.Dimension
+	}
+	return encodeEmbeddings(result, dimensions, encoding, normalize)
+}
+
+// truncateEmbedding returns the first dimensions of an embedding, which is
+// normalized again if requested
+func truncateEmbedding(embedding []float32, dimensions int, normalize bool) []float32 {
+	if len(embedding) <= dimensions {
+		return embedding
+	}
+	embedding = embedding[:dimensions:dimensions]
+	if normalize {
+		llamacpp.NormalizeEmbeddings(embedding)
+	}
+	return embedding
+}
+
+// tokenEmbeddings computes the embedding of every token of each input, and
+// encodes the vectors of each input, truncated to the dimensions
+func tokenEmbeddings(task *Task, req schema.EmbedRequest, opts llamacpp.EmbeddingOptions, dimensions int) (*schema.EmbedResponse, error) {
+	encoding := req.Encoding
+	if encoding == "" {
+		encoding = schema.EncodingFloat32
+	}
+
+	batch, err := task.Context().ComputeTokenEmbeddings(task.Model(), req.Input, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &schema.EmbedResponse{
+		Model:     req.Model,
+		Tokens:    make([]schema.TokenEmbeddings, len(batch)),
+		Dimension: dimensions,
+	}
+	for i, input := range batch {
+		for j := range input.Embeddings {
+			input.Embeddings[j] = truncateEmbedding(input.Embeddings[j], dimensions, opts.Normalize)
+		}
+		data, scales, err := schema.EncodeEmbeddings(input.Embeddings, encoding)
+		if err != nil {
+			return nil, err
+		}
+		tokens := make([]int32, len(input.Tokens))
+		for j, token := range input.Tokens {
+			tokens[j] = int32(token)
+		}
+		result.Tokens[i] = schema.TokenEmbeddings{
+			Tokens:   tokens,
+			Offsets:  input.Offsets,
+			Encoding: encoding,
+			Data:     data,
+			Scales:   scales,
+		}
+		result.Usage.InputTokens += len(tokens)
+	}
+	return result, nil
 }
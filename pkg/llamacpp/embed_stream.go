@@ -0,0 +1,296 @@
+package llamacpp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+
+	// Packages
+	otel "github.com/mutablelogic/go-client/pkg/otel"
+	llama "github.com/mutablelogic/go-llama"
+	schema "github.com/mutablelogic/go-llama/pkg/llamacpp/schema"
+	llamacpp "github.com/mutablelogic/go-llama/sys/llamacpp"
+	attribute "go.opentelemetry.io/otel/attribute"
+	trace "go.opentelemetry.io/otel/trace"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// embedStreamRow is a row of a stream, with the text to embed
+type embedStreamRow struct {
+	schema.EmbedStreamRow
+	text  string
+	embed bool
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+const (
+	// embedStreamRowSize is the largest row which can be read, in bytes
+	embedStreamRowSize = 16 * 1024 * 1024
+
+	// embedStreamBatchRows is the most rows in a batch
+	embedStreamBatchRows = 256
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// EmbedStream embeds the text of each row read from r, which has a JSON
+// object on each line, and calls fn with the embedding of each row in the
+// order the rows are read. Rows are embedded in batches of up to the batch
+// size of one context, which is kept for the whole stream, so that only a
+// batch of rows is held in memory. Rows which cannot be read or embedded are
+// returned with an error, and the stream continues.
+func (l *Llama) EmbedStream(ctx context.Context, req schema.EmbedStreamRequest, r io.Reader, fn func(schema.EmbedStreamRow) error) (err error) {
+	ctx, endSpan := otel.StartSpan(l.tracer, ctx, schema.SpanName("EmbedStream"),
+		attribute.String("request", req.String()),
+	)
+	defer func() { endSpan(err) }()
+
+	if !req.Pooling.Valid() {
+		return llama.ErrInvalidArgument.Withf("invalid pooling %q", req.Pooling)
+	} else if req.Pooling == schema.PoolingNone {
+		return llama.ErrInvalidArgument.Withf("pooling %q is not supported for a stream", req.Pooling)
+	}
+	if !req.Encoding.Valid() {
+		return llama.ErrInvalidArgument.Withf("invalid encoding %q", req.Encoding)
+	}
+	if !req.Overflow.Valid() {
+		return llama.ErrInvalidArgument.Withf("invalid overflow %q", req.Overflow)
+	}
+	if req.ChunkSize < 0 || req.ChunkOverlap < 0 {
+		return llama.ErrInvalidArgument.With("chunk_size and chunk_overlap cannot be negative")
+	}
+	if req.Dimensions < 0 {
+		return llama.ErrInvalidArgument.With("dimensions cannot be negative")
+	}
+	field := req.Field
+	if field == "" {
+		field = schema.DefaultEmbedStreamField
+	}
+	idField := req.IDField
+	if idField == "" {
+		idField = schema.DefaultEmbedStreamIDField
+	}
+
+	// Refuse models without pooling, unless a pooling type is requested, which
+	// is checked again on the context
+	pooling := poolingType(req.Pooling)
+	if pooling == llamacpp.PoolingUnspecified {
+		if err := l.requireCapability(ctx, req.Model, schema.CapEmbedding, llama.ErrNotEmbeddingModel.Withf("model %q", req.Model)); err != nil {
+			return err
+		}
+	}
+
+	// Each batch is embedded with the options of the stream
+	normalize := req.Normalize == nil || *req.Normalize
+	embedReq := schema.EmbedRequest{
+		Model:        req.Model,
+		Normalize:    req.Normalize,
+		Pooling:      req.Pooling,
+		Dimensions:   req.Dimensions,
+		Encoding:     req.Encoding,
+		Overflow:     req.Overflow,
+		ChunkSize:    req.ChunkSize,
+		ChunkOverlap: req.ChunkOverlap,
+	}
+
+	// Build context request for embedding models
+	embeddings := true
+	contextPooling := int32(pooling)
+	contextReq := schema.ContextRequest{
+		LoadModelRequest: schema.LoadModelRequest{
+			Name: req.Model,
+		},
+		Embeddings:  &embeddings,
+		PoolingType: &contextPooling,
+	}
+
+	var rows, errors int
+	var usage schema.Usage
+	err = l.WithContext(ctx, contextReq, func(ctx context.Context, task *Task) error {
+		if task.Context().PoolingType() == llamacpp.PoolingNone {
+			return llama.ErrNotEmbeddingModel
+		}
+
+		// Embeddings can be truncated to fewer dimensions
+		dimensions := req.Dimensions
+		if nembd := int(task.Context().NEmbd()); dimensions > nembd {
+			return llama.ErrInvalidArgument.Withf("dimensions %d is more than the model's %d", dimensions, nembd)
+		} else if dimensions == 0 {
+			dimensions = nembd
+		}
+
+		// Texts which need more than one chunk are refused, unless they are
+		// truncated or chunked
+		maxTokens := task.Context().MaxEmbeddingTokens()
+		if req.ChunkSize > 0 {
+			maxTokens = min(maxTokens, req.ChunkSize)
+		}
+		batchTokens := int(task.Context().BatchSize())
+
+		// Embed the pending rows, and return them in order
+		var pending []embedStreamRow
+		var pendingTokens int
+		flush := func() error {
+			batchUsage, err := l.embedStreamBatch(ctx, task, embedReq, normalize, dimensions, pending)
+			if err != nil {
+				return err
+			}
+			usage.InputTokens += batchUsage.InputTokens
+			usage.CacheHits += batchUsage.CacheHits
+			usage.CacheMisses += batchUsage.CacheMisses
+			for _, row := range pending {
+				if row.Error != "" {
+					errors++
+				}
+				if err := fn(row.EmbedStreamRow); err != nil {
+					return err
+				}
+			}
+			rows += len(pending)
+			pending, pendingTokens = pending[:0], 0
+			return nil
+		}
+
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(nil, embedStreamRowSize)
+		for line := 1; scanner.Scan(); line++ {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			data := bytes.TrimSpace(scanner.Bytes())
+			if len(data) == 0 {
+				continue
+			}
+
+			// Read the row, and count its tokens
+			row := embedStreamRow{EmbedStreamRow: schema.EmbedStreamRow{Line: line}}
+			text, id, err := parseStreamRow(data, field, idField)
+			row.ID = id
+			if err != nil {
+				row.Error = err.Error()
+			} else {
+				tokens, err := streamTokens(task, text)
+				if err != nil {
+					return err
+				}
+				row.text, row.Tokens = text, tokens
+				if tokens > maxTokens && (req.Overflow == "" || req.Overflow == schema.OverflowError) {
+					row.Error = llama.ErrTooLarge.Withf("text has %d tokens, more than %d (set overflow to %q or %q)", tokens, maxTokens, schema.OverflowTruncate, schema.OverflowChunk).Error()
+				} else {
+					row.embed = true
+				}
+			}
+
+			// Embed the pending rows when the batch is full
+			if len(pending) >= embedStreamBatchRows || (row.embed && pendingTokens > 0 && pendingTokens+row.Tokens > batchTokens) {
+				if err := flush(); err != nil {
+					return err
+				}
+			}
+			if row.embed {
+				pendingTokens += row.Tokens
+			}
+			pending = append(pending, row)
+		}
+		if err := scanner.Err(); err != nil {
+			return llama.ErrInvalidArgument.Withf("cannot read rows: %v", err)
+		}
+		return flush()
+	})
+
+	trace.SpanFromContext(ctx).SetAttributes(
+		attribute.Int("rows", rows),
+		attribute.Int("errors", errors),
+		attribute.Int("tokens", usage.InputTokens),
+		attribute.Int("cache.hits", usage.CacheHits),
+		attribute.Int("cache.misses", usage.CacheMisses),
+	)
+	return err
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// embedStreamBatch computes the embeddings of the rows in a batch which have
+// text to embed, using the embedding cache when it is enabled, and returns
+// the usage of the batch
+func (l *Llama) embedStreamBatch(ctx context.Context, task *Task, req schema.EmbedRequest, normalize bool, dimensions int, rows []embedStreamRow) (schema.Usage, error) {
+	var index []int
+	for i, row := range rows {
+		if row.embed {
+			req.Input = append(req.Input, row.text)
+			index = append(index, i)
+		}
+	}
+	if len(index) == 0 {
+		return schema.Usage{}, nil
+	}
+
+	// Compute the embeddings which are not cached
+	result, err := l.embedCached(ctx, req, normalize, true, func(req schema.EmbedRequest) (*schema.EmbedResponse, int, error) {
+		// Lock the model - embedding computation is not thread-safe
+		task.CachedModel().Lock()
+		defer task.CachedModel().Unlock()
+
+		opts := llamacpp.DefaultEmbeddingOptions()
+		opts.Normalize = normalize
+		result, err := chunkEmbeddings(task, req, opts)
+		return result, dimensions, err
+	})
+	if err != nil {
+		return schema.Usage{}, err
+	}
+
+	// Return the embedding of each row
+	for j, i := range index {
+		if result.Data != nil {
+			rows[i].Data = result.Data[j]
+			if j < len(result.Scales) {
+				rows[i].Scale = result.Scales[j]
+			}
+		} else {
+			rows[i].Embedding = result.Embeddings[j]
+		}
+	}
+	return result.Usage, nil
+}
+
+// streamTokens returns the number of tokens in a text, including special
+// tokens
+func streamTokens(task *Task, text string) (int, error) {
+	// Lock the model - tokenization is not thread-safe
+	task.CachedModel().Lock()
+	defer task.CachedModel().Unlock()
+
+	tokens, err := task.Model().Tokenize(text, llamacpp.DefaultTokenizeOptions())
+	if err != nil {
+		return 0, err
+	}
+	return len(tokens), nil
+}
+
+// parseStreamRow returns the text and identifier of a row, which is a JSON
+// object. The identifier is returned when the text cannot be read.
+func parseStreamRow(data []byte, field, idField string) (string, json.RawMessage, error) {
+	var row map[string]json.RawMessage
+	if err := json.Unmarshal(data, &row); err != nil {
+		return "", nil, llama.ErrInvalidArgument.Withf("row is not a JSON object: %v", err)
+	}
+	id := row[idField]
+	value, exists := row[field]
+	if !exists {
+		return "", id, llama.ErrInvalidArgument.Withf("row has no %q field", field)
+	}
+	var text string
+	if err := json.Unmarshal(value, &text); err != nil {
+		return "", id, llama.ErrInvalidArgument.Withf("field %q is not a string", field)
+	}
+	return text, id, nil
+}
@@ -0,0 +1,49 @@
+package llamacpp
+
+import (
+	"testing"
+
+	llama "github.com/mutablelogic/go-llama"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseStreamRow(t *testing.T) {
+	tests := []struct {
+		name string
+		row  string
+		text string
+		id   string
+		err  bool
+	}{
+		{"string id", `{"id": "a", "text": "Hello"}`, "Hello", `"a"`, false},
+		{"number id", `{"id": 42, "text": "Hello"}`, "Hello", `42`, false},
+		{"no id", `{"text": "Hello"}`, "Hello", ``, false},
+		{"empty text", `{"id": 1, "text": ""}`, "", `1`, false},
+		{"missing text", `{"id": 1, "body": "Hello"}`, "", `1`, true},
+		{"text not a string", `{"id": 1, "text": 42}`, "", `1`, true},
+		{"not an object", `["Hello"]`, "", ``, true},
+		{"invalid JSON", `{"text": `, "", ``, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+			text, id, err := parseStreamRow([]byte(test.row), "text", "id")
+			if test.err {
+				assert.ErrorIs(err, llama.ErrInvalidArgument)
+			} else {
+				assert.NoError(err)
+			}
+			assert.Equal(test.text, text)
+			assert.Equal(test.id, string(id))
+		})
+	}
+}
+
+func TestParseStreamRowFields(t *testing.T) {
+	assert := assert.New(t)
+
+	text, id, err := parseStreamRow([]byte(`{"key": "k1", "text": "ignored", "body": "Hello"}`), "body", "key")
+	assert.NoError(err)
+	assert.Equal("Hello", text)
+	assert.Equal(`"k1"`, string(id))
+}
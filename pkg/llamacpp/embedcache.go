@@ -0,0 +1,394 @@
+package llamacpp
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	// Packages
+	llama "github.com/mutablelogic/go-llama"
+	schema "github.com/mutablelogic/go-llama/pkg/llamacpp/schema"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// embedCache holds embeddings by model, options and text, in a memory LRU
+// and optionally in files in a directory, which persist between restarts.
+// The lock only guards the memory tier, and files are read and written
+// outside it.
+type embedCache struct {
+	sync.Mutex
+	size    int                      // Most entries in memory
+	path    string                   // Directory of the persistent tier, or empty
+	limit   uint64                   // Most bytes of files in the persistent tier, or zero
+	bytes   uint64                   // Bytes of files in the persistent tier, when limited
+	pruning bool                     // True while files are removed to be within the limit
+	lru     *list.List               // Entries, most recently used first
+	entries map[string]*list.Element // Entries by key
+	sums    map[string]modelSum      // Checksums of model files by path
+}
+
+// embedCacheFile is a file in the persistent tier
+type embedCacheFile struct {
+	path    string
+	size    uint64
+	modTime time.Time
+}
+
+// embedCacheEntry is an embedding in memory
+type embedCacheEntry struct {
+	key       string
+	embedding []float32
+}
+
+// modelSum is the checksum of a model file, which is computed again if the
+// file changes
+type modelSum struct {
+	size    int64
+	modTime time.Time
+	sum     string
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	// embedCacheExt is the extension of files in the persistent tier
+	embedCacheExt = ".emb"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// LIFECYCLE
+
+// newEmbedCache returns a cache of size entries in memory and, if path is
+// not empty, a persistent tier in that directory. If limit is not zero, the
+// least recently used files are removed when the persistent tier is larger
+// than limit bytes.
+func newEmbedCache(size int, path string, limit uint64) (*embedCache, error) {
+	if size < 0 {
+		return nil, llama.ErrInvalidArgument.Withf("invalid embedding cache size: %d", size)
+	}
+	cache := &embedCache{
+		size:    size,
+		path:    path,
+		limit:   limit,
+		lru:     list.New(),
+		entries: make(map[string]*list.Element),
+		sums:    make(map[string]modelSum),
+	}
+	if path != "" {
+		if err := os.MkdirAll(path, 0755); err != nil {
+			return nil, err
+		}
+	}
+	if path != "" && limit > 0 {
+		files, err := cache.files()
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			cache.bytes += file.size
+		}
+	}
+	return cache, nil
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// modelSum returns the sha256 checksum of a model from the files at paths.
+// For a model in a single file, it is the checksum of the file, and for a
+// split model, it is the checksum of the checksums of its files in order.
+func (c *embedCache) modelSum(model *schema.Model, paths []string) (string, error) {
+	if len(paths) == 1 {
+		return c.fileSum(paths[0], model.SHA256, model.PulledAt)
+	}
+	h := sha256.New()
+	for _, path := range paths {
+		sum, err := c.fileSum(path, "", time.Time{})
+		if err != nil {
+			return "", err
+		}
+		h.Write([]byte(sum))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// fileSum returns the sha256 checksum of a file, which is the recorded
+// checksum if the file has not been modified since it was recorded, or else
+// is computed from the file once. Either is used until the size or
+// modification time of the file changes.
+func (c *embedCache) fileSum(path, recorded string, recordedAt time.Time) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+
+	// Return a checksum used earlier, if the file has not changed
+	c.Lock()
+	sum, ok := c.sums[path]
+	c.Unlock()
+	if ok && sum.size == info.Size() && sum.modTime.Equal(info.ModTime()) {
+		return sum.sum, nil
+	}
+
+	// Use the recorded checksum, or compute the checksum outside the lock,
+	// which may take a while
+	sum = modelSum{size: info.Size(), modTime: info.ModTime(), sum: recorded}
+	if recorded == "" || recordedAt.IsZero() || info.ModTime().After(recordedAt) {
+		f, err := os.Open(path)
+		if err != nil {
+			return "", err
+		}
+		defer f.Close()
+		h := sha256.New()
+		if _, err := io.Copy(h, f); err != nil {
+			return "", err
+		}
+		sum.sum = hex.EncodeToString(h.Sum(nil))
+	}
+
+	c.Lock()
+	c.sums[path] = sum
+	c.Unlock()
+	return sum.sum, nil
+}
+
+// key returns the cache key of an input, from the model checksum, the
+// options which change the embedding, and the text
+func (c *embedCache) key(modelSum string, req schema.EmbedRequest, normalize bool, text string) string {
+	pooling := req.Pooling
+	if pooling == "" {
+		pooling = schema.PoolingDefault
+	}
+	overflow := req.Overflow
+	if overflow == "" {
+		overflow = schema.OverflowError
+	}
+	textSum := sha256.Sum256([]byte(text))
+	h := sha256.New()
+	for _, part := range []string{
+		modelSum,
+		string(pooling),
+		strconv.FormatBool(normalize),
+		string(overflow),
+		strconv.Itoa(req.ChunkSize),
+		strconv.Itoa(req.ChunkOverlap),
+		hex.EncodeToString(textSum[:]),
+	} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// get returns an embedding from memory, or else from the persistent tier
+func (c *embedCache) get(key string) ([]float32, bool) {
+	c.Lock()
+	if elem, ok := c.entries[key]; ok {
+		c.lru.MoveToFront(elem)
+		embedding := slices.Clone(elem.Value.(*embedCacheEntry).embedding)
+		c.Unlock()
+		return embedding, true
+	}
+	c.Unlock()
+	if c.path == "" {
+		return nil, false
+	}
+
+	// Read the file, and mark it as recently used when files are removed by
+	// the time they were last used
+	path := c.filePath(key)
+	data, err := os.ReadFile(path)
+	if err != nil || len(data)%4 != 0 || len(data) == 0 {
+		return nil, false
+	}
+	if c.limit > 0 {
+		now := time.Now()
+		_ = os.Chtimes(path, now, now)
+	}
+	embedding := make([]float32, len(data)/4)
+	for i := range embedding {
+		embedding[i] = math.Float32frombits(binary.LittleEndian.Uint32(data[i*4:]))
+	}
+
+	// Keep the embedding in memory
+	c.Lock()
+	c.add(key, embedding)
+	c.Unlock()
+	return slices.Clone(embedding), true
+}
+
+// put adds an embedding to memory and to the persistent tier
+func (c *embedCache) put(key string, embedding []float32) error {
+	c.Lock()
+	c.add(key, slices.Clone(embedding))
+	c.Unlock()
+	if c.path == "" {
+		return nil
+	}
+
+	// Write to a temporary file, which is renamed into place
+	path := c.filePath(key)
+	if _, err := os.Stat(path); err == nil {
+		return nil
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	data := make([]byte, 0, len(embedding)*4)
+	for _, v := range embedding {
+		data = binary.LittleEndian.AppendUint32(data, math.Float32bits(v))
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), "."+key+"-*")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	if err := os.Rename(f.Name(), path); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	if c.limit == 0 {
+		return nil
+	}
+
+	// Remove the least recently used files when over the limit, unless they
+	// are already being removed
+	c.Lock()
+	c.bytes += uint64(len(data))
+	prune := c.bytes > c.limit && !c.pruning
+	if prune {
+		c.pruning = true
+	}
+	c.Unlock()
+	if prune {
+		return c.prune()
+	}
+	return nil
+}
+
+// prune removes the least recently used files of the persistent tier, until
+// it is nine tenths of the limit, so that it is not pruned on every write
+func (c *embedCache) prune() error {
+	files, err := c.files()
+	var bytes uint64
+	if err == nil {
+		for _, file := range files {
+			bytes += file.size
+		}
+		slices.SortFunc(files, func(a, b embedCacheFile) int {
+			return a.modTime.Compare(b.modTime)
+		})
+		for _, file := range files {
+			if bytes <= c.limit-c.limit/10 {
+				break
+			}
+			if err = os.Remove(file.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				break
+			}
+			bytes -= file.size
+			err = nil
+		}
+	}
+
+	c.Lock()
+	defer c.Unlock()
+	if err == nil {
+		c.bytes = bytes
+	}
+	c.pruning = false
+	return err
+}
+
+// files returns the files of the persistent tier
+func (c *embedCache) files() ([]embedCacheFile, error) {
+	var files []embedCacheFile
+	err := filepath.WalkDir(c.path, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		} else if d.IsDir() || filepath.Ext(path) != embedCacheExt {
+			return nil
+		}
+		info, err := d.Info()
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		files = append(files, embedCacheFile{path: path, size: uint64(info.Size()), modTime: info.ModTime()})
+		return nil
+	})
+	return files, err
+}
+
+// add puts an embedding at the front of the LRU, evicting the least recently
+// used entries, with the lock held
+func (c *embedCache) add(key string, embedding []float32) {
+	if c.size == 0 {
+		return
+	}
+	if elem, ok := c.entries[key]; ok {
+		c.lru.MoveToFront(elem)
+		return
+	}
+	c.entries[key] = c.lru.PushFront(&embedCacheEntry{key: key, embedding: embedding})
+	for c.lru.Len() > c.size {
+		elem := c.lru.Back()
+		c.lru.Remove(elem)
+		delete(c.entries, elem.Value.(*embedCacheEntry).key)
+	}
+}
+
+// filePath returns the path of the file for a key in the persistent tier,
+// in a subdirectory by the first two characters of the key
+func (c *embedCache) filePath(key string) string {
+	return filepath.Join(c.path, key[:2], key+embedCacheExt)
+}
+
+// embedLookup returns the cache key of each input, and the embedding of each
+// input which is in the cache, or nil if it is not
+func (l *Llama) embedLookup(ctx context.Context, req schema.EmbedRequest, normalize bool) ([]string, [][]float32, error) {
+	model, err := l.Store.GetModel(ctx, req.Model)
+	if err != nil {
+		return nil, nil, err
+	}
+	sum, err := l.embedCache.modelSum(model, l.Store.FilePaths(model))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	keys := make([]string, len(req.Input))
+	hits := make([][]float32, len(req.Input))
+	for i, text := range req.Input {
+		keys[i] = l.embedCache.key(sum, req, normalize, text)
+		if embedding, ok := l.embedCache.get(keys[i]); ok {
+			hits[i] = embedding
+		}
+	}
+	return keys, hits, nil
+}
@@ -0,0 +1,232 @@
+package llamacpp
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mutablelogic/go-llama/pkg/llamacpp/schema"
+)
+
+func TestEmbedCache_LRU(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	cache, err := newEmbedCache(2, "", 0)
+	require.NoError(err)
+
+	require.NoError(cache.put("aa1", []float32{1}))
+	require.NoError(cache.put("aa2", []float32{2}))
+
+	// Using the first entry makes the second the least recently used
+	_, ok := cache.get("aa1")
+	assert.True(ok)
+	require.NoError(cache.put("aa3", []float32{3}))
+
+	_, ok = cache.get("aa2")
+	assert.False(ok)
+	embedding, ok := cache.get("aa1")
+	assert.True(ok)
+	assert.Equal([]float32{1}, embedding)
+	embedding, ok = cache.get("aa3")
+	assert.True(ok)
+	assert.Equal([]float32{3}, embedding)
+}
+
+func TestEmbedCache_Clone(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	cache, err := newEmbedCache(1, "", 0)
+	require.NoError(err)
+
+	embedding := []float32{1, 2}
+	require.NoError(cache.put("aa1", embedding))
+	embedding[0] = 0
+
+	cached, ok := cache.get("aa1")
+	require.True(ok)
+	assert.Equal([]float32{1, 2}, cached)
+	cached[1] = 0
+
+	cached, ok = cache.get("aa1")
+	require.True(ok)
+	assert.Equal([]float32{1, 2}, cached)
+}
+
+func TestEmbedCache_Persistent(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	dir := t.TempDir()
+
+	// Without a memory tier, embeddings are only in files
+	cache, err := newEmbedCache(0, dir, 0)
+	require.NoError(err)
+	require.NoError(cache.put("ab12", []float32{0.5, -1.25}))
+	assert.FileExists(filepath.Join(dir, "ab", "ab12"+embedCacheExt))
+
+	// A new cache reads the files
+	cache, err = newEmbedCache(1, dir, 0)
+	require.NoError(err)
+	embedding, ok := cache.get("ab12")
+	assert.True(ok)
+	assert.Equal([]float32{0.5, -1.25}, embedding)
+
+	_, ok = cache.get("cd34")
+	assert.False(ok)
+}
+
+func TestEmbedCache_Limit(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	dir := t.TempDir()
+
+	// Each embedding is a file of eight bytes, so three fit in the limit
+	cache, err := newEmbedCache(0, dir, 24)
+	require.NoError(err)
+	for _, key := range []string{"aa1", "aa2", "aa3"} {
+		require.NoError(cache.put(key, []float32{1, 2}))
+	}
+	for i, key := range []string{"aa1", "aa2", "aa3"} {
+		modTime := time.Now().Add(time.Duration(i-3) * time.Hour)
+		require.NoError(os.Chtimes(cache.filePath(key), modTime, modTime))
+	}
+
+	// Reading the oldest embedding makes it the most recently used, so the
+	// next two are removed when the limit is exceeded
+	_, ok := cache.get("aa1")
+	require.True(ok)
+	require.NoError(cache.put("aa4", []float32{3, 4}))
+	assert.FileExists(cache.filePath("aa1"))
+	assert.NoFileExists(cache.filePath("aa2"))
+	assert.NoFileExists(cache.filePath("aa3"))
+	assert.FileExists(cache.filePath("aa4"))
+
+	// A new cache counts the existing files
+	cache, err = newEmbedCache(0, dir, 24)
+	require.NoError(err)
+	assert.Equal(uint64(16), cache.bytes)
+}
+
+func TestEmbedCache_Key(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	cache, err := newEmbedCache(1, "", 0)
+	require.NoError(err)
+
+	req := schema.EmbedRequest{Model: "model"}
+	key := cache.key("sum", req, true, "hello")
+	assert.Len(key, 64)
+
+	// The default pooling and overflow are the same as no pooling and overflow
+	assert.Equal(key, cache.key("sum", schema.EmbedRequest{Pooling: schema.PoolingDefault, Overflow: schema.OverflowError}, true, "hello"))
+
+	// Everything which changes the embedding changes the key
+	assert.NotEqual(key, cache.key("other", req, true, "hello"))
+	assert.NotEqual(key, cache.key("sum", req, false, "hello"))
+	assert.NotEqual(key, cache.key("sum", req, true, "hello!"))
+	assert.NotEqual(key, cache.key("sum", schema.EmbedRequest{Pooling: schema.PoolingCLS}, true, "hello"))
+	assert.NotEqual(key, cache.key("sum", schema.EmbedRequest{Overflow: schema.OverflowChunk}, true, "hello"))
+	assert.NotEqual(key, cache.key("sum", schema.EmbedRequest{ChunkSize: 16}, true, "hello"))
+}
+
+func TestEmbedCache_ModelSum(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	cache, err := newEmbedCache(1, "", 0)
+	require.NoError(err)
+
+	// The checksum of the file
+	path := filepath.Join(t.TempDir(), "model.gguf")
+	require.NoError(os.WriteFile(path, []byte("hello"), 0644))
+	sum, err := cache.modelSum(&schema.Model{}, []string{path})
+	require.NoError(err)
+	assert.Equal("2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824", sum)
+
+	// The checksum changes with the file
+	require.NoError(os.WriteFile(path, []byte("hello world"), 0644))
+	sum, err = cache.modelSum(&schema.Model{}, []string{path})
+	require.NoError(err)
+	assert.Equal("b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9", sum)
+
+	_, err = cache.modelSum(&schema.Model{}, []string{filepath.Join(t.TempDir(), "missing.gguf")})
+	assert.Error(err)
+}
+
+func TestEmbedCache_RecordedSum(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	cache, err := newEmbedCache(1, "", 0)
+	require.NoError(err)
+
+	// The recorded checksum is used when the file is not modified after it
+	// was recorded
+	path := filepath.Join(t.TempDir(), "model.gguf")
+	require.NoError(os.WriteFile(path, []byte("hello"), 0644))
+	model := &schema.Model{ModelMeta: schema.ModelMeta{SHA256: "recorded", PulledAt: time.Now().Add(time.Hour)}}
+	sum, err := cache.modelSum(model, []string{path})
+	require.NoError(err)
+	assert.Equal("recorded", sum)
+
+	// A file modified in place is checksummed again
+	require.NoError(os.WriteFile(path, []byte("hello world"), 0644))
+	modTime := time.Now().Add(2 * time.Hour)
+	require.NoError(os.Chtimes(path, modTime, modTime))
+	sum, err = cache.modelSum(model, []string{path})
+	require.NoError(err)
+	assert.Equal("b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9", sum)
+}
+
+func TestEmbedCache_SplitSum(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	cache, err := newEmbedCache(1, "", 0)
+	require.NoError(err)
+
+	dir := t.TempDir()
+	paths := []string{filepath.Join(dir, "model-00001-of-00002.gguf"), filepath.Join(dir, "model-00002-of-00002.gguf")}
+	require.NoError(os.WriteFile(paths[0], []byte("hello"), 0644))
+	require.NoError(os.WriteFile(paths[1], []byte("world"), 0644))
+	sum, err := cache.modelSum(&schema.Model{}, paths)
+	require.NoError(err)
+
+	// Every file of a split model changes the checksum
+	require.NoError(os.WriteFile(paths[1], []byte("world!"), 0644))
+	other, err := cache.modelSum(&schema.Model{}, paths)
+	require.NoError(err)
+	assert.NotEqual(sum, other)
+}
+
+func TestEmbedCache_WriteError(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	path, err := filepath.Abs("../../testdata")
+	require.NoError(err)
+	dir := filepath.Join(t.TempDir(), "cache")
+	l, err := New(path, WithEmbeddingCache(1, dir))
+	require.NoError(err)
+	defer l.Close()
+
+	// Replace the directory with a file, so that embeddings cannot be written
+	require.NoError(os.RemoveAll(dir))
+	require.NoError(os.WriteFile(dir, nil, 0644))
+
+	// The computed embeddings are still returned
+	req := schema.EmbedRequest{Model: "stories260K.gguf", Input: []string{"hello"}}
+	result, err := l.embedCached(context.Background(), req, false, true, func(req schema.EmbedRequest) (*schema.EmbedResponse, int, error) {
+		return &schema.EmbedResponse{Model: req.Model, Embeddings: [][]float32{{1, 2}}, Dimension: 2}, 2, nil
+	})
+	require.NoError(err)
+	assert.Equal([][]float32{{1, 2}}, result.Embeddings)
+	assert.Equal(1, result.Usage.CacheMisses)
+}
@@ -56,7 +56,8 @@ func (c *Client) Chat(ctx context.Context, model string, messages []schema.ChatM
 			Stop:          o.Stop,
 			PrefixCache:   o.PrefixCache,
 		},
-		Messages: messages,
+		Messages:  messages,
+		Retrieval: o.Retrieval,
 	}
 
 	req, err := client.NewJSONRequest(reqBody)
@@ -0,0 +1,135 @@
+package httpclient
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	// Packages
+	client "github.com/mutablelogic/go-client"
+	schema "github.com/mutablelogic/go-llama/pkg/llamacpp/schema"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// ListCollections returns all collections on the server.
+func (c *Client) ListCollections(ctx context.Context) ([]schema.Collection, error) {
+	var response []schema.Collection
+	if err := c.DoWithContext(ctx, client.NewRequest(), &response, client.OptPath("collection")); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// GetCollection returns a collection by name.
+func (c *Client) GetCollection(ctx context.Context, name string) (*schema.Collection, error) {
+	if name == "" {
+		return nil, fmt.Errorf("collection name cannot be empty")
+	}
+
+	var response schema.Collection
+	if err := c.DoWithContext(ctx, client.NewRequest(), &response, client.OptPath("collection", name)); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// DeleteCollection deletes a collection and all its documents.
+func (c *Client) DeleteCollection(ctx context.Context, name string) error {
+	if name == "" {
+		return fmt.Errorf("collection name cannot be empty")
+	}
+
+	req := client.NewRequestEx(http.MethodDelete, "")
+
+	// Perform request - expect 204 No Content
+	return c.DoWithContext(ctx, req, nil, client.OptPath("collection", name))
+}
+
+// AddDocuments embeds documents and stores them in a collection. The model
+// is required when the collection does not exist, and it is created.
+//
+// Example:
+//
+//	result, err := client.AddDocuments(ctx, "docs", "embedding-model", []schema.CollectionDocument{
+//		{Text: "The giant panda is a bear", Metadata: map[string]any{"topic": "animals"}},
+//	}, httpclient.WithIndex(schema.IndexHNSW))
+func (c *Client) AddDocuments(ctx context.Context, name, model string, documents []schema.CollectionDocument, opts ...Opt) (*schema.CollectionAddResponse, error) {
+	if name == "" {
+		return nil, fmt.Errorf("collection name cannot be empty")
+	}
+	if len(documents) == 0 {
+		return nil, fmt.Errorf("documents cannot be empty")
+	}
+
+	// Apply options
+	o, err := applyOpts(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	// Build request body
+	reqBody := schema.CollectionAddRequest{
+		Model:     model,
+		Index:     o.Index,
+		Documents: documents,
+	}
+
+	req, err := client.NewJSONRequest(reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	// Perform request
+	var response schema.CollectionAddResponse
+	if err := c.DoWithContext(ctx, req, &response, client.OptPath("collection", name, "documents")); err != nil {
+		return nil, err
+	}
+
+	return &response, nil
+}
+
+// SearchCollection returns the documents in a collection which are most
+// similar to a query.
+//
+// Example:
+//
+//	result, err := client.SearchCollection(ctx, "docs", "What is a panda?", httpclient.WithTopK(3), httpclient.WithFilter("topic", "animals"))
+func (c *Client) SearchCollection(ctx context.Context, name, query string, opts ...Opt) (*schema.CollectionSearchResponse, error) {
+	if name == "" {
+		return nil, fmt.Errorf("collection name cannot be empty")
+	}
+	if query == "" {
+		return nil, fmt.Errorf("query cannot be empty")
+	}
+
+	// Apply options
+	o, err := applyOpts(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	// Build request body
+	reqBody := schema.CollectionSearchRequest{
+		Query:    query,
+		Filter:   o.Filter,
+		MinScore: o.MinScore,
+	}
+	if o.TopK != nil {
+		reqBody.TopK = int(*o.TopK)
+	}
+
+	req, err := client.NewJSONRequest(reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	// Perform request
+	var response schema.CollectionSearchResponse
+	if err := c.DoWithContext(ctx, req, &response, client.OptPath("collection", name, "search")); err != nil {
+		return nil, err
+	}
+
+	return &response, nil
+}
@@ -3,12 +3,24 @@ package httpclient
 import (
 	"context"
 	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
 
 	// Packages
 	client "github.com/mutablelogic/go-client"
 	schema "github.com/mutablelogic/go-llama/pkg/llamacpp/schema"
 )
 
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// jsonStream is a request body with one JSON object per line
+type jsonStream struct {
+	io.Reader
+}
+
 ///////////////////////////////////////////////////////////////////////////////
 // PUBLIC METHODS
 
@@ -30,9 +42,16 @@ func (c *Client) Embed(ctx context.Context, model string, input []string, opts .
 
 	// Build request body
 	reqBody := schema.EmbedRequest{
-		Model:     model,
-		Input:     input,
-		Normalize: o.Normalize,
+		Model:        model,
+		Input:        input,
+		Normalize:    o.Normalize,
+		Pooling:      o.Pooling,
+		Dimensions:   o.Dimensions,
+		Encoding:     o.Encoding,
+		Overflow:     o.Overflow,
+		ChunkSize:    o.ChunkSize,
+		ChunkOverlap: o.Overlap,
+		Aggregate:    o.Aggregate,
 	}
 
 	req, err := client.NewJSONRequest(reqBody)
@@ -48,3 +67,92 @@ func (c *Client) Embed(ctx context.Context, model string, input []string, opts .
 
 	return &response, nil
 }
+
+// EmbedStream embeds the rows read from r, which has a JSON object on each
+// line with the text to embed, and calls fn with the embedding of each row
+// in the order the rows are read. Rows which cannot be embedded have an
+// error, and the stream continues. Use WithFields to set the fields of the
+// text and identifier of each row.
+//
+// Example:
+//
+//	err := client.EmbedStream(ctx, "embedding-model", file, func(row *schema.EmbedStreamRow) error {
+//	    fmt.Println(string(row.ID), len(row.Embedding))
+//	    return nil
+//	})
+func (c *Client) EmbedStream(ctx context.Context, model string, r io.Reader, fn func(*schema.EmbedStreamRow) error, opts ...Opt) error {
+	if model == "" {
+		return fmt.Errorf("model name cannot be empty")
+	} else if fn == nil {
+		return fmt.Errorf("callback cannot be nil")
+	}
+
+	// Apply options
+	o, err := applyOpts(opts...)
+	if err != nil {
+		return err
+	}
+
+	// Build the query
+	query := url.Values{"model": {model}}
+	if o.Field != "" {
+		query.Set("field", o.Field)
+	}
+	if o.IDField != "" {
+		query.Set("id_field", o.IDField)
+	}
+	if o.Normalize != nil {
+		query.Set("normalize", strconv.FormatBool(*o.Normalize))
+	}
+	if o.Pooling != "" {
+		query.Set("pooling", string(o.Pooling))
+	}
+	if o.Dimensions > 0 {
+		query.Set("dimensions", strconv.Itoa(o.Dimensions))
+	}
+	if o.Encoding != "" {
+		query.Set("encoding", string(o.Encoding))
+	}
+	if o.Overflow != "" {
+		query.Set("overflow", string(o.Overflow))
+	}
+	if o.ChunkSize > 0 {
+		query.Set("chunk_size", strconv.Itoa(o.ChunkSize))
+	}
+	if o.Overlap > 0 {
+		query.Set("chunk_overlap", strconv.Itoa(o.Overlap))
+	}
+
+	// Perform request, with rows returned while the body is sent
+	var response schema.EmbedStreamRow
+	return c.DoWithContext(ctx, jsonStream{r}, &response,
+		client.OptPath("embed", "stream"),
+		client.OptQuery(query),
+		client.OptNoTimeout(),
+		client.OptJsonStreamCallback(func(v any) error {
+			row := *v.(*schema.EmbedStreamRow)
+			response = schema.EmbedStreamRow{}
+
+			// An error which ends the stream
+			if row.Line == 0 && row.Error != "" {
+				return fmt.Errorf("%s", row.Error)
+			}
+			return fn(&row)
+		}),
+	)
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+func (jsonStream) Method() string {
+	return http.MethodPost
+}
+
+func (jsonStream) Accept() string {
+	return client.ContentTypeJsonStream
+}
+
+func (jsonStream) Type() string {
+	return client.ContentTypeJsonStream
+}
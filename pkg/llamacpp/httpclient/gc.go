@@ -0,0 +1,34 @@
+package httpclient
+
+import (
+	"context"
+
+	// Packages
+	client "github.com/mutablelogic/go-client"
+	schema "github.com/mutablelogic/go-llama/pkg/llamacpp/schema"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// GarbageCollect removes temporary files left in the store by interrupted
+// pulls, imports and uploads and, if the store is over quota, evicts the least
+// recently used models which are not pinned or loaded. If dryRun is true, the
+// files which would be removed are returned without removing them.
+func (c *Client) GarbageCollect(ctx context.Context, dryRun bool) (*schema.GCResponse, error) {
+	req, err := client.NewJSONRequest(schema.GCRequest{
+		DryRun: dryRun,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Perform request
+	var response schema.GCResponse
+	if err := c.DoWithContext(ctx, req, &response, client.OptPath("gc")); err != nil {
+		return nil, err
+	}
+
+	// Return the response
+	return &response, nil
+}
@@ -4,23 +4,71 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
+	"net/url"
+	"strconv"
 
 	// Packages
 	client "github.com/mutablelogic/go-client"
 	schema "github.com/mutablelogic/go-llama/pkg/llamacpp/schema"
 )
 
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// uploadRequest is a request with a model file as the raw body
+type uploadRequest struct {
+	io.Reader
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	contentTypeBinary = "application/octet-stream"
+)
+
 ///////////////////////////////////////////////////////////////////////////////
 // PUBLIC METHODS
 
 // ListModels returns a list of all available models from the llama API.
-func (c *Client) ListModels(ctx context.Context) ([]*schema.CachedModel, error) {
+// Use WithLabel, WithArch, WithCapability, WithMinSize and WithMaxSize to
+// filter the models.
+func (c *Client) ListModels(ctx context.Context, opts ...Opt) ([]*schema.CachedModel, error) {
+	// Apply options
+	o, err := applyOpts(opts...)
+	if err != nil {
+		return nil, err
+	}
+
 	req := client.NewRequest()
 
+	// Set up request options
+	query := url.Values{}
+	for _, label := range o.filter.Label {
+		query.Add("label", label)
+	}
+	if o.filter.Arch != "" {
+		query.Set("arch", o.filter.Arch)
+	}
+	for _, capability := range o.filter.Capability {
+		query.Add("capability", capability)
+	}
+	if o.filter.MinSize > 0 {
+		query.Set("min_size", strconv.FormatUint(o.filter.MinSize, 10))
+	}
+	if o.filter.MaxSize > 0 {
+		query.Set("max_size", strconv.FormatUint(o.filter.MaxSize, 10))
+	}
+	reqOpts := []client.RequestOpt{client.OptPath("model")}
+	if len(query) > 0 {
+		reqOpts = append(reqOpts, client.OptQuery(query))
+	}
+
 	// Perform request
 	var response []*schema.CachedModel
-	if err := c.DoWithContext(ctx, req, &response, client.OptPath("model")); err != nil {
+	if err := c.DoWithContext(ctx, req, &response, reqOpts...); err != nil {
 		return nil, err
 	}
 
@@ -29,16 +77,29 @@ func (c *Client) ListModels(ctx context.Context) ([]*schema.CachedModel, error)
 }
 
 // GetModel retrieves a specific model by its ID.
-func (c *Client) GetModel(ctx context.Context, id string) (*schema.CachedModel, error) {
+// Use WithTensors to include the tensor layout of the model file.
+func (c *Client) GetModel(ctx context.Context, id string, opts ...Opt) (*schema.CachedModel, error) {
 	if id == "" {
 		return nil, fmt.Errorf("model id cannot be empty")
 	}
 
+	// Apply options
+	o, err := applyOpts(opts...)
+	if err != nil {
+		return nil, err
+	}
+
 	req := client.NewRequest()
 
+	// Set up request options
+	reqOpts := []client.RequestOpt{client.OptPath("model", id)}
+	if o.Tensors {
+		reqOpts = append(reqOpts, client.OptQuery(url.Values{"tensors": {"true"}}))
+	}
+
 	// Perform request
 	var response schema.CachedModel
-	if err := c.DoWithContext(ctx, req, &response, client.OptPath("model", id)); err != nil {
+	if err := c.DoWithContext(ctx, req, &response, reqOpts...); err != nil {
 		return nil, err
 	}
 
@@ -46,6 +107,25 @@ func (c *Client) GetModel(ctx context.Context, id string) (*schema.CachedModel,
 	return &response, nil
 }
 
+// ListRemoteModels returns the GGUF models in a Hugging Face repository,
+// given as "org/repo" with an optional "@branch".
+func (c *Client) ListRemoteModels(ctx context.Context, repo string) ([]*schema.RemoteModel, error) {
+	if repo == "" {
+		return nil, fmt.Errorf("repository cannot be empty")
+	}
+
+	req := client.NewRequest()
+
+	// Perform request
+	var response []*schema.RemoteModel
+	if err := c.DoWithContext(ctx, req, &response, client.OptPath("model-remote"), client.OptQuery(url.Values{"repo": {repo}})); err != nil {
+		return nil, err
+	}
+
+	// Return the response
+	return response, nil
+}
+
 // LoadModel loads a model into memory with the given options.
 func (c *Client) LoadModel(ctx context.Context, name string, opts ...Opt) (*schema.CachedModel, error) {
 	if name == "" {
@@ -83,7 +163,8 @@ func (c *Client) LoadModel(ctx context.Context, name string, opts ...Opt) (*sche
 }
 
 // PullModel downloads and caches a model from a URL.
-// Use WithProgressCallback to receive download progress updates.
+// Use WithProgressCallback to receive download progress updates, and WithToken
+// to pull from a gated or private Hugging Face repository.
 //
 // Example:
 //
@@ -108,7 +189,8 @@ func (c *Client) PullModel(ctx context.Context, url string, opts ...Opt) (*schem
 
 	// Build request body
 	reqBody := schema.PullModelRequest{
-		URL: url,
+		URL:   url,
+		Token: o.token,
 	}
 
 	req, err := client.NewJSONRequest(reqBody)
@@ -159,6 +241,59 @@ func (c *Client) PullModel(ctx context.Context, url string, opts ...Opt) (*schem
 	return &response, nil
 }
 
+// ImportModel imports a model file on the server into the store. Use WithDest
+// to set the path in the store, and WithImportMode to hardlink or symlink the
+// file rather than copying it.
+func (c *Client) ImportModel(ctx context.Context, path string, opts ...Opt) (*schema.CachedModel, error) {
+	if path == "" {
+		return nil, fmt.Errorf("model path cannot be empty")
+	}
+
+	// Apply options
+	o, err := applyOpts(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	// Build request body
+	req, err := client.NewJSONRequest(schema.ImportModelRequest{
+		Path: path,
+		Dest: o.dest,
+		Mode: o.importMode,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Perform request
+	var response schema.CachedModel
+	if err := c.DoWithContext(ctx, req, &response, client.OptPath("model-import"), client.OptNoTimeout()); err != nil {
+		return nil, err
+	}
+
+	// Return the response
+	return &response, nil
+}
+
+// UploadModel uploads a model file, read from r, to the server and stores
+// it at dest, a path relative to the store.
+func (c *Client) UploadModel(ctx context.Context, dest string, r io.Reader) (*schema.CachedModel, error) {
+	if dest == "" {
+		return nil, fmt.Errorf("model path cannot be empty")
+	} else if r == nil {
+		return nil, fmt.Errorf("model file cannot be nil")
+	}
+
+	// Perform request, streaming the file as the request body
+	var response schema.CachedModel
+	if err := c.DoWithContext(ctx, &uploadRequest{r}, &response, client.OptPath("model", dest), client.OptNoTimeout()); err != nil {
+		return nil, err
+	}
+
+	// Return the response
+	return &response, nil
+}
+
 // UnloadModel unloads a model from memory and returns the unloaded model.
 func (c *Client) UnloadModel(ctx context.Context, id string) (*schema.CachedModel, error) {
 	if id == "" {
@@ -200,3 +335,51 @@ func (c *Client) DeleteModel(ctx context.Context, id string) error {
 
 	return nil
 }
+
+// PinModel pins or unpins a model in the store. A pinned model is not
+// evicted by garbage collection when the store is over quota.
+func (c *Client) PinModel(ctx context.Context, id string, pinned bool) (*schema.CachedModel, error) {
+	return c.UpdateModel(ctx, id, schema.UpdateModelRequest{
+		Pinned: &pinned,
+	})
+}
+
+// UpdateModel pins or unpins a model in the store, or replaces its labels or
+// notes. Fields of the request which are nil are not changed.
+func (c *Client) UpdateModel(ctx context.Context, id string, update schema.UpdateModelRequest) (*schema.CachedModel, error) {
+	if id == "" {
+		return nil, fmt.Errorf("model id cannot be empty")
+	}
+
+	req, err := client.NewJSONRequestEx(http.MethodPatch, update, client.ContentTypeJson)
+	if err != nil {
+		return nil, err
+	}
+
+	// Perform request
+	var response schema.CachedModel
+	if err := c.DoWithContext(ctx, req, &response, client.OptPath("model", id)); err != nil {
+		return nil, err
+	}
+
+	// Return the response
+	return &response, nil
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// Method returns the HTTP method of an upload
+func (r *uploadRequest) Method() string {
+	return http.MethodPut
+}
+
+// Accept returns the accepted mime-type of the response
+func (r *uploadRequest) Accept() string {
+	return client.ContentTypeJson
+}
+
+// Type returns the mime-type of the model file
+func (r *uploadRequest) Type() string {
+	return contentTypeBinary
+}
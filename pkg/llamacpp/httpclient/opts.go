@@ -11,6 +11,12 @@ import (
 // TYPES
 
 type opt struct {
+	// Model query options
+	Tensors bool
+
+	// Model list options
+	filter schema.ListModelsRequest
+
 	// Model loading options
 	Gpu    *int32
 	Layers *int32
@@ -29,10 +35,35 @@ type opt struct {
 	PrefixCache   *bool
 
 	// Chat options
-	System *string
+	System    *string
+	Retrieval *schema.ChatRetrieval
 
 	// Embedding options
-	Normalize *bool
+	Normalize  *bool
+	Pooling    schema.Pooling
+	Dimensions int
+	Encoding   schema.EmbeddingEncoding
+	Overflow   schema.EmbeddingOverflow
+	ChunkSize  int
+	Overlap    int
+	Aggregate  schema.EmbeddingAggregate
+	Field      string
+	IDField    string
+
+	// Rerank options
+	TopN            int
+	ReturnDocuments bool
+
+	// Collection options
+	Index    schema.CollectionIndex
+	Filter   map[string]any
+	MinScore float64
+
+	// Similarity options
+	Query     string
+	Cluster   schema.ClusterMethod
+	Clusters  int
+	Threshold *float64
 
 	// Tokenizer options
 	AddSpecial     *bool
@@ -40,6 +71,13 @@ type opt struct {
 	RemoveSpecial  *bool
 	UnparseSpecial *bool
 
+	// Pull options
+	token string
+
+	// Import options
+	dest       string
+	importMode schema.ImportMode
+
 	// Streaming callback
 	chunkCallback     func(*schema.CompletionChunk) error
 	chatChunkCallback func(*schema.ChatChunk) error
@@ -62,6 +100,65 @@ func applyOpts(opts ...Opt) (*opt, error) {
 	return o, nil
 }
 
+///////////////////////////////////////////////////////////////////////////////
+// OPTIONS - MODEL QUERY
+
+// WithTensors includes the tensor layout when retrieving a model.
+func WithTensors(tensors bool) Opt {
+	return func(o *opt) error {
+		o.Tensors = tensors
+		return nil
+	}
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// OPTIONS - MODEL LIST
+
+// WithLabel lists only models with all of the given labels.
+func WithLabel(labels ...string) Opt {
+	return func(o *opt) error {
+		o.filter.Label = append(o.filter.Label, labels...)
+		return nil
+	}
+}
+
+// WithArch lists only models with the given architecture, e.g. "llama".
+func WithArch(arch string) Opt {
+	return func(o *opt) error {
+		o.filter.Arch = arch
+		return nil
+	}
+}
+
+// WithCapability lists only models with all of the given capabilities.
+func WithCapability(capabilities ...schema.Capability) Opt {
+	return func(o *opt) error {
+		for _, capability := range capabilities {
+			o.filter.Capability = append(o.filter.Capability, string(capability))
+		}
+		return nil
+	}
+}
+
+// WithMinSize lists only models of at least the given size in bytes.
+func WithMinSize(size uint64) Opt {
+	return func(o *opt) error {
+		o.filter.MinSize = size
+		return nil
+	}
+}
+
+// WithMaxSize lists only models of at most the given size in bytes.
+func WithMaxSize(size uint64) Opt {
+	return func(o *opt) error {
+		if o.filter.MinSize > 0 && size > 0 && size < o.filter.MinSize {
+			return fmt.Errorf("max size %d is less than min size %d", size, o.filter.MinSize)
+		}
+		o.filter.MaxSize = size
+		return nil
+	}
+}
+
 ///////////////////////////////////////////////////////////////////////////////
 // OPTIONS - MODEL LOADING
 
@@ -136,7 +233,8 @@ func WithTopP(topP float32) Opt {
 	}
 }
 
-// WithTopK sets the top-k sampling parameter.
+// WithTopK sets the top-k sampling parameter, or the number of results of a
+// collection search or a similarity ranking.
 func WithTopK(topK int32) Opt {
 	return func(o *opt) error {
 		if topK < 1 {
@@ -201,6 +299,21 @@ func WithSystem(system string) Opt {
 	}
 }
 
+// WithRetrieval grounds a chat in the documents of a collection, which are
+// added to the last user message with citation markers.
+func WithRetrieval(retrieval schema.ChatRetrieval) Opt {
+	return func(o *opt) error {
+		if retrieval.Collection == "" {
+			return fmt.Errorf("retrieval collection cannot be empty")
+		}
+		if retrieval.TopK < 0 {
+			return fmt.Errorf("retrieval top_k cannot be negative")
+		}
+		o.Retrieval = &retrieval
+		return nil
+	}
+}
+
 // WithChunkCallback sets a callback function to receive streaming chunks.
 // This enables streaming support for text completion.
 func WithChunkCallback(callback func(*schema.CompletionChunk) error) Opt {
@@ -218,6 +331,15 @@ func WithChatChunkCallback(callback func(*schema.ChatChunk) error) Opt {
 	}
 }
 
+// WithToken sets the Hugging Face token used by the server to pull a model
+// from a gated or private repository.
+func WithToken(token string) Opt {
+	return func(o *opt) error {
+		o.token = token
+		return nil
+	}
+}
+
 // WithProgressCallback sets a callback function to receive progress updates.
 // This enables streaming support for model pull operations.
 func WithProgressCallback(callback func(filename string, bytesReceived, totalBytes uint64) error) Opt {
@@ -227,6 +349,31 @@ func WithProgressCallback(callback func(filename string, bytesReceived, totalByt
 	}
 }
 
+///////////////////////////////////////////////////////////////////////////////
+// OPTIONS - IMPORT
+
+// WithDest sets the path of an imported model relative to the store.
+func WithDest(dest string) Opt {
+	return func(o *opt) error {
+		o.dest = dest
+		return nil
+	}
+}
+
+// WithImportMode sets whether an imported model file is copied, hardlinked
+// or symlinked into the store.
+func WithImportMode(mode schema.ImportMode) Opt {
+	return func(o *opt) error {
+		switch mode {
+		case schema.ImportCopy, schema.ImportHardlink, schema.ImportSymlink:
+			o.importMode = mode
+			return nil
+		default:
+			return fmt.Errorf("invalid import mode %q", mode)
+		}
+	}
+}
+
 ///////////////////////////////////////////////////////////////////////////////
 // OPTIONS - EMBEDDING
 
@@ -238,6 +385,214 @@ func WithNormalize(normalize bool) Opt {
 	}
 }
 
+// WithPooling sets how token embeddings are combined. With schema.PoolingNone,
+// the embedding of every token is returned.
+func WithPooling(pooling schema.Pooling) Opt {
+	return func(o *opt) error {
+		if !pooling.Valid() {
+			return fmt.Errorf("invalid pooling %q", pooling)
+		}
+		o.Pooling = pooling
+		return nil
+	}
+}
+
+// WithDimensions truncates embeddings to the first dimensions, which are
+// normalized again, for models trained with Matryoshka representation
+// learning.
+func WithDimensions(dimensions int) Opt {
+	return func(o *opt) error {
+		if dimensions < 0 {
+			return fmt.Errorf("dimensions cannot be negative")
+		}
+		o.Dimensions = dimensions
+		return nil
+	}
+}
+
+// WithEncoding sets the encoding of embeddings, which are numbers by default.
+// Other encodings are packed into base64, and the int8 and binary encodings
+// return the scale of each embedding for dequantizing.
+func WithEncoding(encoding schema.EmbeddingEncoding) Opt {
+	return func(o *opt) error {
+		if !encoding.Valid() {
+			return fmt.Errorf("invalid encoding %q", encoding)
+		}
+		o.Encoding = encoding
+		return nil
+	}
+}
+
+// WithOverflow sets what happens to inputs which are too long to embed at
+// once: refuse them, truncate them, or embed them in chunks.
+func WithOverflow(overflow schema.EmbeddingOverflow) Opt {
+	return func(o *opt) error {
+		if !overflow.Valid() {
+			return fmt.Errorf("invalid overflow %q", overflow)
+		}
+		o.Overflow = overflow
+		return nil
+	}
+}
+
+// WithChunkSize sets the most tokens in each chunk of a long input, and the
+// number of tokens repeated between chunks. For embeddings, a size of zero
+// is the most tokens the model can embed at once, and for Chunk it is
+// schema.DefaultChunkSize.
+func WithChunkSize(size, overlap int) Opt {
+	return func(o *opt) error {
+		if size < 0 || overlap < 0 {
+			return fmt.Errorf("chunk size and overlap cannot be negative")
+		} else if size > 0 && overlap >= size {
+			return fmt.Errorf("chunk overlap must be less than the chunk size")
+		}
+		o.ChunkSize = size
+		o.Overlap = overlap
+		return nil
+	}
+}
+
+// WithAggregate sets how the chunks of a long input are combined. With
+// schema.AggregateNone, the embedding of each chunk is returned.
+func WithAggregate(aggregate schema.EmbeddingAggregate) Opt {
+	return func(o *opt) error {
+		if !aggregate.Valid() {
+			return fmt.Errorf("invalid aggregate %q", aggregate)
+		}
+		o.Aggregate = aggregate
+		return nil
+	}
+}
+
+// WithFields sets the fields of each row of an embedding stream with the
+// text to embed and the identifier of the row, which are "text" and "id" by
+// default.
+func WithFields(text, id string) Opt {
+	return func(o *opt) error {
+		o.Field = text
+		o.IDField = id
+		return nil
+	}
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// OPTIONS - RERANK
+
+// WithTopN limits the number of reranked documents returned.
+func WithTopN(n int) Opt {
+	return func(o *opt) error {
+		if n < 0 {
+			return fmt.Errorf("top_n cannot be negative")
+		}
+		o.TopN = n
+		return nil
+	}
+}
+
+// WithReturnDocuments includes the document text in rerank results.
+func WithReturnDocuments() Opt {
+	return func(o *opt) error {
+		o.ReturnDocuments = true
+		return nil
+	}
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// OPTIONS - COLLECTIONS
+
+// WithIndex sets the search index of a collection when it is created.
+func WithIndex(index schema.CollectionIndex) Opt {
+	return func(o *opt) error {
+		if !index.Valid() {
+			return fmt.Errorf("invalid index %q", index)
+		}
+		o.Index = index
+		return nil
+	}
+}
+
+// WithFilter returns only documents with a metadata value, or with any of
+// the values when there are several.
+func WithFilter(key string, values ...any) Opt {
+	return func(o *opt) error {
+		if key == "" || len(values) == 0 {
+			return fmt.Errorf("filter requires a key and a value")
+		}
+		if o.Filter == nil {
+			o.Filter = make(map[string]any)
+		}
+		if len(values) == 1 {
+			o.Filter[key] = values[0]
+		} else {
+			o.Filter[key] = values
+		}
+		return nil
+	}
+}
+
+// WithMinScore returns only documents with at least this similarity.
+func WithMinScore(score float64) Opt {
+	return func(o *opt) error {
+		o.MinScore = score
+		return nil
+	}
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// OPTIONS - SIMILARITY
+
+// WithQuery ranks texts by similarity to a query, rather than comparing
+// every pair of texts.
+func WithQuery(query string) Opt {
+	return func(o *opt) error {
+		if query == "" {
+			return fmt.Errorf("query cannot be empty")
+		}
+		o.Query = query
+		return nil
+	}
+}
+
+// WithKMeans groups texts into k clusters with k-means.
+func WithKMeans(k int) Opt {
+	return func(o *opt) error {
+		if k < 1 {
+			return fmt.Errorf("clusters must be at least 1")
+		}
+		o.Cluster = schema.ClusterKMeans
+		o.Clusters = k
+		o.Threshold = nil
+		return nil
+	}
+}
+
+// WithAgglomerative groups texts by merging the most similar clusters, until
+// there are k clusters or no clusters have an average similarity of at least
+// the threshold set with WithThreshold. Set k to zero to merge by the
+// threshold alone.
+func WithAgglomerative(k int) Opt {
+	return func(o *opt) error {
+		if k < 0 {
+			return fmt.Errorf("clusters cannot be negative")
+		}
+		o.Cluster = schema.ClusterAgglomerative
+		o.Clusters = k
+		return nil
+	}
+}
+
+// WithThreshold sets the least average similarity of merged clusters, for
+// agglomerative clustering.
+func WithThreshold(threshold float64) Opt {
+	return func(o *opt) error {
+		if threshold < -1 || threshold > 1 {
+			return fmt.Errorf("threshold must be between -1 and 1")
+		}
+		o.Threshold = &threshold
+		return nil
+	}
+}
+
 ///////////////////////////////////////////////////////////////////////////////
 // OPTIONS - TOKENIZER
 
@@ -0,0 +1,145 @@
+package httpclient
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	// Packages
+	client "github.com/mutablelogic/go-client"
+	schema "github.com/mutablelogic/go-llama/pkg/llamacpp/schema"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// StartPullJob starts downloading a model from a URL in the background, and
+// returns the pull job. If the URL is already being pulled, the existing job
+// is returned. Use WithToken to pull from a gated or private Hugging Face
+// repository.
+func (c *Client) StartPullJob(ctx context.Context, url string, opts ...Opt) (*schema.PullJob, error) {
+	if url == "" {
+		return nil, fmt.Errorf("model URL cannot be empty")
+	}
+
+	// Apply options
+	o, err := applyOpts(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	// Build request body
+	req, err := client.NewJSONRequest(schema.PullModelRequest{
+		URL:   url,
+		Token: o.token,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Perform request
+	var response schema.PullJob
+	if err := c.DoWithContext(ctx, req, &response, client.OptPath("pull")); err != nil {
+		return nil, err
+	}
+
+	// Return the response
+	return &response, nil
+}
+
+// ListPullJobs returns the running pull jobs, and those which finished recently.
+func (c *Client) ListPullJobs(ctx context.Context) ([]*schema.PullJob, error) {
+	req := client.NewRequest()
+
+	// Perform request
+	var response []*schema.PullJob
+	if err := c.DoWithContext(ctx, req, &response, client.OptPath("pull")); err != nil {
+		return nil, err
+	}
+
+	// Return the response
+	return response, nil
+}
+
+// GetPullJob returns a pull job by id.
+func (c *Client) GetPullJob(ctx context.Context, id string) (*schema.PullJob, error) {
+	if id == "" {
+		return nil, fmt.Errorf("pull job id cannot be empty")
+	}
+
+	req := client.NewRequest()
+
+	// Perform request
+	var response schema.PullJob
+	if err := c.DoWithContext(ctx, req, &response, client.OptPath("pull", id)); err != nil {
+		return nil, err
+	}
+
+	// Return the response
+	return &response, nil
+}
+
+// CancelPullJob cancels a pull job and returns the cancelled job.
+func (c *Client) CancelPullJob(ctx context.Context, id string) (*schema.PullJob, error) {
+	if id == "" {
+		return nil, fmt.Errorf("pull job id cannot be empty")
+	}
+
+	req := client.NewRequestEx(http.MethodDelete, client.ContentTypeJson)
+
+	// Perform request
+	var response schema.PullJob
+	if err := c.DoWithContext(ctx, req, &response, client.OptPath("pull", id)); err != nil {
+		return nil, err
+	}
+
+	// Return the response
+	return &response, nil
+}
+
+// WatchPullJob calls the function with each progress event of a pull job,
+// starting with the events which have already happened, until the job
+// finishes. The last event has the final status of the job.
+//
+// Example:
+//
+//	err := client.WatchPullJob(ctx, job.ID, func(job *schema.PullJob) error {
+//	    fmt.Printf("%s: %.1f%% (%d bytes/s)\n", job.Filename, job.Percentage, job.BytesPerSecond)
+//	    return nil
+//	})
+func (c *Client) WatchPullJob(ctx context.Context, id string, fn func(*schema.PullJob) error) error {
+	if id == "" {
+		return fmt.Errorf("pull job id cannot be empty")
+	} else if fn == nil {
+		return fmt.Errorf("callback cannot be nil")
+	}
+
+	req := client.NewRequest()
+
+	// Set up request options
+	reqOpts := []client.RequestOpt{
+		client.OptPath("pull", id),
+		client.OptQuery(url.Values{"stream": {"true"}}),
+		client.OptReqHeader("Accept", "text/event-stream"),
+		client.OptNoTimeout(),
+		client.OptTextStreamCallback(func(evt client.TextStreamEvent) error {
+			switch evt.Event {
+			case schema.ModelPullProgressType, schema.ModelPullCompleteType, schema.ModelPullErrorType:
+				var job schema.PullJob
+				if err := evt.Json(&job); err != nil {
+					return fmt.Errorf("failed to parse pull job: %w", err)
+				}
+				// An error which is not from the job itself
+				if job.ID == "" && job.Error != "" {
+					return fmt.Errorf("%s", job.Error)
+				}
+				return fn(&job)
+			}
+			return nil
+		}),
+	}
+
+	// Perform request
+	return c.DoWithContext(ctx, req, nil, reqOpts...)
+}
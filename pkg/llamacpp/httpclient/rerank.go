@@ -0,0 +1,56 @@
+package httpclient
+
+import (
+	"context"
+	"fmt"
+
+	// Packages
+	client "github.com/mutablelogic/go-client"
+	schema "github.com/mutablelogic/go-llama/pkg/llamacpp/schema"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Rerank scores documents against a query, and returns the documents sorted
+// by relevance.
+//
+// Example:
+//
+//	result, err := client.Rerank(ctx, "rerank-model", "What is a panda?", []string{"hi", "The giant panda is a bear"}, httpclient.WithTopN(1))
+func (c *Client) Rerank(ctx context.Context, model, query string, documents []string, opts ...Opt) (*schema.RerankResponse, error) {
+	if model == "" {
+		return nil, fmt.Errorf("model name cannot be empty")
+	}
+	if query == "" {
+		return nil, fmt.Errorf("query cannot be empty")
+	}
+
+	// Apply options
+	o, err := applyOpts(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	// Build request body
+	reqBody := schema.RerankRequest{
+		Model:           model,
+		Query:           query,
+		Documents:       documents,
+		TopN:            o.TopN,
+		ReturnDocuments: o.ReturnDocuments,
+	}
+
+	req, err := client.NewJSONRequest(reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	// Perform request
+	var response schema.RerankResponse
+	if err := c.DoWithContext(ctx, req, &response, client.OptPath("rerank")); err != nil {
+		return nil, err
+	}
+
+	return &response, nil
+}
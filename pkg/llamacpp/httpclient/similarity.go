@@ -0,0 +1,62 @@
+package httpclient
+
+import (
+	"context"
+	"fmt"
+
+	// Packages
+	client "github.com/mutablelogic/go-client"
+	schema "github.com/mutablelogic/go-llama/pkg/llamacpp/schema"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Similarity compares texts by the cosine similarity of their embeddings,
+// and returns the similarity of every pair of texts. Use WithQuery to rank
+// the texts by similarity to a query instead, and WithKMeans or
+// WithAgglomerative to group the texts into clusters.
+//
+// Example:
+//
+//	result, err := client.Similarity(ctx, "embedding-model", []string{"a cat", "a kitten", "a car"}, httpclient.WithAgglomerative(0, 0.8))
+func (c *Client) Similarity(ctx context.Context, model string, input []string, opts ...Opt) (*schema.SimilarityResponse, error) {
+	if model == "" {
+		return nil, fmt.Errorf("model name cannot be empty")
+	}
+	if len(input) == 0 {
+		return nil, fmt.Errorf("input cannot be empty")
+	}
+
+	// Apply options
+	o, err := applyOpts(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	// Build request body
+	reqBody := schema.SimilarityRequest{
+		Model:     model,
+		Input:     input,
+		Query:     o.Query,
+		Cluster:   o.Cluster,
+		Clusters:  o.Clusters,
+		Threshold: o.Threshold,
+	}
+	if o.TopK != nil {
+		reqBody.TopK = int(*o.TopK)
+	}
+
+	req, err := client.NewJSONRequest(reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	// Perform request
+	var response schema.SimilarityResponse
+	if err := c.DoWithContext(ctx, req, &response, client.OptPath("similarity")); err != nil {
+		return nil, err
+	}
+
+	return &response, nil
+}
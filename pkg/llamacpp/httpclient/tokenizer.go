@@ -87,3 +87,43 @@ func (c *Client) Detokenize(ctx context.Context, model string, tokens []schema.T
 
 	return &response, nil
 }
+
+// Chunk splits text into chunks of at most a number of tokens of the model,
+// at markdown headings, paragraphs, sentences or words where possible. Use
+// WithChunkSize to set the size of each chunk and the overlap between them.
+//
+// Example:
+//
+//	result, err := client.Chunk(ctx, "llama-7b", text, httpclient.WithChunkSize(256, 32))
+func (c *Client) Chunk(ctx context.Context, model, text string, opts ...Opt) (*schema.ChunkResponse, error) {
+	if model == "" {
+		return nil, fmt.Errorf("model name cannot be empty")
+	}
+
+	// Apply options
+	o, err := applyOpts(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	// Build request body
+	reqBody := schema.ChunkRequest{
+		Model:   model,
+		Text:    text,
+		Size:    o.ChunkSize,
+		Overlap: o.Overlap,
+	}
+
+	req, err := client.NewJSONRequest(reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	// Perform request
+	var response schema.ChunkResponse
+	if err := c.DoWithContext(ctx, req, &response, client.OptPath("chunk")); err != nil {
+		return nil, err
+	}
+
+	return &response, nil
+}
@@ -109,6 +109,29 @@ func TestChatCreate_MissingMessages(t *testing.T) {
 	assert.Equal(t, http.StatusBadRequest, rw.Code)
 }
 
+func TestChatCreate_InvalidRetrieval(t *testing.T) {
+	llama := setupTestLlama(t)
+	defer func() {
+		_ = llama.Close()
+	}()
+
+	router := http.NewServeMux()
+	RegisterChatHandlers(router, "/api", llama, noopMiddleware())
+
+	for _, reqBody := range []string{
+		`{"model": "test-model", "messages": [{"role": "user", "content": "Hi"}], "retrieval": {}}`,
+		`{"model": "test-model", "messages": [{"role": "user", "content": "Hi"}], "retrieval": {"collection": "docs", "top_k": -1}}`,
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/api/chat", strings.NewReader(reqBody))
+		req.Header.Set("Content-Type", "application/json")
+		rw := httptest.NewRecorder()
+
+		router.ServeHTTP(rw, req)
+
+		assert.Equal(t, http.StatusBadRequest, rw.Code, reqBody)
+	}
+}
+
 func TestChatCreate_InvalidJSON(t *testing.T) {
 	llama := setupTestLlama(t)
 	defer func() {
@@ -0,0 +1,124 @@
+package httphandler
+
+import (
+	"net/http"
+
+	// Packages
+	llamacpp "github.com/mutablelogic/go-llama/pkg/llamacpp"
+	schema "github.com/mutablelogic/go-llama/pkg/llamacpp/schema"
+	httprequest "github.com/mutablelogic/go-server/pkg/httprequest"
+	httpresponse "github.com/mutablelogic/go-server/pkg/httpresponse"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// RegisterCollectionHandlers registers HTTP handlers for Collection operations
+func RegisterCollectionHandlers(router *http.ServeMux, prefix string, llamaInstance *llamacpp.Llama, middleware HTTPMiddlewareFuncs) {
+	// GET /collection - list all collections
+	router.HandleFunc("GET "+joinPath(prefix, "collection"), middleware.Wrap(func(w http.ResponseWriter, r *http.Request) {
+		_ = collectionList(w, r, llamaInstance)
+	}))
+
+	// GET /collection/{name} - get a collection
+	// DELETE /collection/{name} - delete a collection and its documents
+	router.HandleFunc(joinPath(prefix, "collection/{name}"), middleware.Wrap(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			_ = collectionGet(w, r, llamaInstance)
+		case http.MethodDelete:
+			_ = collectionDelete(w, r, llamaInstance)
+		default:
+			_ = httpresponse.Error(w, httpresponse.Err(http.StatusMethodNotAllowed), r.Method)
+		}
+	}))
+
+	// POST /collection/{name}/documents - embed and store documents
+	router.HandleFunc("POST "+joinPath(prefix, "collection/{name}/documents"), middleware.Wrap(func(w http.ResponseWriter, r *http.Request) {
+		_ = collectionAdd(w, r, llamaInstance)
+	}))
+
+	// POST /collection/{name}/search - find the documents most similar to a query
+	router.HandleFunc("POST "+joinPath(prefix, "collection/{name}/search"), middleware.Wrap(func(w http.ResponseWriter, r *http.Request) {
+		_ = collectionSearch(w, r, llamaInstance)
+	}))
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// collectionList handles GET /collection requests to list all collections
+func collectionList(w http.ResponseWriter, r *http.Request, llamaInstance *llamacpp.Llama) error {
+	collections, err := llamaInstance.ListCollections(r.Context())
+	if err != nil {
+		return httpresponse.Error(w, httperr(err))
+	}
+
+	return httpresponse.JSON(w, http.StatusOK, httprequest.Indent(r), collections)
+}
+
+// collectionGet handles GET /collection/{name} requests to get a collection
+func collectionGet(w http.ResponseWriter, r *http.Request, llamaInstance *llamacpp.Llama) error {
+	collection, err := llamaInstance.GetCollection(r.Context(), r.PathValue("name"))
+	if err != nil {
+		return httpresponse.Error(w, httperr(err))
+	}
+
+	return httpresponse.JSON(w, http.StatusOK, httprequest.Indent(r), collection)
+}
+
+// collectionDelete handles DELETE /collection/{name} requests to delete a
+// collection and its documents
+func collectionDelete(w http.ResponseWriter, r *http.Request, llamaInstance *llamacpp.Llama) error {
+	if err := llamaInstance.DeleteCollection(r.Context(), r.PathValue("name")); err != nil {
+		return httpresponse.Error(w, httperr(err))
+	}
+
+	return httpresponse.JSON(w, http.StatusNoContent, httprequest.Indent(r), nil)
+}
+
+// collectionAdd handles POST /collection/{name}/documents requests to embed
+// and store documents
+func collectionAdd(w http.ResponseWriter, r *http.Request, llamaInstance *llamacpp.Llama) error {
+	var req schema.CollectionAddRequest
+	if err := httprequest.Read(r, &req); err != nil {
+		return httpresponse.Error(w, httpresponse.ErrBadRequest.With("failed to read request"), err.Error())
+	}
+
+	if len(req.Documents) == 0 {
+		return httpresponse.Error(w, httpresponse.ErrBadRequest.With("documents are required"))
+	}
+	if !req.Index.Valid() {
+		return httpresponse.Error(w, httpresponse.ErrBadRequest.Withf("invalid index %q", req.Index))
+	}
+
+	result, err := llamaInstance.AddDocuments(r.Context(), r.PathValue("name"), req)
+	if err != nil {
+		return httpresponse.Error(w, httperr(err))
+	}
+
+	return httpresponse.JSON(w, http.StatusOK, httprequest.Indent(r), result)
+}
+
+// collectionSearch handles POST /collection/{name}/search requests to find
+// the documents most similar to a query
+func collectionSearch(w http.ResponseWriter, r *http.Request, llamaInstance *llamacpp.Llama) error {
+	var req schema.CollectionSearchRequest
+	if err := httprequest.Read(r, &req); err != nil {
+		return httpresponse.Error(w, httpresponse.ErrBadRequest.With("failed to read request"), err.Error())
+	}
+
+	if req.Query == "" {
+		return httpresponse.Error(w, httpresponse.ErrBadRequest.With("query is required"))
+	}
+	if req.TopK < 0 {
+		return httpresponse.Error(w, httpresponse.ErrBadRequest.With("top_k cannot be negative"))
+	}
+
+	result, err := llamaInstance.SearchCollection(r.Context(), r.PathValue("name"), req)
+	if err != nil {
+		return httpresponse.Error(w, httperr(err))
+	}
+
+	return httpresponse.JSON(w, http.StatusOK, httprequest.Indent(r), result)
+}
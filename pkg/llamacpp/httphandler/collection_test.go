@@ -0,0 +1,125 @@
+package httphandler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mutablelogic/go-llama/pkg/llamacpp/schema"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TESTS - COLLECTIONS
+
+func TestCollectionList_Empty(t *testing.T) {
+	llama := setupTestLlama(t)
+	defer func() {
+		_ = llama.Close()
+	}()
+
+	router := http.NewServeMux()
+	RegisterCollectionHandlers(router, "/api", llama, noopMiddleware())
+
+	req := httptest.NewRequest(http.MethodGet, "/api/collection", nil)
+	rw := httptest.NewRecorder()
+	router.ServeHTTP(rw, req)
+	require.Equal(t, http.StatusOK, rw.Code)
+
+	var collections []schema.Collection
+	require.NoError(t, json.Unmarshal(rw.Body.Bytes(), &collections))
+	assert.Empty(t, collections)
+}
+
+func TestCollection_NotFound(t *testing.T) {
+	llama := setupTestLlama(t)
+	defer func() {
+		_ = llama.Close()
+	}()
+
+	router := http.NewServeMux()
+	RegisterCollectionHandlers(router, "/api", llama, noopMiddleware())
+
+	for _, tc := range []struct {
+		method, path, body string
+	}{
+		{http.MethodGet, "/api/collection/missing", ""},
+		{http.MethodDelete, "/api/collection/missing", ""},
+		{http.MethodPost, "/api/collection/missing/search", `{"query": "hello"}`},
+	} {
+		req := httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.body))
+		req.Header.Set("Content-Type", "application/json")
+		rw := httptest.NewRecorder()
+		router.ServeHTTP(rw, req)
+		assert.Equal(t, http.StatusNotFound, rw.Code, tc.method+" "+tc.path)
+	}
+}
+
+func TestCollectionAdd_InvalidRequest(t *testing.T) {
+	llama := setupTestLlama(t)
+	defer func() {
+		_ = llama.Close()
+	}()
+
+	router := http.NewServeMux()
+	RegisterCollectionHandlers(router, "/api", llama, noopMiddleware())
+
+	for _, tc := range []struct {
+		name, body string
+	}{
+		{"docs", `{invalid json}`},
+		{"docs", `{"model": "test-model"}`},
+		{"docs", `{"model": "test-model", "documents": []}`},
+		{"docs", `{"model": "test-model", "index": "lsh", "documents": [{"text": "hello"}]}`},
+		{"docs", `{"model": "test-model", "documents": [{"text": ""}]}`},
+		{"docs", `{"documents": [{"text": "hello"}]}`},
+		{".docs", `{"model": "test-model", "documents": [{"text": "hello"}]}`},
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/api/collection/"+tc.name+"/documents", strings.NewReader(tc.body))
+		req.Header.Set("Content-Type", "application/json")
+		rw := httptest.NewRecorder()
+		router.ServeHTTP(rw, req)
+		assert.Equal(t, http.StatusBadRequest, rw.Code, tc.body)
+	}
+}
+
+func TestCollectionSearch_InvalidRequest(t *testing.T) {
+	llama := setupTestLlama(t)
+	defer func() {
+		_ = llama.Close()
+	}()
+
+	router := http.NewServeMux()
+	RegisterCollectionHandlers(router, "/api", llama, noopMiddleware())
+
+	for _, body := range []string{
+		`{invalid json}`,
+		`{}`,
+		`{"query": "hello", "top_k": -1}`,
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/api/collection/docs/search", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rw := httptest.NewRecorder()
+		router.ServeHTTP(rw, req)
+		assert.Equal(t, http.StatusBadRequest, rw.Code, body)
+	}
+}
+
+func TestCollection_MethodNotAllowed(t *testing.T) {
+	llama := setupTestLlama(t)
+	defer func() {
+		_ = llama.Close()
+	}()
+
+	router := http.NewServeMux()
+	RegisterCollectionHandlers(router, "/api", llama, noopMiddleware())
+
+	req := httptest.NewRequest(http.MethodPut, "/api/collection/docs", nil)
+	rw := httptest.NewRecorder()
+	router.ServeHTTP(rw, req)
+	assert.Equal(t, http.StatusMethodNotAllowed, rw.Code)
+}
@@ -1,6 +1,8 @@
 package httphandler
 
 import (
+	"encoding/json"
+	"errors"
 	"net/http"
 
 	// Packages
@@ -10,6 +12,14 @@ import (
 	httpresponse "github.com/mutablelogic/go-server/pkg/httpresponse"
 )
 
+///////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+const (
+	// contentTypeJSONStream is the content type of one JSON object per line
+	contentTypeJSONStream = "application/x-ndjson"
+)
+
 ///////////////////////////////////////////////////////////////////////////////
 // PUBLIC METHODS
 
@@ -23,6 +33,15 @@ func RegisterEmbedHandlers(router *http.ServeMux, prefix string, llamaInstance *
 			_ = httpresponse.Error(w, httpresponse.Err(http.StatusMethodNotAllowed), r.Method)
 		}
 	}))
+
+	router.HandleFunc(joinPath(prefix, "embed/stream"), middleware.Wrap(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost:
+			_ = embedStream(w, r, llamaInstance)
+		default:
+			_ = httpresponse.Error(w, httpresponse.Err(http.StatusMethodNotAllowed), r.Method)
+		}
+	}))
 }
 
 ///////////////////////////////////////////////////////////////////////////////
@@ -46,3 +65,53 @@ func embedCreate(w http.ResponseWriter, r *http.Request, llamaInstance *llamacpp
 
 	return httpresponse.JSON(w, http.StatusOK, httprequest.Indent(r), result)
 }
+
+// embedStream handles POST /embed/stream requests to embed the rows of a
+// body with one JSON object per line. The options are read from the query,
+// and the embedding of each row is written as it is computed, one JSON
+// object per line.
+func embedStream(w http.ResponseWriter, r *http.Request, llamaInstance *llamacpp.Llama) error {
+	var req schema.EmbedStreamRequest
+	if err := httprequest.Query(r.URL.Query(), &req); err != nil {
+		return httpresponse.Error(w, httpresponse.ErrBadRequest.With(err.Error()))
+	}
+
+	if req.Model == "" {
+		return httpresponse.Error(w, httpresponse.ErrBadRequest.With("model is required"))
+	}
+
+	// Rows are written while the body is read
+	rc := http.NewResponseController(w)
+	_ = rc.EnableFullDuplex()
+
+	// Write the header with the first row, so that errors before then are
+	// returned with a status code
+	var enc *json.Encoder
+	err := llamaInstance.EmbedStream(r.Context(), req, r.Body, func(row schema.EmbedStreamRow) error {
+		if enc == nil {
+			w.Header().Set("Content-Type", contentTypeJSONStream)
+			w.WriteHeader(http.StatusOK)
+			enc = json.NewEncoder(w)
+		}
+		if err := enc.Encode(row); err != nil {
+			return err
+		}
+		if err := rc.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		if enc == nil {
+			return httpresponse.Error(w, httperr(err))
+		}
+		return enc.Encode(schema.EmbedStreamRow{Error: err.Error()})
+	}
+
+	// Return an empty stream when there are no rows
+	if enc == nil {
+		w.Header().Set("Content-Type", contentTypeJSONStream)
+		w.WriteHeader(http.StatusOK)
+	}
+	return nil
+}
@@ -331,3 +331,170 @@ func TestEmbedCreate_WithEncodingFormat(t *testing.T) {
 	// Should process request with encoding format, but will fail due to non-existent model
 	assert.NotEqual(t, http.StatusOK, rw.Code)
 }
+
+func TestEmbedCreate_InvalidPooling(t *testing.T) {
+	llama := setupTestLlama(t)
+	defer func() {
+		_ = llama.Close()
+	}()
+
+	router := http.NewServeMux()
+	RegisterEmbedHandlers(router, "/api", llama, noopMiddleware())
+
+	for _, reqBody := range []string{
+		`{"model": "test-model", "input": ["Hello world"], "pooling": "rank"}`,
+		`{"model": "test-model", "input": ["Hello world"], "pooling": "none", "encoding": "int4"}`,
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/api/embed", strings.NewReader(reqBody))
+		req.Header.Set("Content-Type", "application/json")
+		rw := httptest.NewRecorder()
+
+		router.ServeHTTP(rw, req)
+
+		// Unknown pooling types and encodings are refused before the model is found
+		assert.Equal(t, http.StatusBadRequest, rw.Code, reqBody)
+	}
+}
+
+func TestEmbedCreate_InvalidOverflow(t *testing.T) {
+	llama := setupTestLlama(t)
+	defer func() {
+		_ = llama.Close()
+	}()
+
+	router := http.NewServeMux()
+	RegisterEmbedHandlers(router, "/api", llama, noopMiddleware())
+
+	for _, reqBody := range []string{
+		`{"model": "test-model", "input": ["Hello world"], "overflow": "split"}`,
+		`{"model": "test-model", "input": ["Hello world"], "overflow": "chunk", "aggregate": "max"}`,
+		`{"model": "test-model", "input": ["Hello world"], "overflow": "chunk", "chunk_size": -1}`,
+		`{"model": "test-model", "input": ["Hello world"], "overflow": "chunk", "chunk_overlap": -1}`,
+		`{"model": "test-model", "input": ["Hello world"], "overflow": "truncate", "pooling": "none"}`,
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/api/embed", strings.NewReader(reqBody))
+		req.Header.Set("Content-Type", "application/json")
+		rw := httptest.NewRecorder()
+
+		router.ServeHTTP(rw, req)
+
+		// Unknown strategies are refused before the model is found
+		assert.Equal(t, http.StatusBadRequest, rw.Code, reqBody)
+	}
+}
+
+func TestEmbedCreate_InvalidEncoding(t *testing.T) {
+	llama := setupTestLlama(t)
+	defer func() {
+		_ = llama.Close()
+	}()
+
+	router := http.NewServeMux()
+	RegisterEmbedHandlers(router, "/api", llama, noopMiddleware())
+
+	for _, reqBody := range []string{
+		`{"model": "test-model", "input": ["Hello world"], "encoding": "int4"}`,
+		`{"model": "test-model", "input": ["Hello world"], "dimensions": -1}`,
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/api/embed", strings.NewReader(reqBody))
+		req.Header.Set("Content-Type", "application/json")
+		rw := httptest.NewRecorder()
+
+		router.ServeHTTP(rw, req)
+
+		assert.Equal(t, http.StatusBadRequest, rw.Code, reqBody)
+	}
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// TESTS - EMBED STREAM
+
+func TestEmbedStream_MissingModel(t *testing.T) {
+	llama := setupTestLlama(t)
+	defer func() {
+		_ = llama.Close()
+	}()
+
+	router := http.NewServeMux()
+	RegisterEmbedHandlers(router, "/api", llama, noopMiddleware())
+
+	reqBody := `{"id": 1, "text": "Hello world"}` + "\n"
+	req := httptest.NewRequest(http.MethodPost, "/api/embed/stream", strings.NewReader(reqBody))
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	rw := httptest.NewRecorder()
+
+	router.ServeHTTP(rw, req)
+
+	assert.Equal(t, http.StatusBadRequest, rw.Code)
+}
+
+func TestEmbedStream_InvalidQuery(t *testing.T) {
+	llama := setupTestLlama(t)
+	defer func() {
+		_ = llama.Close()
+	}()
+
+	router := http.NewServeMux()
+	RegisterEmbedHandlers(router, "/api", llama, noopMiddleware())
+
+	req := httptest.NewRequest(http.MethodPost, "/api/embed/stream?model=test-model&dimensions=abc", strings.NewReader(""))
+	rw := httptest.NewRecorder()
+
+	router.ServeHTTP(rw, req)
+
+	assert.Equal(t, http.StatusBadRequest, rw.Code)
+}
+
+func TestEmbedStream_InvalidEncoding(t *testing.T) {
+	llama := setupTestLlama(t)
+	defer func() {
+		_ = llama.Close()
+	}()
+
+	router := http.NewServeMux()
+	RegisterEmbedHandlers(router, "/api", llama, noopMiddleware())
+
+	req := httptest.NewRequest(http.MethodPost, "/api/embed/stream?model=test-model&encoding=int4", strings.NewReader(""))
+	rw := httptest.NewRecorder()
+
+	router.ServeHTTP(rw, req)
+
+	assert.Equal(t, http.StatusBadRequest, rw.Code)
+}
+
+func TestEmbedStream_UnknownModel(t *testing.T) {
+	llama := setupTestLlama(t)
+	defer func() {
+		_ = llama.Close()
+	}()
+
+	router := http.NewServeMux()
+	RegisterEmbedHandlers(router, "/api", llama, noopMiddleware())
+
+	reqBody := `{"id": 1, "text": "Hello world"}` + "\n"
+	req := httptest.NewRequest(http.MethodPost, "/api/embed/stream?model=test-model", strings.NewReader(reqBody))
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	rw := httptest.NewRecorder()
+
+	router.ServeHTTP(rw, req)
+
+	// The error is returned with a status code, since no rows were written
+	assert.NotEqual(t, http.StatusOK, rw.Code)
+}
+
+func TestEmbedStream_MethodNotAllowed(t *testing.T) {
+	llama := setupTestLlama(t)
+	defer func() {
+		_ = llama.Close()
+	}()
+
+	router := http.NewServeMux()
+	RegisterEmbedHandlers(router, "/api", llama, noopMiddleware())
+
+	req := httptest.NewRequest(http.MethodGet, "/api/embed/stream", nil)
+	rw := httptest.NewRecorder()
+
+	router.ServeHTTP(rw, req)
+
+	assert.Equal(t, http.StatusMethodNotAllowed, rw.Code)
+}
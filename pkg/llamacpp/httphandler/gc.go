@@ -0,0 +1,47 @@
+package httphandler
+
+import (
+	"net/http"
+
+	// Packages
+	llamacpp "github.com/mutablelogic/go-llama/pkg/llamacpp"
+	schema "github.com/mutablelogic/go-llama/pkg/llamacpp/schema"
+	httprequest "github.com/mutablelogic/go-server/pkg/httprequest"
+	httpresponse "github.com/mutablelogic/go-server/pkg/httpresponse"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// RegisterGCHandlers registers HTTP handlers for garbage collection of the model store
+func RegisterGCHandlers(router *http.ServeMux, prefix string, llamaInstance *llamacpp.Llama, middleware HTTPMiddlewareFuncs) {
+	// GET /gc - list the files which garbage collection would remove
+	// POST /gc - remove temporary files, and evict models when over quota
+	router.HandleFunc(joinPath(prefix, "gc"), middleware.Wrap(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			_ = gc(w, r, llamaInstance, schema.GCRequest{DryRun: true})
+		case http.MethodPost:
+			var req schema.GCRequest
+			if err := httprequest.Read(r, &req); err != nil {
+				_ = httpresponse.Error(w, httpresponse.ErrBadRequest.With(err.Error()))
+				return
+			}
+			_ = gc(w, r, llamaInstance, req)
+		default:
+			_ = httpresponse.Error(w, httpresponse.Err(http.StatusMethodNotAllowed), r.Method)
+		}
+	}))
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// gc handles GET and POST /gc requests to garbage collect the model store
+func gc(w http.ResponseWriter, r *http.Request, llamaInstance *llamacpp.Llama, req schema.GCRequest) error {
+	result, err := llamaInstance.GarbageCollect(r.Context(), req)
+	if err != nil {
+		return httpresponse.Error(w, httperr(err))
+	}
+	return httpresponse.JSON(w, http.StatusOK, httprequest.Indent(r), result)
+}
@@ -0,0 +1,58 @@
+package httphandler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	schema "github.com/mutablelogic/go-llama/pkg/llamacpp/schema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TESTS - GARBAGE COLLECTION
+
+func TestGC_DryRun(t *testing.T) {
+	llama := setupTestLlama(t)
+	defer func() {
+		_ = llama.Close()
+	}()
+
+	router := http.NewServeMux()
+	RegisterGCHandlers(router, "/api", llama, noopMiddleware())
+
+	req := httptest.NewRequest(http.MethodGet, "/api/gc", nil)
+	rw := httptest.NewRecorder()
+
+	router.ServeHTTP(rw, req)
+
+	require.Equal(t, http.StatusOK, rw.Code)
+	var result schema.GCResponse
+	require.NoError(t, json.Unmarshal(rw.Body.Bytes(), &result))
+	assert.True(t, result.DryRun)
+	assert.Empty(t, result.Removed)
+}
+
+func TestGC_Collect(t *testing.T) {
+	llama := setupTestLlama(t)
+	defer func() {
+		_ = llama.Close()
+	}()
+
+	router := http.NewServeMux()
+	RegisterGCHandlers(router, "/api", llama, noopMiddleware())
+
+	req := httptest.NewRequest(http.MethodPost, "/api/gc", strings.NewReader(`{}`))
+	req.Header.Set("Content-Type", "application/json")
+	rw := httptest.NewRecorder()
+
+	router.ServeHTTP(rw, req)
+
+	require.Equal(t, http.StatusOK, rw.Code)
+	var result schema.GCResponse
+	require.NoError(t, json.Unmarshal(rw.Body.Bytes(), &result))
+	assert.False(t, result.DryRun)
+}
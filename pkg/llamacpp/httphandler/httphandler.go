@@ -23,9 +23,14 @@ type HTTPMiddlewareFuncs []func(http.HandlerFunc) http.HandlerFunc
 // router with the given path prefix. The Llama instance must be non-nil.
 func RegisterHandlers(router *http.ServeMux, prefix string, llamaInstance *llamacpp.Llama, middleware HTTPMiddlewareFuncs) {
 	RegisterModelHandlers(router, prefix, llamaInstance, middleware)
+	RegisterPullHandlers(router, prefix, llamaInstance, middleware)
+	RegisterGCHandlers(router, prefix, llamaInstance, middleware)
 	RegisterCompletionHandlers(router, prefix, llamaInstance, middleware)
 	RegisterChatHandlers(router, prefix, llamaInstance, middleware)
 	RegisterEmbedHandlers(router, prefix, llamaInstance, middleware)
+	RegisterRerankHandlers(router, prefix, llamaInstance, middleware)
+	RegisterSimilarityHandlers(router, prefix, llamaInstance, middleware)
+	RegisterCollectionHandlers(router, prefix, llamaInstance, middleware)
 	RegisterTokenizerHandlers(router, prefix, llamaInstance, middleware)
 }
 
@@ -64,6 +69,10 @@ func httperr(err error) error {
 	switch {
 	case errors.Is(err, llama.ErrNotFound):
 		return httpresponse.ErrNotFound.With(err.Error())
+	case errors.Is(err, llama.ErrTooLarge):
+		return httpresponse.Err(http.StatusRequestEntityTooLarge).With(err.Error())
+	case errors.Is(err, llama.ErrReadOnly):
+		return httpresponse.ErrForbidden.With(err.Error())
 	case errors.Is(err, llama.ErrModelNotLoaded):
 		// Model exists but not loaded - this is a conflict, not a bad request
 		return httpresponse.ErrConflict.With(err.Error())
@@ -74,7 +83,8 @@ func httperr(err error) error {
 		errors.Is(err, llama.ErrInvalidBatch),
 		errors.Is(err, llama.ErrBatchFull),
 		errors.Is(err, llama.ErrNoKVSlot),
-		errors.Is(err, llama.ErrNotEmbeddingModel):
+		errors.Is(err, llama.ErrNotEmbeddingModel),
+		errors.Is(err, llama.ErrNotSupported):
 		return httpresponse.ErrBadRequest.With(err.Error())
 	default:
 		return httpresponse.ErrInternalError.With(err.Error())
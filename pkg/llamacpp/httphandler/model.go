@@ -16,7 +16,7 @@ import (
 
 // RegisterModelHandlers registers HTTP handlers for Model operations
 func RegisterModelHandlers(router *http.ServeMux, prefix string, llamaInstance *llamacpp.Llama, middleware HTTPMiddlewareFuncs) {
-	// GET /model - list all models
+	// GET /model - list all models (?label=&arch=&capability=&min_size=&max_size= to filter)
 	// POST /model - pull (download) a model from URL
 	router.HandleFunc(joinPath(prefix, "model"), middleware.Wrap(func(w http.ResponseWriter, r *http.Request) {
 		switch r.Method {
@@ -29,8 +29,22 @@ func RegisterModelHandlers(router *http.ServeMux, prefix string, llamaInstance *
 		}
 	}))
 
-	// GET /model/{id} - get a specific model
+	// GET /model-remote - list the models in a remote repository (?repo=org/repo),
+	// which is not under /model so that it cannot be a model id
+	router.HandleFunc("GET "+joinPath(prefix, "model-remote"), middleware.Wrap(func(w http.ResponseWriter, r *http.Request) {
+		_ = modelRemoteList(w, r, llamaInstance)
+	}))
+
+	// POST /model-import - import a model file on the server into the store,
+	// which is not under /model so that it cannot be a model id
+	router.HandleFunc("POST "+joinPath(prefix, "model-import"), middleware.Wrap(func(w http.ResponseWriter, r *http.Request) {
+		_ = modelImport(w, r, llamaInstance)
+	}))
+
+	// GET /model/{id} - get a specific model (?tensors=true includes the tensor layout)
 	// POST /model/{id} - load/unload a model by id
+	// PUT /model/{path} - upload a model file to the path in the store
+	// PATCH /model/{id} - pin or unpin a model, or set its labels and notes
 	// DELETE /model/{id} - delete a specific model from disk
 	router.HandleFunc(joinPath(prefix, "model/{id...}"), middleware.Wrap(func(w http.ResponseWriter, r *http.Request) {
 		switch r.Method {
@@ -38,6 +52,10 @@ func RegisterModelHandlers(router *http.ServeMux, prefix string, llamaInstance *
 			_ = modelGet(w, r, llamaInstance)
 		case http.MethodPost:
 			_ = modelLoadUnload(w, r, llamaInstance)
+		case http.MethodPut:
+			_ = modelUpload(w, r, llamaInstance)
+		case http.MethodPatch:
+			_ = modelUpdate(w, r, llamaInstance)
 		case http.MethodDelete:
 			_ = modelDelete(w, r, llamaInstance)
 		default:
@@ -49,12 +67,42 @@ func RegisterModelHandlers(router *http.ServeMux, prefix string, llamaInstance *
 ///////////////////////////////////////////////////////////////////////////////
 // PRIVATE METHODS
 
-// modelList handles GET /model requests to list all available models
+// modelList handles GET /model requests to list all available models, which
+// can be filtered by label, architecture, capability and size
 func modelList(w http.ResponseWriter, r *http.Request, llamaInstance *llamacpp.Llama) error {
+	var req schema.ListModelsRequest
+	if err := httprequest.Query(r.URL.Query(), &req); err != nil {
+		return httpresponse.Error(w, httpresponse.ErrBadRequest.With(err.Error()))
+	}
+
 	models, err := llamaInstance.ListModels(r.Context())
 	if err != nil {
 		return httpresponse.Error(w, httperr(err))
 	}
+
+	// Filter the models
+	result := make([]*schema.CachedModel, 0, len(models))
+	for _, model := range models {
+		if req.Match(&model.Model) {
+			result = append(result, model)
+		}
+	}
+	return httpresponse.JSON(w, http.StatusOK, httprequest.Indent(r), result)
+}
+
+// modelRemoteList handles GET /model-remote requests to list the models in a remote repository
+func modelRemoteList(w http.ResponseWriter, r *http.Request, llamaInstance *llamacpp.Llama) error {
+	var req schema.RemoteModelRequest
+	if err := httprequest.Query(r.URL.Query(), &req); err != nil {
+		return httpresponse.Error(w, httpresponse.ErrBadRequest.With(err.Error()))
+	} else if req.Repo == "" {
+		return httpresponse.Error(w, httpresponse.ErrBadRequest.With("repo is required"))
+	}
+
+	models, err := llamaInstance.ListRemoteModels(r.Context(), req)
+	if err != nil {
+		return httpresponse.Error(w, httperr(err))
+	}
 	return httpresponse.JSON(w, http.StatusOK, httprequest.Indent(r), models)
 }
 
@@ -65,10 +113,31 @@ func modelGet(w http.ResponseWriter, r *http.Request, llamaInstance *llamacpp.Ll
 		return httpresponse.Error(w, httpresponse.ErrBadRequest.With("model id is required"))
 	}
 
+	var req schema.GetModelRequest
+	if err := httprequest.Query(r.URL.Query(), &req); err != nil {
+		return httpresponse.Error(w, httpresponse.ErrBadRequest.With(err.Error()))
+	}
+
 	model, err := llamaInstance.GetModel(r.Context(), id)
 	if err != nil {
 		return httpresponse.Error(w, httperr(err))
 	}
+
+	// Attach the tensor layout to a copy, so the cached model is not modified
+	if req.Tensors {
+		tensors, err := llamaInstance.GetModelTensors(r.Context(), id)
+		if err != nil {
+			return httpresponse.Error(w, httperr(err))
+		}
+		result := &schema.CachedModel{
+			Model:    model.Model,
+			LoadedAt: model.LoadedAt,
+			Runtime:  model.Runtime,
+		}
+		result.Tensors = tensors
+		return httpresponse.JSON(w, http.StatusOK, httprequest.Indent(r), result)
+	}
+
 	return httpresponse.JSON(w, http.StatusOK, httprequest.Indent(r), model)
 }
 
@@ -138,6 +207,61 @@ func modelPull(w http.ResponseWriter, r *http.Request, llamaInstance *llamacpp.L
 	return httpresponse.JSON(w, http.StatusCreated, httprequest.Indent(r), model)
 }
 
+// modelImport handles POST /model-import requests to import a model file on the server
+func modelImport(w http.ResponseWriter, r *http.Request, llamaInstance *llamacpp.Llama) error {
+	var req schema.ImportModelRequest
+	if err := httprequest.Read(r, &req); err != nil {
+		return httpresponse.Error(w, httpresponse.ErrBadRequest.With(err.Error()))
+	}
+
+	if req.Path == "" {
+		return httpresponse.Error(w, httpresponse.ErrBadRequest.With("model path is required"))
+	}
+
+	model, err := llamaInstance.ImportModel(r.Context(), req)
+	if err != nil {
+		return httpresponse.Error(w, httperr(err))
+	}
+	return httpresponse.JSON(w, http.StatusCreated, httprequest.Indent(r), model)
+}
+
+// modelUpload handles PUT /model/{path} requests to upload a model file, sent
+// as the raw request body, to the path in the store
+func modelUpload(w http.ResponseWriter, r *http.Request, llamaInstance *llamacpp.Llama) error {
+	path := r.PathValue("id")
+	if path == "" {
+		return httpresponse.Error(w, httpresponse.ErrBadRequest.With("model path is required"))
+	}
+
+	model, err := llamaInstance.UploadModel(r.Context(), path, r.Body)
+	if err != nil {
+		return httpresponse.Error(w, httperr(err))
+	}
+	return httpresponse.JSON(w, http.StatusCreated, httprequest.Indent(r), model)
+}
+
+// modelUpdate handles PATCH /model/{id} requests to pin or unpin a model, or
+// to set its labels and notes
+func modelUpdate(w http.ResponseWriter, r *http.Request, llamaInstance *llamacpp.Llama) error {
+	id := r.PathValue("id")
+	if id == "" {
+		return httpresponse.Error(w, httpresponse.ErrBadRequest.With("model id is required"))
+	}
+
+	var req schema.UpdateModelRequest
+	if err := httprequest.Read(r, &req); err != nil {
+		return httpresponse.Error(w, httpresponse.ErrBadRequest.With(err.Error()))
+	} else if req.Pinned == nil && req.Labels == nil && req.Notes == nil {
+		return httpresponse.Error(w, httpresponse.ErrBadRequest.With("nothing to update"))
+	}
+
+	model, err := llamaInstance.UpdateModel(r.Context(), id, req)
+	if err != nil {
+		return httpresponse.Error(w, httperr(err))
+	}
+	return httpresponse.JSON(w, http.StatusOK, httprequest.Indent(r), model)
+}
+
 // modelLoadUnload handles POST /model/{id} requests to load or unload a specific model by id
 func modelLoadUnload(w http.ResponseWriter, r *http.Request, llamaInstance *llamacpp.Llama) error {
 	var req schema.LoadModelRequest
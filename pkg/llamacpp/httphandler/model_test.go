@@ -1,9 +1,12 @@
 package httphandler
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"os"
 	"strings"
 	"testing"
 
@@ -114,6 +117,81 @@ func TestModelGet_NonExistent(t *testing.T) {
 	assert.Equal(t, http.StatusNotFound, rw.Code)
 }
 
+func TestModelGet_NonExistentTensors(t *testing.T) {
+	llama := setupTestLlama(t)
+	defer func() {
+		_ = llama.Close()
+	}()
+
+	router := http.NewServeMux()
+	RegisterModelHandlers(router, "/api", llama, noopMiddleware())
+
+	req := httptest.NewRequest(http.MethodGet, "/api/model/nonexistent?tensors=true", nil)
+	rw := httptest.NewRecorder()
+
+	router.ServeHTTP(rw, req)
+
+	// Should return 404 for nonexistent model
+	assert.Equal(t, http.StatusNotFound, rw.Code)
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// TESTS - REMOTE MODELS
+
+func TestModelRemote_EmptyRepo(t *testing.T) {
+	llama := setupTestLlama(t)
+	defer func() {
+		_ = llama.Close()
+	}()
+
+	router := http.NewServeMux()
+	RegisterModelHandlers(router, "/api", llama, noopMiddleware())
+
+	req := httptest.NewRequest(http.MethodGet, "/api/model-remote", nil)
+	rw := httptest.NewRecorder()
+
+	router.ServeHTTP(rw, req)
+
+	// Should return 400 when the repository is missing
+	assert.Equal(t, http.StatusBadRequest, rw.Code)
+}
+
+func TestModelRemote_InvalidRepo(t *testing.T) {
+	llama := setupTestLlama(t)
+	defer func() {
+		_ = llama.Close()
+	}()
+
+	router := http.NewServeMux()
+	RegisterModelHandlers(router, "/api", llama, noopMiddleware())
+
+	req := httptest.NewRequest(http.MethodGet, "/api/model-remote?repo=org", nil)
+	rw := httptest.NewRecorder()
+
+	router.ServeHTTP(rw, req)
+
+	// Should return 400 for a repository which is not org/repo
+	assert.Equal(t, http.StatusBadRequest, rw.Code)
+}
+
+func TestModelRemote_ModelNamedRemote(t *testing.T) {
+	llama := setupTestLlama(t)
+	defer func() {
+		_ = llama.Close()
+	}()
+
+	router := http.NewServeMux()
+	RegisterModelHandlers(router, "/api", llama, noopMiddleware())
+
+	req := httptest.NewRequest(http.MethodGet, "/api/model/remote", nil)
+	rw := httptest.NewRecorder()
+
+	router.ServeHTTP(rw, req)
+
+	// A model named remote is looked up like any other model
+	assert.Equal(t, http.StatusNotFound, rw.Code)
+}
+
 ///////////////////////////////////////////////////////////////////////////////
 // TESTS - PULL MODEL
 
@@ -346,3 +424,231 @@ func TestModelDelete_NonExistentModel(t *testing.T) {
 	// Should return 404 for nonexistent model
 	assert.Equal(t, http.StatusNotFound, rw.Code)
 }
+
+///////////////////////////////////////////////////////////////////////////////
+// TESTS - IMPORT AND UPLOAD MODEL
+
+func TestModelImport_EmptyPath(t *testing.T) {
+	llama := setupTestLlama(t)
+	defer func() {
+		_ = llama.Close()
+	}()
+
+	router := http.NewServeMux()
+	RegisterModelHandlers(router, "/api", llama, noopMiddleware())
+
+	req := httptest.NewRequest(http.MethodPost, "/api/model-import", strings.NewReader(`{"path": ""}`))
+	req.Header.Set("Content-Type", "application/json")
+	rw := httptest.NewRecorder()
+
+	router.ServeHTTP(rw, req)
+
+	assert.Equal(t, http.StatusBadRequest, rw.Code)
+}
+
+func TestModelImport_ModelNamedImport(t *testing.T) {
+	llama := setupTestLlama(t)
+	defer func() {
+		_ = llama.Close()
+	}()
+
+	router := http.NewServeMux()
+	RegisterModelHandlers(router, "/api", llama, noopMiddleware())
+
+	req := httptest.NewRequest(http.MethodPost, "/api/model/import", strings.NewReader(`{}`))
+	req.Header.Set("Content-Type", "application/json")
+	rw := httptest.NewRecorder()
+
+	router.ServeHTTP(rw, req)
+
+	// A model named import is loaded like any other model
+	assert.Equal(t, http.StatusNotFound, rw.Code)
+}
+
+func TestModelImport_NonExistent(t *testing.T) {
+	llama := setupTestLlama(t)
+	defer func() {
+		_ = llama.Close()
+	}()
+
+	router := http.NewServeMux()
+	RegisterModelHandlers(router, "/api", llama, noopMiddleware())
+
+	req := httptest.NewRequest(http.MethodPost, "/api/model-import", strings.NewReader(`{"path": "/nonexistent/model.gguf"}`))
+	req.Header.Set("Content-Type", "application/json")
+	rw := httptest.NewRecorder()
+
+	router.ServeHTTP(rw, req)
+
+	assert.Equal(t, http.StatusNotFound, rw.Code)
+}
+
+func TestModelUpload_InvalidFile(t *testing.T) {
+	llama := setupTestLlama(t)
+	defer func() {
+		_ = llama.Close()
+	}()
+
+	router := http.NewServeMux()
+	RegisterModelHandlers(router, "/api", llama, noopMiddleware())
+
+	req := httptest.NewRequest(http.MethodPut, "/api/model/invalid.gguf", strings.NewReader("not a gguf file"))
+	rw := httptest.NewRecorder()
+
+	router.ServeHTTP(rw, req)
+
+	assert.NotEqual(t, http.StatusCreated, rw.Code)
+}
+
+func TestModelUpload_Success(t *testing.T) {
+	llama := setupTestLlama(t)
+	defer func() {
+		_ = llama.Close()
+	}()
+
+	router := http.NewServeMux()
+	RegisterModelHandlers(router, "/api", llama, noopMiddleware())
+
+	data, err := os.ReadFile("../../../testdata/stories260K.gguf")
+	require.NoError(t, err)
+	req := httptest.NewRequest(http.MethodPut, "/api/model/llama/stories.gguf", bytes.NewReader(data))
+	rw := httptest.NewRecorder()
+
+	router.ServeHTTP(rw, req)
+
+	require.Equal(t, http.StatusCreated, rw.Code)
+	var model schema.CachedModel
+	require.NoError(t, json.Unmarshal(rw.Body.Bytes(), &model))
+	assert.Equal(t, "llama/stories.gguf", model.Path)
+}
+
+func TestModelUpdate_Pin(t *testing.T) {
+	llama := setupTestLlama(t)
+	defer func() {
+		_ = llama.Close()
+	}()
+
+	router := http.NewServeMux()
+	RegisterModelHandlers(router, "/api", llama, noopMiddleware())
+
+	data, err := os.ReadFile("../../../testdata/stories260K.gguf")
+	require.NoError(t, err)
+	_, err = llama.UploadModel(context.Background(), "stories.gguf", bytes.NewReader(data))
+	require.NoError(t, err)
+
+	// Nothing to update
+	req := httptest.NewRequest(http.MethodPatch, "/api/model/stories.gguf", strings.NewReader(`{}`))
+	req.Header.Set("Content-Type", "application/json")
+	rw := httptest.NewRecorder()
+	router.ServeHTTP(rw, req)
+	assert.Equal(t, http.StatusBadRequest, rw.Code)
+
+	// Pin the model
+	req = httptest.NewRequest(http.MethodPatch, "/api/model/stories.gguf", strings.NewReader(`{"pinned": true}`))
+	req.Header.Set("Content-Type", "application/json")
+	rw = httptest.NewRecorder()
+	router.ServeHTTP(rw, req)
+	require.Equal(t, http.StatusOK, rw.Code)
+	var model schema.CachedModel
+	require.NoError(t, json.Unmarshal(rw.Body.Bytes(), &model))
+	assert.True(t, model.Pinned)
+}
+
+func TestModelUpdate_Labels(t *testing.T) {
+	llama := setupTestLlama(t)
+	defer func() {
+		_ = llama.Close()
+	}()
+
+	router := http.NewServeMux()
+	RegisterModelHandlers(router, "/api", llama, noopMiddleware())
+
+	data, err := os.ReadFile("../../../testdata/stories260K.gguf")
+	require.NoError(t, err)
+	_, err = llama.UploadModel(context.Background(), "stories.gguf", bytes.NewReader(data))
+	require.NoError(t, err)
+
+	// Set labels and notes
+	req := httptest.NewRequest(http.MethodPatch, "/api/model/stories.gguf", strings.NewReader(`{"labels": ["approved-prod", "tiny"], "notes": "Test model"}`))
+	req.Header.Set("Content-Type", "application/json")
+	rw := httptest.NewRecorder()
+	router.ServeHTTP(rw, req)
+	require.Equal(t, http.StatusOK, rw.Code)
+	var model schema.CachedModel
+	require.NoError(t, json.Unmarshal(rw.Body.Bytes(), &model))
+	assert.Equal(t, []string{"approved-prod", "tiny"}, model.Labels)
+	assert.Equal(t, "Test model", model.Notes)
+	assert.NotEmpty(t, model.SHA256)
+
+	// Filter the list of models
+	for query, want := range map[string]int{
+		"":                          1,
+		"?label=approved-prod":      1,
+		"?label=tiny&label=missing": 0,
+		"?arch=llama":               1,
+		"?arch=gemma":               0,
+		"?max_size=1":               0,
+		"?min_size=1":               1,
+	} {
+		req = httptest.NewRequest(http.MethodGet, "/api/model"+query, nil)
+		rw = httptest.NewRecorder()
+		router.ServeHTTP(rw, req)
+		require.Equal(t, http.StatusOK, rw.Code, query)
+		var models []schema.CachedModel
+		require.NoError(t, json.Unmarshal(rw.Body.Bytes(), &models), query)
+		assert.Len(t, models, want, query)
+	}
+}
+
+func TestModelCapabilities(t *testing.T) {
+	llama := setupTestLlama(t)
+	defer func() {
+		_ = llama.Close()
+	}()
+
+	router := http.NewServeMux()
+	RegisterModelHandlers(router, "/api", llama, noopMiddleware())
+	RegisterChatHandlers(router, "/api", llama, noopMiddleware())
+	RegisterEmbedHandlers(router, "/api", llama, noopMiddleware())
+
+	data, err := os.ReadFile("../../../testdata/stories260K.gguf")
+	require.NoError(t, err)
+	model, err := llama.UploadModel(context.Background(), "stories.gguf", bytes.NewReader(data))
+	require.NoError(t, err)
+
+	// The test model has no chat template or pooling
+	assert.Equal(t, []schema.Capability{schema.CapCompletion}, model.Capabilities)
+
+	// Filter the list of models by capability
+	for query, want := range map[string]int{
+		"?capability=completion":                 1,
+		"?capability=embedding":                  0,
+		"?capability=completion&capability=chat": 0,
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/api/model"+query, nil)
+		rw := httptest.NewRecorder()
+		router.ServeHTTP(rw, req)
+		require.Equal(t, http.StatusOK, rw.Code, query)
+		var models []schema.CachedModel
+		require.NoError(t, json.Unmarshal(rw.Body.Bytes(), &models), query)
+		assert.Len(t, models, want, query)
+	}
+
+	// Requests for a capability the model does not have are refused before
+	// the model is loaded
+	for path, body := range map[string]string{
+		"/api/embed": `{"model": "stories.gguf", "input": ["Hello world"]}`,
+		"/api/chat":  `{"model": "stories.gguf", "messages": [{"role": "user", "content": "Hello world"}]}`,
+	} {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rw := httptest.NewRecorder()
+		router.ServeHTTP(rw, req)
+		assert.Equal(t, http.StatusBadRequest, rw.Code, path)
+		assert.Contains(t, rw.Body.String(), "stories.gguf", path)
+	}
+	models, err := llama.ListModels(context.Background())
+	require.NoError(t, err)
+	require.Len(t, models, 1)
+	assert.True(t, models[0].LoadedAt.IsZero())
+}
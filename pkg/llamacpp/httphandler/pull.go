@@ -0,0 +1,141 @@
+package httphandler
+
+import (
+	"net/http"
+
+	// Packages
+	llamacpp "github.com/mutablelogic/go-llama/pkg/llamacpp"
+	schema "github.com/mutablelogic/go-llama/pkg/llamacpp/schema"
+	httprequest "github.com/mutablelogic/go-server/pkg/httprequest"
+	httpresponse "github.com/mutablelogic/go-server/pkg/httpresponse"
+	types "github.com/mutablelogic/go-server/pkg/types"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// RegisterPullHandlers registers HTTP handlers for asynchronous model pulls
+func RegisterPullHandlers(router *http.ServeMux, prefix string, llamaInstance *llamacpp.Llama, middleware HTTPMiddlewareFuncs) {
+	// GET /pull - list pull jobs
+	// POST /pull - start pulling a model from URL, returning the job
+	router.HandleFunc(joinPath(prefix, "pull"), middleware.Wrap(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			_ = pullList(w, r, llamaInstance)
+		case http.MethodPost:
+			_ = pullStart(w, r, llamaInstance)
+		default:
+			_ = httpresponse.Error(w, httpresponse.Err(http.StatusMethodNotAllowed), r.Method)
+		}
+	}))
+
+	// GET /pull/{id} - get a pull job (?stream replays and follows progress events)
+	// DELETE /pull/{id} - cancel a pull job
+	router.HandleFunc(joinPath(prefix, "pull/{id}"), middleware.Wrap(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			_ = pullGet(w, r, llamaInstance)
+		case http.MethodDelete:
+			_ = pullCancel(w, r, llamaInstance)
+		default:
+			_ = httpresponse.Error(w, httpresponse.Err(http.StatusMethodNotAllowed), r.Method)
+		}
+	}))
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// pullList handles GET /pull requests to list pull jobs
+func pullList(w http.ResponseWriter, r *http.Request, llamaInstance *llamacpp.Llama) error {
+	jobs, err := llamaInstance.ListPullJobs(r.Context())
+	if err != nil {
+		return httpresponse.Error(w, httperr(err))
+	}
+	return httpresponse.JSON(w, http.StatusOK, httprequest.Indent(r), jobs)
+}
+
+// pullStart handles POST /pull requests to start pulling a model in the background
+func pullStart(w http.ResponseWriter, r *http.Request, llamaInstance *llamacpp.Llama) error {
+	var req schema.PullModelRequest
+	if err := httprequest.Read(r, &req); err != nil {
+		return httpresponse.Error(w, httpresponse.ErrBadRequest.With(err.Error()))
+	}
+
+	if req.URL == "" {
+		return httpresponse.Error(w, httpresponse.ErrBadRequest.With("model URL is required"))
+	}
+
+	job, err := llamaInstance.StartPullJob(r.Context(), req)
+	if err != nil {
+		return httpresponse.Error(w, httperr(err))
+	}
+	return httpresponse.JSON(w, http.StatusAccepted, httprequest.Indent(r), job)
+}
+
+// pullGet handles GET /pull/{id} requests to get a pull job, or to stream
+// its progress events when the stream query parameter is set or the client
+// accepts a text stream
+func pullGet(w http.ResponseWriter, r *http.Request, llamaInstance *llamacpp.Llama) error {
+	id := r.PathValue("id")
+	if id == "" {
+		return httpresponse.Error(w, httpresponse.ErrBadRequest.With("pull job id is required"))
+	}
+
+	// Return the job unless streaming
+	_, stream := r.URL.Query()["stream"]
+	if accept := r.Header.Get("Accept"); accept != "" {
+		if mimetype, err := types.ParseContentType(accept); err != nil {
+			return httpresponse.Error(w, httpresponse.ErrBadRequest.With("invalid Accept header"), err.Error())
+		} else if mimetype == types.ContentTypeTextStream {
+			stream = true
+		}
+	}
+	if !stream {
+		job, err := llamaInstance.GetPullJob(r.Context(), id)
+		if err != nil {
+			return httpresponse.Error(w, httperr(err))
+		}
+		return httpresponse.JSON(w, http.StatusOK, httprequest.Indent(r), job)
+	}
+
+	// Check the job exists before starting the stream
+	if _, err := llamaInstance.GetPullJob(r.Context(), id); err != nil {
+		return httpresponse.Error(w, httperr(err))
+	}
+	textStream := httpresponse.NewTextStream(w)
+	if textStream == nil {
+		return httpresponse.Error(w, httpresponse.ErrInternalError.With("cannot create text stream"))
+	}
+	defer textStream.Close()
+
+	// Replay and follow the progress events, ending with the final status
+	if err := llamaInstance.WatchPullJob(r.Context(), id, func(job *schema.PullJob) error {
+		switch job.Status {
+		case schema.PullJobRunning:
+			textStream.Write(schema.ModelPullProgressType, job)
+		case schema.PullJobComplete:
+			textStream.Write(schema.ModelPullCompleteType, job)
+		default:
+			textStream.Write(schema.ModelPullErrorType, job)
+		}
+		return nil
+	}); err != nil && r.Context().Err() == nil {
+		textStream.Write(schema.ModelPullErrorType, map[string]string{"error": err.Error()})
+	}
+	return nil
+}
+
+// pullCancel handles DELETE /pull/{id} requests to cancel a pull job
+func pullCancel(w http.ResponseWriter, r *http.Request, llamaInstance *llamacpp.Llama) error {
+	id := r.PathValue("id")
+	if id == "" {
+		return httpresponse.Error(w, httpresponse.ErrBadRequest.With("pull job id is required"))
+	}
+
+	job, err := llamaInstance.CancelPullJob(r.Context(), id)
+	if err != nil {
+		return httpresponse.Error(w, httperr(err))
+	}
+	return httpresponse.JSON(w, http.StatusOK, httprequest.Indent(r), job)
+}
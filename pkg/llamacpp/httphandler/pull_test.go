@@ -0,0 +1,122 @@
+package httphandler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	schema "github.com/mutablelogic/go-llama/pkg/llamacpp/schema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TESTS - PULL JOBS
+
+func TestPullStart_EmptyURL(t *testing.T) {
+	llama := setupTestLlama(t)
+	defer func() {
+		_ = llama.Close()
+	}()
+
+	router := http.NewServeMux()
+	RegisterPullHandlers(router, "/api", llama, noopMiddleware())
+
+	req := httptest.NewRequest(http.MethodPost, "/api/pull", strings.NewReader(`{"url": ""}`))
+	req.Header.Set("Content-Type", "application/json")
+	rw := httptest.NewRecorder()
+
+	router.ServeHTTP(rw, req)
+
+	assert.Equal(t, http.StatusBadRequest, rw.Code)
+}
+
+func TestPullList_Empty(t *testing.T) {
+	llama := setupTestLlama(t)
+	defer func() {
+		_ = llama.Close()
+	}()
+
+	router := http.NewServeMux()
+	RegisterPullHandlers(router, "/api", llama, noopMiddleware())
+
+	req := httptest.NewRequest(http.MethodGet, "/api/pull", nil)
+	rw := httptest.NewRecorder()
+
+	router.ServeHTTP(rw, req)
+
+	assert.Equal(t, http.StatusOK, rw.Code)
+	var jobs []*schema.PullJob
+	assert.NoError(t, json.Unmarshal(rw.Body.Bytes(), &jobs))
+	assert.Empty(t, jobs)
+}
+
+func TestPullGet_NonExistent(t *testing.T) {
+	llama := setupTestLlama(t)
+	defer func() {
+		_ = llama.Close()
+	}()
+
+	router := http.NewServeMux()
+	RegisterPullHandlers(router, "/api", llama, noopMiddleware())
+
+	for _, method := range []string{http.MethodGet, http.MethodDelete} {
+		req := httptest.NewRequest(method, "/api/pull/unknown", nil)
+		rw := httptest.NewRecorder()
+
+		router.ServeHTTP(rw, req)
+
+		assert.Equal(t, http.StatusNotFound, rw.Code, method)
+	}
+}
+
+func TestPullStart_Stream(t *testing.T) {
+	llama := setupTestLlama(t)
+	defer func() {
+		_ = llama.Close()
+	}()
+
+	// Serve the test model
+	data, err := os.ReadFile("../../../testdata/stories260K.gguf")
+	require.NoError(t, err)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "model.gguf", time.Time{}, bytes.NewReader(data))
+	}))
+	defer server.Close()
+
+	router := http.NewServeMux()
+	RegisterPullHandlers(router, "/api", llama, noopMiddleware())
+
+	// Start the pull, which returns the job
+	req := httptest.NewRequest(http.MethodPost, "/api/pull", strings.NewReader(`{"url": "`+server.URL+`/model.gguf"}`))
+	req.Header.Set("Content-Type", "application/json")
+	rw := httptest.NewRecorder()
+	router.ServeHTTP(rw, req)
+	require.Equal(t, http.StatusAccepted, rw.Code)
+	var job schema.PullJob
+	require.NoError(t, json.Unmarshal(rw.Body.Bytes(), &job))
+	assert.NotEmpty(t, job.ID)
+
+	// Stream the progress events until the pull completes
+	req = httptest.NewRequest(http.MethodGet, "/api/pull/"+job.ID+"?stream", nil)
+	rw = httptest.NewRecorder()
+	router.ServeHTTP(rw, req)
+	assert.Contains(t, rw.Header().Get("Content-Type"), "text/event-stream")
+	assert.Contains(t, rw.Body.String(), schema.ModelPullCompleteType)
+
+	// The job is complete
+	req = httptest.NewRequest(http.MethodGet, "/api/pull/"+job.ID, nil)
+	rw = httptest.NewRecorder()
+	router.ServeHTTP(rw, req)
+	require.Equal(t, http.StatusOK, rw.Code)
+	require.NoError(t, json.Unmarshal(rw.Body.Bytes(), &job))
+	assert.Equal(t, schema.PullJobComplete, job.Status)
+	if assert.NotNil(t, job.Result) {
+		assert.Equal(t, "model.gguf", job.Result.Path)
+	}
+}
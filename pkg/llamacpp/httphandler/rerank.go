@@ -0,0 +1,57 @@
+package httphandler
+
+import (
+	"net/http"
+
+	// Packages
+	llamacpp "github.com/mutablelogic/go-llama/pkg/llamacpp"
+	schema "github.com/mutablelogic/go-llama/pkg/llamacpp/schema"
+	httprequest "github.com/mutablelogic/go-server/pkg/httprequest"
+	httpresponse "github.com/mutablelogic/go-server/pkg/httpresponse"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// RegisterRerankHandlers registers HTTP handlers for Rerank operations
+func RegisterRerankHandlers(router *http.ServeMux, prefix string, llamaInstance *llamacpp.Llama, middleware HTTPMiddlewareFuncs) {
+	router.HandleFunc(joinPath(prefix, "rerank"), middleware.Wrap(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost:
+			_ = rerankCreate(w, r, llamaInstance)
+		default:
+			_ = httpresponse.Error(w, httpresponse.Err(http.StatusMethodNotAllowed), r.Method)
+		}
+	}))
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// rerankCreate handles POST /rerank requests to score documents against a query
+func rerankCreate(w http.ResponseWriter, r *http.Request, llamaInstance *llamacpp.Llama) error {
+	var req schema.RerankRequest
+	if err := httprequest.Read(r, &req); err != nil {
+		return httpresponse.Error(w, httpresponse.ErrBadRequest.With("failed to read request"), err.Error())
+	}
+
+	if req.Model == "" {
+		return httpresponse.Error(w, httpresponse.ErrBadRequest.With("model is required"))
+	}
+	if req.Query == "" {
+		return httpresponse.Error(w, httpresponse.ErrBadRequest.With("query is required"))
+	}
+	if len(req.Documents) == 0 {
+		return httpresponse.Error(w, httpresponse.ErrBadRequest.With("documents are required"))
+	}
+	if req.TopN < 0 {
+		return httpresponse.Error(w, httpresponse.ErrBadRequest.With("top_n cannot be negative"))
+	}
+
+	result, err := llamaInstance.Rerank(r.Context(), req)
+	if err != nil {
+		return httpresponse.Error(w, httperr(err))
+	}
+
+	return httpresponse.JSON(w, http.StatusOK, httprequest.Indent(r), result)
+}
@@ -0,0 +1,103 @@
+package httphandler
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TESTS - RERANK
+
+func TestRerankCreate_InvalidRequest(t *testing.T) {
+	llama := setupTestLlama(t)
+	defer func() {
+		_ = llama.Close()
+	}()
+
+	router := http.NewServeMux()
+	RegisterRerankHandlers(router, "/api", llama, noopMiddleware())
+
+	for _, body := range []string{
+		`{invalid json}`,
+		`{"query": "What is a panda?", "documents": ["hi"]}`,
+		`{"model": "test-model", "documents": ["hi"]}`,
+		`{"model": "test-model", "query": "What is a panda?"}`,
+		`{"model": "test-model", "query": "What is a panda?", "documents": []}`,
+		`{"model": "test-model", "query": "What is a panda?", "documents": ["hi"], "top_n": -1}`,
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/api/rerank", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rw := httptest.NewRecorder()
+		router.ServeHTTP(rw, req)
+		assert.Equal(t, http.StatusBadRequest, rw.Code, body)
+	}
+}
+
+func TestRerankCreate_MethodNotAllowed(t *testing.T) {
+	llama := setupTestLlama(t)
+	defer func() {
+		_ = llama.Close()
+	}()
+
+	router := http.NewServeMux()
+	RegisterRerankHandlers(router, "/api", llama, noopMiddleware())
+
+	req := httptest.NewRequest(http.MethodGet, "/api/rerank", nil)
+	rw := httptest.NewRecorder()
+	router.ServeHTTP(rw, req)
+	assert.Equal(t, http.StatusMethodNotAllowed, rw.Code)
+}
+
+func TestRerankCreate_NotFound(t *testing.T) {
+	llama := setupTestLlama(t)
+	defer func() {
+		_ = llama.Close()
+	}()
+
+	router := http.NewServeMux()
+	RegisterRerankHandlers(router, "/api", llama, noopMiddleware())
+
+	body := `{"model": "missing.gguf", "query": "What is a panda?", "documents": ["hi"]}`
+	req := httptest.NewRequest(http.MethodPost, "/api/rerank", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rw := httptest.NewRecorder()
+	router.ServeHTTP(rw, req)
+	assert.Equal(t, http.StatusNotFound, rw.Code)
+}
+
+func TestRerankCreate_NotRerankModel(t *testing.T) {
+	llama := setupTestLlama(t)
+	defer func() {
+		_ = llama.Close()
+	}()
+
+	router := http.NewServeMux()
+	RegisterRerankHandlers(router, "/api", llama, noopMiddleware())
+
+	data, err := os.ReadFile("../../../testdata/stories260K.gguf")
+	require.NoError(t, err)
+	_, err = llama.UploadModel(context.Background(), "stories.gguf", bytes.NewReader(data))
+	require.NoError(t, err)
+
+	// The test model has no rank pooling, so is refused before it is loaded
+	body := `{"model": "stories.gguf", "query": "What is a panda?", "documents": ["hi"]}`
+	req := httptest.NewRequest(http.MethodPost, "/api/rerank", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rw := httptest.NewRecorder()
+	router.ServeHTTP(rw, req)
+	assert.Equal(t, http.StatusBadRequest, rw.Code)
+	assert.Contains(t, rw.Body.String(), "cannot rerank")
+
+	models, err := llama.ListModels(context.Background())
+	require.NoError(t, err)
+	require.Len(t, models, 1)
+	assert.True(t, models[0].LoadedAt.IsZero())
+}
@@ -0,0 +1,58 @@
+package httphandler
+
+import (
+	"net/http"
+
+	// Packages
+	llamacpp "github.com/mutablelogic/go-llama/pkg/llamacpp"
+	schema "github.com/mutablelogic/go-llama/pkg/llamacpp/schema"
+	httprequest "github.com/mutablelogic/go-server/pkg/httprequest"
+	httpresponse "github.com/mutablelogic/go-server/pkg/httpresponse"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// RegisterSimilarityHandlers registers HTTP handlers for Similarity operations
+func RegisterSimilarityHandlers(router *http.ServeMux, prefix string, llamaInstance *llamacpp.Llama, middleware HTTPMiddlewareFuncs) {
+	router.HandleFunc(joinPath(prefix, "similarity"), middleware.Wrap(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost:
+			_ = similarityCreate(w, r, llamaInstance)
+		default:
+			_ = httpresponse.Error(w, httpresponse.Err(http.StatusMethodNotAllowed), r.Method)
+		}
+	}))
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// similarityCreate handles POST /similarity requests to compare, rank or
+// cluster texts
+func similarityCreate(w http.ResponseWriter, r *http.Request, llamaInstance *llamacpp.Llama) error {
+	var req schema.SimilarityRequest
+	if err := httprequest.Read(r, &req); err != nil {
+		return httpresponse.Error(w, httpresponse.ErrBadRequest.With("failed to read request"), err.Error())
+	}
+
+	if req.Model == "" {
+		return httpresponse.Error(w, httpresponse.ErrBadRequest.With("model is required"))
+	}
+	if len(req.Input) == 0 {
+		return httpresponse.Error(w, httpresponse.ErrBadRequest.With("input is required"))
+	}
+	if req.TopK < 0 {
+		return httpresponse.Error(w, httpresponse.ErrBadRequest.With("top_k cannot be negative"))
+	}
+	if !req.Cluster.Valid() {
+		return httpresponse.Error(w, httpresponse.ErrBadRequest.Withf("invalid cluster method %q", req.Cluster))
+	}
+
+	result, err := llamaInstance.Similarity(r.Context(), req)
+	if err != nil {
+		return httpresponse.Error(w, httperr(err))
+	}
+
+	return httpresponse.JSON(w, http.StatusOK, httprequest.Indent(r), result)
+}
@@ -0,0 +1,96 @@
+package httphandler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	schema "github.com/mutablelogic/go-llama/pkg/llamacpp/schema"
+	"github.com/stretchr/testify/assert"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TESTS - SIMILARITY
+
+func TestSimilarityCreate_ModelNotFound(t *testing.T) {
+	llama := setupTestLlama(t)
+	defer func() {
+		_ = llama.Close()
+	}()
+
+	router := http.NewServeMux()
+	RegisterSimilarityHandlers(router, "/api", llama, noopMiddleware())
+
+	reqBody := `{"model": "test-model", "input": ["a cat", "a kitten"], "cluster": "kmeans", "clusters": 1}`
+	req := httptest.NewRequest(http.MethodPost, "/api/similarity", strings.NewReader(reqBody))
+	req.Header.Set("Content-Type", "application/json")
+	rw := httptest.NewRecorder()
+	router.ServeHTTP(rw, req)
+
+	// Should fail because the model doesn't exist
+	assert.NotEqual(t, http.StatusOK, rw.Code)
+}
+
+func TestSimilarityCreate_InvalidRequest(t *testing.T) {
+	llama := setupTestLlama(t)
+	defer func() {
+		_ = llama.Close()
+	}()
+
+	router := http.NewServeMux()
+	RegisterSimilarityHandlers(router, "/api", llama, noopMiddleware())
+
+	for _, body := range []string{
+		`{invalid json}`,
+		`{"input": ["a cat"]}`,
+		`{"model": "test-model"}`,
+		`{"model": "test-model", "input": []}`,
+		`{"model": "test-model", "input": ["a cat"], "query": "cat", "top_k": -1}`,
+		`{"model": "test-model", "input": ["a cat"], "cluster": "dbscan"}`,
+		`{"model": "test-model", "input": ["a cat"], "cluster": "kmeans"}`,
+		`{"model": "test-model", "input": ["a cat"], "cluster": "kmeans", "clusters": -1}`,
+		`{"model": "test-model", "input": ["a cat"], "cluster": "agglomerative"}`,
+		`{"model": "test-model", "input": ["a cat"], "cluster": "agglomerative", "threshold": 1.5}`,
+		`{"model": "test-model", "input": [` + strings.Repeat(`"a cat", `, schema.MaxSimilarityInput) + `"a dog"]}`,
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/api/similarity", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rw := httptest.NewRecorder()
+		router.ServeHTTP(rw, req)
+		assert.Equal(t, http.StatusBadRequest, rw.Code, body)
+	}
+}
+
+func TestSimilarityCreate_ZeroThreshold(t *testing.T) {
+	llama := setupTestLlama(t)
+	defer func() {
+		_ = llama.Close()
+	}()
+
+	router := http.NewServeMux()
+	RegisterSimilarityHandlers(router, "/api", llama, noopMiddleware())
+
+	// A threshold of zero is a threshold, so the request reaches the model
+	body := `{"model": "test-model", "input": ["a cat"], "cluster": "agglomerative", "threshold": 0}`
+	req := httptest.NewRequest(http.MethodPost, "/api/similarity", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rw := httptest.NewRecorder()
+	router.ServeHTTP(rw, req)
+	assert.Equal(t, http.StatusNotFound, rw.Code)
+}
+
+func TestSimilarityCreate_MethodNotAllowed(t *testing.T) {
+	llama := setupTestLlama(t)
+	defer func() {
+		_ = llama.Close()
+	}()
+
+	router := http.NewServeMux()
+	RegisterSimilarityHandlers(router, "/api", llama, noopMiddleware())
+
+	req := httptest.NewRequest(http.MethodGet, "/api/similarity", nil)
+	rw := httptest.NewRecorder()
+	router.ServeHTTP(rw, req)
+	assert.Equal(t, http.StatusMethodNotAllowed, rw.Code)
+}
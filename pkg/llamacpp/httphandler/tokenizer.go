@@ -13,7 +13,7 @@ import (
 ///////////////////////////////////////////////////////////////////////////////
 // PUBLIC METHODS
 
-// RegisterTokenizerHandlers registers HTTP handlers for Tokenize/Detokenize operations
+// RegisterTokenizerHandlers registers HTTP handlers for Tokenize/Detokenize/Chunk operations
 func RegisterTokenizerHandlers(router *http.ServeMux, prefix string, llamaInstance *llamacpp.Llama, middleware HTTPMiddlewareFuncs) {
 	router.HandleFunc(joinPath(prefix, "tokenize"), middleware.Wrap(func(w http.ResponseWriter, r *http.Request) {
 		switch r.Method {
@@ -32,6 +32,15 @@ func RegisterTokenizerHandlers(router *http.ServeMux, prefix string, llamaInstan
 			_ = httpresponse.Error(w, httpresponse.Err(http.StatusMethodNotAllowed), r.Method)
 		}
 	}))
+
+	router.HandleFunc(joinPath(prefix, "chunk"), middleware.Wrap(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost:
+			_ = chunkCreate(w, r, llamaInstance)
+		default:
+			_ = httpresponse.Error(w, httpresponse.Err(http.StatusMethodNotAllowed), r.Method)
+		}
+	}))
 }
 
 ///////////////////////////////////////////////////////////////////////////////
@@ -74,3 +83,31 @@ func detokenizeCreate(w http.ResponseWriter, r *http.Request, llamaInstance *lla
 
 	return httpresponse.JSON(w, http.StatusOK, httprequest.Indent(r), result)
 }
+
+// chunkCreate handles POST /chunk requests to split text into chunks of tokens
+func chunkCreate(w http.ResponseWriter, r *http.Request, llamaInstance *llamacpp.Llama) error {
+	var req schema.ChunkRequest
+	if err := httprequest.Read(r, &req); err != nil {
+		return httpresponse.Error(w, httpresponse.ErrBadRequest.With("failed to read request"), err.Error())
+	}
+
+	if req.Model == "" {
+		return httpresponse.Error(w, httpresponse.ErrBadRequest.With("model is required"))
+	}
+	size := req.Size
+	if size == 0 {
+		size = schema.DefaultChunkSize
+	}
+	if size < 0 || req.Overlap < 0 {
+		return httpresponse.Error(w, httpresponse.ErrBadRequest.With("size and overlap cannot be negative"))
+	} else if req.Overlap >= size {
+		return httpresponse.Error(w, httpresponse.ErrBadRequest.With("overlap must be less than the size"))
+	}
+
+	result, err := llamaInstance.Chunk(r.Context(), req)
+	if err != nil {
+		return httpresponse.Error(w, httperr(err))
+	}
+
+	return httpresponse.JSON(w, http.StatusOK, httprequest.Indent(r), result)
+}
@@ -334,3 +334,63 @@ func TestDetokenize_LargeTokenArray(t *testing.T) {
 	// Should process large token array, but will fail due to non-existent model
 	assert.NotEqual(t, http.StatusOK, rw.Code)
 }
+
+///////////////////////////////////////////////////////////////////////////////
+// TESTS - CHUNK
+
+func TestChunkCreate_InvalidRequest(t *testing.T) {
+	llama := setupTestLlama(t)
+	defer func() {
+		_ = llama.Close()
+	}()
+
+	router := http.NewServeMux()
+	RegisterTokenizerHandlers(router, "/api", llama, noopMiddleware())
+
+	for _, body := range []string{
+		`{invalid json}`,
+		`{"text": "Hello world"}`,
+		`{"model": "test-model", "text": "Hello world", "size": -1}`,
+		`{"model": "test-model", "text": "Hello world", "overlap": -1}`,
+		`{"model": "test-model", "text": "Hello world", "size": 10, "overlap": 10}`,
+		`{"model": "test-model", "text": "Hello world", "overlap": 512}`,
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/api/chunk", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rw := httptest.NewRecorder()
+		router.ServeHTTP(rw, req)
+		assert.Equal(t, http.StatusBadRequest, rw.Code, body)
+	}
+}
+
+func TestChunkCreate_ModelNotFound(t *testing.T) {
+	llama := setupTestLlama(t)
+	defer func() {
+		_ = llama.Close()
+	}()
+
+	router := http.NewServeMux()
+	RegisterTokenizerHandlers(router, "/api", llama, noopMiddleware())
+
+	reqBody := `{"model": "test-model", "text": "Hello world", "size": 16, "overlap": 4}`
+	req := httptest.NewRequest(http.MethodPost, "/api/chunk", strings.NewReader(reqBody))
+	req.Header.Set("Content-Type", "application/json")
+	rw := httptest.NewRecorder()
+	router.ServeHTTP(rw, req)
+	assert.Equal(t, http.StatusNotFound, rw.Code)
+}
+
+func TestChunkCreate_MethodNotAllowed(t *testing.T) {
+	llama := setupTestLlama(t)
+	defer func() {
+		_ = llama.Close()
+	}()
+
+	router := http.NewServeMux()
+	RegisterTokenizerHandlers(router, "/api", llama, noopMiddleware())
+
+	req := httptest.NewRequest(http.MethodGet, "/api/chunk", nil)
+	rw := httptest.NewRecorder()
+	router.ServeHTTP(rw, req)
+	assert.Equal(t, http.StatusMethodNotAllowed, rw.Code)
+}
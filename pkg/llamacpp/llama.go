@@ -3,11 +3,13 @@ package llamacpp
 import (
 	"context"
 	"fmt"
+	"path/filepath"
 	"sync"
 
 	// Packages
 	"github.com/mutablelogic/go-client"
 	otel "github.com/mutablelogic/go-client/pkg/otel"
+	collection "github.com/mutablelogic/go-llama/pkg/llamacpp/collection"
 	schema "github.com/mutablelogic/go-llama/pkg/llamacpp/schema"
 	store "github.com/mutablelogic/go-llama/pkg/llamacpp/store"
 	llamacpp "github.com/mutablelogic/go-llama/sys/llamacpp"
@@ -21,7 +23,10 @@ type Llama struct {
 	sync.RWMutex
 	opt
 	*store.Store
-	cached map[string]*schema.CachedModel
+	cached      map[string]*schema.CachedModel
+	pulls       pullJobs
+	embedCache  *embedCache
+	collections *collection.Collections
 }
 
 ///////////////////////////////////////////////////////////////////////////////
@@ -59,6 +64,7 @@ func New(path string, opts ...Opt) (*Llama, error) {
 	} else {
 		instance.Store = store
 	}
+	instance.collections = collection.New(filepath.Join(instance.Store.Path(), collectionsDir))
 
 	// Apply options
 	for _, opt := range opts {
@@ -66,6 +72,24 @@ func New(path string, opts ...Opt) (*Llama, error) {
 			return nil, err
 		}
 	}
+	if len(instance.credentials) > 0 {
+		instance.Store.SetCredentials(instance.credentials...)
+	}
+	if instance.quota > 0 {
+		instance.Store.SetQuota(instance.quota)
+	}
+	for _, path := range instance.readonly {
+		if err := instance.Store.AddReadOnlyRoot(path); err != nil {
+			return nil, err
+		}
+	}
+	if instance.embedCacheSize > 0 || instance.embedCachePath != "" {
+		if cache, err := newEmbedCache(instance.embedCacheSize, instance.embedCachePath, instance.embedCacheLimit); err != nil {
+			return nil, err
+		} else {
+			instance.embedCache = cache
+		}
+	}
 
 	// Return success
 	return instance, nil
@@ -77,6 +101,9 @@ func (l *Llama) Close() error {
 		return nil
 	}
 
+	// Cancel any pulls in progress
+	l.pulls.cancelAll()
+
 	// Lock the instance
 	l.Lock()
 	defer l.Unlock()
@@ -2,7 +2,7 @@ package llamacpp
 
 import (
 	"context"
-	"path/filepath"
+	"io"
 	"time"
 
 	// Packages
@@ -58,11 +58,14 @@ func (l *Llama) LoadModel(ctx context.Context, req schema.LoadModelRequest) (res
 	}
 
 	// Load the model
-	handle, err := llamacpp.LoadModel(filepath.Join(l.Store.Path(), model.Path), params)
+	handle, err := llamacpp.LoadModel(l.Store.FilePath(model), params)
 	if err != nil {
 		return nil, err
 	}
 
+	// Record the load, so recently used models are kept when the store is over quota
+	l.Store.MarkLoaded(model)
+
 	// Return the cached model
 	cached := &schema.CachedModel{
 		Model:    *model,
@@ -148,6 +151,17 @@ func (l *Llama) GetModel(ctx context.Context, name string) (result *schema.Cache
 	}, nil
 }
 
+// GetModelTensors returns the tensor layout of a model by name, including a
+// breakdown by tensor type and the individual tensors.
+func (l *Llama) GetModelTensors(ctx context.Context, name string) (result *schema.ModelTensors, err error) {
+	ctx, endSpan := otel.StartSpan(l.tracer, ctx, schema.SpanName("GetModelTensors"),
+		attribute.String("request", name),
+	)
+	defer func() { endSpan(err) }()
+
+	return l.Store.GetModelTensors(ctx, name, true)
+}
+
 // populateRuntime fills runtime stats for a loaded model if missing.
 func (l *Llama) populateRuntime(cached *schema.CachedModel) {
 	if cached == nil || cached.Handle == nil || cached.Runtime != nil {
@@ -166,6 +180,19 @@ func (l *Llama) populateRuntime(cached *schema.CachedModel) {
 	}
 }
 
+// requireCapability returns err if the model has not got the capability, so
+// that requests for a model of the wrong kind are refused before the model
+// is loaded. Returns any error finding the model.
+func (l *Llama) requireCapability(ctx context.Context, name string, capability schema.Capability, err error) error {
+	model, modelErr := l.Store.GetModel(ctx, name)
+	if modelErr != nil {
+		return modelErr
+	} else if !model.HasCapability(capability) {
+		return err
+	}
+	return nil
+}
+
 // UnloadModel unloads a model from memory and removes it from the cache.
 // Returns the model (now uncached with zero timestamp) and any error.
 func (l *Llama) UnloadModel(ctx context.Context, name string) (result *schema.CachedModel, err error) {
@@ -235,6 +262,91 @@ func (l *Llama) DeleteModel(ctx context.Context, name string) (err error) {
 	return l.Store.DeleteModel(ctx, model.Path)
 }
 
+// UpdateModel pins or unpins a model in the store, or replaces its labels
+// or notes, and returns the cached model. A pinned model is not evicted by
+// garbage collection when the store is over quota.
+func (l *Llama) UpdateModel(ctx context.Context, name string, req schema.UpdateModelRequest) (result *schema.CachedModel, err error) {
+	ctx, endSpan := otel.StartSpan(l.tracer, ctx, schema.SpanName("UpdateModel"),
+		attribute.String("name", name),
+		attribute.String("request", req.String()),
+	)
+	defer func() { endSpan(err) }()
+
+	l.Lock()
+	defer l.Unlock()
+
+	// Pin or unpin the model
+	var model *schema.Model
+	if req.Pinned != nil {
+		if model, err = l.Store.PinModel(ctx, name, *req.Pinned); err != nil {
+			return nil, err
+		}
+	}
+
+	// Replace the labels and notes
+	if req.Labels != nil || req.Notes != nil {
+		model, err = l.Store.UpdateModelMeta(ctx, name, func(meta *schema.ModelMeta) {
+			if req.Labels != nil {
+				meta.Labels = *req.Labels
+			}
+			if req.Notes != nil {
+				meta.Notes = *req.Notes
+			}
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Return the model unchanged if there is nothing to update
+	if model == nil {
+		if model, err = l.Store.GetModel(ctx, name); err != nil {
+			return nil, err
+		}
+	}
+
+	// Update the cached model if loaded
+	if cached, ok := l.cached[model.Path]; ok {
+		cached.Pinned = model.Pinned
+		cached.ModelMeta = model.ModelMeta
+		return cached, nil
+	}
+	return &schema.CachedModel{
+		Model: *model,
+	}, nil
+}
+
+// GarbageCollect removes temporary files left in the store by interrupted
+// pulls, imports and uploads and, if the store is over quota, evicts the least
+// recently used models which are not pinned or loaded. With a dry run, the
+// files which would be removed are returned without removing them.
+func (l *Llama) GarbageCollect(ctx context.Context, req schema.GCRequest) (result *schema.GCResponse, err error) {
+	ctx, endSpan := otel.StartSpan(l.tracer, ctx, schema.SpanName("GarbageCollect"),
+		attribute.String("request", req.String()),
+	)
+	defer func() { endSpan(err) }()
+
+	l.RLock()
+	defer l.RUnlock()
+
+	// Loaded models are not evicted
+	loaded := make([]string, 0, len(l.cached))
+	for path := range l.cached {
+		loaded = append(loaded, path)
+	}
+	return l.Store.GarbageCollect(ctx, req.DryRun, loaded...)
+}
+
+// ListRemoteModels returns the GGUF models in a Hugging Face repository.
+func (l *Llama) ListRemoteModels(ctx context.Context, req schema.RemoteModelRequest) (result []*schema.RemoteModel, err error) {
+	ctx, endSpan := otel.StartSpan(l.tracer, ctx, schema.SpanName("ListRemoteModels"),
+		attribute.String("request", req.String()),
+	)
+	defer func() { endSpan(err) }()
+
+	return l.Store.ListRemoteModels(ctx, req.Repo)
+}
+
 // PullModel downloads a model from the given URL and returns the cached model.
 func (l *Llama) PullModel(ctx context.Context, req schema.PullModelRequest, fn PullCallback) (result *schema.CachedModel, err error) {
 	ctx, endSpan := otel.StartSpan(l.tracer, ctx, schema.SpanName("PullModel"),
@@ -243,7 +355,46 @@ func (l *Llama) PullModel(ctx context.Context, req schema.PullModelRequest, fn P
 	defer func() { endSpan(err) }()
 
 	// Download the model using the store
-	model, err := l.Store.PullModel(ctx, req.URL, store.ClientCallback(fn))
+	model, err := l.Store.PullModel(ctx, req.URL, store.ClientCallback(fn), store.WithToken(req.Token))
+	if err != nil {
+		return nil, err
+	}
+
+	// Return the cached model
+	return &schema.CachedModel{
+		Model: *model,
+	}, nil
+}
+
+// ImportModel imports a model file on the server into the store, by copying,
+// hardlinking or symlinking it, and returns the cached model.
+func (l *Llama) ImportModel(ctx context.Context, req schema.ImportModelRequest) (result *schema.CachedModel, err error) {
+	ctx, endSpan := otel.StartSpan(l.tracer, ctx, schema.SpanName("ImportModel"),
+		attribute.String("request", req.String()),
+	)
+	defer func() { endSpan(err) }()
+
+	model, err := l.Store.ImportModel(ctx, req.Path, req.Dest, req.Mode)
+	if err != nil {
+		return nil, err
+	}
+
+	// Return the cached model
+	return &schema.CachedModel{
+		Model: *model,
+	}, nil
+}
+
+// UploadModel writes a model file read from r into the store at dest, a path
+// relative to the store, and returns the cached model. Files larger than the
+// upload limit are refused with ErrTooLarge.
+func (l *Llama) UploadModel(ctx context.Context, dest string, r io.Reader) (result *schema.CachedModel, err error) {
+	ctx, endSpan := otel.StartSpan(l.tracer, ctx, schema.SpanName("UploadModel"),
+		attribute.String("dest", dest),
+	)
+	defer func() { endSpan(err) }()
+
+	model, err := l.Store.WriteModel(ctx, dest, r, l.uploadLimit)
 	if err != nil {
 		return nil, err
 	}
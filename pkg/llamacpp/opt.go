@@ -1,6 +1,9 @@
 package llamacpp
 
 import (
+	// Packages
+	llama "github.com/mutablelogic/go-llama"
+	store "github.com/mutablelogic/go-llama/pkg/llamacpp/store"
 	"go.opentelemetry.io/otel/trace"
 )
 
@@ -11,7 +14,16 @@ type Opt func(*opt) error
 
 // opt contains configuration for the Llama instance
 type opt struct {
-	tracer trace.Tracer
+	tracer      trace.Tracer
+	credentials []store.Credential
+	readonly    []string
+	uploadLimit int64
+	quota       uint64
+
+	// Embedding cache
+	embedCacheSize  int
+	embedCachePath  string
+	embedCacheLimit uint64
 }
 
 ///////////////////////////////////////////////////////////////////////////////
@@ -25,3 +37,69 @@ func WithTracer(tracer trace.Tracer) Opt {
 		return nil
 	}
 }
+
+// WithUploadLimit sets the maximum size in bytes of a model file uploaded
+// to the store. Zero, the default, allows files of any size.
+func WithUploadLimit(limit int64) Opt {
+	return func(o *opt) error {
+		if limit < 0 {
+			return llama.ErrInvalidArgument.Withf("invalid upload limit: %d", limit)
+		}
+		o.uploadLimit = limit
+		return nil
+	}
+}
+
+// WithCredentials sets the credentials for downloading models from hosts
+// other than Hugging Face, such as private mirrors.
+func WithCredentials(credentials ...store.Credential) Opt {
+	return func(o *opt) error {
+		o.credentials = append(o.credentials, credentials...)
+		return nil
+	}
+}
+
+// WithReadOnlyStores adds directories of models which are listed and loaded
+// alongside the store, but never written to. They can be shared between
+// servers. Models are pulled, imported and deleted only in the store.
+func WithReadOnlyStores(paths ...string) Opt {
+	return func(o *opt) error {
+		o.readonly = append(o.readonly, paths...)
+		return nil
+	}
+}
+
+// WithEmbeddingCache caches embeddings by model file, options and text, so
+// that only new texts are embedded. Up to size embeddings are kept in memory,
+// and if path is not empty, all embeddings are also kept in files in that
+// directory, which persist between restarts.
+func WithEmbeddingCache(size int, path string) Opt {
+	return func(o *opt) error {
+		if size < 0 {
+			return llama.ErrInvalidArgument.Withf("invalid embedding cache size: %d", size)
+		}
+		o.embedCacheSize = size
+		o.embedCachePath = path
+		return nil
+	}
+}
+
+// WithEmbeddingCacheLimit sets the maximum size in bytes of the directory of
+// the embedding cache. When it is larger, the least recently used embeddings
+// are removed. Zero, the default, is no limit.
+func WithEmbeddingCacheLimit(limit uint64) Opt {
+	return func(o *opt) error {
+		o.embedCacheLimit = limit
+		return nil
+	}
+}
+
+// WithQuota sets the maximum number of bytes used by models in the store.
+// When the store is over quota, garbage collection evicts the least recently
+// used models which are not pinned or loaded. Zero, the default, is no quota.
+func WithQuota(quota uint64) Opt {
+	return func(o *opt) error {
+		o.quota = quota
+		return nil
+	}
+}
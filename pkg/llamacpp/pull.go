@@ -0,0 +1,343 @@
+package llamacpp
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+
+	// Packages
+	otel "github.com/mutablelogic/go-client/pkg/otel"
+	llama "github.com/mutablelogic/go-llama"
+	schema "github.com/mutablelogic/go-llama/pkg/llamacpp/schema"
+	attribute "go.opentelemetry.io/otel/attribute"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// PullJobCallback receives the progress of a pull job
+type PullJobCallback func(*schema.PullJob) error
+
+// pullJobs tracks asynchronous model pulls by id
+type pullJobs struct {
+	sync.Mutex
+	jobs map[string]*pullJob
+}
+
+// pullJob is an asynchronous model pull. Progress events are kept so they
+// can be replayed to clients which attach after the pull has started.
+type pullJob struct {
+	sync.Mutex
+	job     schema.PullJob
+	cancel  context.CancelFunc
+	events  []schema.PullJob
+	changed chan struct{} // Closed and replaced when an event is added
+	done    chan struct{} // Closed when the pull has finished
+	start   time.Time     // When the current file was started
+	offset  uint64        // Bytes received of the current file when it was started
+	last    time.Time     // When the last event was added
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	pullJobIDSize     = 8
+	pullEventInterval = time.Second
+	pullJobRetention  = time.Hour
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// StartPullJob starts downloading a model in the background and returns the
+// pull job immediately. If the URL is already being pulled, the existing job
+// is returned rather than downloading the model twice. The pull is not
+// cancelled with the context; use CancelPullJob.
+func (l *Llama) StartPullJob(ctx context.Context, req schema.PullModelRequest) (result *schema.PullJob, err error) {
+	ctx, endSpan := otel.StartSpan(l.tracer, ctx, schema.SpanName("StartPullJob"),
+		attribute.String("request", req.String()),
+	)
+	defer func() { endSpan(err) }()
+
+	if req.URL == "" {
+		return nil, llama.ErrInvalidArgument.With("model URL is required")
+	}
+
+	l.pulls.Lock()
+	defer l.pulls.Unlock()
+
+	// Remove finished jobs which have expired
+	l.pulls.prune(time.Now().Add(-pullJobRetention))
+
+	// Attach to an existing pull of the same URL
+	for _, job := range l.pulls.jobs {
+		if snapshot := job.snapshot(); snapshot.URL == req.URL && !snapshot.Done() {
+			return snapshot, nil
+		}
+	}
+
+	// Create the job, which runs until the pull finishes or it is cancelled
+	ctx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	job := newPullJob(newPullJobID(), req.URL, cancel)
+	if l.pulls.jobs == nil {
+		l.pulls.jobs = make(map[string]*pullJob)
+	}
+	l.pulls.jobs[job.job.ID] = job
+
+	// Pull the model in the background
+	go func() {
+		defer cancel()
+		model, err := l.PullModel(ctx, req, job.progress)
+		job.finish(model, err)
+	}()
+
+	// Return the job
+	return job.snapshot(), nil
+}
+
+// ListPullJobs returns the running pull jobs, and those which finished in
+// the last hour, in the order they were started.
+func (l *Llama) ListPullJobs(ctx context.Context) (result []*schema.PullJob, err error) {
+	_, endSpan := otel.StartSpan(l.tracer, ctx, schema.SpanName("ListPullJobs"))
+	defer func() { endSpan(err) }()
+
+	l.pulls.Lock()
+	defer l.pulls.Unlock()
+
+	// Remove finished jobs which have expired
+	l.pulls.prune(time.Now().Add(-pullJobRetention))
+
+	result = make([]*schema.PullJob, 0, len(l.pulls.jobs))
+	for _, job := range l.pulls.jobs {
+		result = append(result, job.snapshot())
+	}
+	slices.SortFunc(result, func(a, b *schema.PullJob) int {
+		return a.StartedAt.Compare(b.StartedAt)
+	})
+	return result, nil
+}
+
+// GetPullJob returns a pull job by id.
+func (l *Llama) GetPullJob(ctx context.Context, id string) (result *schema.PullJob, err error) {
+	_, endSpan := otel.StartSpan(l.tracer, ctx, schema.SpanName("GetPullJob"),
+		attribute.String("id", id),
+	)
+	defer func() { endSpan(err) }()
+
+	job, err := l.pulls.get(id)
+	if err != nil {
+		return nil, err
+	}
+	return job.snapshot(), nil
+}
+
+// CancelPullJob cancels a pull job and waits for it to finish, returning
+// the job. Cancelling a finished job has no effect. The partial download
+// is kept, so pulling the same URL again resumes it.
+func (l *Llama) CancelPullJob(ctx context.Context, id string) (result *schema.PullJob, err error) {
+	ctx, endSpan := otel.StartSpan(l.tracer, ctx, schema.SpanName("CancelPullJob"),
+		attribute.String("id", id),
+	)
+	defer func() { endSpan(err) }()
+
+	job, err := l.pulls.get(id)
+	if err != nil {
+		return nil, err
+	}
+
+	// Cancel and wait for the pull to finish
+	job.cancel()
+	select {
+	case <-job.done:
+		return job.snapshot(), nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// WatchPullJob calls the function with each progress event of a pull job,
+// starting with the events which have already happened, until the job
+// finishes or the context is cancelled. The last event has the final
+// status of the job.
+func (l *Llama) WatchPullJob(ctx context.Context, id string, fn PullJobCallback) (err error) {
+	ctx, endSpan := otel.StartSpan(l.tracer, ctx, schema.SpanName("WatchPullJob"),
+		attribute.String("id", id),
+	)
+	defer func() { endSpan(err) }()
+
+	job, err := l.pulls.get(id)
+	if err != nil {
+		return err
+	}
+
+	for n := 0; ; {
+		events, changed, done := job.since(n)
+		for i := range events {
+			if err := fn(&events[i]); err != nil {
+				return err
+			}
+		}
+		if done {
+			return nil
+		}
+		n += len(events)
+
+		// Wait for the next event
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS - JOBS
+
+// newPullJobID returns a random job id
+func newPullJobID() string {
+	var id [pullJobIDSize]byte
+	rand.Read(id[:])
+	return hex.EncodeToString(id[:])
+}
+
+// get returns a pull job by id
+func (p *pullJobs) get(id string) (*pullJob, error) {
+	p.Lock()
+	defer p.Unlock()
+	if job, exists := p.jobs[id]; exists {
+		return job, nil
+	}
+	return nil, llama.ErrNotFound.Withf("pull job %q", id)
+}
+
+// prune removes the jobs which finished before the given time
+func (p *pullJobs) prune(before time.Time) {
+	for id, job := range p.jobs {
+		if snapshot := job.snapshot(); snapshot.Done() && snapshot.FinishedAt.Before(before) {
+			delete(p.jobs, id)
+		}
+	}
+}
+
+// cancelAll cancels all running jobs
+func (p *pullJobs) cancelAll() {
+	p.Lock()
+	defer p.Unlock()
+	for _, job := range p.jobs {
+		job.cancel()
+	}
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS - JOB
+
+func newPullJob(id, url string, cancel context.CancelFunc) *pullJob {
+	now := time.Now()
+	job := &pullJob{
+		job: schema.PullJob{
+			ID:        id,
+			URL:       url,
+			Status:    schema.PullJobRunning,
+			StartedAt: now,
+		},
+		cancel:  cancel,
+		changed: make(chan struct{}),
+		done:    make(chan struct{}),
+		start:   now,
+	}
+	job.emit(now)
+	return job
+}
+
+// progress updates the job from a download progress callback. An event is
+// added when a file is started or finished, and otherwise at most once per
+// pullEventInterval.
+func (j *pullJob) progress(filename string, received, total uint64) error {
+	j.Lock()
+	defer j.Unlock()
+
+	// Restart the rate calculation for each file
+	now := time.Now()
+	started := filename != j.job.Filename || received < j.offset
+	if started {
+		j.start, j.offset = now, received
+	}
+
+	// Update progress
+	j.job.Filename = filename
+	j.job.BytesReceived = received
+	j.job.TotalBytes = total
+	j.job.Percentage = 0
+	if total > 0 {
+		j.job.Percentage = float64(received) * 100.0 / float64(total)
+	}
+
+	// Update the download rate and the time remaining
+	j.job.BytesPerSecond, j.job.ETA = 0, 0
+	if elapsed := now.Sub(j.start).Seconds(); elapsed > 0 && received > j.offset {
+		j.job.BytesPerSecond = uint64(float64(received-j.offset) / elapsed)
+	}
+	if j.job.BytesPerSecond > 0 && total > received {
+		j.job.ETA = (total - received + j.job.BytesPerSecond - 1) / j.job.BytesPerSecond
+	}
+
+	// Add an event
+	if started || received == total || now.Sub(j.last) >= pullEventInterval {
+		j.emit(now)
+	}
+	return nil
+}
+
+// finish sets the result of the pull and adds the final event
+func (j *pullJob) finish(model *schema.CachedModel, err error) {
+	j.Lock()
+	defer j.Unlock()
+
+	now := time.Now()
+	j.job.FinishedAt = now
+	j.job.BytesPerSecond, j.job.ETA = 0, 0
+	switch {
+	case err == nil:
+		j.job.Status = schema.PullJobComplete
+		j.job.Result = &model.Model
+	case errors.Is(err, context.Canceled):
+		j.job.Status = schema.PullJobCancelled
+		j.job.Error = err.Error()
+	default:
+		j.job.Status = schema.PullJobFailed
+		j.job.Error = err.Error()
+	}
+	j.emit(now)
+	close(j.done)
+}
+
+// emit adds an event with the current state of the job, and wakes any
+// watchers. The caller must hold the lock.
+func (j *pullJob) emit(now time.Time) {
+	j.events = append(j.events, j.job)
+	j.last = now
+	close(j.changed)
+	j.changed = make(chan struct{})
+}
+
+// snapshot returns a copy of the current state of the job
+func (j *pullJob) snapshot() *schema.PullJob {
+	j.Lock()
+	defer j.Unlock()
+	job := j.job
+	return &job
+}
+
+// since returns the events from index n, a channel which is closed when
+// there are more events, and whether the job has finished
+func (j *pullJob) since(n int) ([]schema.PullJob, <-chan struct{}, bool) {
+	j.Lock()
+	defer j.Unlock()
+	return slices.Clone(j.events[n:]), j.changed, j.job.Done()
+}
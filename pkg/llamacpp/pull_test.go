@@ -0,0 +1,130 @@
+package llamacpp_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	llama "github.com/mutablelogic/go-llama"
+	llamacpp "github.com/mutablelogic/go-llama/pkg/llamacpp"
+	schema "github.com/mutablelogic/go-llama/pkg/llamacpp/schema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newPullJobServer serves the test model, sending the first half and then
+// waiting until the release channel is closed before sending the rest
+func newPullJobServer(t *testing.T) (*httptest.Server, chan struct{}) {
+	data, err := os.ReadFile(filepath.Join(testdataPath, "stories260K.gguf"))
+	require.NoError(t, err)
+
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+		if r.Method == http.MethodHead {
+			return
+		}
+		w.Write(data[:len(data)/2])
+		w.(http.Flusher).Flush()
+		select {
+		case <-release:
+			w.Write(data[len(data)/2:])
+		case <-r.Context().Done():
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, release
+}
+
+func TestLlama_PullJob(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	l, err := llamacpp.New(t.TempDir())
+	require.NoError(err)
+	defer l.Close()
+
+	server, release := newPullJobServer(t)
+	ctx := context.Background()
+
+	// Start a pull, which returns immediately
+	job, err := l.StartPullJob(ctx, schema.PullModelRequest{URL: server.URL + "/model.gguf"})
+	require.NoError(err)
+	assert.NotEmpty(job.ID)
+	assert.Equal(schema.PullJobRunning, job.Status)
+
+	// A second pull of the same URL attaches to the job
+	other, err := l.StartPullJob(ctx, schema.PullModelRequest{URL: server.URL + "/model.gguf"})
+	require.NoError(err)
+	assert.Equal(job.ID, other.ID)
+
+	jobs, err := l.ListPullJobs(ctx)
+	require.NoError(err)
+	require.Len(jobs, 1)
+	assert.Equal(job.ID, jobs[0].ID)
+
+	// Follow the progress until the pull completes
+	close(release)
+	var events []*schema.PullJob
+	require.NoError(l.WatchPullJob(ctx, job.ID, func(job *schema.PullJob) error {
+		events = append(events, job)
+		return nil
+	}))
+	require.GreaterOrEqual(len(events), 2)
+	last := events[len(events)-1]
+	assert.Equal(schema.PullJobComplete, last.Status)
+	assert.Equal(last.TotalBytes, last.BytesReceived)
+	require.NotNil(last.Result)
+	assert.Equal("model.gguf", last.Result.Path)
+	assert.False(last.FinishedAt.IsZero())
+
+	// The finished job is still reported, and the events are replayed
+	job, err = l.GetPullJob(ctx, job.ID)
+	require.NoError(err)
+	assert.Equal(schema.PullJobComplete, job.Status)
+	var replayed int
+	require.NoError(l.WatchPullJob(ctx, job.ID, func(*schema.PullJob) error {
+		replayed++
+		return nil
+	}))
+	assert.Equal(len(events), replayed)
+
+	// Unknown jobs are not found
+	_, err = l.GetPullJob(ctx, "unknown")
+	assert.ErrorIs(err, llama.ErrNotFound)
+}
+
+func TestLlama_PullJobCancel(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	l, err := llamacpp.New(t.TempDir())
+	require.NoError(err)
+	defer l.Close()
+
+	server, _ := newPullJobServer(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	job, err := l.StartPullJob(ctx, schema.PullModelRequest{URL: server.URL + "/model.gguf"})
+	require.NoError(err)
+
+	// Cancelling waits for the pull to stop
+	job, err = l.CancelPullJob(ctx, job.ID)
+	require.NoError(err)
+	assert.Equal(schema.PullJobCancelled, job.Status)
+	assert.NotEmpty(job.Error)
+	assert.Nil(job.Result)
+
+	// A new pull of the same URL starts a new job
+	other, err := l.StartPullJob(ctx, schema.PullModelRequest{URL: server.URL + "/model.gguf"})
+	require.NoError(err)
+	assert.NotEqual(job.ID, other.ID)
+	_, err = l.CancelPullJob(ctx, other.ID)
+	require.NoError(err)
+}
@@ -3,6 +3,9 @@ package llamacpp
 import (
 	"regexp"
 	"strings"
+
+	// Packages
+	schema "github.com/mutablelogic/go-llama/pkg/llamacpp/schema"
 )
 
 ///////////////////////////////////////////////////////////////////////////////
@@ -184,29 +187,7 @@ func HasThinkingTags(text string) bool {
 // IsThinkingTemplate checks if a chat template string suggests it's a reasoning template
 // by looking for common thinking-related patterns
 func IsThinkingTemplate(template string) bool {
-	if template == "" {
-		return false
-	}
-
-	// Check for common thinking model indicators in templates
-	thinkingIndicators := []string{
-		"<think>",
-		"</think>",
-		"<reasoning>",
-		"</reasoning>",
-		"<scratchpad>",
-		"thinking",
-		"reason",
-	}
-
-	templateLower := strings.ToLower(template)
-	for _, indicator := range thinkingIndicators {
-		if strings.Contains(templateLower, indicator) {
-			return true
-		}
-	}
-
-	return false
+	return schema.IsThinkingTemplate(template)
 }
 
 // IsThinkingModelName checks if a model name/architecture suggests it's a reasoning model
@@ -0,0 +1,115 @@
+package llamacpp
+
+import (
+	"context"
+	"slices"
+
+	// Packages
+	otel "github.com/mutablelogic/go-client/pkg/otel"
+	llama "github.com/mutablelogic/go-llama"
+	schema "github.com/mutablelogic/go-llama/pkg/llamacpp/schema"
+	llamacpp "github.com/mutablelogic/go-llama/sys/llamacpp"
+	attribute "go.opentelemetry.io/otel/attribute"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Rerank scores documents against a query with a cross-encoder model.
+// Loads the model if not already cached, creates a context with rank pooling,
+// and returns the documents sorted by relevance, limited to TopN if set.
+func (l *Llama) Rerank(ctx context.Context, req schema.RerankRequest) (result *schema.RerankResponse, err error) {
+	ctx, endSpan := otel.StartSpan(l.tracer, ctx, schema.SpanName("Rerank"),
+		attribute.String("request", req.String()),
+	)
+	defer func() { endSpan(err) }()
+
+	if req.Query == "" {
+		return nil, llama.ErrInvalidArgument.With("query is required")
+	}
+	if req.TopN < 0 {
+		return nil, llama.ErrInvalidArgument.With("top_n cannot be negative")
+	}
+
+	// Refuse models without rank pooling, which is checked again on the context
+	if err := l.requireCapability(ctx, req.Model, schema.CapRerank, llama.ErrNotSupported.Withf("model %q cannot rerank", req.Model)); err != nil {
+		return nil, err
+	}
+
+	// Build context request with embeddings enabled, to extract the scores
+	embeddings := true
+	contextReq := schema.ContextRequest{
+		LoadModelRequest: schema.LoadModelRequest{
+			Name: req.Model,
+		},
+		Embeddings: &embeddings,
+	}
+
+	err = l.WithContext(ctx, contextReq, func(ctx context.Context, task *Task) error {
+		// Lock the model - score computation is not thread-safe
+		task.CachedModel().Lock()
+		defer task.CachedModel().Unlock()
+
+		if task.Context().PoolingType() != llamacpp.PoolingRank {
+			return llama.ErrNotSupported.Withf("model %q cannot rerank", req.Model)
+		}
+
+		// Build the input of each query and document pair, and count the tokens
+		pairs := make([][]llamacpp.Token, len(req.Documents))
+		var inputTokens int
+		for i, document := range req.Documents {
+			tokens, err := task.Model().RerankTokens(req.Query, document)
+			if err != nil {
+				return err
+			}
+			pairs[i] = tokens
+			inputTokens += len(tokens)
+		}
+
+		// Score each pair
+		scores, err := task.Context().ComputeRankScores(pairs)
+		if err != nil {
+			return err
+		}
+
+		result = &schema.RerankResponse{
+			Model:   req.Model,
+			Results: rerankResults(req, scores),
+			Usage: schema.Usage{
+				InputTokens:  inputTokens,
+				OutputTokens: 0,
+			},
+		}
+		return nil
+	})
+	return
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// rerankResults returns the results sorted by descending score, keeping the
+// document order for equal scores, and limited to TopN results if set
+func rerankResults(req schema.RerankRequest, scores []float32) []schema.RerankResult {
+	results := make([]schema.RerankResult, len(scores))
+	for i, score := range scores {
+		results[i] = schema.RerankResult{Index: i, Score: score}
+		if req.ReturnDocuments {
+			results[i].Document = req.Documents[i]
+		}
+	}
+	slices.SortStableFunc(results, func(a, b schema.RerankResult) int {
+		switch {
+		case a.Score > b.Score:
+			return -1
+		case a.Score < b.Score:
+			return 1
+		default:
+			return 0
+		}
+	})
+	if req.TopN > 0 && req.TopN < len(results) {
+		results = results[:req.TopN]
+	}
+	return results
+}
@@ -0,0 +1,153 @@
+package llamacpp
+
+import (
+	"context"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+
+	// Packages
+	llama "github.com/mutablelogic/go-llama"
+	schema "github.com/mutablelogic/go-llama/pkg/llamacpp/schema"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// retrievalData is the data of the retrieval template
+type retrievalData struct {
+	Query     string
+	Documents []retrievalDocument
+}
+
+// retrievalDocument is a document in the retrieval template
+type retrievalDocument struct {
+	Marker   int
+	ID       string
+	Text     string
+	Metadata map[string]any
+	Score    float64
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+var (
+	// reCitation matches citation markers, such as [1] or [1, 2]
+	reCitation = regexp.MustCompile(`\[(\d+(?:\s*,\s*\d+)*)\]`)
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// retrieve finds the documents of a collection which are most similar to the
+// last user message, and returns the messages with the documents added to
+// that message, and the documents as sources
+func (l *Llama) retrieve(ctx context.Context, retrieval schema.ChatRetrieval, messages []schema.ChatMessage) ([]schema.ChatMessage, []schema.ChatSource, error) {
+	topK := retrieval.TopK
+	if topK == 0 {
+		topK = schema.DefaultRetrievalTopK
+	}
+	source := retrieval.Template
+	if source == "" {
+		source = schema.DefaultRetrievalTemplate
+	}
+	tmpl, err := template.New("retrieval").Option("missingkey=error").Parse(source)
+	if err != nil {
+		return nil, nil, llama.ErrInvalidArgument.Withf("retrieval template: %v", err)
+	}
+
+	// Find the last user message
+	last := -1
+	for i, message := range slices.Backward(messages) {
+		if message.Role == "user" {
+			last = i
+			break
+		}
+	}
+	if last < 0 {
+		return nil, nil, llama.ErrInvalidArgument.With("retrieval requires a user message")
+	}
+
+	// The embedding model must be the model of the collection
+	if retrieval.Model != "" {
+		collection, err := l.collections.Get(retrieval.Collection)
+		if err != nil {
+			return nil, nil, err
+		}
+		if model := collection.Meta().Model; model != retrieval.Model {
+			return nil, nil, llama.ErrInvalidArgument.Withf("collection %q uses model %q", retrieval.Collection, model)
+		}
+	}
+
+	// Search the collection
+	query := messages[last].Content
+	result, err := l.SearchCollection(ctx, retrieval.Collection, schema.CollectionSearchRequest{
+		Query:    query,
+		TopK:     topK,
+		Filter:   retrieval.Filter,
+		MinScore: retrieval.MinScore,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(result.Results) == 0 {
+		return messages, []schema.ChatSource{}, nil
+	}
+
+	// Add the documents to the message, with citation markers
+	content, sources, err := retrievalContent(tmpl, query, result.Results)
+	if err != nil {
+		return nil, nil, err
+	}
+	messages = slices.Clone(messages)
+	messages[last].Content = content
+	return messages, sources, nil
+}
+
+// retrievalContent returns the user message with the documents, numbered
+// from one, and the documents as sources
+func retrievalContent(tmpl *template.Template, query string, docs []schema.CollectionResult) (string, []schema.ChatSource, error) {
+	data := retrievalData{Query: query}
+	sources := make([]schema.ChatSource, len(docs))
+	for i, doc := range docs {
+		data.Documents = append(data.Documents, retrievalDocument{
+			Marker:   i + 1,
+			ID:       doc.ID,
+			Text:     doc.Text,
+			Metadata: doc.Metadata,
+			Score:    doc.Score,
+		})
+		sources[i] = schema.ChatSource{
+			Marker:   i + 1,
+			ID:       doc.ID,
+			Score:    doc.Score,
+			Metadata: doc.Metadata,
+		}
+	}
+	var content strings.Builder
+	if err := tmpl.Execute(&content, data); err != nil {
+		return "", nil, llama.ErrInvalidArgument.Withf("retrieval template: %v", err)
+	}
+	return content.String(), sources, nil
+}
+
+// citations returns the identifiers of the sources cited by their markers in
+// a message, in order of first citation
+func citations(text string, sources []schema.ChatSource) []string {
+	var result []string
+	for _, match := range reCitation.FindAllStringSubmatch(text, -1) {
+		for marker := range strings.SplitSeq(match[1], ",") {
+			n, err := strconv.Atoi(strings.TrimSpace(marker))
+			if err != nil || n < 1 || n > len(sources) {
+				continue
+			}
+			if id := sources[n-1].ID; !slices.Contains(result, id) {
+				result = append(result, id)
+			}
+		}
+	}
+	return result
+}
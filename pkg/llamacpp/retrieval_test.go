@@ -0,0 +1,49 @@
+package llamacpp
+
+import (
+	"testing"
+	"text/template"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mutablelogic/go-llama/pkg/llamacpp/schema"
+)
+
+func TestRetrievalContent(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	docs := []schema.CollectionResult{
+		{CollectionDocument: schema.CollectionDocument{ID: "panda", Text: "The giant panda is a bear."}, Score: 0.9},
+		{CollectionDocument: schema.CollectionDocument{ID: "koala", Text: "The koala is a marsupial.", Metadata: map[string]any{"topic": "animals"}}, Score: 0.5},
+	}
+
+	// The default template numbers the documents, then asks the question
+	tmpl := template.Must(template.New("retrieval").Parse(schema.DefaultRetrievalTemplate))
+	content, sources, err := retrievalContent(tmpl, "What is a panda?", docs)
+	require.NoError(err)
+	assert.Contains(content, "[1] The giant panda is a bear.\n\n[2] The koala is a marsupial.\n\nQuestion: What is a panda?")
+	assert.Equal([]schema.ChatSource{
+		{Marker: 1, ID: "panda", Score: 0.9},
+		{Marker: 2, ID: "koala", Score: 0.5, Metadata: map[string]any{"topic": "animals"}},
+	}, sources)
+
+	// Templates can use the identifiers and metadata of the documents
+	tmpl = template.Must(template.New("retrieval").Option("missingkey=error").Parse(`{{ range .Documents }}{{ .ID }}:{{ .Metadata.topic }};{{ end }}`))
+	_, _, err = retrievalContent(tmpl, "What is a panda?", docs[1:])
+	require.NoError(err)
+	_, _, err = retrievalContent(tmpl, "What is a panda?", docs)
+	assert.Error(err)
+}
+
+func TestCitations(t *testing.T) {
+	assert := assert.New(t)
+
+	sources := []schema.ChatSource{{Marker: 1, ID: "a"}, {Marker: 2, ID: "b"}, {Marker: 3, ID: "c"}}
+	assert.Nil(citations("No sources.", sources))
+	assert.Equal([]string{"b"}, citations("Pandas are bears [2].", sources))
+	assert.Equal([]string{"c", "a"}, citations("Yes [3]. Also [1, 3] and [3,1].", sources))
+	assert.Equal([]string{"a"}, citations("See [1], not [4] or [0] or [x].", sources))
+	assert.Nil(citations("Pandas are bears [1].", nil))
+}
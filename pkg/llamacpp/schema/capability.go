@@ -0,0 +1,138 @@
+package schema
+
+import (
+	"slices"
+	"strings"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// Capability is something a model can be used for, detected from the
+// metadata in its GGUF file.
+type Capability string
+
+///////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	CapCompletion Capability = "completion" // Text completion, for a model with a causal decoder
+	CapChat       Capability = "chat"       // Chat, for a completion model with a chat template
+	CapTools      Capability = "tools"      // Tool calls, for a chat template which accepts tools
+	CapThinking   Capability = "thinking"   // Reasoning, for a chat template with thinking markers
+	CapInfill     Capability = "infill"     // Fill-in-the-middle, for a model with FIM tokens
+	CapEmbedding  Capability = "embedding"  // Embeddings, for a model with mean, CLS or last-token pooling
+	CapRerank     Capability = "rerank"     // Reranking, for a model with rank pooling
+)
+
+const (
+	// Pooling types in the <arch>.pooling_type metadata key
+	poolingNone = 0
+	poolingMean = 1
+	poolingCLS  = 2
+	poolingLast = 3
+	poolingRank = 4
+)
+
+var (
+	// encoderArchitectures have no causal decoder, so cannot generate text
+	encoderArchitectures = []string{
+		"bert", "nomic-bert", "nomic-bert-moe", "jina-bert-v2", "jina-bert-v3",
+		"modern-bert", "neo-bert", "t5encoder",
+	}
+
+	// fimTokenKeys are the metadata keys of the prefix, suffix and middle
+	// tokens of a fill-in-the-middle model, in the current and older forms
+	fimTokenKeys = [][]string{
+		{"tokenizer.ggml.fim_pre_token_id", "tokenizer.ggml.fim_suf_token_id", "tokenizer.ggml.fim_mid_token_id"},
+		{"tokenizer.ggml.prefix_token_id", "tokenizer.ggml.suffix_token_id", "tokenizer.ggml.middle_token_id"},
+	}
+
+	// thinkingIndicators are the strings in a chat template which suggest a
+	// reasoning model
+	thinkingIndicators = []string{
+		"<think>", "</think>", "<reasoning>", "</reasoning>", "<scratchpad>", "thinking", "reason",
+	}
+)
+
+const (
+	// toolUseTemplateKey is the metadata key of a separate chat template for
+	// tool use
+	toolUseTemplateKey = "tokenizer.chat_template.tool_use"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// HasCapability returns true if the model has the capability.
+func (m Model) HasCapability(capability Capability) bool {
+	return slices.Contains(m.Capabilities, capability)
+}
+
+// IsThinkingTemplate checks if a chat template string suggests it's a
+// reasoning template by looking for common thinking-related patterns
+func IsThinkingTemplate(template string) bool {
+	if template == "" {
+		return false
+	}
+	templateLower := strings.ToLower(template)
+	for _, indicator := range thinkingIndicators {
+		if strings.Contains(templateLower, indicator) {
+			return true
+		}
+	}
+	return false
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// capabilities returns the capabilities of a model from its architecture,
+// chat template and metadata
+func capabilities(arch, template string, meta map[string]any) []Capability {
+	result := []Capability{}
+
+	// Text generation needs a causal decoder
+	decoder := !slices.Contains(encoderArchitectures, arch)
+	if causal, ok := meta[arch+".attention.causal"].(bool); ok && !causal {
+		decoder = false
+	}
+	if decoder {
+		result = append(result, CapCompletion)
+		if template != "" {
+			result = append(result, CapChat)
+			if _, ok := meta[toolUseTemplateKey]; ok || strings.Contains(template, "tools") {
+				result = append(result, CapTools)
+			}
+			if IsThinkingTemplate(template) {
+				result = append(result, CapThinking)
+			}
+		}
+		for _, keys := range fimTokenKeys {
+			if hasKeys(meta, keys...) {
+				result = append(result, CapInfill)
+				break
+			}
+		}
+	}
+
+	// Embeddings and reranking depend on the pooling type
+	switch getInt32(meta, arch+".pooling_type") {
+	case poolingMean, poolingCLS, poolingLast:
+		result = append(result, CapEmbedding)
+	case poolingRank:
+		result = append(result, CapRerank)
+	}
+
+	return result
+}
+
+// hasKeys returns true if the metadata has all the keys
+func hasKeys(meta map[string]any, keys ...string) bool {
+	for _, key := range keys {
+		if _, ok := meta[key]; !ok {
+			return false
+		}
+	}
+	return true
+}
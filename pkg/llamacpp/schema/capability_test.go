@@ -0,0 +1,55 @@
+package schema
+
+import (
+	"testing"
+
+	// Packages
+	assert "github.com/stretchr/testify/assert"
+)
+
+func TestCapabilities(t *testing.T) {
+	assert := assert.New(t)
+
+	tests := []struct {
+		name     string
+		arch     string
+		template string
+		meta     map[string]any
+		want     []Capability
+	}{
+		{"base", "llama", "", map[string]any{}, []Capability{CapCompletion}},
+		{"chat", "llama", "{{ messages }}", map[string]any{}, []Capability{CapCompletion, CapChat}},
+		{"tools", "qwen2", "{% if tools %}{{ tools }}{% endif %}", map[string]any{}, []Capability{CapCompletion, CapChat, CapTools}},
+		{"tool use template", "llama", "{{ messages }}", map[string]any{toolUseTemplateKey: "{{ tools }}"}, []Capability{CapCompletion, CapChat, CapTools}},
+		{"thinking", "qwen3", "<think>\n{{ content }}</think>", map[string]any{}, []Capability{CapCompletion, CapChat, CapThinking}},
+		{"infill", "llama", "", map[string]any{
+			"tokenizer.ggml.fim_pre_token_id": uint32(1),
+			"tokenizer.ggml.fim_suf_token_id": uint32(2),
+			"tokenizer.ggml.fim_mid_token_id": uint32(3),
+		}, []Capability{CapCompletion, CapInfill}},
+		{"incomplete infill", "llama", "", map[string]any{
+			"tokenizer.ggml.prefix_token_id": uint32(1),
+		}, []Capability{CapCompletion}},
+		{"encoder", "bert", "", map[string]any{"bert.pooling_type": uint32(poolingMean)}, []Capability{CapEmbedding}},
+		{"non-causal", "gemma-embedding", "{{ messages }}", map[string]any{
+			"gemma-embedding.attention.causal": false,
+			"gemma-embedding.pooling_type":     uint32(poolingLast),
+		}, []Capability{CapEmbedding}},
+		{"decoder embedding", "qwen3", "", map[string]any{"qwen3.pooling_type": uint32(poolingLast)}, []Capability{CapCompletion, CapEmbedding}},
+		{"rerank", "bert", "", map[string]any{"bert.pooling_type": uint32(poolingRank)}, []Capability{CapRerank}},
+		{"no pooling", "bert", "", map[string]any{"bert.pooling_type": uint32(poolingNone)}, []Capability{}},
+	}
+	for _, test := range tests {
+		assert.Equal(test.want, capabilities(test.arch, test.template, test.meta), test.name)
+	}
+}
+
+func TestListModelsRequest_MatchCapability(t *testing.T) {
+	assert := assert.New(t)
+
+	model := &Model{Capabilities: []Capability{CapCompletion, CapChat}}
+	assert.True(ListModelsRequest{}.Match(model))
+	assert.True(ListModelsRequest{Capability: []string{"chat"}}.Match(model))
+	assert.True(ListModelsRequest{Capability: []string{"Completion", "chat"}}.Match(model))
+	assert.False(ListModelsRequest{Capability: []string{"chat", "embedding"}}.Match(model))
+}
@@ -13,7 +13,20 @@ type ChatMessage struct {
 // It embeds CompletionRequest to reuse sampling and model options.
 type ChatRequest struct {
 	CompletionRequest
-	Messages []ChatMessage `json:"messages"`
+	Messages  []ChatMessage  `json:"messages"`
+	Retrieval *ChatRetrieval `json:"retrieval,omitempty"` // Ground the response in the documents of a collection
+}
+
+// ChatRetrieval contains parameters for finding the documents of a collection
+// which are most similar to the last user message, which are added to the
+// message with citation markers.
+type ChatRetrieval struct {
+	Model      string         `json:"model,omitempty"`     // Embedding model, which must be the model of the collection (default: the model of the collection)
+	Collection string         `json:"collection"`          // Collection name
+	TopK       int            `json:"top_k,omitempty"`     // Number of documents (default: 4)
+	Filter     map[string]any `json:"filter,omitempty"`    // Metadata values which documents must have, as for a collection search
+	MinScore   float64        `json:"min_score,omitempty"` // Lowest similarity of a document
+	Template   string         `json:"template,omitempty"`  // Go template of the user message, with .Query and .Documents (default: DefaultRetrievalTemplate)
 }
 
 // ChatResponse contains the generated assistant message.
@@ -21,15 +34,42 @@ type ChatResponse struct {
 	Model        string       `json:"model"`                   // Model used
 	Thinking     *ChatMessage `json:"thinking,omitempty"`      // Optional reasoning message
 	Message      ChatMessage  `json:"message"`                 // Assistant message
+	Sources      []ChatSource `json:"sources,omitempty"`       // Documents added to the last user message, when retrieval is requested
+	Citations    []string     `json:"citations,omitempty"`     // Identifiers of the sources cited in the message, in order of first citation
 	Usage        Usage        `json:"usage"`                   // Token usage
 	FinishReason string       `json:"finish_reason,omitempty"` // Reason generation ended
 }
 
+// ChatSource is a document which was added to a chat, with its citation marker.
+type ChatSource struct {
+	Marker   int            `json:"marker"`             // Number of the citation marker, as in [1]
+	ID       string         `json:"id"`                 // Document identifier
+	Score    float64        `json:"score"`              // Cosine similarity to the last user message
+	Metadata map[string]any `json:"metadata,omitempty"` // Document metadata
+}
+
 // ChatChunk contains a streamed chat chunk.
 type ChatChunk struct {
 	Message ChatMessage `json:"message"`
 }
 
+///////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+const (
+	// DefaultRetrievalTopK is the number of documents added to a chat when
+	// not set
+	DefaultRetrievalTopK = 4
+
+	// DefaultRetrievalTemplate is the template of the last user message, when
+	// documents are added to a chat
+	DefaultRetrievalTemplate = `Answer using the numbered sources below. Cite each source you use by its number in square brackets, such as [1].
+
+{{ range .Documents }}[{{ .Marker }}] {{ .Text }}
+
+{{ end }}Question: {{ .Query }}`
+)
+
 ///////////////////////////////////////////////////////////////////////////////
 // STRINGIFY
 
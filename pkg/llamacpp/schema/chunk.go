@@ -0,0 +1,47 @@
+package schema
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// ChunkRequest contains parameters for splitting text into chunks of tokens.
+type ChunkRequest struct {
+	Model   string `json:"model"`             // Model name or path, whose tokenizer counts tokens
+	Text    string `json:"text"`              // Text to split
+	Size    int    `json:"size,omitempty"`    // Most tokens in a chunk (default: 512)
+	Overlap int    `json:"overlap,omitempty"` // Most tokens repeated from the end of the previous chunk
+}
+
+// ChunkResponse contains the chunks of a text, in order.
+type ChunkResponse struct {
+	Model  string      `json:"model"`  // Model used
+	Chunks []TextChunk `json:"chunks"` // Chunks of the text
+	Usage  Usage       `json:"usage"`  // Tokens in the text
+}
+
+// TextChunk is a span of a text, without leading and trailing whitespace.
+type TextChunk struct {
+	Index  int    `json:"index"`  // Index of the chunk
+	Start  int    `json:"start"`  // Start character offset of the chunk in the text
+	End    int    `json:"end"`    // End character offset of the chunk in the text
+	Tokens int    `json:"tokens"` // Tokens in the chunk, without special tokens
+	Text   string `json:"text"`   // Text of the chunk
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+const (
+	// DefaultChunkSize is the most tokens in a chunk, when not set
+	DefaultChunkSize = 512
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (r ChunkRequest) String() string {
+	return stringify(r)
+}
+
+func (r ChunkResponse) String() string {
+	return stringify(r)
+}
@@ -0,0 +1,115 @@
+package schema
+
+import "time"
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// CollectionIndex is how the documents of a collection are searched.
+type CollectionIndex string
+
+// Collection is a named set of documents with their embeddings, which are
+// computed with a single model.
+type Collection struct {
+	Name      string          `json:"name"`              // Collection name
+	Model     string          `json:"model"`             // Model which embeds documents and queries
+	Dimension int             `json:"dimension"`         // Embedding dimension
+	Index     CollectionIndex `json:"index"`             // Search index
+	Documents int             `json:"documents"`         // Number of documents
+	Created   time.Time       `json:"created"`           // When the collection was created
+	Modified  time.Time       `json:"modified,omitzero"` // When documents were last added
+}
+
+// CollectionDocument is a text with metadata, which is stored in a collection.
+type CollectionDocument struct {
+	ID       string         `json:"id,omitempty"`       // Document identifier (default: from the checksum of the text)
+	Text     string         `json:"text"`               // Text which is embedded
+	Metadata map[string]any `json:"metadata,omitempty"` // Metadata, which can be used to filter searches
+}
+
+// CollectionAddRequest contains documents to embed and store in a collection,
+// which is created if it does not exist.
+type CollectionAddRequest struct {
+	Model     string               `json:"model,omitempty"` // Model name, required when the collection is created
+	Index     CollectionIndex      `json:"index,omitempty"` // Search index, when the collection is created (default: flat)
+	Documents []CollectionDocument `json:"documents"`       // Documents, which replace documents with the same identifier
+}
+
+// CollectionAddResponse contains the identifiers of the stored documents.
+type CollectionAddResponse struct {
+	Collection Collection `json:"collection"` // Collection, after the documents are added
+	IDs        []string   `json:"ids"`        // Identifier of each document in the request
+	Usage      Usage      `json:"usage"`      // Token usage
+}
+
+// CollectionSearchRequest contains parameters for finding the documents in a
+// collection which are most similar to a query.
+type CollectionSearchRequest struct {
+	Query    string         `json:"query"`               // Text to search for
+	TopK     int            `json:"top_k,omitempty"`     // Number of results to return (default: 10)
+	Filter   map[string]any `json:"filter,omitempty"`    // Metadata values which documents must have, or lists of values of which they must have one
+	MinScore float64        `json:"min_score,omitempty"` // Lowest similarity of a result
+}
+
+// CollectionSearchResponse contains the most similar documents.
+type CollectionSearchResponse struct {
+	Collection string             `json:"collection"` // Collection name
+	Model      string             `json:"model"`      // Model used to embed the query
+	Results    []CollectionResult `json:"results"`    // Results, most similar first
+	Usage      Usage              `json:"usage"`      // Token usage
+}
+
+// CollectionResult is a document which is similar to a query.
+type CollectionResult struct {
+	CollectionDocument
+	Score float64 `json:"score"` // Cosine similarity to the query
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+const (
+	IndexFlat CollectionIndex = "flat" // Compare the query with every document
+	IndexHNSW CollectionIndex = "hnsw" // Approximate search of a hierarchical navigable small world graph
+)
+
+const (
+	// DefaultCollectionTopK is the number of search results when not set
+	DefaultCollectionTopK = 10
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Valid returns true if the index is empty or known.
+func (i CollectionIndex) Valid() bool {
+	switch i {
+	case "", IndexFlat, IndexHNSW:
+		return true
+	default:
+		return false
+	}
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (c Collection) String() string {
+	return stringify(c)
+}
+
+func (r CollectionAddRequest) String() string {
+	return stringify(r)
+}
+
+func (r CollectionAddResponse) String() string {
+	return stringify(r)
+}
+
+func (r CollectionSearchRequest) String() string {
+	return stringify(r)
+}
+
+func (r CollectionSearchResponse) String() string {
+	return stringify(r)
+}
@@ -1,21 +1,359 @@
 package schema
 
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
 ///////////////////////////////////////////////////////////////////////////////
 // TYPES
 
+// Pooling is how the token embeddings of a text are combined into one vector.
+type Pooling string
+
+// EmbeddingEncoding is how per-token embeddings are encoded in a response.
+type EmbeddingEncoding string
+
+// EmbeddingOverflow is what happens to an input with more tokens than the
+// model can embed at once.
+type EmbeddingOverflow string
+
+// EmbeddingAggregate is how the chunks of an input are combined.
+type EmbeddingAggregate string
+
 // EmbedRequest contains parameters for generating embeddings.
 type EmbedRequest struct {
-	Model     string   `json:"model"`               // Model name
-	Input     []string `json:"input"`               // Text(s) to embed
-	Normalize *bool    `json:"normalize,omitempty"` // L2-normalize embeddings (default: true)
+	Model        string             `json:"model"`                   // Model name
+	Input        []string           `json:"input"`                   // Text(s) to embed
+	Normalize    *bool              `json:"normalize,omitempty"`     // L2-normalize embeddings (default: true)
+	Pooling      Pooling            `json:"pooling,omitempty"`       // Pooling type (default: from model)
+	Dimensions   int                `json:"dimensions,omitempty"`    // Truncate embeddings to this many dimensions (default: all)
+	Encoding     EmbeddingEncoding  `json:"encoding,omitempty"`      // Encoding of embeddings (default: float32)
+	Overflow     EmbeddingOverflow  `json:"overflow,omitempty"`      // Handling of long inputs (default: error)
+	ChunkSize    int                `json:"chunk_size,omitempty"`    // Most tokens in a chunk (default: most the model can embed at once)
+	ChunkOverlap int                `json:"chunk_overlap,omitempty"` // Tokens repeated between chunks
+	Aggregate    EmbeddingAggregate `json:"aggregate,omitempty"`     // Combining of chunks (default: mean)
 }
 
 // EmbedResponse contains the generated embeddings.
 type EmbedResponse struct {
-	Model      string      `json:"model"`      // Model used
-	Embeddings [][]float32 `json:"embeddings"` // One embedding vector per input
-	Dimension  int         `json:"dimension"`  // Embedding dimension
-	Usage      Usage       `json:"usage"`      // Token usage
+	Model      string            `json:"model"`              // Model used
+	Embeddings [][]float32       `json:"embeddings"`         // One embedding vector per input, when the encoding is float32
+	Encoding   EmbeddingEncoding `json:"encoding,omitempty"` // Encoding of the data, or float32 for embeddings
+	Data       []string          `json:"data,omitempty"`     // One packed embedding per input, for other encodings
+	Scales     []float32         `json:"scales,omitempty"`   // Scale of each packed embedding, for int8 and binary encodings
+	Tokens     []TokenEmbeddings `json:"tokens,omitempty"`   // Per-token embeddings for each input, when pooling is none
+	Chunks     []EmbeddingChunk  `json:"chunks,omitempty"`   // Embedding of each chunk, when chunks are not aggregated
+	Dimension  int               `json:"dimension"`          // Embedding dimension
+	Usage      Usage             `json:"usage"`              // Token usage
+}
+
+// EmbeddingChunk is the embedding of a span of an input.
+type EmbeddingChunk struct {
+	Index     int       `json:"index"`               // Index of the input
+	Start     int       `json:"start"`               // Start character offset of the span in the input
+	End       int       `json:"end"`                 // End character offset of the span in the input
+	Tokens    int       `json:"tokens"`              // Tokens embedded, including special tokens
+	Embedding []float32 `json:"embedding,omitempty"` // Embedding of the span, when the encoding is float32
+	Data      string    `json:"data,omitempty"`      // Packed embedding of the span, for other encodings
+	Scale     float32   `json:"scale,omitempty"`     // Scale of the packed embedding, for int8 and binary encodings
+}
+
+// TokenEmbeddings contains the embedding of each token of an input, with
+// the vectors packed into base64 in the encoding.
+type TokenEmbeddings struct {
+	Tokens   []int32           `json:"tokens"`           // Token IDs
+	Offsets  [][2]int          `json:"offsets"`          // Start and end byte offsets of each token in the input
+	Encoding EmbeddingEncoding `json:"encoding"`         // Encoding of the vectors
+	Data     string            `json:"data"`             // One vector per token, in token order
+	Scales   []float32         `json:"scales,omitempty"` // Scale of each vector, for int8 and binary encodings
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+const (
+	PoolingDefault Pooling = "default" // Pooling type from the model
+	PoolingNone    Pooling = "none"    // No pooling, returns an embedding for each token
+	PoolingMean    Pooling = "mean"    // Mean of the token embeddings
+	PoolingCLS     Pooling = "cls"     // Embedding of the first token
+	PoolingLast    Pooling = "last"    // Embedding of the last token
+)
+
+const (
+	EncodingFloat32 EmbeddingEncoding = "float32" // Numbers, or four bytes per value when packed
+	EncodingBase64  EmbeddingEncoding = "base64"  // Four bytes per value
+	EncodingFloat16 EmbeddingEncoding = "float16" // Two bytes per value, IEEE 754 half precision
+	EncodingInt8    EmbeddingEncoding = "int8"    // One signed byte per value, times the scale of the vector
+	EncodingBinary  EmbeddingEncoding = "binary"  // One bit per value, for the sign, times the scale of the vector
+)
+
+const (
+	OverflowError    EmbeddingOverflow = "error"    // Refuse inputs which are too long
+	OverflowTruncate EmbeddingOverflow = "truncate" // Embed the start of the input
+	OverflowChunk    EmbeddingOverflow = "chunk"    // Embed the input in overlapping chunks
+)
+
+const (
+	AggregateMean EmbeddingAggregate = "mean" // Mean of the chunks, weighted by their tokens
+	AggregateNone EmbeddingAggregate = "none" // One embedding per chunk
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Valid returns true if the pooling type is empty or known.
+func (p Pooling) Valid() bool {
+	switch p {
+	case "", PoolingDefault, PoolingNone, PoolingMean, PoolingCLS, PoolingLast:
+		return true
+	default:
+		return false
+	}
+}
+
+// Valid returns true if the encoding is empty or known.
+func (e EmbeddingEncoding) Valid() bool {
+	switch e {
+	case "", EncodingFloat32, EncodingBase64, EncodingFloat16, EncodingInt8, EncodingBinary:
+		return true
+	default:
+		return false
+	}
+}
+
+// Valid returns true if the overflow strategy is empty or known.
+func (o EmbeddingOverflow) Valid() bool {
+	switch o {
+	case "", OverflowError, OverflowTruncate, OverflowChunk:
+		return true
+	default:
+		return false
+	}
+}
+
+// Valid returns true if the aggregation is empty or known.
+func (a EmbeddingAggregate) Valid() bool {
+	switch a {
+	case "", AggregateMean, AggregateNone:
+		return true
+	default:
+		return false
+	}
+}
+
+// EncodeEmbeddings packs vectors into a base64 string with the encoding,
+// which is float32 when empty. For the int8 and binary encodings, the scale
+// of each vector is also returned, for dequantizing.
+func EncodeEmbeddings(vectors [][]float32, encoding EmbeddingEncoding) (string, []float32, error) {
+	var data []byte
+	var scales []float32
+	switch encoding {
+	case "", EncodingFloat32, EncodingBase64:
+		for _, vector := range vectors {
+			for _, v := range vector {
+				data = binary.LittleEndian.AppendUint32(data, math.Float32bits(v))
+			}
+		}
+	case EncodingFloat16:
+		for _, vector := range vectors {
+			for _, v := range vector {
+				data = binary.LittleEndian.AppendUint16(data, float32ToFloat16(v))
+			}
+		}
+	case EncodingInt8:
+		// Symmetric quantization, so each value is the integer times the scale
+		scales = make([]float32, len(vectors))
+		for i, vector := range vectors {
+			var maxAbs float64
+			for _, v := range vector {
+				maxAbs = max(maxAbs, math.Abs(float64(v)))
+			}
+			scales[i] = float32(maxAbs / 127)
+			for _, v := range vector {
+				var q float64
+				if maxAbs > 0 {
+					q = math.Round(float64(v) * 127 / maxAbs)
+				}
+				data = append(data, byte(int8(max(min(q, 127), -127))))
+			}
+		}
+	case EncodingBinary:
+		// One bit per value, set when positive, with the most significant bit
+		// first, and the scale is the mean magnitude
+		scales = make([]float32, len(vectors))
+		for i, vector := range vectors {
+			var sum float64
+			bits := make([]byte, (len(vector)+7)/8)
+			for j, v := range vector {
+				sum += math.Abs(float64(v))
+				if v > 0 {
+					bits[j/8] |= 0x80 >> (j % 8)
+				}
+			}
+			if len(vector) > 0 {
+				scales[i] = float32(sum / float64(len(vector)))
+			}
+			data = append(data, bits...)
+		}
+	default:
+		return "", nil, fmt.Errorf("invalid encoding %q", encoding)
+	}
+	return base64.StdEncoding.EncodeToString(data), scales, nil
+}
+
+// DecodeEmbeddings unpacks n vectors of a dimension from a base64 string,
+// using the scale of each vector for the int8 and binary encodings.
+func DecodeEmbeddings(data string, encoding EmbeddingEncoding, scales []float32, n, dimension int) ([][]float32, error) {
+	bytes, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return nil, err
+	}
+
+	// Check the length of the data, and the scales
+	var size int
+	switch encoding {
+	case "", EncodingFloat32, EncodingBase64:
+		size = dimension * 4
+	case EncodingFloat16:
+		size = dimension * 2
+	case EncodingInt8:
+		size = dimension
+	case EncodingBinary:
+		size = (dimension + 7) / 8
+	default:
+		return nil, fmt.Errorf("invalid encoding %q", encoding)
+	}
+	if len(bytes) != n*size {
+		return nil, fmt.Errorf("%d bytes cannot be split into %d vectors of dimension %d", len(bytes), n, dimension)
+	}
+	if (encoding == EncodingInt8 || encoding == EncodingBinary) && len(scales) != n {
+		return nil, fmt.Errorf("expected %d scales, got %d", n, len(scales))
+	}
+
+	// Decode each vector
+	vectors := make([][]float32, n)
+	for i := range vectors {
+		vector, b := make([]float32, dimension), bytes[i*size:(i+1)*size]
+		for j := range vector {
+			switch encoding {
+			case EncodingFloat16:
+				vector[j] = float16ToFloat32(binary.LittleEndian.Uint16(b[j*2:]))
+			case EncodingInt8:
+				vector[j] = float32(int8(b[j])) * scales[i]
+			case EncodingBinary:
+				if b[j/8]&(0x80>>(j%8)) != 0 {
+					vector[j] = scales[i]
+				} else {
+					vector[j] = -scales[i]
+				}
+			default:
+				vector[j] = math.Float32frombits(binary.LittleEndian.Uint32(b[j*4:]))
+			}
+		}
+		vectors[i] = vector
+	}
+	return vectors, nil
+}
+
+// Vectors returns the embedding of each input, decoding them if they are
+// encoded.
+func (r EmbedResponse) Vectors() ([][]float32, error) {
+	if r.Data == nil {
+		return r.Embeddings, nil
+	}
+	vectors := make([][]float32, len(r.Data))
+	for i, data := range r.Data {
+		var scales []float32
+		if r.Scales != nil {
+			if i >= len(r.Scales) {
+				return nil, fmt.Errorf("missing scale for input %d", i)
+			}
+			scales = r.Scales[i : i+1]
+		}
+		vector, err := DecodeEmbeddings(data, r.Encoding, scales, 1, r.Dimension)
+		if err != nil {
+			return nil, err
+		}
+		vectors[i] = vector[0]
+	}
+	return vectors, nil
+}
+
+// Vectors decodes the embedding of each token, which has the dimension in
+// the response.
+func (t TokenEmbeddings) Vectors(dimension int) ([][]float32, error) {
+	return DecodeEmbeddings(t.Data, t.Encoding, t.Scales, len(t.Tokens), dimension)
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// float32ToFloat16 converts to IEEE 754 half precision, rounding to nearest
+// even, with overflow to infinity
+func float32ToFloat16(f float32) uint16 {
+	bits := math.Float32bits(f)
+	sign := uint16(bits>>16) & 0x8000
+	exp := int(bits>>23) & 0xff
+	mant := bits & 0x7fffff
+
+	// Infinity and NaN
+	if exp == 0xff {
+		if mant != 0 {
+			return sign | 0x7e00
+		}
+		return sign | 0x7c00
+	}
+
+	// Rebias the exponent
+	e := exp - 127 + 15
+	switch {
+	case e >= 0x1f:
+		return sign | 0x7c00
+	case e <= 0:
+		// Subnormal, or too small for half precision
+		if e < -10 {
+			return sign
+		}
+		mant |= 0x800000
+		shift := uint(14 - e)
+		h := mant >> shift
+		rem, half := mant&(1<<shift-1), uint32(1)<<(shift-1)
+		if rem > half || (rem == half && h&1 == 1) {
+			h++
+		}
+		return sign | uint16(h)
+	default:
+		// A carry from rounding moves into the exponent
+		h := uint32(e)<<10 | mant>>13
+		rem := mant & 0x1fff
+		if rem > 0x1000 || (rem == 0x1000 && h&1 == 1) {
+			h++
+		}
+		return sign | uint16(h)
+	}
+}
+
+// float16ToFloat32 converts from IEEE 754 half precision
+func float16ToFloat32(h uint16) float32 {
+	sign := uint32(h&0x8000) << 16
+	exp := uint32(h>>10) & 0x1f
+	mant := uint32(h & 0x3ff)
+	switch exp {
+	case 0:
+		// Zero and subnormals, which are mant x 2^-24
+		f := float32(mant) / (1 << 24)
+		if sign != 0 {
+			return -f
+		}
+		return f
+	case 0x1f:
+		return math.Float32frombits(sign | 0x7f800000 | mant<<13)
+	default:
+		return math.Float32frombits(sign | (exp-15+127)<<23 | mant<<13)
+	}
 }
 
 ///////////////////////////////////////////////////////////////////////////////
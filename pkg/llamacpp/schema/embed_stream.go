@@ -0,0 +1,57 @@
+package schema
+
+import "encoding/json"
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// EmbedStreamRequest contains parameters for embedding a stream of rows. It
+// is read from the query of a request, which has one JSON object per line in
+// its body.
+type EmbedStreamRequest struct {
+	Model        string            `json:"model"`                   // Model name
+	Field        string            `json:"field,omitempty"`         // Field of each row with the text to embed (default: text)
+	IDField      string            `json:"id_field,omitempty"`      // Field of each row with its identifier (default: id)
+	Normalize    *bool             `json:"normalize,omitempty"`     // L2-normalize embeddings (default: true)
+	Pooling      Pooling           `json:"pooling,omitempty"`       // Pooling type (default: from model), which cannot be none
+	Dimensions   int               `json:"dimensions,omitempty"`    // Truncate embeddings to this many dimensions (default: all)
+	Encoding     EmbeddingEncoding `json:"encoding,omitempty"`      // Encoding of embeddings (default: float32)
+	Overflow     EmbeddingOverflow `json:"overflow,omitempty"`      // Handling of long texts (default: error)
+	ChunkSize    int               `json:"chunk_size,omitempty"`    // Most tokens in a chunk (default: most the model can embed at once)
+	ChunkOverlap int               `json:"chunk_overlap,omitempty"` // Tokens repeated between chunks
+}
+
+// EmbedStreamRow is the embedding of a row, or the reason it was not
+// embedded. Rows are returned in the order they are read, one JSON object
+// per line. A row without a line is an error which ends the stream.
+type EmbedStreamRow struct {
+	Line      int             `json:"line,omitempty"`      // Line of the row in the request, from one
+	ID        json.RawMessage `json:"id,omitempty"`        // Identifier of the row, as it was read
+	Embedding []float32       `json:"embedding,omitempty"` // Embedding of the text, when the encoding is float32
+	Data      string          `json:"data,omitempty"`      // Packed embedding of the text, for other encodings
+	Scale     float32         `json:"scale,omitempty"`     // Scale of the packed embedding, for int8 and binary encodings
+	Tokens    int             `json:"tokens,omitempty"`    // Tokens in the text, including special tokens
+	Error     string          `json:"error,omitempty"`     // Reason the row was not embedded
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+const (
+	// DefaultEmbedStreamField is the field of each row with the text to embed
+	DefaultEmbedStreamField = "text"
+
+	// DefaultEmbedStreamIDField is the field of each row with its identifier
+	DefaultEmbedStreamIDField = "id"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (r EmbedStreamRequest) String() string {
+	return stringify(r)
+}
+
+func (r EmbedStreamRow) String() string {
+	return stringify(r)
+}
@@ -0,0 +1,134 @@
+package schema
+
+import (
+	"encoding/base64"
+	"math"
+	"testing"
+
+	// Packages
+	assert "github.com/stretchr/testify/assert"
+	require "github.com/stretchr/testify/require"
+)
+
+func TestFloat16(t *testing.T) {
+	assert := assert.New(t)
+
+	for f, h := range map[float32]uint16{
+		0:            0x0000,
+		1:            0x3c00,
+		-2:           0xc000,
+		0.5:          0x3800,
+		65504:        0x7bff, // Largest half
+		1e6:          0x7c00, // Overflows to infinity
+		6.1035156e-5: 0x0400, // Smallest normal half
+		5.9604645e-8: 0x0001, // Smallest subnormal half
+		1e-9:         0x0000, // Underflows to zero
+		1.0009766:    0x3c01,
+		1.00048828:   0x3c00, // Halfway, rounds to even
+	} {
+		assert.Equal(h, float32ToFloat16(f), f)
+	}
+	assert.Equal(uint16(0x7c00), float32ToFloat16(float32(math.Inf(1))))
+	assert.True(math.IsNaN(float64(float16ToFloat32(float32ToFloat16(float32(math.NaN()))))))
+
+	// Values which are exact in half precision survive a round trip
+	for _, f := range []float32{0, 1, -2, 0.5, 65504, 6.1035156e-5, 5.9604645e-8, -0.333251953125} {
+		assert.Equal(f, float16ToFloat32(float32ToFloat16(f)), f)
+	}
+}
+
+func TestEncodeEmbeddings(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	// Floats survive a round trip
+	vectors := [][]float32{{0.25, -1, 3}, {0, 0.5, -0.125}}
+	for _, encoding := range []EmbeddingEncoding{"", EncodingFloat32, EncodingBase64, EncodingFloat16} {
+		data, scales, err := EncodeEmbeddings(vectors, encoding)
+		require.NoError(err, encoding)
+		assert.Nil(scales, encoding)
+		tokens := TokenEmbeddings{Tokens: []int32{1, 2}, Encoding: encoding, Data: data}
+		decoded, err := tokens.Vectors(3)
+		require.NoError(err, encoding)
+		assert.Equal(vectors, decoded, encoding)
+	}
+
+	// Each encoding has a smaller size
+	for encoding, size := range map[EmbeddingEncoding]int{EncodingFloat32: 24, EncodingFloat16: 12, EncodingInt8: 6, EncodingBinary: 2} {
+		data, _, err := EncodeEmbeddings(vectors, encoding)
+		require.NoError(err, encoding)
+		bytes, err := base64.StdEncoding.DecodeString(data)
+		require.NoError(err, encoding)
+		assert.Len(bytes, size, encoding)
+	}
+
+	// Quantized values are scaled by the largest magnitude
+	data, scales, err := EncodeEmbeddings(vectors, EncodingInt8)
+	require.NoError(err)
+	assert.Equal([]float32{3.0 / 127, 0.5 / 127}, scales)
+	decoded, err := DecodeEmbeddings(data, EncodingInt8, scales, 2, 3)
+	require.NoError(err)
+	for i := range vectors {
+		assert.InDeltaSlice(vectors[i], decoded[i], float64(scales[i]/2))
+	}
+
+	// Binary values keep the sign, and are scaled by the mean magnitude
+	data, scales, err = EncodeEmbeddings([][]float32{{1, -1, 2, 0, 1, 1, 1, 1, -1}}, EncodingBinary)
+	require.NoError(err)
+	assert.Equal([]float32{1}, scales)
+	bytes, _ := base64.StdEncoding.DecodeString(data)
+	assert.Equal([]byte{0xaf, 0x00}, bytes)
+	decoded, err = DecodeEmbeddings(data, EncodingBinary, scales, 1, 9)
+	require.NoError(err)
+	assert.Equal([][]float32{{1, -1, 1, -1, 1, 1, 1, 1, -1}}, decoded)
+
+	// Zero vectors have a zero scale
+	_, scales, err = EncodeEmbeddings([][]float32{{0, 0}}, EncodingInt8)
+	require.NoError(err)
+	assert.Equal([]float32{0}, scales)
+
+	_, _, err = EncodeEmbeddings(vectors, "int4")
+	assert.Error(err)
+	data, _, _ = EncodeEmbeddings(vectors, EncodingFloat32)
+	_, err = TokenEmbeddings{Tokens: []int32{1, 2, 3, 4}, Encoding: EncodingFloat32, Data: data}.Vectors(3)
+	assert.Error(err)
+	_, err = DecodeEmbeddings(data, EncodingInt8, nil, 1, 24)
+	assert.Error(err)
+}
+
+func TestEmbedResponse_Vectors(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	vectors := [][]float32{{0.25, -1}, {0, 0.5}}
+	response := EmbedResponse{Embeddings: vectors, Dimension: 2}
+	decoded, err := response.Vectors()
+	require.NoError(err)
+	assert.Equal(vectors, decoded)
+
+	// Packed embeddings are decoded
+	response = EmbedResponse{Encoding: EncodingInt8, Dimension: 2}
+	for _, vector := range vectors {
+		data, scales, err := EncodeEmbeddings([][]float32{vector}, EncodingInt8)
+		require.NoError(err)
+		response.Data = append(response.Data, data)
+		response.Scales = append(response.Scales, scales...)
+	}
+	decoded, err = response.Vectors()
+	require.NoError(err)
+	require.Len(decoded, 2)
+	for i := range vectors {
+		assert.InDeltaSlice(vectors[i], decoded[i], 0.01)
+	}
+}
+
+func TestPoolingValid(t *testing.T) {
+	assert := assert.New(t)
+	for _, p := range []Pooling{"", PoolingDefault, PoolingNone, PoolingMean, PoolingCLS, PoolingLast} {
+		assert.True(p.Valid(), p)
+	}
+	assert.False(Pooling("rank").Valid())
+	assert.True(EmbeddingEncoding("").Valid())
+	assert.True(EmbeddingEncoding("base64").Valid())
+	assert.False(EmbeddingEncoding("int4").Valid())
+}
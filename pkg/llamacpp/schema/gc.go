@@ -0,0 +1,52 @@
+package schema
+
+import (
+	"time"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// GCRequest contains the parameters for garbage collection of the store.
+type GCRequest struct {
+	DryRun bool `json:"dry_run,omitempty"` // List the files which would be removed, without removing them
+}
+
+// GCReason is the reason a file is removed by garbage collection.
+type GCReason string
+
+// GCFile is a file or model removed by garbage collection.
+type GCFile struct {
+	Path       string    `json:"path"`
+	Reason     GCReason  `json:"reason"`
+	Size       uint64    `json:"size"`
+	LastLoaded time.Time `json:"last_loaded,omitzero"` // For an evicted model, the time it was last loaded
+}
+
+// GCResponse is the result of garbage collection of the store.
+type GCResponse struct {
+	DryRun  bool      `json:"dry_run,omitempty"`
+	Removed []*GCFile `json:"removed"`
+	Freed   uint64    `json:"freed"`           // Bytes removed, or which would be removed
+	Usage   uint64    `json:"usage"`           // Bytes used by models in the store after garbage collection
+	Quota   uint64    `json:"quota,omitempty"` // Maximum bytes used by the store, or zero for no quota
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+const (
+	GCTemp    GCReason = "temp"    // A temporary file left by an interrupted pull, import or upload
+	GCEvicted GCReason = "evicted" // A model evicted because the store is over quota
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (r GCRequest) String() string {
+	return stringify(r)
+}
+
+func (r GCResponse) String() string {
+	return stringify(r)
+}
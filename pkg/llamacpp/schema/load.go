@@ -19,9 +19,29 @@ type LoadModelRequest struct {
 
 // PullModelRequest contains the parameters for downloading a model from a URL.
 type PullModelRequest struct {
-	URL string `json:"url"` // URL to download the model from (supports hf:// and https://)
+	URL   string `json:"url"`             // URL to download the model from (supports hf://, https://, ollama:// and oci://)
+	Token string `json:"token,omitempty"` // Hugging Face token for gated and private repositories
 }
 
+// ImportModelRequest contains the parameters for importing a model file on
+// the server into the store.
+type ImportModelRequest struct {
+	Path string     `json:"path"`           // Path of the GGUF file, or the first file of a split model
+	Dest string     `json:"dest,omitempty"` // Path of the model relative to the store (default is the filename)
+	Mode ImportMode `json:"mode,omitempty"` // How the file is imported (default is copy)
+}
+
+// UpdateModelRequest contains the parameters for updating a model in the
+// store. Fields which are nil are not changed.
+type UpdateModelRequest struct {
+	Pinned *bool     `json:"pinned,omitempty"` // Pin (true) or unpin (false) the model, so it is not evicted when the store is over quota
+	Labels *[]string `json:"labels,omitempty"` // Replace the labels of the model
+	Notes  *string   `json:"notes,omitempty"`  // Replace the notes of the model
+}
+
+// ImportMode is how a model file is imported into the store.
+type ImportMode string
+
 // CachedModel represents a model loaded in memory.
 // For server builds, it embeds ServerModel which provides the Handle and RWMutex.
 type CachedModel struct {
@@ -40,10 +60,20 @@ type ContextRequest struct {
 	Threads       *int32  `json:"threads,omitempty"`        // Number of threads (nil = default)
 	AttentionType *int32  `json:"attention_type,omitempty"` // Attention type: -1=auto, 0=causal, 1=non-causal (nil = auto)
 	FlashAttn     *int32  `json:"flash_attn,omitempty"`     // Flash attention: -1=auto, 0=disabled, 1=enabled (nil = auto)
+	PoolingType   *int32  `json:"pooling_type,omitempty"`   // Pooling: -1=model, 0=none, 1=mean, 2=cls, 3=last, 4=rank (nil = model)
 	Embeddings    *bool   `json:"embeddings,omitempty"`     // Enable embeddings extraction (nil = false)
 	KVUnified     *bool   `json:"kv_unified,omitempty"`     // Use unified KV cache (nil = default, required for BERT)
 }
 
+///////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+const (
+	ImportCopy     ImportMode = "copy"
+	ImportHardlink ImportMode = "hardlink"
+	ImportSymlink  ImportMode = "symlink"
+)
+
 ///////////////////////////////////////////////////////////////////////////////
 // STRINGIFY
 
@@ -52,6 +82,18 @@ func (r LoadModelRequest) String() string {
 }
 
 func (r PullModelRequest) String() string {
+	// Never include the token
+	if r.Token != "" {
+		r.Token = "********"
+	}
+	return stringify(r)
+}
+
+func (r ImportModelRequest) String() string {
+	return stringify(r)
+}
+
+func (r UpdateModelRequest) String() string {
 	return stringify(r)
 }
 
@@ -1,5 +1,11 @@
 package schema
 
+import (
+	"slices"
+	"strings"
+	"time"
+)
+
 ///////////////////////////////////////////////////////////////////////////////
 // TYPES
 
@@ -11,6 +17,24 @@ type Model struct {
 	Architecture string `json:"architecture,omitempty"`
 	Description  string `json:"description,omitempty"`
 
+	// What the model can be used for, detected from its metadata
+	Capabilities []Capability `json:"capabilities,omitempty"`
+
+	// Files
+	Size   uint64 `json:"size,omitempty"`   // Size of the model files in bytes
+	Shards int    `json:"shards,omitempty"` // Number of files, for a model split across files
+
+	// Store
+	Root     string `json:"root,omitempty"`     // Directory of the store which contains the model
+	ReadOnly bool   `json:"readonly,omitempty"` // Model is in a read-only store, and cannot be deleted
+
+	// Usage
+	Pinned     bool      `json:"pinned,omitempty"`    // Model is never evicted when the store is over quota
+	LastLoaded time.Time `json:"lastLoaded,omitzero"` // Time the model was last loaded into memory
+
+	// Labels, notes and provenance, from the sidecar file of the model
+	ModelMeta
+
 	// Chat template
 	ChatTemplate string `json:"chatTemplate,omitempty"`
 
@@ -23,6 +47,88 @@ type Model struct {
 
 	// Raw metadata key/value pairs from the model
 	Meta map[string]any `json:"meta,omitempty"`
+
+	// Tensor layout, only populated when requested
+	Tensors *ModelTensors `json:"tensors,omitempty"`
+}
+
+// ModelMeta is the metadata for a model which is not read from the model
+// file, and is kept in a sidecar file next to it in the store.
+type ModelMeta struct {
+	Labels   []string  `json:"labels,omitempty"`  // User labels, such as "approved-prod" or "embedding"
+	Notes    string    `json:"notes,omitempty"`   // User notes
+	Source   string    `json:"source,omitempty"`  // URL the model was pulled or imported from
+	PulledAt time.Time `json:"pulledAt,omitzero"` // Time the model was pulled or imported
+	SHA256   string    `json:"sha256,omitempty"`  // Checksum of the model file, for a model in a single file
+
+	// Chat template, system prompt and parameters from the registry the
+	// model was pulled from
+	Preset *ModelPreset `json:"preset,omitempty"`
+}
+
+// ModelPreset is the chat template, system prompt and parameters for a model
+// pulled from an Ollama or OCI registry, from the layers of its manifest.
+type ModelPreset struct {
+	Template string         `json:"template,omitempty"` // Chat template, in Go template syntax
+	System   string         `json:"system,omitempty"`   // System prompt
+	Params   map[string]any `json:"params,omitempty"`   // Parameters, such as "temperature" and "stop"
+}
+
+// ListModelsRequest contains the query parameters for filtering the models
+// in the store. Models match all of the filters which are set.
+type ListModelsRequest struct {
+	Label      []string `json:"label,omitempty"`      // Models with all of these labels
+	Arch       string   `json:"arch,omitempty"`       // Models with this architecture, e.g. "llama"
+	MinSize    uint64   `json:"min_size,omitempty"`   // Models of at least this size in bytes
+	MaxSize    uint64   `json:"max_size,omitempty"`   // Models of at most this size in bytes
+	Capability []string `json:"capability,omitempty"` // Models with all of these capabilities, e.g. "embedding"
+}
+
+// GetModelRequest contains the query parameters for retrieving a model.
+type GetModelRequest struct {
+	Tensors bool `json:"tensors,omitempty"` // Include the tensor layout
+}
+
+// RemoteModelRequest contains the query parameters for listing the models in a remote repository.
+type RemoteModelRequest struct {
+	Repo string `json:"repo"` // Hugging Face repository as "org/repo", with optional "@branch"
+}
+
+// RemoteModel describes a GGUF model in a Hugging Face repository. A model
+// split across files is a single entry.
+type RemoteModel struct {
+	Path   string `json:"path"`             // Path in the repository, or of the first file of a split model
+	URL    string `json:"url"`              // URL to pull the model
+	Quant  string `json:"quant,omitempty"`  // Quantization type from the filename, e.g. "Q4_K_M"
+	Size   uint64 `json:"size"`             // Size of the model files in bytes
+	Shards int    `json:"shards,omitempty"` // Number of files, for a model split across files
+}
+
+// ModelTensors describes the tensors stored in a model file.
+type ModelTensors struct {
+	Count   int               `json:"count"`             // Number of tensors
+	Params  uint64            `json:"params"`            // Total number of elements
+	Size    uint64            `json:"size"`              // Total size of tensor data in bytes
+	Types   []ModelTensorType `json:"types"`             // Breakdown by tensor type, largest first
+	Tensors []ModelTensor     `json:"tensors,omitempty"` // Individual tensors, in file order
+}
+
+// ModelTensorType summarises the tensors of a single ggml type.
+type ModelTensorType struct {
+	Type   string `json:"type"`   // ggml type name, e.g. "q4_K"
+	Count  int    `json:"count"`  // Number of tensors
+	Params uint64 `json:"params"` // Number of elements
+	Size   uint64 `json:"size"`   // Size in bytes
+}
+
+// ModelTensor describes a single tensor in a model file.
+type ModelTensor struct {
+	Name   string  `json:"name"`
+	Type   string  `json:"type"`
+	Shape  []int64 `json:"shape"`
+	Offset uint64  `json:"offset"` // Offset relative to the start of the data section
+	Size   uint64  `json:"size"`
+	Shard  int     `json:"shard,omitempty"` // Number of the file which contains the tensor, for a model split across files
 }
 
 // ModelRuntime represents runtime statistics for a loaded model.
@@ -36,6 +142,70 @@ type ModelRuntime struct {
 	ModelSize uint64 `json:"modelSizeBytes,omitempty"`
 }
 
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// HasLabel returns true if the model has the label. Labels are compared
+// without regard to case.
+func (m ModelMeta) HasLabel(label string) bool {
+	for _, l := range m.Labels {
+		if strings.EqualFold(l, label) {
+			return true
+		}
+	}
+	return false
+}
+
+// Match returns true if the model matches all of the filters which are set.
+func (r ListModelsRequest) Match(model *Model) bool {
+	for _, label := range r.Label {
+		if !model.HasLabel(label) {
+			return false
+		}
+	}
+	if r.Arch != "" && !strings.EqualFold(model.Architecture, r.Arch) {
+		return false
+	}
+	if r.MinSize > 0 && model.Size < r.MinSize {
+		return false
+	}
+	if r.MaxSize > 0 && model.Size > r.MaxSize {
+		return false
+	}
+	for _, capability := range r.Capability {
+		if !model.HasCapability(Capability(strings.ToLower(capability))) {
+			return false
+		}
+	}
+	return true
+}
+
+// Merge adds the tensors of another file of a split model, which is the
+// file numbered shard.
+func (m *ModelTensors) Merge(other *ModelTensors, shard int) {
+	m.Count += other.Count
+	m.Params += other.Params
+	m.Size += other.Size
+
+	// Combine the totals of each type
+	for _, t := range other.Types {
+		i := slices.IndexFunc(m.Types, func(u ModelTensorType) bool { return u.Type == t.Type })
+		if i < 0 {
+			m.Types = append(m.Types, t)
+			continue
+		}
+		m.Types[i].Count += t.Count
+		m.Types[i].Params += t.Params
+		m.Types[i].Size += t.Size
+	}
+	sortTensorTypes(m.Types)
+
+	for _, t := range other.Tensors {
+		t.Shard = shard
+		m.Tensors = append(m.Tensors, t)
+	}
+}
+
 ///////////////////////////////////////////////////////////////////////////////
 // STRINGIFY
 
@@ -43,6 +213,34 @@ func (m Model) String() string {
 	return stringify(m)
 }
 
+func (m RemoteModel) String() string {
+	return stringify(m)
+}
+
 func (m ModelRuntime) String() string {
 	return stringify(m)
 }
+
+func (m ModelMeta) String() string {
+	return stringify(m)
+}
+
+func (p ModelPreset) String() string {
+	return stringify(p)
+}
+
+func (r ListModelsRequest) String() string {
+	return stringify(r)
+}
+
+func (r GetModelRequest) String() string {
+	return stringify(r)
+}
+
+func (r RemoteModelRequest) String() string {
+	return stringify(r)
+}
+
+func (m ModelTensors) String() string {
+	return stringify(m)
+}
@@ -1,12 +1,20 @@
-//go:build !client
-
 package schema
 
 import (
+	"sort"
+
 	// Packages
 	gguf "github.com/mutablelogic/go-llama/sys/gguf"
 )
 
+///////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+// MetaArrayLimit is the maximum number of array elements included in
+// Model.Meta. Larger arrays, such as the tokenizer vocabulary, are
+// summarised by type and length.
+const MetaArrayLimit = 256
+
 ///////////////////////////////////////////////////////////////////////////////
 // CONSTRUCTORS
 
@@ -19,17 +27,19 @@ func NewModelFromGGUF(basePath, relPath string, ctx *gguf.Context) (*Model, erro
 	}
 
 	arch := ctx.Architecture()
-	meta, err := ctx.AllMetadata()
+	meta, err := ctx.AllMetadataWithLimit(MetaArrayLimit)
 	if err != nil {
 		return nil, err
 	}
 
+	template := ctx.ChatTemplate()
 	model := &Model{
 		Path:          relPath,
 		Name:          ctx.Name(),
 		Architecture:  arch,
 		Description:   ctx.Description(),
-		ChatTemplate:  ctx.ChatTemplate(),
+		Capabilities:  capabilities(arch, template, meta),
+		ChatTemplate:  template,
 		ContextSize:   getInt32(meta, arch+".context_length"),
 		EmbeddingSize: getInt32(meta, arch+".embedding_length"),
 		LayerCount:    getInt32(meta, arch+".block_count"),
@@ -41,9 +51,73 @@ func NewModelFromGGUF(basePath, relPath string, ctx *gguf.Context) (*Model, erro
 	return model, nil
 }
 
+// NewModelTensorsFromGGUF builds the tensor layout of a GGUF file, including
+// a breakdown of tensor count, elements and size by ggml type. If detail is
+// true, the individual tensors are also included.
+func NewModelTensorsFromGGUF(ctx *gguf.Context, detail bool) (*ModelTensors, error) {
+	if ctx == nil {
+		return nil, gguf.ErrInvalidContext
+	}
+
+	tensors, err := ctx.Tensors()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ModelTensors{
+		Count: len(tensors),
+		Types: []ModelTensorType{},
+	}
+	types := make(map[string]*ModelTensorType)
+	for _, t := range tensors {
+		name := t.Type.String()
+		params := t.Elements()
+		result.Params += params
+		result.Size += t.Size
+
+		// Accumulate per-type totals
+		summary, exists := types[name]
+		if !exists {
+			summary = &ModelTensorType{Type: name}
+			types[name] = summary
+		}
+		summary.Count++
+		summary.Params += params
+		summary.Size += t.Size
+
+		if detail {
+			result.Tensors = append(result.Tensors, ModelTensor{
+				Name:   t.Name,
+				Type:   name,
+				Shape:  t.Shape,
+				Offset: t.Offset,
+				Size:   t.Size,
+			})
+		}
+	}
+
+	// Order types by size, largest first
+	for _, summary := range types {
+		result.Types = append(result.Types, *summary)
+	}
+	sortTensorTypes(result.Types)
+
+	return result, nil
+}
+
 ///////////////////////////////////////////////////////////////////////////////
 // PRIVATE HELPERS
 
+// sortTensorTypes orders tensor types by size, largest first, and then by name
+func sortTensorTypes(types []ModelTensorType) {
+	sort.Slice(types, func(i, j int) bool {
+		if types[i].Size != types[j].Size {
+			return types[i].Size > types[j].Size
+		}
+		return types[i].Type < types[j].Type
+	})
+}
+
 func getInt32(meta map[string]any, key string) int32 {
 	if v, ok := meta[key]; ok {
 		switch val := v.(type) {
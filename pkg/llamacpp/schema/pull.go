@@ -0,0 +1,51 @@
+package schema
+
+import (
+	"time"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// PullJobStatus is the state of an asynchronous model pull.
+type PullJobStatus string
+
+// PullJob describes an asynchronous model pull. Progress is for the file
+// currently being downloaded, which is one of several for a split model.
+type PullJob struct {
+	ID     string        `json:"id"`
+	URL    string        `json:"url"`
+	Status PullJobStatus `json:"status"`
+	ModelPullProgress
+	BytesPerSecond uint64    `json:"bytes_per_second,omitempty"` // Average download rate of the current file
+	ETA            uint64    `json:"eta_seconds,omitempty"`      // Estimated seconds until the current file is downloaded
+	Error          string    `json:"error,omitempty"`            // Reason the pull failed
+	Result         *Model    `json:"result,omitempty"`           // Model, when the pull is complete
+	StartedAt      time.Time `json:"started_at"`
+	FinishedAt     time.Time `json:"finished_at,omitzero"`
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+const (
+	PullJobRunning   PullJobStatus = "running"
+	PullJobComplete  PullJobStatus = "complete"
+	PullJobFailed    PullJobStatus = "failed"
+	PullJobCancelled PullJobStatus = "cancelled"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Done returns true if the pull job has finished, successfully or not.
+func (j PullJob) Done() bool {
+	return j.Status != PullJobRunning
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (j PullJob) String() string {
+	return stringify(j)
+}
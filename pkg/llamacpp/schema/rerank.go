@@ -0,0 +1,42 @@
+package schema
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// RerankRequest contains parameters for scoring documents against a query.
+type RerankRequest struct {
+	Model           string   `json:"model"`                      // Model name
+	Query           string   `json:"query"`                      // Query to score documents against
+	Documents       []string `json:"documents"`                  // Documents to score
+	TopN            int      `json:"top_n,omitempty"`            // Number of results to return (default: all)
+	ReturnDocuments bool     `json:"return_documents,omitempty"` // Include the document text in results
+}
+
+// RerankResponse contains the documents sorted by relevance.
+type RerankResponse struct {
+	Model   string         `json:"model"`   // Model used
+	Results []RerankResult `json:"results"` // Results, most relevant first
+	Usage   Usage          `json:"usage"`   // Token usage
+}
+
+// RerankResult is the relevance score of a single document.
+type RerankResult struct {
+	Index    int     `json:"index"`              // Index of the document in the request
+	Document string  `json:"document,omitempty"` // Document text, if requested
+	Score    float32 `json:"relevance_score"`    // Relevance score, higher is more relevant
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (r RerankRequest) String() string {
+	return stringify(r)
+}
+
+func (r RerankResponse) String() string {
+	return stringify(r)
+}
+
+func (r RerankResult) String() string {
+	return stringify(r)
+}
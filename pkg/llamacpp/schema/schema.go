@@ -9,8 +9,10 @@ import (
 
 // Usage tracks token usage for requests.
 type Usage struct {
-	InputTokens  int `json:"input_tokens"`  // Tokens in input (prompt/text to embed)
-	OutputTokens int `json:"output_tokens"` // Tokens generated (0 for embeddings)
+	InputTokens  int `json:"input_tokens"`           // Tokens in input (prompt/text to embed)
+	OutputTokens int `json:"output_tokens"`          // Tokens generated (0 for embeddings)
+	CacheHits    int `json:"cache_hits,omitempty"`   // Inputs returned from the embedding cache
+	CacheMisses  int `json:"cache_misses,omitempty"` // Inputs computed and added to the embedding cache
 }
 
 // TotalTokens returns the sum of input and output tokens.
@@ -0,0 +1,79 @@
+package schema
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// ClusterMethod is how texts are grouped by similarity.
+type ClusterMethod string
+
+// SimilarityRequest contains parameters for comparing texts by the cosine
+// similarity of their embeddings.
+type SimilarityRequest struct {
+	Model     string        `json:"model"`               // Embedding model name
+	Input     []string      `json:"input"`               // Texts to compare
+	Query     string        `json:"query,omitempty"`     // Rank the texts by similarity to a query, rather than comparing every pair
+	TopK      int           `json:"top_k,omitempty"`     // Number of ranked results (default: all)
+	Cluster   ClusterMethod `json:"cluster,omitempty"`   // Group the texts into clusters (default: none)
+	Clusters  int           `json:"clusters,omitempty"`  // Number of clusters, required for k-means
+	Threshold *float64      `json:"threshold,omitempty"` // Least average similarity of merged clusters, for agglomerative clustering (default: none)
+}
+
+// SimilarityResponse contains the similarity of texts. The matrix is
+// returned when there is no query and the texts are not clustered.
+type SimilarityResponse struct {
+	Model    string              `json:"model"`              // Model used
+	Matrix   [][]float64         `json:"matrix,omitempty"`   // Similarity of every pair of texts
+	Results  []SimilarityResult  `json:"results,omitempty"`  // Texts ranked by similarity to the query, most similar first
+	Clusters []SimilarityCluster `json:"clusters,omitempty"` // Clusters of texts, largest first
+	Usage    Usage               `json:"usage"`              // Token usage
+}
+
+// SimilarityResult is the similarity of a text to the query.
+type SimilarityResult struct {
+	Index int     `json:"index"` // Index of the text in the request
+	Score float64 `json:"score"` // Cosine similarity to the query
+}
+
+// SimilarityCluster is a group of similar texts.
+type SimilarityCluster struct {
+	Members        []int   `json:"members"`        // Indexes of the texts in the request
+	Representative int     `json:"representative"` // Index of the text most similar to the others
+	Cohesion       float64 `json:"cohesion"`       // Average similarity between the texts, or 1 for a single text
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+const (
+	ClusterNone          ClusterMethod = ""              // Do not cluster the texts
+	ClusterKMeans        ClusterMethod = "kmeans"        // Group the texts into a number of clusters around centroids
+	ClusterAgglomerative ClusterMethod = "agglomerative" // Merge the most similar clusters, down to a number of clusters or a threshold
+)
+
+// MaxSimilarityInput is the most texts which can be compared in a request,
+// since the similarity of every pair is computed
+const MaxSimilarityInput = 1024
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Valid returns true if the cluster method is empty or known.
+func (m ClusterMethod) Valid() bool {
+	switch m {
+	case ClusterNone, ClusterKMeans, ClusterAgglomerative:
+		return true
+	default:
+		return false
+	}
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (r SimilarityRequest) String() string {
+	return stringify(r)
+}
+
+func (r SimilarityResponse) String() string {
+	return stringify(r)
+}
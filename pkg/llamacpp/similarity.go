@@ -0,0 +1,152 @@
+package llamacpp
+
+import (
+	"cmp"
+	"context"
+	"slices"
+
+	// Packages
+	otel "github.com/mutablelogic/go-client/pkg/otel"
+	llama "github.com/mutablelogic/go-llama"
+	schema "github.com/mutablelogic/go-llama/pkg/llamacpp/schema"
+	llamacpp "github.com/mutablelogic/go-llama/sys/llamacpp"
+	attribute "go.opentelemetry.io/otel/attribute"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Similarity compares texts by the cosine similarity of their embeddings.
+// With a query, the texts are ranked by similarity to the query, and
+// otherwise the similarity of every pair of texts is returned. The texts can
+// also be grouped into clusters, in which case the pairs are not returned.
+func (l *Llama) Similarity(ctx context.Context, req schema.SimilarityRequest) (result *schema.SimilarityResponse, err error) {
+	ctx, endSpan := otel.StartSpan(l.tracer, ctx, schema.SpanName("Similarity"),
+		attribute.String("request", req.String()),
+	)
+	defer func() { endSpan(err) }()
+
+	// Check the request
+	if len(req.Input) == 0 {
+		return nil, llama.ErrInvalidArgument.With("input is required")
+	} else if len(req.Input) > schema.MaxSimilarityInput {
+		return nil, llama.ErrInvalidArgument.Withf("input has %d texts, more than %d", len(req.Input), schema.MaxSimilarityInput)
+	}
+	if req.TopK < 0 {
+		return nil, llama.ErrInvalidArgument.With("top_k cannot be negative")
+	}
+	if req.Clusters < 0 {
+		return nil, llama.ErrInvalidArgument.With("clusters cannot be negative")
+	}
+	if req.Threshold != nil && (*req.Threshold < -1 || *req.Threshold > 1) {
+		return nil, llama.ErrInvalidArgument.With("threshold must be between -1 and 1")
+	}
+	switch req.Cluster {
+	case schema.ClusterNone:
+		// No clustering
+	case schema.ClusterKMeans:
+		if req.Clusters == 0 {
+			return nil, llama.ErrInvalidArgument.With("clusters is required for k-means")
+		}
+	case schema.ClusterAgglomerative:
+		if req.Clusters == 0 && req.Threshold == nil {
+			return nil, llama.ErrInvalidArgument.With("clusters or threshold is required for agglomerative clustering")
+		}
+	default:
+		return nil, llama.ErrInvalidArgument.Withf("invalid cluster method %q", req.Cluster)
+	}
+
+	// Embed the query first, then the texts
+	input := req.Input
+	if req.Query != "" {
+		input = append([]string{req.Query}, req.Input...)
+	}
+	embeddings, err := l.Embed(ctx, schema.EmbedRequest{
+		Model: req.Model,
+		Input: input,
+	})
+	if err != nil {
+		return nil, err
+	}
+	batch := llamacpp.BatchEmbeddings{
+		Embeddings: embeddings.Embeddings,
+		Dimension:  embeddings.Dimension,
+	}
+	result = &schema.SimilarityResponse{
+		Model: req.Model,
+		Usage: embeddings.Usage,
+	}
+
+	// Rank the texts by similarity to the query
+	if req.Query != "" {
+		topK := req.TopK
+		if topK == 0 {
+			topK = len(req.Input)
+		}
+		for _, i := range batch.MostSimilar(0, topK) {
+			result.Results = append(result.Results, schema.SimilarityResult{
+				Index: i - 1,
+				Score: llamacpp.CosineSimilarity(batch.Embeddings[0], batch.Embeddings[i]),
+			})
+		}
+		batch.Embeddings = batch.Embeddings[1:]
+	}
+
+	// Cluster the texts, or compare every pair
+	switch req.Cluster {
+	case schema.ClusterKMeans:
+		result.Clusters = similarityClusters(batch.SimilarityMatrix(), batch.KMeans(req.Clusters))
+	case schema.ClusterAgglomerative:
+		threshold := -1.0
+		if req.Threshold != nil {
+			threshold = *req.Threshold
+		}
+		result.Clusters = similarityClusters(batch.SimilarityMatrix(), batch.Agglomerative(req.Clusters, threshold))
+	default:
+		if req.Query == "" {
+			result.Matrix = batch.SimilarityMatrix()
+		}
+	}
+	return result, nil
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// similarityClusters returns the members of each cluster, with the member
+// which is most similar to the others and the average similarity of the
+// members, largest cluster first
+func similarityClusters(matrix [][]float64, labels []int) []schema.SimilarityCluster {
+	var result []schema.SimilarityCluster
+	for i, label := range labels {
+		if label == len(result) {
+			result = append(result, schema.SimilarityCluster{})
+		}
+		result[label].Members = append(result[label].Members, i)
+	}
+	for i, cluster := range result {
+		var total float64
+		best, bestSum := cluster.Members[0], 0.0
+		for j, a := range cluster.Members {
+			var sum float64
+			for _, b := range cluster.Members {
+				if a != b {
+					sum += matrix[a][b]
+				}
+			}
+			if j == 0 || sum > bestSum {
+				best, bestSum = a, sum
+			}
+			total += sum
+		}
+		result[i].Representative = best
+		result[i].Cohesion = 1
+		if n := len(cluster.Members); n > 1 {
+			result[i].Cohesion = total / float64(n*(n-1))
+		}
+	}
+	slices.SortStableFunc(result, func(a, b schema.SimilarityCluster) int {
+		return cmp.Compare(len(b.Members), len(a.Members))
+	})
+	return result
+}
@@ -0,0 +1,34 @@
+package llamacpp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mutablelogic/go-llama/pkg/llamacpp/schema"
+)
+
+func TestSimilarityClusters(t *testing.T) {
+	assert := assert.New(t)
+	matrix := [][]float64{
+		{1, 0.2, 0.9, 0.8},
+		{0.2, 1, 0.1, 0.3},
+		{0.9, 0.1, 1, 0.7},
+		{0.8, 0.3, 0.7, 1},
+	}
+
+	// The largest cluster is first, with the member most similar to the others
+	clusters := similarityClusters(matrix, []int{0, 1, 0, 0})
+	assert.Len(clusters, 2)
+	assert.Equal([]int{0, 2, 3}, clusters[0].Members)
+	assert.Equal(0, clusters[0].Representative)
+	assert.InDelta(0.8, clusters[0].Cohesion, 1e-9)
+	assert.Equal(schema.SimilarityCluster{Members: []int{1}, Representative: 1, Cohesion: 1}, clusters[1])
+
+	// Clusters of the same size keep their order
+	clusters = similarityClusters(matrix, []int{0, 1, 1, 0})
+	assert.Len(clusters, 2)
+	assert.Equal([]int{0, 3}, clusters[0].Members)
+	assert.Equal([]int{1, 2}, clusters[1].Members)
+	assert.InDelta(0.1, clusters[1].Cohesion, 1e-9)
+}
@@ -39,6 +39,11 @@ const (
 
 type Client struct {
 	*client.Client
+	hf          *url.URL      // Hugging Face endpoint
+	token       string        // Default Hugging Face token
+	credentials []Credential  // Credentials for other hosts
+	retries     int           // Number of times to retry a transient download error
+	backoff     time.Duration // Initial delay before retrying, which doubles on each retry
 }
 
 type ClientModel struct {
@@ -69,10 +74,15 @@ func NewClient(opts ...client.ClientOpt) (*Client, error) {
 	defaults := []client.ClientOpt{
 		client.OptEndpoint("http://localhost/"),
 	}
+	hf, err := hfEndpoint()
+	if err != nil {
+		return nil, err
+	}
 	if c, err := client.New(append(defaults, opts...)...); err != nil {
 		return nil, err
 	} else {
-		return &Client{Client: c}, nil
+		c.Client.CheckRedirect = checkRedirect
+		return &Client{Client: c, hf: hf, token: hfToken(), retries: defaultPullRetries, backoff: defaultPullBackoff}, nil
 	}
 }
 func NewClientModel(w io.Writer, fn ClientCallback) *ClientModel {
@@ -117,20 +127,30 @@ func (c *Client) GetModelSize(ctx context.Context, url string) (uint64, error) {
 
 // GetDestPath returns the suggested destination path for a model URL without downloading.
 func (c *Client) GetDestPath(url string) (string, error) {
+	if isRegistryURL(url) {
+		ref, err := parseRegistryRef(url)
+		if err != nil {
+			return "", err
+		}
+		return ref.destPath(), nil
+	}
 	_, _, destPath, err := c.parseModelUrl(url)
 	return destPath, err
 }
 
-// PullModel downloads a model from the given URL and returns the suggested destination path.
+// PullModel downloads a model from the given URL to w and returns the suggested destination path.
 // Supports HuggingFace URLs with hf:// scheme and regular HTTP(S) URLs.
 // If a callback is provided, it first makes a HEAD request and calls the callback with
 // (filename, 0, total_size). If the callback returns an error, the download is aborted.
+// The download is not resumed if it is interrupted; use PullModelFile to download to a file
+// which can be resumed.
 func (c *Client) PullModel(ctx context.Context, w io.Writer, url string, fn ClientCallback, additionalOpts ...client.RequestOpt) (destPath string, err error) {
 	// Parse URL to get the actual download URL, options, and destination path
 	httpURL, opts, destPath, err := c.parseModelUrl(url)
 	if err != nil {
 		return "", err
 	}
+	opts = append(opts, c.authorize(httpURL, new(opt))...)
 
 	// If callback provided, do HEAD request first to get size and allow callback to abort
 	if fn != nil {
@@ -184,14 +204,66 @@ func (c *Client) PullModel(ctx context.Context, w io.Writer, url string, fn Clie
 	return destPath, nil
 }
 
+// PullModelFile downloads a model from the given URL into the partial file at path, and returns
+// the suggested destination path. Supports HuggingFace URLs with hf:// scheme and regular HTTP(S) URLs.
+// The state of the download is kept in a sidecar file next to the partial file, so an interrupted
+// download is resumed with a range request when PullModelFile is called again with the same path and URL.
+// Transient errors are retried with backoff, and the file is verified against its sha256 checksum
+// when known. If a callback is provided, it is first called with (filename, bytes_received, total_size)
+// and if the callback returns an error, the download is aborted. Requests are authorized with any
+// token or credentials for the host.
+func (c *Client) PullModelFile(ctx context.Context, path string, url string, fn ClientCallback, opts ...Opt) (destPath string, err error) {
+	destPath, _, err = c.pullModel(ctx, path, url, fn, opts...)
+	return destPath, err
+}
+
 ///////////////////////////////////////////////////////////////////////////////
 // PRIVATE METHODS
 
+// pullModel downloads a model as PullModelFile does, and also returns the sha256
+// checksum of the downloaded file
+func (c *Client) pullModel(ctx context.Context, path string, url string, fn ClientCallback, opts ...Opt) (destPath, sum string, err error) {
+	o, err := applyOpts(opts...)
+	if err != nil {
+		return "", "", err
+	}
+
+	// Parse URL to get the actual download URL, options, and destination path
+	httpURL, reqOpts, destPath, err := c.parseModelUrl(url)
+	if err != nil {
+		return "", "", err
+	}
+
+	// Download the model, still returning the destination path if the download fails
+	sum, err = c.pull(ctx, path, httpURL, "", fn, append(reqOpts, c.authorize(httpURL, o)...))
+	return destPath, sum, err
+}
+
 func (g *ClientModel) Unmarshal(headers http.Header, r io.Reader) error {
-	// Determine the size from headers
-	if size := headers.Get(types.ContentLengthHeader); size != "" {
-		if size_, err := strconv.ParseUint(size, 10, 64); err == nil {
-			g.size = size_
+	// A partial response must start at the number of bytes already written,
+	// and a full response restarts the download from the beginning
+	if start, total, ok := parseContentRange(headers.Get(headerContentRange)); ok {
+		if start != g.n {
+			return llama.ErrInvalidArgument.Withf("response range starts at %d, expected %d", start, g.n)
+		} else if total > 0 {
+			g.size = total
+		}
+	} else {
+		if g.n > 0 {
+			w, ok := g.w.(interface{ Reset() error })
+			if !ok {
+				return errRangeIgnored
+			} else if err := w.Reset(); err != nil {
+				return err
+			}
+			g.n = 0
+		}
+
+		// Determine the size from headers
+		if size := headers.Get(types.ContentLengthHeader); size != "" {
+			if size_, err := strconv.ParseUint(size, 10, 64); err == nil {
+				g.size = size_
+			}
 		}
 	}
 
@@ -204,14 +276,17 @@ func (g *ClientModel) Unmarshal(headers http.Header, r io.Reader) error {
 		}
 	}
 
-	// Read and validate GGUF header (first 4 bytes should be "GGUF")
-	magic := make([]byte, 4)
-	if n, err := io.ReadFull(r, magic); err != nil {
-		return err
-	} else if n != 4 || string(magic) != "GGUF" {
-		return llama.ErrInvalidModel.With("invalid GGUF file: expected magic header 'GGUF'")
-	} else if _, err := g.Write(magic); err != nil {
-		return err
+	// Read and validate GGUF header (first 4 bytes should be "GGUF") when
+	// downloading from the beginning of the file
+	if g.n == 0 {
+		magic := make([]byte, 4)
+		if n, err := io.ReadFull(r, magic); err != nil {
+			return err
+		} else if n != 4 || string(magic) != "GGUF" {
+			return llama.ErrInvalidModel.With("invalid GGUF file: expected magic header 'GGUF'")
+		} else if _, err := g.Write(magic); err != nil {
+			return err
+		}
 	}
 
 	// Copy the rest of the data
@@ -246,21 +321,33 @@ func (h *headUnmarshaler) Unmarshal(headers http.Header, r io.Reader) error {
 }
 
 // parseModelUrl converts URLs into HTTP download URLs, request options, and determines the final destination path.
-// Supports both hf:// scheme and regular https:// URLs
+// Supports both hf:// scheme and regular https:// URLs. Hugging Face URLs are downloaded from the
+// configured Hugging Face endpoint.
 func (c *Client) parseModelUrl(urlStr string) (*url.URL, []client.RequestOpt, string, error) {
 	u, err := url.Parse(urlStr)
 	if err != nil {
 		return nil, nil, "", llama.ErrInvalidArgument.Withf("invalid URL: %v", err)
 	}
 
+	var httpURL *url.URL
+	var opts []client.RequestOpt
+	var destPath string
 	switch u.Scheme {
 	case schemeHF:
-		return parseHFScheme(u)
+		httpURL, opts, destPath, err = parseHFScheme(u)
 	case schemeHTTP, schemeHTTPS:
-		return parseHTTPScheme(u)
+		if u.Host == c.hf.Host && u.Host != hostHuggingFace {
+			// URL on the configured endpoint
+			u = &url.URL{Scheme: schemeHTTPS, Host: hostHuggingFace, Path: u.Path, RawQuery: u.RawQuery}
+		}
+		httpURL, opts, destPath, err = parseHTTPScheme(u)
 	default:
 		return nil, nil, "", llama.ErrInvalidArgument.Withf("unsupported URL scheme: %q", u.Scheme)
 	}
+	if err != nil {
+		return nil, nil, "", err
+	}
+	return c.hfURL(httpURL), opts, destPath, nil
 }
 
 // parseHFScheme handles hf://user/repo[@branch]/path/file URLs
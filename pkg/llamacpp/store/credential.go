@@ -0,0 +1,141 @@
+package store
+
+import (
+	"encoding/base64"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	// Packages
+	client "github.com/mutablelogic/go-client"
+	llama "github.com/mutablelogic/go-llama"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// Credential authorizes downloads from hosts which match a pattern, with
+// either a bearer token or a user and password for basic authentication.
+type Credential struct {
+	Host     string `json:"host"`           // Host pattern, as for path.Match, such as "*.example.com"
+	Token    string `json:"-"`              // Bearer token
+	User     string `json:"user,omitempty"` // User for basic authentication
+	Password string `json:"-"`              // Password for basic authentication
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	envHFToken       = "HF_TOKEN"
+	envHFTokenPath   = "HF_TOKEN_PATH"
+	envHFHome        = "HF_HOME"
+	hfTokenFilename  = "token"
+	hfHomeDir        = "huggingface"
+	authScheme       = "Basic"
+	redactedSecret   = "********"
+	credentialFormat = "host=token or host=user:password"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// LIFECYCLE
+
+// ParseCredential parses a credential from "host=token" or
+// "host=user:password", where host may be a pattern such as "*.example.com"
+func ParseCredential(value string) (Credential, error) {
+	host, secret, ok := strings.Cut(value, "=")
+	if !ok || host == "" || secret == "" {
+		return Credential{}, llama.ErrInvalidArgument.Withf("invalid credential: expected %s", credentialFormat)
+	} else if _, err := path.Match(host, ""); err != nil {
+		return Credential{}, llama.ErrInvalidArgument.Withf("invalid credential host pattern %q", host)
+	}
+	if user, password, ok := strings.Cut(secret, ":"); ok {
+		return Credential{Host: host, User: user, Password: password}, nil
+	}
+	return Credential{Host: host, Token: secret}, nil
+}
+
+// hfToken returns the default Hugging Face token, from the HF_TOKEN
+// environment variable or else from the token file saved by the Hugging
+// Face CLI, or an empty string if there is no token
+func hfToken() string {
+	if token := strings.TrimSpace(os.Getenv(envHFToken)); token != "" {
+		return token
+	}
+	tokenPath := os.Getenv(envHFTokenPath)
+	if tokenPath == "" {
+		if home := os.Getenv(envHFHome); home != "" {
+			tokenPath = filepath.Join(home, hfTokenFilename)
+		} else if cache, err := os.UserCacheDir(); err == nil {
+			tokenPath = filepath.Join(cache, hfHomeDir, hfTokenFilename)
+		}
+	}
+	if data, err := os.ReadFile(tokenPath); err == nil {
+		return strings.TrimSpace(string(data))
+	}
+	return ""
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+// String returns the credential with the secret redacted
+func (c Credential) String() string {
+	if c.User != "" {
+		return c.Host + "=" + c.User + ":" + redactedSecret
+	}
+	return c.Host + "=" + redactedSecret
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// match returns true if the credential applies to the host of the URL,
+// with or without the port
+func (c Credential) match(u *url.URL) bool {
+	for _, host := range []string{u.Host, u.Hostname()} {
+		if ok, err := path.Match(c.Host, host); err == nil && ok {
+			return true
+		}
+	}
+	return false
+}
+
+// token returns the authorization token for the credential
+func (c Credential) token() client.Token {
+	if c.User != "" {
+		return client.Token{Scheme: authScheme, Value: base64.StdEncoding.EncodeToString([]byte(c.User + ":" + c.Password))}
+	}
+	return client.Token{Scheme: client.Bearer, Value: c.Token}
+}
+
+// authorize returns the request options which authorize requests to the
+// URL. The authorization is not sent on redirects to other hosts.
+func (c *Client) authorize(u *url.URL, o *opt) []client.RequestOpt {
+	if token := c.authorization(u, o); token.Value != "" {
+		return []client.RequestOpt{client.OptToken(token)}
+	}
+	return nil
+}
+
+// authorization returns the token for requests to the URL. For the Hugging
+// Face endpoint, a token from the request options takes precedence, then any
+// matching credential, and then the default token. For other hosts, the first
+// matching credential is used.
+func (c *Client) authorization(u *url.URL, o *opt) client.Token {
+	isHF := u.Host == c.hf.Host
+	if isHF && o.token != "" {
+		return client.Token{Scheme: client.Bearer, Value: o.token}
+	}
+	for _, credential := range c.credentials {
+		if credential.match(u) {
+			return credential.token()
+		}
+	}
+	if isHF {
+		return client.Token{Scheme: client.Bearer, Value: c.token}
+	}
+	return client.Token{}
+}
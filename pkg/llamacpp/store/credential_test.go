@@ -0,0 +1,133 @@
+package store
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	// Packages
+	llama "github.com/mutablelogic/go-llama"
+	schema "github.com/mutablelogic/go-llama/pkg/llamacpp/schema"
+	assert "github.com/stretchr/testify/assert"
+	require "github.com/stretchr/testify/require"
+)
+
+func TestParseCredential(t *testing.T) {
+	assert := assert.New(t)
+
+	credential, err := ParseCredential("models.example.com=secret")
+	assert.NoError(err)
+	assert.Equal(Credential{Host: "models.example.com", Token: "secret"}, credential)
+	assert.Equal("models.example.com=********", credential.String())
+
+	credential, err = ParseCredential("*.example.com=user:pass=word")
+	assert.NoError(err)
+	assert.Equal(Credential{Host: "*.example.com", User: "user", Password: "pass=word"}, credential)
+	assert.Equal("*.example.com=user:********", credential.String())
+
+	for _, value := range []string{"", "example.com", "=secret", "example.com=", "[=secret"} {
+		_, err := ParseCredential(value)
+		assert.ErrorIs(err, llama.ErrInvalidArgument, value)
+	}
+}
+
+func TestHFToken(t *testing.T) {
+	assert := assert.New(t)
+
+	// The token file in HF_HOME
+	home := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(home, "token"), []byte("file-token\n"), 0600))
+	t.Setenv(envHFToken, "")
+	t.Setenv(envHFTokenPath, "")
+	t.Setenv(envHFHome, home)
+	assert.Equal("file-token", hfToken())
+
+	// HF_TOKEN_PATH takes precedence over HF_HOME
+	path := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(path, []byte("path-token"), 0600))
+	t.Setenv(envHFTokenPath, path)
+	assert.Equal("path-token", hfToken())
+
+	// HF_TOKEN takes precedence over the token file
+	t.Setenv(envHFToken, "env-token")
+	assert.Equal("env-token", hfToken())
+
+	// No token
+	t.Setenv(envHFToken, "")
+	t.Setenv(envHFTokenPath, filepath.Join(t.TempDir(), "missing"))
+	assert.Equal("", hfToken())
+}
+
+func TestClient_Authorize(t *testing.T) {
+	assert := assert.New(t)
+
+	t.Setenv(envHFEndpoint, "")
+	t.Setenv(envHFToken, "default")
+	c, err := NewClient()
+	require.NoError(t, err)
+	c.credentials = []Credential{
+		{Host: "*.example.com", User: "user", Password: "password"},
+		{Host: "example.com:8080", Token: "mirror"},
+	}
+
+	// authorization returns the authorization header for the URL
+	authorization := func(rawURL string, opts ...Opt) string {
+		u, err := url.Parse(rawURL)
+		require.NoError(t, err)
+		o, err := applyOpts(opts...)
+		require.NoError(t, err)
+		if token := c.authorization(u, o); token.Value != "" {
+			return token.String()
+		}
+		return ""
+	}
+
+	assert.Equal("Bearer default", authorization("https://huggingface.co/org/repo/resolve/main/model.gguf"))
+	assert.Equal("Bearer request", authorization("https://huggingface.co/org/repo/resolve/main/model.gguf", WithToken("request")))
+	assert.Equal("Basic dXNlcjpwYXNzd29yZA==", authorization("https://models.example.com/model.gguf", WithToken("request")))
+	assert.Equal("Bearer mirror", authorization("http://example.com:8080/model.gguf"))
+	assert.Equal("", authorization("https://example.com/model.gguf"))
+}
+
+func TestStore_PullModel_Credential(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	s, _ := newPullServer(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, password, ok := r.BasicAuth(); !ok || user != "user" || password != "password" {
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+		s.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	store, err := New(t.TempDir())
+	require.NoError(err)
+	store.client.backoff = 0
+
+	// Without credentials the pull is refused
+	_, err = store.PullModel(context.Background(), server.URL+"/model.gguf", nil)
+	assert.Error(err)
+
+	// With credentials for the host the model is downloaded
+	u, err := url.Parse(server.URL)
+	require.NoError(err)
+	credential, err := ParseCredential(u.Host + "=user:password")
+	require.NoError(err)
+	store.SetCredentials(credential)
+	model, err := store.PullModel(context.Background(), server.URL+"/model.gguf", nil)
+	require.NoError(err)
+	assert.Equal("model.gguf", model.Path)
+}
+
+func TestPullModelRequest_String(t *testing.T) {
+	req := schema.PullModelRequest{URL: "hf://org/repo", Token: "hf_secret"}
+	assert.NotContains(t, req.String(), "hf_secret")
+	assert.Contains(t, req.String(), "hf://org/repo")
+}
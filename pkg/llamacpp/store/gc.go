@@ -0,0 +1,216 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	// Packages
+	llama "github.com/mutablelogic/go-llama"
+	schema "github.com/mutablelogic/go-llama/pkg/llamacpp/schema"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	// gcTempAge is the time since a temporary file was last modified before
+	// it is removed, so files being written by a pull, import or upload in
+	// progress are kept
+	gcTempAge = time.Hour
+)
+
+var (
+	// gcTempPatterns match the temporary files in the store directory and its
+	// subdirectories, which are left behind by interrupted pulls, imports,
+	// uploads, patches and metadata updates
+	gcTempPatterns = []string{tempPattern, ".gguf-*.partial", ".gguf-*.partial" + pullStateExt}
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// SetQuota sets the maximum number of bytes used by models in the store
+// directory, which is enforced by GarbageCollect. Models in read-only
+// directories are not counted. Zero, the default, is no quota.
+func (s *Store) SetQuota(quota uint64) {
+	s.Lock()
+	defer s.Unlock()
+	s.quota = quota
+}
+
+// MarkLoaded records that a model was loaded into memory, so that models
+// which have not been used recently are evicted first when the store is over
+// quota. Models in read-only directories are ignored.
+func (s *Store) MarkLoaded(model *schema.Model) {
+	if model.ReadOnly {
+		return
+	}
+	model.LastLoaded = time.Now()
+	s.usage.loaded(model.Path, model.LastLoaded)
+	_ = s.usage.save()
+}
+
+// PinModel pins or unpins a model by name, and returns the model. A pinned
+// model is never evicted when the store is over quota. Returns ErrReadOnly
+// for a model in a read-only directory, which is never evicted.
+func (s *Store) PinModel(ctx context.Context, name string, pinned bool) (*schema.Model, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	// Find the model
+	model, err := s.getModel(ctx, name)
+	if err != nil {
+		return nil, err
+	} else if model.ReadOnly {
+		return nil, llama.ErrReadOnly.Withf("model %q is in read-only directory %s", model.Path, model.Root)
+	}
+
+	// Pin the model
+	s.usage.pin(model.Path, pinned)
+	if err := s.usage.save(); err != nil {
+		return nil, llama.ErrOpenFailed.Withf("failed to save usage: %v", err)
+	}
+	model.Pinned = pinned
+
+	// Return success
+	return model, nil
+}
+
+// GarbageCollect removes temporary files left in the store directory by
+// interrupted pulls, imports and uploads, which have not been modified for an
+// hour. If the store has a quota and the models in the store directory use
+// more than the quota, models are evicted in order of least recent use until
+// the store is within the quota. A model's last use is the later of when it
+// was last loaded and when its file was last modified. Pinned models, and
+// those with paths in keep, are not evicted. If dryRun is true, the files
+// which would be removed are returned, but not removed.
+func (s *Store) GarbageCollect(ctx context.Context, dryRun bool, keep ...string) (*schema.GCResponse, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	result := &schema.GCResponse{
+		DryRun:  dryRun,
+		Removed: []*schema.GCFile{},
+		Quota:   s.quota,
+	}
+
+	// Remove temporary files
+	var errs error
+	temps, err := s.tempFiles(time.Now().Add(-gcTempAge))
+	if err != nil {
+		return nil, err
+	}
+	for _, temp := range temps {
+		if !dryRun {
+			if err := os.Remove(filepath.Join(s.path, temp.Path)); err != nil && !errors.Is(err, os.ErrNotExist) {
+				errs = errors.Join(errs, llama.ErrOpenFailed.Withf("failed to remove %s: %v", temp.Path, err))
+				continue
+			}
+		}
+		result.Removed = append(result.Removed, temp)
+		result.Freed += temp.Size
+	}
+
+	// Determine the models which can be evicted
+	models, err := s.listModels(ctx)
+	if err != nil {
+		return nil, err
+	}
+	kept := make(map[string]bool, len(keep))
+	for _, path := range keep {
+		kept[path] = true
+	}
+	candidates := make([]*schema.Model, 0, len(models))
+	lastUsed := make(map[string]time.Time, len(models))
+	for _, model := range models {
+		if model.ReadOnly {
+			continue
+		}
+		result.Usage += model.Size
+		if model.Pinned || kept[model.Path] {
+			continue
+		}
+		lastUsed[model.Path] = model.LastLoaded
+		if info, err := os.Stat(s.FilePath(model)); err == nil && info.ModTime().After(model.LastLoaded) {
+			lastUsed[model.Path] = info.ModTime()
+		}
+		candidates = append(candidates, model)
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return lastUsed[candidates[i].Path].Before(lastUsed[candidates[j].Path])
+	})
+
+	// Evict models until the store is within the quota
+	for _, model := range candidates {
+		if s.quota == 0 || result.Usage <= s.quota {
+			break
+		}
+		if !dryRun {
+			if err := s.deleteModel(model); err != nil {
+				errs = errors.Join(errs, err)
+				continue
+			}
+		}
+		result.Removed = append(result.Removed, &schema.GCFile{
+			Path:       model.Path,
+			Reason:     schema.GCEvicted,
+			Size:       model.Size,
+			LastLoaded: model.LastLoaded,
+		})
+		result.Freed += model.Size
+		result.Usage -= model.Size
+	}
+
+	// Return the result, and any errors removing files
+	return result, errs
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// tempFiles returns the temporary files in the store directory and its
+// subdirectories which were last modified before the given time, with their
+// paths relative to the store directory. Hidden subdirectories are skipped,
+// as they are when models are listed. The caller must hold the lock.
+func (s *Store) tempFiles(before time.Time) ([]*schema.GCFile, error) {
+	var result []*schema.GCFile
+	err := filepath.Walk(s.path, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		} else if info.IsDir() {
+			if path != s.path && strings.HasPrefix(info.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		} else if !info.Mode().IsRegular() || !info.ModTime().Before(before) {
+			return nil
+		}
+		for _, pattern := range gcTempPatterns {
+			if ok, err := filepath.Match(pattern, info.Name()); err != nil {
+				return llama.ErrInvalidArgument.Withf("invalid pattern %q: %v", pattern, err)
+			} else if !ok {
+				continue
+			}
+			relPath, err := filepath.Rel(s.path, path)
+			if err != nil {
+				return err
+			}
+			result = append(result, &schema.GCFile{
+				Path:   relPath,
+				Reason: schema.GCTemp,
+				Size:   uint64(info.Size()),
+			})
+			break
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
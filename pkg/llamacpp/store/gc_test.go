@@ -0,0 +1,149 @@
+package store
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	// Packages
+	llama "github.com/mutablelogic/go-llama"
+	schema "github.com/mutablelogic/go-llama/pkg/llamacpp/schema"
+	assert "github.com/stretchr/testify/assert"
+	require "github.com/stretchr/testify/require"
+)
+
+// gcPaths returns the paths of the removed files
+func gcPaths(result *schema.GCResponse) []string {
+	paths := make([]string, 0, len(result.Removed))
+	for _, file := range result.Removed {
+		paths = append(paths, file.Path)
+	}
+	return paths
+}
+
+func TestStore_GarbageCollect_Temp(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	tempDir := t.TempDir()
+	copyTestModel(t, tempDir, "model.gguf")
+	store, err := New(tempDir)
+	require.NoError(err)
+
+	// Stale temporary files are removed, including those next to models in
+	// subdirectories, and recent ones are kept
+	require.NoError(os.MkdirAll(filepath.Join(tempDir, "org", "repo"), 0755))
+	stale := time.Now().Add(-2 * gcTempAge)
+	for _, name := range []string{".gguf-1234.tmp", ".gguf-abcd.partial", ".gguf-abcd.partial.json", ".gguf-recent.tmp", "org/repo/.gguf-5678.tmp"} {
+		path := filepath.Join(tempDir, name)
+		require.NoError(os.WriteFile(path, []byte("temp"), 0644))
+		if name != ".gguf-recent.tmp" {
+			require.NoError(os.Chtimes(path, stale, stale))
+		}
+	}
+
+	// A dry run lists the files without removing them
+	result, err := store.GarbageCollect(context.Background(), true)
+	require.NoError(err)
+	assert.True(result.DryRun)
+	assert.ElementsMatch([]string{".gguf-1234.tmp", ".gguf-abcd.partial", ".gguf-abcd.partial.json", filepath.Join("org", "repo", ".gguf-5678.tmp")}, gcPaths(result))
+	assert.Equal(uint64(16), result.Freed)
+	assert.FileExists(filepath.Join(tempDir, ".gguf-1234.tmp"))
+
+	// Without a quota, models are not evicted
+	result, err = store.GarbageCollect(context.Background(), false)
+	require.NoError(err)
+	assert.Len(result.Removed, 4)
+	assert.NotZero(result.Usage)
+	assert.NoFileExists(filepath.Join(tempDir, ".gguf-1234.tmp"))
+	assert.NoFileExists(filepath.Join(tempDir, "org", "repo", ".gguf-5678.tmp"))
+	assert.NoFileExists(filepath.Join(tempDir, ".gguf-abcd.partial.json"))
+	assert.FileExists(filepath.Join(tempDir, ".gguf-recent.tmp"))
+	assert.FileExists(filepath.Join(tempDir, "model.gguf"))
+}
+
+func TestStore_GarbageCollect_Quota(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	tempDir := t.TempDir()
+	past := time.Now().Add(-24 * time.Hour)
+	for _, name := range []string{"a.gguf", "b.gguf", "c.gguf", "d.gguf", "e.gguf"} {
+		path := copyTestModel(t, tempDir, name)
+		require.NoError(os.Chtimes(path, past, past))
+	}
+	store, err := New(tempDir)
+	require.NoError(err)
+	models, err := store.ListModels(context.Background())
+	require.NoError(err)
+	size := models[0].Size
+
+	// Record loads, oldest first, and pin one model
+	for i, name := range []string{"c.gguf", "a.gguf", "b.gguf"} {
+		store.usage.loaded(name, past.Add(time.Duration(i+1)*time.Hour))
+	}
+	_, err = store.PinModel(context.Background(), "d", true)
+	require.NoError(err)
+
+	// Within the quota, nothing is evicted
+	store.SetQuota(5 * size)
+	result, err := store.GarbageCollect(context.Background(), false)
+	require.NoError(err)
+	assert.Empty(result.Removed)
+	assert.Equal(5*size, result.Usage)
+
+	// Over the quota, models are evicted in order of least recent use,
+	// except pinned models and those in use
+	store.SetQuota(2 * size)
+	result, err = store.GarbageCollect(context.Background(), true, "e.gguf")
+	require.NoError(err)
+	assert.Equal([]string{"c.gguf", "a.gguf", "b.gguf"}, gcPaths(result))
+	assert.Equal(schema.GCEvicted, result.Removed[0].Reason)
+	assert.True(past.Add(time.Hour).Equal(result.Removed[0].LastLoaded))
+	assert.Equal(2*size, result.Usage)
+	assert.FileExists(filepath.Join(tempDir, "c.gguf"))
+
+	result, err = store.GarbageCollect(context.Background(), false, "e.gguf")
+	require.NoError(err)
+	assert.Len(result.Removed, 3)
+	models, err = store.ListModels(context.Background())
+	require.NoError(err)
+	require.Len(models, 2)
+	assert.Equal("d.gguf", models[0].Path)
+	assert.True(models[0].Pinned)
+	assert.Equal("e.gguf", models[1].Path)
+}
+
+func TestStore_PinModel(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	store, _ := newLayeredStore(t)
+
+	// Pins and loads are persisted
+	model, err := store.PinModel(context.Background(), "local", true)
+	require.NoError(err)
+	assert.True(model.Pinned)
+	store.MarkLoaded(model)
+	assert.FileExists(filepath.Join(store.Path(), usageFilename))
+
+	other, err := New(store.Path())
+	require.NoError(err)
+	model, err = other.GetModel(context.Background(), "local")
+	require.NoError(err)
+	assert.True(model.Pinned)
+	assert.False(model.LastLoaded.IsZero())
+
+	// Models can be unpinned
+	model, err = other.PinModel(context.Background(), "local", false)
+	require.NoError(err)
+	assert.False(model.Pinned)
+
+	// Models in read-only directories cannot be pinned
+	_, err = store.PinModel(context.Background(), "shared", true)
+	assert.ErrorIs(err, llama.ErrReadOnly)
+	_, err = store.PinModel(context.Background(), "missing", true)
+	assert.ErrorIs(err, llama.ErrNotFound)
+}
@@ -0,0 +1,283 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	// Packages
+	client "github.com/mutablelogic/go-client"
+	llama "github.com/mutablelogic/go-llama"
+	schema "github.com/mutablelogic/go-llama/pkg/llamacpp/schema"
+	gguf "github.com/mutablelogic/go-llama/sys/gguf"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// hfTreeEntry is an entry returned by the Hugging Face tree API
+type hfTreeEntry struct {
+	Type string `json:"type"`
+	Path string `json:"path"`
+	Size uint64 `json:"size"`
+	LFS  *struct {
+		OID  string `json:"oid"`
+		Size uint64 `json:"size"`
+	} `json:"lfs,omitempty"`
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	envHFEndpoint       = "HF_ENDPOINT"
+	hfTreeAPIPathFormat = "/api/models/%s/tree/%s/%s"
+	hfTreeEntryFile     = "file"
+	hfProjectorPrefix   = "mmproj"
+	defaultQuant        = "Q4_K_M"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+var (
+	// reQuant matches the quantization type in a model filename, such as
+	// "Q4_K_M", "IQ2_XXS", "Q8_0", "BF16" or "MXFP4"
+	reQuant = regexp.MustCompile(`(?i)(?:^|[-._])(I?Q[1-8](?:_[0-9A-Z]{1,3})*|BF16|F16|F32|MXFP4)(?:[-._]|$)`)
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// LIFECYCLE
+
+// hfEndpoint returns the Hugging Face endpoint, which can be set with the
+// HF_ENDPOINT environment variable to use a mirror or a local server
+func hfEndpoint() (*url.URL, error) {
+	endpoint := os.Getenv(envHFEndpoint)
+	if endpoint == "" {
+		return &url.URL{Scheme: schemeHTTPS, Host: hostHuggingFace}, nil
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil || (u.Scheme != schemeHTTP && u.Scheme != schemeHTTPS) || u.Host == "" {
+		return nil, llama.ErrInvalidArgument.Withf("invalid %s: %q", envHFEndpoint, endpoint)
+	}
+	return &url.URL{Scheme: u.Scheme, Host: u.Host}, nil
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// ListRemoteModels returns the GGUF models in a Hugging Face repository,
+// given as "org/repo" with an optional "@branch". A model split across files
+// is returned as a single entry with the combined size, and an incomplete
+// split model is omitted. The models are sorted by path.
+func (c *Client) ListRemoteModels(ctx context.Context, repo string, opts ...Opt) ([]*schema.RemoteModel, error) {
+	o, err := applyOpts(opts...)
+	if err != nil {
+		return nil, err
+	}
+	repo, branch, ok := strings.Cut(repo, "@")
+	if !ok {
+		branch = defaultBranch
+	}
+	if parts := strings.Split(repo, "/"); len(parts) != 2 || parts[0] == "" || parts[1] == "" || branch == "" {
+		return nil, llama.ErrInvalidArgument.Withf("invalid repository %q: expected org/repo[@branch]", repo)
+	}
+
+	// List all the files in the repository
+	entries, err := c.hfTree(ctx, repo, branch, "", true, append([]client.RequestOpt{client.OptNoTimeout()}, c.authorize(c.hf, o)...))
+	if err != nil {
+		return nil, err
+	}
+
+	// Collect the models, combining the files of split models
+	type splitSet struct {
+		model *schema.RemoteModel
+		count int
+		n     int
+	}
+	result := make([]*schema.RemoteModel, 0, len(entries))
+	sets := make(map[string]*splitSet)
+	for _, entry := range entries {
+		if entry.Type != hfTreeEntryFile || path.Ext(entry.Path) != gguf.FileExtension {
+			continue
+		}
+		size := entry.Size
+		if entry.LFS != nil && entry.LFS.Size > 0 {
+			size = entry.LFS.Size
+		}
+		prefix, _, count, ok := parseSplitPath(entry.Path)
+		if !ok {
+			result = append(result, newRemoteModel(repo, branch, entry.Path, size))
+			continue
+		}
+		key := splitPath(prefix, 1, count)
+		set, exists := sets[key]
+		if !exists {
+			set = &splitSet{model: newRemoteModel(repo, branch, key, 0), count: count}
+			set.model.Shards = count
+			sets[key] = set
+		}
+		set.model.Size += size
+		set.n++
+	}
+	for _, set := range sets {
+		if set.n == set.count {
+			result = append(result, set.model)
+		}
+	}
+
+	// Sort models by path for predictable ordering
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Path < result[j].Path
+	})
+	return result, nil
+}
+
+// ResolveModel returns the URL of a GGUF file for a Hugging Face repository
+// URL "hf://org/repo[@branch][:quant]", choosing the model with the given
+// quantization type, or by default "Q4_K_M" or else the first model in the
+// repository. Other URLs are returned unchanged.
+func (c *Client) ResolveModel(ctx context.Context, rawURL string, opts ...Opt) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme != schemeHF || u.Host == "" || strings.Contains(strings.Trim(u.Path, "/"), "/") || strings.Trim(u.Path, "/") == "" {
+		return rawURL, nil
+	}
+
+	// Parse the quantization type
+	repo, quant, _ := strings.Cut(u.Host+"/"+strings.Trim(u.Path, "/"), ":")
+
+	// List the models in the repository
+	models, err := c.ListRemoteModels(ctx, repo, opts...)
+	if err != nil {
+		return "", err
+	}
+
+	// Choose a model, ignoring multimodal projectors
+	var available []string
+	var first *schema.RemoteModel
+	for _, model := range models {
+		if strings.HasPrefix(path.Base(model.Path), hfProjectorPrefix) {
+			continue
+		} else if first == nil {
+			first = model
+		}
+		if quant == "" && strings.EqualFold(model.Quant, defaultQuant) {
+			return model.URL, nil
+		} else if quant != "" && strings.EqualFold(model.Quant, quant) {
+			return model.URL, nil
+		}
+		if model.Quant != "" {
+			available = append(available, model.Quant)
+		}
+	}
+	if quant == "" && first != nil {
+		return first.URL, nil
+	} else if first == nil {
+		return "", llama.ErrNotFound.Withf("no GGUF models in %q", repo)
+	}
+	return "", llama.ErrNotFound.Withf("no %s model in %q (available: %s)", quant, repo, strings.Join(available, ", "))
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// hfTree returns the entries in a directory of a Hugging Face repository,
+// including subdirectories if recursive is true
+func (c *Client) hfTree(ctx context.Context, repo, branch, dir string, recursive bool, opts []client.RequestOpt) ([]hfTreeEntry, error) {
+	apiURL := url.URL{
+		Scheme: c.hf.Scheme,
+		Host:   c.hf.Host,
+		Path:   fmt.Sprintf(hfTreeAPIPathFormat, repo, url.PathEscape(branch), dir),
+	}
+	if recursive {
+		apiURL.RawQuery = "recursive=true"
+	}
+
+	var entries []hfTreeEntry
+	apiOpts := append([]client.RequestOpt{client.OptReqEndpoint(apiURL.String())}, opts...)
+	if err := c.DoWithContext(ctx, client.NewRequest(), &entries, apiOpts...); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// hfSHA256 returns the sha256 checksum of an LFS file from the Hugging Face tree API
+func (c *Client) hfSHA256(ctx context.Context, repo, branch, filePath string, opts []client.RequestOpt) (string, error) {
+	dir := path.Dir(filePath)
+	if dir == "." {
+		dir = ""
+	}
+	entries, err := c.hfTree(ctx, repo, branch, dir, false, opts)
+	if err != nil {
+		return "", err
+	}
+	for _, entry := range entries {
+		if entry.Path == filePath && entry.LFS != nil {
+			if sum := parseSHA256(entry.LFS.OID); sum != "" {
+				return sum, nil
+			}
+		}
+	}
+	return "", llama.ErrNotFound.Withf("checksum for %q", filePath)
+}
+
+// hfURL returns a Hugging Face URL on the configured endpoint
+func (c *Client) hfURL(u *url.URL) *url.URL {
+	if u == nil || u.Host != hostHuggingFace {
+		return u
+	}
+	result := *u
+	result.Scheme, result.Host = c.hf.Scheme, c.hf.Host
+	return &result
+}
+
+// parseHuggingFaceURL returns the repository, branch and file path of a
+// Hugging Face /repo/resolve/branch/path download URL
+func (c *Client) parseHuggingFaceURL(u *url.URL) (repo, branch, filePath string, ok bool) {
+	if u.Host != c.hf.Host {
+		return "", "", "", false
+	}
+	parts := strings.Split(strings.TrimPrefix(u.Path, "/"), "/")
+	if len(parts) < 5 || parts[2] != "resolve" {
+		return "", "", "", false
+	}
+	return parts[0] + "/" + parts[1], parts[3], strings.Join(parts[4:], "/"), true
+}
+
+// newRemoteModel returns a model in a Hugging Face repository
+func newRemoteModel(repo, branch, filePath string, size uint64) *schema.RemoteModel {
+	if branch != defaultBranch {
+		repo += "@" + branch
+	}
+	return &schema.RemoteModel{
+		Path:  filePath,
+		URL:   schemeHF + "://" + repo + "/" + filePath,
+		Quant: parseQuant(filePath),
+		Size:  size,
+	}
+}
+
+// parseQuant returns the quantization type from a model filename in upper
+// case, or an empty string if there is none. The last match is used, so a
+// version number earlier in the name is not mistaken for the type.
+func parseQuant(filePath string) string {
+	name := strings.TrimSuffix(path.Base(filePath), gguf.FileExtension)
+	if prefix, _, _, ok := parseSplitPath(filePath); ok {
+		name = path.Base(prefix)
+	}
+	var quant string
+	for i := 0; i < len(name); {
+		loc := reQuant.FindStringSubmatchIndex(name[i:])
+		if loc == nil {
+			break
+		}
+		quant = name[i+loc[2] : i+loc[3]]
+		i += loc[3]
+	}
+	return strings.ToUpper(quant)
+}
@@ -0,0 +1,187 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	// Packages
+	llama "github.com/mutablelogic/go-llama"
+	assert "github.com/stretchr/testify/assert"
+	require "github.com/stretchr/testify/require"
+)
+
+// newHFServer returns a stand-in for the Hugging Face API and file downloads,
+// serving the test model for every GGUF file in the "org/repo" repository
+func newHFServer(t *testing.T) *httptest.Server {
+	data, err := os.ReadFile(filepath.Join(testdataPath, "stories260K.gguf"))
+	require.NoError(t, err)
+	sum := sha256.Sum256(data)
+	lfs := map[string]any{"oid": hex.EncodeToString(sum[:]), "size": len(data)}
+
+	files := []map[string]any{
+		{"type": "file", "path": "README.md", "size": 100},
+		{"type": "file", "path": "model-Q8_0.gguf", "size": 134, "lfs": lfs},
+		{"type": "file", "path": "model-Q4_K_M.gguf", "size": 134, "lfs": lfs},
+		{"type": "file", "path": "mmproj-model-f16.gguf", "size": 134, "lfs": lfs},
+		{"type": "directory", "path": "Q2_K"},
+		{"type": "file", "path": "Q2_K/model-Q2_K-00001-of-00002.gguf", "size": 134, "lfs": lfs},
+		{"type": "file", "path": "Q2_K/model-Q2_K-00002-of-00002.gguf", "size": 134, "lfs": lfs},
+		{"type": "file", "path": "IQ4/model-IQ4_XS-00001-of-00003.gguf", "size": 134, "lfs": lfs},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/models/org/repo/tree/main/{dir...}", func(w http.ResponseWriter, r *http.Request) {
+		dir := r.PathValue("dir")
+		result := []map[string]any{}
+		for _, file := range files {
+			path := file["path"].(string)
+			if r.URL.Query().Get("recursive") == "true" || filepath.Dir(path) == filepath.Clean("./"+dir) {
+				result = append(result, file)
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(result)
+	})
+	mux.HandleFunc("/org/repo/resolve/main/{path...}", func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.PathValue("path"), ".gguf") {
+			http.NotFound(w, r)
+			return
+		}
+		http.ServeContent(w, r, filepath.Base(r.PathValue("path")), time.Time{}, bytes.NewReader(data))
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	t.Setenv(envHFEndpoint, server.URL)
+	return server
+}
+
+func TestParseQuant(t *testing.T) {
+	tests := map[string]string{
+		"Llama-3.2-1B-Instruct-Q4_K_M.gguf":              "Q4_K_M",
+		"qwen2.5-7b-instruct-q4_k_m-00001-of-00002.gguf": "Q4_K_M",
+		"Mistral-7B-Instruct-v0.3.Q8_0.gguf":             "Q8_0",
+		"gemma-2-2b-it-IQ2_XXS.gguf":                     "IQ2_XXS",
+		"gpt-oss-20b-MXFP4.gguf":                         "MXFP4",
+		"mmproj-model-f16.gguf":                          "F16",
+		"Q4_0/phi-2.Q4_0.gguf":                           "Q4_0",
+		"model-bf16.gguf":                                "BF16",
+		"stories260K.gguf":                               "",
+	}
+	for name, want := range tests {
+		assert.Equal(t, want, parseQuant(name), name)
+	}
+}
+
+func TestHFEndpoint(t *testing.T) {
+	assert := assert.New(t)
+
+	t.Setenv(envHFEndpoint, "")
+	endpoint, err := hfEndpoint()
+	assert.NoError(err)
+	assert.Equal("https://huggingface.co", endpoint.String())
+
+	t.Setenv(envHFEndpoint, "http://localhost:8080/")
+	endpoint, err = hfEndpoint()
+	assert.NoError(err)
+	assert.Equal("http://localhost:8080", endpoint.String())
+
+	// Hugging Face URLs are downloaded from the endpoint
+	client, err := NewClient()
+	require.NoError(t, err)
+	httpURL, _, destPath, err := client.parseModelUrl("hf://org/repo/model.gguf")
+	assert.NoError(err)
+	assert.Equal("http://localhost:8080/org/repo/resolve/main/model.gguf?download=true", httpURL.String())
+	assert.Equal("repo/model.gguf", destPath)
+	httpURL, _, _, err = client.parseModelUrl("http://localhost:8080/org/repo/blob/dev/model.gguf")
+	assert.NoError(err)
+	assert.Equal("http://localhost:8080/org/repo/resolve/dev/model.gguf?download=true", httpURL.String())
+
+	t.Setenv(envHFEndpoint, "localhost")
+	_, err = NewClient()
+	assert.ErrorIs(err, llama.ErrInvalidArgument)
+}
+
+func TestClient_ListRemoteModels(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	newHFServer(t)
+	client, err := NewClient()
+	require.NoError(err)
+
+	models, err := client.ListRemoteModels(context.Background(), "org/repo")
+	require.NoError(err)
+	require.Len(models, 4)
+
+	// Split models are combined, and incomplete split models are omitted
+	assert.Equal("Q2_K/model-Q2_K-00001-of-00002.gguf", models[0].Path)
+	assert.Equal("Q2_K", models[0].Quant)
+	assert.Equal(2, models[0].Shards)
+	assert.Equal(uint64(1185376*2), models[0].Size)
+	assert.Equal("hf://org/repo/Q2_K/model-Q2_K-00001-of-00002.gguf", models[0].URL)
+	assert.Equal("mmproj-model-f16.gguf", models[1].Path)
+	assert.Equal("model-Q4_K_M.gguf", models[2].Path)
+	assert.Equal("Q4_K_M", models[2].Quant)
+	assert.Equal(uint64(1185376), models[2].Size)
+	assert.Equal(0, models[2].Shards)
+	assert.Equal("model-Q8_0.gguf", models[3].Path)
+
+	// Invalid repository
+	_, err = client.ListRemoteModels(context.Background(), "org")
+	assert.ErrorIs(err, llama.ErrInvalidArgument)
+}
+
+func TestClient_ResolveModel(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	newHFServer(t)
+	client, err := NewClient()
+	require.NoError(err)
+
+	tests := map[string]string{
+		"hf://org/repo":                  "hf://org/repo/model-Q4_K_M.gguf",
+		"hf://org/repo:q8_0":             "hf://org/repo/model-Q8_0.gguf",
+		"hf://org/repo:Q2_K":             "hf://org/repo/Q2_K/model-Q2_K-00001-of-00002.gguf",
+		"hf://org/repo/model-Q8_0.gguf":  "hf://org/repo/model-Q8_0.gguf",
+		"https://example.com/model.gguf": "https://example.com/model.gguf",
+	}
+	for url, want := range tests {
+		got, err := client.ResolveModel(context.Background(), url)
+		if assert.NoError(err, url) {
+			assert.Equal(want, got, url)
+		}
+	}
+
+	// The projector is not a model, and incomplete split models are omitted
+	for _, url := range []string{"hf://org/repo:F16", "hf://org/repo:IQ4_XS"} {
+		_, err = client.ResolveModel(context.Background(), url)
+		assert.ErrorIs(err, llama.ErrNotFound, url)
+	}
+}
+
+func TestStore_PullModel_Repo(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	newHFServer(t)
+	store, err := New(t.TempDir())
+	require.NoError(err)
+
+	// The repository is resolved to a model, which is downloaded from the
+	// endpoint and verified against the checksum from the tree API
+	model, err := store.PullModel(context.Background(), "hf://org/repo:Q8_0", nil)
+	require.NoError(err)
+	assert.Equal(filepath.Join("repo", "model-Q8_0.gguf"), model.Path)
+	assert.FileExists(filepath.Join(store.Path(), "repo", "model-Q8_0.gguf"))
+}
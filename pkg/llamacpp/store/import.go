@@ -0,0 +1,300 @@
+package store
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	// Packages
+	llama "github.com/mutablelogic/go-llama"
+	schema "github.com/mutablelogic/go-llama/pkg/llamacpp/schema"
+	gguf "github.com/mutablelogic/go-llama/sys/gguf"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// contextReader is a reader which returns an error once the context is done
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	// tempPattern is the pattern for temporary files in the store directory,
+	// which are hidden so they are not scanned as models
+	tempPattern = ".gguf-*.tmp"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// ImportModel imports the GGUF file at path into the store and returns the
+// model. The model is stored at dest, a path relative to the store, or with
+// the same filename if dest is empty. The file is copied, hardlinked or
+// symlinked according to mode, which defaults to copy. If the file is one of
+// a split model, all the files of the model are imported and named from dest.
+// The files are validated before they are imported, and dest must not
+// already exist. The source path, the time of the import and the checksum of
+// a copied single file model are written to the sidecar metadata file.
+func (s *Store) ImportModel(ctx context.Context, path, dest string, mode schema.ImportMode) (*schema.Model, error) {
+	switch mode {
+	case "":
+		mode = schema.ImportCopy
+	case schema.ImportCopy, schema.ImportHardlink, schema.ImportSymlink:
+		// Valid
+	default:
+		return nil, llama.ErrInvalidArgument.Withf("invalid import mode %q", mode)
+	}
+
+	// Make the path absolute, so that a symlink can be followed from the store
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return nil, llama.ErrInvalidArgument.Withf("invalid path %q: %v", path, err)
+	}
+
+	// Determine the destination, which for a split model names the set of files
+	if dest == "" {
+		dest = filepath.Base(path)
+	}
+	if name := splitName(dest); name != "" {
+		dest = name
+	}
+	dest, err = modelPath(dest)
+	if err != nil {
+		return nil, err
+	}
+
+	// Validate the source files and determine their destinations
+	srcs := splitPaths(path)
+	dests := []string{dest}
+	if len(srcs) > 1 {
+		dests = splitPaths(splitPath(strings.TrimSuffix(dest, gguf.FileExtension), 1, len(srcs)))
+	}
+	for _, src := range srcs {
+		if err := validateModel(src); err != nil {
+			return nil, err
+		}
+	}
+
+	// Copy the files to temporary files in the store, so they can be renamed
+	// into place once all of them are complete
+	var sum string
+	if mode == schema.ImportCopy {
+		for i, src := range srcs {
+			temp, fileSum, err := s.copyTemp(ctx, src)
+			if err != nil {
+				return nil, err
+			}
+			defer os.Remove(temp)
+			srcs[i], sum = temp, fileSum
+		}
+	}
+	if len(srcs) > 1 {
+		sum = ""
+	}
+
+	// Move or link the files into the store
+	s.Lock()
+	defer s.Unlock()
+	return s.install(srcs, dests, mode, fileURL(path), sum)
+}
+
+// WriteModel writes a model file read from r into the store at dest, a path
+// relative to the store, and returns the model. At most limit bytes are read
+// if limit is positive, and ErrTooLarge is returned for a larger file. The
+// file is written to a temporary file, which is renamed into place once it
+// is complete and valid, and dest must not already exist. A split model is
+// written one file at a time, and is listed once all of its files exist. The
+// time of the upload and the checksum of the file are written to the sidecar
+// metadata file.
+func (s *Store) WriteModel(ctx context.Context, dest string, r io.Reader, limit int64) (*schema.Model, error) {
+	dest, err := modelPath(dest)
+	if err != nil {
+		return nil, err
+	}
+
+	// Check the destination before reading the file
+	if _, err := os.Lstat(filepath.Join(s.path, dest)); err == nil {
+		return nil, llama.ErrInvalidArgument.Withf("model already exists at %s", dest)
+	}
+
+	// Write and validate the temporary file
+	temp, sum, err := s.writeTemp(ctx, r, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(temp)
+	if err := validateModel(temp); err != nil {
+		return nil, err
+	}
+
+	// Rename the file into place
+	s.Lock()
+	defer s.Unlock()
+	return s.install([]string{temp}, []string{dest}, schema.ImportCopy, "", sum)
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// install moves each source file into the store at the corresponding path
+// relative to the store, or links it for the hardlink and symlink modes, and
+// returns the model. The source and checksum are written to the sidecar
+// metadata file of the model. The caller must hold the lock. If any file
+// cannot be installed, those already installed are removed.
+func (s *Store) install(srcs, dests []string, mode schema.ImportMode, source, sum string) (*schema.Model, error) {
+	// Check the destinations do not exist
+	for _, dest := range dests {
+		if _, err := os.Lstat(filepath.Join(s.path, dest)); err == nil {
+			return nil, llama.ErrInvalidArgument.Withf("model already exists at %s", dest)
+		}
+	}
+
+	// Move or link the files
+	installed := make([]string, 0, len(dests))
+	for i, dest := range dests {
+		destPath := filepath.Join(s.path, dest)
+		if err := os.MkdirAll(filepath.Dir(destPath), 0755); err != nil {
+			removeAll(installed)
+			return nil, llama.ErrOpenFailed.Withf("failed to create directory: %v", err)
+		}
+		var err error
+		switch mode {
+		case schema.ImportHardlink:
+			err = os.Link(srcs[i], destPath)
+		case schema.ImportSymlink:
+			err = os.Symlink(srcs[i], destPath)
+		default:
+			err = os.Rename(srcs[i], destPath)
+		}
+		if err != nil {
+			removeAll(installed)
+			return nil, llama.ErrOpenFailed.Withf("failed to import model: %v", err)
+		}
+		installed = append(installed, destPath)
+	}
+
+	// Load the models, combining the files of a split model
+	models := make([]*schema.Model, 0, len(installed))
+	for _, path := range installed {
+		model, err := s.loadModel(path)
+		if err != nil {
+			removeAll(installed)
+			return nil, err
+		}
+		models = append(models, model)
+	}
+	_ = s.index.save()
+	model := models[0]
+	if merged := mergeSplits(models); len(merged) == 1 {
+		model = merged[0]
+	}
+
+	// The model is in the store, so failing to write the metadata is not an error
+	if meta, err := setProvenance(installed[0], source, sum, nil); err == nil {
+		model.ModelMeta = meta
+	}
+	return model, nil
+}
+
+// copyTemp copies the file at path to a temporary file in the store
+// directory, and returns the path and checksum of the temporary file
+func (s *Store) copyTemp(ctx context.Context, path string) (string, string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", "", llama.ErrOpenFailed.Withf("%s: %v", filepath.Base(path), err)
+	}
+	defer f.Close()
+	return s.writeTemp(ctx, f, 0)
+}
+
+// writeTemp writes the contents of r to a temporary file in the store
+// directory, reading at most limit bytes if limit is positive, and returns
+// the path and sha256 checksum of the temporary file
+func (s *Store) writeTemp(ctx context.Context, r io.Reader, limit int64) (string, string, error) {
+	f, err := os.CreateTemp(s.path, tempPattern)
+	if err != nil {
+		return "", "", llama.ErrOpenFailed.Withf("failed to create temporary file: %v", err)
+	}
+
+	// Read one byte more than the limit, to detect a file which is too large
+	if limit > 0 {
+		r = io.LimitReader(r, limit+1)
+	}
+	h := sha256.New()
+	n, err := io.Copy(io.MultiWriter(f, h), &contextReader{ctx: ctx, r: r})
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+
+	switch {
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		os.Remove(f.Name())
+		return "", "", err
+	case err != nil:
+		os.Remove(f.Name())
+		return "", "", llama.ErrOpenFailed.Withf("failed to write model: %v", err)
+	case limit > 0 && n > limit:
+		os.Remove(f.Name())
+		return "", "", llama.ErrTooLarge.Withf("model is larger than %d bytes", limit)
+	}
+	return f.Name(), hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Read implements io.Reader, returning the context error once it is done
+func (r *contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.r.Read(p)
+}
+
+// validateModel returns an error if the file at path is not a readable
+// GGUF file
+func validateModel(path string) error {
+	if info, err := os.Stat(path); err != nil {
+		return llama.ErrNotFound.Withf("%s: %v", filepath.Base(path), err)
+	} else if !info.Mode().IsRegular() {
+		return llama.ErrInvalidArgument.Withf("not a file: %s", filepath.Base(path))
+	}
+	ctx, err := gguf.Open(path)
+	if err != nil {
+		return err
+	}
+	return ctx.Close()
+}
+
+// modelPath returns the cleaned path of a model relative to the store,
+// with the GGUF file extension. Paths outside the store, and hidden files
+// and directories, are not allowed.
+func modelPath(path string) (string, error) {
+	path = filepath.Clean(filepath.FromSlash(path))
+	if !filepath.IsLocal(path) {
+		return "", llama.ErrInvalidArgument.Withf("invalid model path %q", path)
+	}
+	for _, elem := range strings.Split(path, string(filepath.Separator)) {
+		if strings.HasPrefix(elem, ".") {
+			return "", llama.ErrInvalidArgument.Withf("invalid model path %q", path)
+		}
+	}
+	if filepath.Ext(path) != gguf.FileExtension {
+		path += gguf.FileExtension
+	}
+	return path, nil
+}
+
+// removeAll removes the files at the paths, ignoring errors
+func removeAll(paths []string) {
+	for _, path := range paths {
+		_ = os.Remove(path)
+	}
+}
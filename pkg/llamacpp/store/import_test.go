@@ -0,0 +1,155 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	// Packages
+	llama "github.com/mutablelogic/go-llama"
+	schema "github.com/mutablelogic/go-llama/pkg/llamacpp/schema"
+	assert "github.com/stretchr/testify/assert"
+	require "github.com/stretchr/testify/require"
+)
+
+// tempFiles returns the temporary files left in the store directory
+func tempFiles(t *testing.T, dir string) []string {
+	t.Helper()
+	matches, err := filepath.Glob(filepath.Join(dir, tempPattern))
+	require.NoError(t, err)
+	return matches
+}
+
+func TestModelPath(t *testing.T) {
+	assert := assert.New(t)
+
+	tests := map[string]string{
+		"model.gguf":        "model.gguf",
+		"model":             "model.gguf",
+		"llama/model.gguf":  filepath.Join("llama", "model.gguf"),
+		"./llama/../a.gguf": "a.gguf",
+	}
+	for path, want := range tests {
+		got, err := modelPath(path)
+		if assert.NoError(err, path) {
+			assert.Equal(want, got, path)
+		}
+	}
+
+	for _, path := range []string{"", "/model.gguf", "../model.gguf", ".hidden.gguf", "llama/.cache/model.gguf"} {
+		_, err := modelPath(path)
+		assert.ErrorIs(err, llama.ErrInvalidArgument, path)
+	}
+}
+
+func TestStore_ImportModel(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	src := copyTestModel(t, t.TempDir(), "stories.gguf")
+	store, err := New(t.TempDir())
+	require.NoError(err)
+
+	// Import the file with each mode
+	for _, mode := range []schema.ImportMode{"", schema.ImportCopy, schema.ImportHardlink, schema.ImportSymlink} {
+		dest := filepath.Join("imported", "stories-"+string(mode)+".gguf")
+		model, err := store.ImportModel(context.Background(), src, dest, mode)
+		require.NoError(err, mode)
+		assert.Equal(dest, model.Path, mode)
+		assert.Equal("llama", model.Architecture, mode)
+	}
+	info, err := os.Lstat(filepath.Join(store.Path(), "imported", "stories-symlink.gguf"))
+	require.NoError(err)
+	assert.Equal(os.ModeSymlink, info.Mode().Type())
+
+	// The default destination is the filename
+	model, err := store.ImportModel(context.Background(), src, "", schema.ImportCopy)
+	require.NoError(err)
+	assert.Equal("stories.gguf", model.Path)
+	models, err := store.ListModels(context.Background())
+	require.NoError(err)
+	assert.Len(models, 5)
+
+	// The destination must not exist
+	_, err = store.ImportModel(context.Background(), src, "", schema.ImportCopy)
+	assert.ErrorIs(err, llama.ErrInvalidArgument)
+
+	// Invalid arguments
+	_, err = store.ImportModel(context.Background(), src, "other.gguf", "move")
+	assert.ErrorIs(err, llama.ErrInvalidArgument)
+	_, err = store.ImportModel(context.Background(), src, "../other.gguf", schema.ImportCopy)
+	assert.ErrorIs(err, llama.ErrInvalidArgument)
+	_, err = store.ImportModel(context.Background(), filepath.Join(t.TempDir(), "missing.gguf"), "", schema.ImportCopy)
+	assert.ErrorIs(err, llama.ErrNotFound)
+
+	// Invalid GGUF files are not imported
+	invalid := filepath.Join(t.TempDir(), "invalid.gguf")
+	require.NoError(os.WriteFile(invalid, []byte("not a gguf file"), 0644))
+	_, err = store.ImportModel(context.Background(), invalid, "", schema.ImportCopy)
+	assert.Error(err)
+	assert.NoFileExists(filepath.Join(store.Path(), "invalid.gguf"))
+	assert.Empty(tempFiles(t, store.Path()))
+}
+
+func TestStore_ImportSplitModel(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	dir := t.TempDir()
+	copyTestModel(t, dir, "big-00001-of-00002.gguf")
+	src := copyTestModel(t, dir, "big-00002-of-00002.gguf")
+	store, err := New(t.TempDir())
+	require.NoError(err)
+
+	// All the files are imported and named from the destination
+	model, err := store.ImportModel(context.Background(), src, "llama/large", schema.ImportHardlink)
+	require.NoError(err)
+	assert.Equal(filepath.Join("llama", "large-00001-of-00002.gguf"), model.Path)
+	assert.Equal(2, model.Shards)
+	assert.FileExists(filepath.Join(store.Path(), "llama", "large-00002-of-00002.gguf"))
+
+	// An incomplete split model is not imported
+	os.Remove(src)
+	_, err = store.ImportModel(context.Background(), filepath.Join(dir, "big-00001-of-00002.gguf"), "other", schema.ImportCopy)
+	assert.ErrorIs(err, llama.ErrNotFound)
+	assert.NoFileExists(filepath.Join(store.Path(), "other-00001-of-00002.gguf"))
+}
+
+func TestStore_WriteModel(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	data, err := os.ReadFile(filepath.Join(testdataPath, "stories260K.gguf"))
+	require.NoError(err)
+	store, err := New(t.TempDir())
+	require.NoError(err)
+
+	// Files larger than the limit are refused
+	_, err = store.WriteModel(context.Background(), "stories.gguf", bytes.NewReader(data), int64(len(data)-1))
+	assert.ErrorIs(err, llama.ErrTooLarge)
+	assert.NoFileExists(filepath.Join(store.Path(), "stories.gguf"))
+
+	// Invalid files are refused
+	_, err = store.WriteModel(context.Background(), "invalid.gguf", bytes.NewReader([]byte("not a gguf file")), 0)
+	assert.Error(err)
+	assert.NoFileExists(filepath.Join(store.Path(), "invalid.gguf"))
+
+	// The file is written within the limit
+	model, err := store.WriteModel(context.Background(), "llama/stories", bytes.NewReader(data), int64(len(data)))
+	require.NoError(err)
+	assert.Equal(filepath.Join("llama", "stories.gguf"), model.Path)
+	assert.Equal(uint64(len(data)), model.Size)
+
+	// The destination must not exist
+	_, err = store.WriteModel(context.Background(), "llama/stories.gguf", bytes.NewReader(data), 0)
+	assert.ErrorIs(err, llama.ErrInvalidArgument)
+
+	// Cancelled writes are removed
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = store.WriteModel(ctx, "cancelled.gguf", bytes.NewReader(data), 0)
+	assert.ErrorIs(err, context.Canceled)
+	assert.Empty(tempFiles(t, store.Path()))
+}
@@ -0,0 +1,178 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	// Packages
+	schema "github.com/mutablelogic/go-llama/pkg/llamacpp/schema"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// index is a persistent cache of model metadata, keyed by the path of each
+// model relative to the store root. Entries are validated against the file
+// size and modification time, so unchanged files are not parsed again.
+type index struct {
+	sync.Mutex
+	path    string
+	entries map[string]*indexEntry
+	dirty   bool
+}
+
+// indexEntry is the cached metadata for a single file. A nil Model records
+// a file which is not a valid GGUF model, so it is skipped until it changes.
+type indexEntry struct {
+	Size    int64         `json:"size"`
+	ModTime time.Time     `json:"modtime"`
+	Model   *schema.Model `json:"model,omitempty"`
+}
+
+// indexFile is the on-disk format of the index
+type indexFile struct {
+	Version int                    `json:"version"`
+	Models  map[string]*indexEntry `json:"models"`
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	// Name of the index file in the store root. It is hidden, so it is not
+	// scanned as a model.
+	indexFilename = ".gguf-index.json"
+
+	// Version of the index format. Increment when the metadata derived from
+	// a GGUF file changes, so that existing indexes are rebuilt.
+	indexVersion = 3
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// LIFECYCLE
+
+// newIndex returns the index stored in the file at path, loading any existing
+// index file. A missing, unreadable or outdated index file is ignored.
+func newIndex(path string) *index {
+	idx := &index{
+		path:    path,
+		entries: make(map[string]*indexEntry),
+	}
+	if data, err := os.ReadFile(idx.path); err == nil {
+		var file indexFile
+		if err := json.Unmarshal(data, &file); err == nil && file.Version == indexVersion && file.Models != nil {
+			idx.entries = file.Models
+		}
+	}
+	return idx
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// get returns the cached model for the file at relPath, and true if the cache
+// entry matches the file size and modification time
+func (idx *index) get(relPath string, info os.FileInfo) (*schema.Model, bool) {
+	idx.Lock()
+	defer idx.Unlock()
+	entry, exists := idx.entries[relPath]
+	if !exists || entry.Size != info.Size() || !entry.ModTime.Equal(info.ModTime()) {
+		return nil, false
+	}
+	return cloneModel(entry.Model), true
+}
+
+// put sets the cached model for the file at relPath
+func (idx *index) put(relPath string, info os.FileInfo, model *schema.Model) {
+	idx.Lock()
+	defer idx.Unlock()
+	idx.entries[relPath] = &indexEntry{
+		Size:    info.Size(),
+		ModTime: info.ModTime(),
+		Model:   cloneModel(model),
+	}
+	idx.dirty = true
+}
+
+// remove removes the cached model for the file at relPath
+func (idx *index) remove(relPath string) {
+	idx.Lock()
+	defer idx.Unlock()
+	if _, exists := idx.entries[relPath]; exists {
+		delete(idx.entries, relPath)
+		idx.dirty = true
+	}
+}
+
+// retain removes all entries which are not in paths
+func (idx *index) retain(paths map[string]bool) {
+	idx.Lock()
+	defer idx.Unlock()
+	for relPath := range idx.entries {
+		if !paths[relPath] {
+			delete(idx.entries, relPath)
+			idx.dirty = true
+		}
+	}
+}
+
+// models returns the valid models in the index, sorted by path
+func (idx *index) models() []*schema.Model {
+	idx.Lock()
+	defer idx.Unlock()
+	result := make([]*schema.Model, 0, len(idx.entries))
+	for _, entry := range idx.entries {
+		if entry.Model != nil {
+			result = append(result, cloneModel(entry.Model))
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Path < result[j].Path
+	})
+	return result
+}
+
+// save writes the index file if it has changed. The file is written to a
+// temporary file and renamed, so a partially written index is never read.
+func (idx *index) save() error {
+	idx.Lock()
+	defer idx.Unlock()
+	if !idx.dirty {
+		return nil
+	}
+
+	data, err := json.Marshal(indexFile{Version: indexVersion, Models: idx.entries})
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(idx.path), ".gguf-index-*.tmp")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	if err = errors.Join(err, tmp.Close()); err == nil {
+		err = os.Rename(tmp.Name(), idx.path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	idx.dirty = false
+	return nil
+}
+
+// cloneModel returns a shallow copy of the model, so that callers cannot
+// modify the cached entry
+func cloneModel(model *schema.Model) *schema.Model {
+	if model == nil {
+		return nil
+	}
+	clone := *model
+	return &clone
+}
@@ -0,0 +1,143 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	llama "github.com/mutablelogic/go-llama"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// copyTestModel copies the stories260K test model into dir with the given name
+func copyTestModel(t *testing.T, dir, name string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(testdataPath, "stories260K.gguf"))
+	require.NoError(t, err)
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.WriteFile(path, data, 0644))
+	return path
+}
+
+func TestIndex_Persisted(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	tempDir := t.TempDir()
+	path := copyTestModel(t, tempDir, "stories260K.gguf")
+
+	// Scanning writes the index
+	store, err := New(tempDir)
+	require.NoError(err)
+	models, err := store.ListModels(context.Background())
+	require.NoError(err)
+	require.Len(models, 1)
+	assert.FileExists(filepath.Join(tempDir, indexFilename))
+
+	// Change the cached name in the index file
+	data, err := os.ReadFile(filepath.Join(tempDir, indexFilename))
+	require.NoError(err)
+	var file indexFile
+	require.NoError(json.Unmarshal(data, &file))
+	require.Contains(file.Models, "stories260K.gguf")
+	file.Models["stories260K.gguf"].Model.Name = "cached"
+	data, err = json.Marshal(file)
+	require.NoError(err)
+	require.NoError(os.WriteFile(filepath.Join(tempDir, indexFilename), data, 0644))
+
+	// A new store uses the cached metadata for the unchanged file
+	store, err = New(tempDir)
+	require.NoError(err)
+	model, err := store.GetModel(context.Background(), "stories260K.gguf")
+	require.NoError(err)
+	assert.Equal("cached", model.Name)
+
+	// Modifying the file causes it to be parsed again
+	later := time.Now().Add(time.Minute)
+	require.NoError(os.Chtimes(path, later, later))
+	model, err = store.GetModel(context.Background(), "stories260K.gguf")
+	require.NoError(err)
+	assert.Equal("llama", model.Name)
+}
+
+func TestIndex_OutdatedVersion(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	tempDir := t.TempDir()
+	copyTestModel(t, tempDir, "stories260K.gguf")
+	data, err := json.Marshal(indexFile{Version: indexVersion + 1, Models: map[string]*indexEntry{}})
+	require.NoError(err)
+	require.NoError(os.WriteFile(filepath.Join(tempDir, indexFilename), data, 0644))
+
+	store, err := New(tempDir)
+	require.NoError(err)
+	models, err := store.ListModels(context.Background())
+	require.NoError(err)
+	assert.Len(models, 1)
+}
+
+func TestIndex_InvalidAndDeleted(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	tempDir := t.TempDir()
+	copyTestModel(t, tempDir, "a.gguf")
+	copyTestModel(t, tempDir, "b.gguf")
+	require.NoError(os.WriteFile(filepath.Join(tempDir, "invalid.gguf"), []byte("not a model"), 0644))
+
+	store, err := New(tempDir)
+	require.NoError(err)
+	models, err := store.ListModels(context.Background())
+	require.NoError(err)
+	assert.Len(models, 2)
+
+	// Invalid files are recorded, so they are not parsed again
+	store.index.Lock()
+	assert.Contains(store.index.entries, "invalid.gguf")
+	assert.Nil(store.index.entries["invalid.gguf"].Model)
+	store.index.Unlock()
+
+	// Deleted models are removed from the index
+	require.NoError(store.DeleteModel(context.Background(), "a.gguf"))
+	require.NoError(os.Remove(filepath.Join(tempDir, "invalid.gguf")))
+	models, err = store.ListModels(context.Background())
+	require.NoError(err)
+	assert.Len(models, 1)
+	store.index.Lock()
+	assert.Len(store.index.entries, 1)
+	store.index.Unlock()
+}
+
+func TestStore_Watch(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	tempDir := t.TempDir()
+	copyTestModel(t, tempDir, "a.gguf")
+
+	store, err := New(tempDir)
+	require.NoError(err)
+	assert.ErrorIs(store.Watch(context.Background(), 0), llama.ErrInvalidArgument)
+
+	// Start watching
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- store.Watch(ctx, 10*time.Millisecond) }()
+	defer func() {
+		cancel()
+		assert.NoError(<-done)
+	}()
+
+	// New files appear in the listing after the next poll
+	require.Eventually(func() bool { return store.watching.Load() }, time.Second, 5*time.Millisecond)
+	copyTestModel(t, tempDir, "b.gguf")
+	assert.Eventually(func() bool {
+		models, err := store.ListModels(context.Background())
+		return err == nil && len(models) == 2
+	}, 5*time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,131 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	// Packages
+	llama "github.com/mutablelogic/go-llama"
+	schema "github.com/mutablelogic/go-llama/pkg/llamacpp/schema"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	// metaExt is appended to the path of a model file for the path of its
+	// sidecar metadata file, which is not scanned as a model
+	metaExt = ".meta.json"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// UpdateModelMeta modifies the labels and notes of a model by name with
+// edit, writes them to the sidecar file of the model, and returns the model.
+// Labels are trimmed, and empty and duplicate labels are removed. Returns
+// ErrReadOnly for a model in a read-only directory.
+func (s *Store) UpdateModelMeta(ctx context.Context, name string, edit func(*schema.ModelMeta)) (*schema.Model, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	// Find the model
+	model, err := s.getModel(ctx, name)
+	if err != nil {
+		return nil, err
+	} else if model.ReadOnly {
+		return nil, llama.ErrReadOnly.Withf("model %q is in read-only directory %s", model.Path, model.Root)
+	}
+
+	// Edit and write the metadata
+	meta := model.ModelMeta
+	meta.Labels = append([]string(nil), meta.Labels...)
+	edit(&meta)
+	meta.Labels = cleanLabels(meta.Labels)
+	if err := writeMeta(s.FilePath(model), meta); err != nil {
+		return nil, err
+	}
+	model.ModelMeta = meta
+
+	// Return success
+	return model, nil
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// readMeta returns the metadata in the sidecar file of the model file at
+// path, or false if there is no readable sidecar file
+func readMeta(path string) (schema.ModelMeta, bool) {
+	var meta schema.ModelMeta
+	data, err := os.ReadFile(path + metaExt)
+	if err != nil {
+		return meta, false
+	} else if err := json.Unmarshal(data, &meta); err != nil {
+		return meta, false
+	}
+	return meta, true
+}
+
+// writeMeta writes the sidecar file of the model file at path. The file is
+// written to a temporary file and renamed, so a partially written file is
+// never read.
+func writeMeta(path string, meta schema.ModelMeta) error {
+	data, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), tempPattern)
+	if err != nil {
+		return llama.ErrOpenFailed.Withf("failed to write metadata: %v", err)
+	}
+	_, err = tmp.Write(data)
+	if err = errors.Join(err, tmp.Close()); err == nil {
+		err = os.Rename(tmp.Name(), path+metaExt)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return llama.ErrOpenFailed.Withf("failed to write metadata: %v", err)
+	}
+	return nil
+}
+
+// setProvenance sets the source, time, checksum and preset in the sidecar
+// file of the model file at path, keeping any existing labels and notes, and
+// returns the metadata
+func setProvenance(path, source, sum string, preset *schema.ModelPreset) (schema.ModelMeta, error) {
+	meta, _ := readMeta(path)
+	meta.Source = source
+	meta.PulledAt = time.Now()
+	meta.SHA256 = sum
+	meta.Preset = preset
+	return meta, writeMeta(path, meta)
+}
+
+// cleanLabels returns the labels with surrounding whitespace trimmed, and
+// empty labels and duplicates, compared without regard to case, removed
+func cleanLabels(labels []string) []string {
+	result := make([]string, 0, len(labels))
+	for _, label := range labels {
+		label = strings.TrimSpace(label)
+		if label == "" || (schema.ModelMeta{Labels: result}).HasLabel(label) {
+			continue
+		}
+		result = append(result, label)
+	}
+	if len(result) == 0 {
+		return nil
+	}
+	return result
+}
+
+// fileURL returns the URL of a local file, as the source of an imported model
+func fileURL(path string) string {
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(path)}).String()
+}
@@ -0,0 +1,120 @@
+package store
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	// Packages
+	llama "github.com/mutablelogic/go-llama"
+	schema "github.com/mutablelogic/go-llama/pkg/llamacpp/schema"
+	assert "github.com/stretchr/testify/assert"
+	require "github.com/stretchr/testify/require"
+)
+
+// testModelSum returns the sha256 checksum of the test model
+func testModelSum(t *testing.T) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(testdataPath, "stories260K.gguf"))
+	require.NoError(t, err)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func TestCleanLabels(t *testing.T) {
+	assert := assert.New(t)
+	assert.Nil(cleanLabels(nil))
+	assert.Nil(cleanLabels([]string{"", "  "}))
+	assert.Equal([]string{"prod", "embedding"}, cleanLabels([]string{" prod ", "embedding", "PROD", ""}))
+}
+
+func TestStore_PullModel_Meta(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	_, server := newPullServer(t)
+	store, err := New(t.TempDir())
+	require.NoError(err)
+
+	// The source, time and checksum of a pulled model are recorded
+	url := server.URL + "/model.gguf"
+	model, err := store.PullModel(context.Background(), url, nil)
+	require.NoError(err)
+	assert.Equal(url, model.Source)
+	assert.Equal(testModelSum(t), model.SHA256)
+	assert.False(model.PulledAt.IsZero())
+	assert.FileExists(filepath.Join(store.Path(), "model.gguf"+metaExt))
+
+	// The sidecar file is not listed as a model
+	models, err := store.ListModels(context.Background())
+	require.NoError(err)
+	require.Len(models, 1)
+	assert.Equal(url, models[0].Source)
+}
+
+func TestStore_ImportModel_Meta(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	src := copyTestModel(t, t.TempDir(), "stories.gguf")
+	store, err := New(t.TempDir())
+	require.NoError(err)
+
+	// A copied file has a checksum
+	model, err := store.ImportModel(context.Background(), src, "copy.gguf", schema.ImportCopy)
+	require.NoError(err)
+	assert.True(strings.HasPrefix(model.Source, "file://"))
+	assert.True(strings.HasSuffix(model.Source, "/stories.gguf"))
+	assert.Equal(testModelSum(t), model.SHA256)
+	assert.False(model.PulledAt.IsZero())
+
+	// A linked file does not
+	model, err = store.ImportModel(context.Background(), src, "link.gguf", schema.ImportSymlink)
+	require.NoError(err)
+	assert.NotEmpty(model.Source)
+	assert.Empty(model.SHA256)
+}
+
+func TestStore_UpdateModelMeta(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	store, _ := newLayeredStore(t)
+
+	// Labels and notes are cleaned and persisted
+	model, err := store.UpdateModelMeta(context.Background(), "local", func(meta *schema.ModelMeta) {
+		meta.Labels = []string{" approved-prod", "embedding", "Embedding"}
+		meta.Notes = "Used by search"
+	})
+	require.NoError(err)
+	assert.Equal([]string{"approved-prod", "embedding"}, model.Labels)
+
+	other, err := New(store.Path())
+	require.NoError(err)
+	model, err = other.GetModel(context.Background(), "local")
+	require.NoError(err)
+	assert.Equal([]string{"approved-prod", "embedding"}, model.Labels)
+	assert.Equal("Used by search", model.Notes)
+
+	// Editing keeps the fields which are not changed
+	model, err = other.UpdateModelMeta(context.Background(), "local", func(meta *schema.ModelMeta) {
+		meta.Labels = nil
+	})
+	require.NoError(err)
+	assert.Empty(model.Labels)
+	assert.Equal("Used by search", model.Notes)
+
+	// Models in read-only directories cannot be edited
+	_, err = store.UpdateModelMeta(context.Background(), "shared", func(meta *schema.ModelMeta) {})
+	assert.ErrorIs(err, llama.ErrReadOnly)
+	_, err = store.UpdateModelMeta(context.Background(), "missing", func(meta *schema.ModelMeta) {})
+	assert.ErrorIs(err, llama.ErrNotFound)
+
+	// The sidecar file is removed with the model
+	require.NoError(store.DeleteModel(context.Background(), "local"))
+	assert.NoFileExists(filepath.Join(store.Path(), "local.gguf"+metaExt))
+}
@@ -0,0 +1,37 @@
+package store
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+type opt struct {
+	// Hugging Face token, overriding the default token
+	token string
+}
+
+// Opt is an option to set on a request to a remote repository.
+type Opt func(*opt) error
+
+///////////////////////////////////////////////////////////////////////////////
+// LIFECYCLE
+
+func applyOpts(opts ...Opt) (*opt, error) {
+	o := new(opt)
+	for _, opt := range opts {
+		if err := opt(o); err != nil {
+			return nil, err
+		}
+	}
+	return o, nil
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// OPTIONS
+
+// WithToken sets the Hugging Face token for the request, for gated and
+// private repositories. It overrides the token from the environment.
+func WithToken(token string) Opt {
+	return func(o *opt) error {
+		o.token = token
+		return nil
+	}
+}
@@ -0,0 +1,395 @@
+package store
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	// Packages
+	client "github.com/mutablelogic/go-client"
+	llama "github.com/mutablelogic/go-llama"
+	httpresponse "github.com/mutablelogic/go-server/pkg/httpresponse"
+	types "github.com/mutablelogic/go-server/pkg/types"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// remoteFile describes the file at a download URL, from a HEAD request
+type remoteFile struct {
+	Filename string
+	Size     uint64
+	ETag     string // Entity tag for conditional range requests
+	SHA256   string // Expected checksum, if known
+}
+
+// pullState is the sidecar state of a partial download, which is used to
+// check that the remote file is unchanged before resuming
+type pullState struct {
+	URL    string `json:"url"`
+	Size   uint64 `json:"size,omitempty"`
+	ETag   string `json:"etag,omitempty"`
+	SHA256 string `json:"sha256,omitempty"`
+}
+
+// partialWriter appends to a partial download, hashing the file contents
+type partialWriter struct {
+	f *os.File
+	h hash.Hash
+}
+
+// linkedHeadersKey is the context key for collecting X-Linked-* headers
+// from redirect responses
+type linkedHeadersKey struct{}
+
+///////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	headerRange        = "Range"
+	headerIfRange      = "If-Range"
+	headerContentRange = "Content-Range"
+	headerETag         = "ETag"
+	headerLinkedETag   = "X-Linked-Etag"
+	headerLinkedSize   = "X-Linked-Size"
+	pullStateExt       = ".json"
+	defaultPullRetries = 5
+	defaultPullBackoff = time.Second
+	maxPullBackoff     = 30 * time.Second
+	maxRedirects       = 10
+)
+
+var (
+	// errRangeIgnored is returned when resuming a download from a server
+	// which returned the whole file, and the partial file cannot be reset
+	errRangeIgnored = llama.ErrInvalidArgument.With("server does not support range requests")
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS - DOWNLOAD
+
+// pull downloads httpURL into the partial file at path, resuming from any
+// existing partial download of the same remote file. Transient failures are
+// retried with exponential backoff. The file is verified against the expected
+// sha256 checksum, which is sum if not empty, or else is read from the
+// response headers when known. On success the sidecar state is removed, and
+// the checksum of the file is returned.
+func (c *Client) pull(ctx context.Context, path string, httpURL *url.URL, sum string, fn ClientCallback, opts []client.RequestOpt) (string, error) {
+	// Describe the remote file
+	remote, err := c.head(ctx, httpURL, opts)
+	if err != nil {
+		return "", err
+	} else if sum != "" {
+		remote.SHA256 = sum
+	}
+
+	// Open the partial file, discarding it if the remote file has changed
+	state := pullState{URL: httpURL.String(), Size: remote.Size, ETag: remote.ETag, SHA256: remote.SHA256}
+	w, err := openPartial(path, state)
+	if err != nil {
+		return "", err
+	}
+	defer w.f.Close()
+
+	// Call callback with size info. If callback returns error, abort download
+	offset := w.size()
+	if fn != nil {
+		if err := fn(remote.Filename, offset, remote.Size); err != nil {
+			return "", err
+		}
+	}
+
+	// Download the remainder of the file, retrying transient errors
+	backoff := c.backoff
+	for attempt := 0; ; attempt++ {
+		offset = w.size()
+		if remote.Size > 0 && offset >= remote.Size {
+			break
+		}
+		err = c.get(ctx, w, httpURL, remote, offset, fn, opts)
+		if err == nil || (offset > 0 && remote.Size == 0 && isStatus(err, http.StatusRequestedRangeNotSatisfiable)) {
+			// Complete, or the partial file is already complete when the size is unknown
+			break
+		} else if attempt >= c.retries || !isTransient(ctx, err) {
+			// An invalid file cannot be resumed
+			if errors.Is(err, llama.ErrInvalidModel) {
+				removePartial(path)
+			}
+			return "", err
+		}
+
+		// Wait before retrying
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, maxPullBackoff)
+	}
+
+	// Verify the size and checksum
+	if remote.Size > 0 && w.size() != remote.Size {
+		return "", llama.ErrInvalidModel.Withf("downloaded %d bytes, expected %d", w.size(), remote.Size)
+	}
+	sum = hex.EncodeToString(w.h.Sum(nil))
+	if remote.SHA256 != "" && sum != remote.SHA256 {
+		removePartial(path)
+		return "", llama.ErrInvalidModel.Withf("sha256 checksum mismatch: got %s, expected %s", sum, remote.SHA256)
+	}
+
+	// Remove the sidecar state, the partial file is now complete
+	if err := w.f.Close(); err != nil {
+		return "", err
+	}
+	_ = os.Remove(path + pullStateExt)
+
+	return sum, nil
+}
+
+// head returns information about the remote file. X-Linked-* headers on
+// redirect responses (as used by Hugging Face for LFS files) take precedence,
+// and for Hugging Face URLs the checksum is otherwise read from the tree API.
+func (c *Client) head(ctx context.Context, httpURL *url.URL, opts []client.RequestOpt) (*remoteFile, error) {
+	var headers http.Header
+	linked := make(http.Header)
+	headReq := client.NewRequestEx(http.MethodHead, client.ContentTypeAny)
+	headOpts := append([]client.RequestOpt{client.OptReqEndpoint(httpURL.String())}, opts...)
+	if err := c.DoWithContext(context.WithValue(ctx, linkedHeadersKey{}, linked), headReq, &headUnmarshaler{headers: &headers}, headOpts...); err != nil {
+		return nil, err
+	}
+	for key, values := range linked {
+		if headers.Get(key) == "" {
+			headers[key] = values
+		}
+	}
+
+	remote := new(remoteFile)
+	if size, err := strconv.ParseUint(headers.Get(types.ContentLengthHeader), 10, 64); err == nil {
+		remote.Size = size
+	}
+	if size, err := strconv.ParseUint(headers.Get(headerLinkedSize), 10, 64); err == nil {
+		remote.Size = size
+	}
+	if disposition := headers.Get(types.ContentDispositonHeader); disposition != "" {
+		if _, params, err := mime.ParseMediaType(disposition); err == nil {
+			remote.Filename = params["filename"]
+		}
+	}
+
+	// Strong entity tags are used for conditional range requests
+	if etag := headers.Get(headerETag); etag != "" && !strings.HasPrefix(etag, "W/") {
+		remote.ETag = etag
+	}
+
+	// The checksum of an LFS file is its linked entity tag, or is read from the API
+	if sum := parseSHA256(headers.Get(headerLinkedETag)); sum != "" {
+		remote.SHA256 = sum
+	} else if repo, branch, filePath, ok := c.parseHuggingFaceURL(httpURL); ok {
+		if sum, err := c.hfSHA256(ctx, repo, branch, filePath, opts); err == nil {
+			remote.SHA256 = sum
+		}
+	}
+
+	return remote, nil
+}
+
+// get downloads the file from offset, appending to the partial file
+func (c *Client) get(ctx context.Context, w *partialWriter, httpURL *url.URL, remote *remoteFile, offset uint64, fn ClientCallback, opts []client.RequestOpt) error {
+	model := NewClientModel(w, fn)
+	model.filename = remote.Filename
+	model.size = remote.Size
+	model.n = offset
+
+	reqOpts := []client.RequestOpt{client.OptReqEndpoint(httpURL.String())}
+	if offset > 0 {
+		reqOpts = append(reqOpts, client.OptReqHeader(headerRange, fmt.Sprintf("bytes=%d-", offset)))
+		if remote.ETag != "" {
+			reqOpts = append(reqOpts, client.OptReqHeader(headerIfRange, remote.ETag))
+		}
+	}
+	reqOpts = append(reqOpts, opts...)
+
+	return c.DoWithContext(ctx, client.MethodGet, model, reqOpts...)
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS - PARTIAL FILES
+
+// openPartial opens the partial file at path for appending. If the sidecar
+// state does not match the remote file, any existing partial file is
+// truncated. The existing contents are hashed so the checksum covers the
+// whole file when the download is resumed.
+func openPartial(path string, state pullState) (*partialWriter, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, llama.ErrOpenFailed.Withf("failed to open partial file: %v", err)
+	}
+	w := &partialWriter{f: f, h: sha256.New()}
+
+	// Check the sidecar state
+	var existing pullState
+	resume := false
+	if data, err := os.ReadFile(path + pullStateExt); err == nil && json.Unmarshal(data, &existing) == nil {
+		if info, err := f.Stat(); err == nil {
+			resume = existing == state && (state.Size == 0 || uint64(info.Size()) <= state.Size)
+		}
+	}
+
+	// Truncate or hash the existing contents
+	if !resume {
+		err = w.Reset()
+	} else {
+		_, err = io.Copy(w.h, f)
+	}
+	if err == nil {
+		err = writeState(path+pullStateExt, state)
+	}
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return w, nil
+}
+
+// removePartial removes a partial file and its sidecar state
+func removePartial(path string) {
+	_ = os.Remove(path)
+	_ = os.Remove(path + pullStateExt)
+}
+
+// partialPath returns the path of the partial download of url in the
+// directory dir, which is hidden so it is not scanned as a model
+func partialPath(dir, url string) string {
+	sum := sha256.Sum256([]byte(url))
+	return filepath.Join(dir, ".gguf-"+hex.EncodeToString(sum[:8])+".partial")
+}
+
+// writeState writes the sidecar state of a partial download
+func writeState(path string, state pullState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// Write appends to the partial file
+func (w *partialWriter) Write(p []byte) (int, error) {
+	n, err := w.f.Write(p)
+	w.h.Write(p[:n])
+	return n, err
+}
+
+// Reset truncates the partial file, when the download restarts from the beginning
+func (w *partialWriter) Reset() error {
+	if err := w.f.Truncate(0); err != nil {
+		return err
+	} else if _, err := w.f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	w.h.Reset()
+	return nil
+}
+
+// size returns the number of bytes in the partial file
+func (w *partialWriter) size() uint64 {
+	if offset, err := w.f.Seek(0, io.SeekCurrent); err == nil {
+		return uint64(offset)
+	}
+	return 0
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS - HELPERS
+
+// isTransient returns true if a download error may succeed when retried:
+// network errors, truncated responses, timeouts and server errors
+func isTransient(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	var httpErr httpresponse.Err
+	if errors.As(err, &httpErr) {
+		code := int(httpErr)
+		return code == http.StatusRequestTimeout || code == http.StatusTooManyRequests || code >= http.StatusInternalServerError
+	}
+	var llamaErr llama.Error
+	var pathErr *fs.PathError
+	return !errors.As(err, &llamaErr) && !errors.As(err, &pathErr)
+}
+
+// isStatus returns true if the error is a HTTP error with the given status code
+func isStatus(err error, code int) bool {
+	var httpErr httpresponse.Err
+	return errors.As(err, &httpErr) && int(httpErr) == code
+}
+
+// checkRedirect follows redirects, collecting X-Linked-* headers from the
+// redirect responses when requested through the request context
+func checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= maxRedirects {
+		return fmt.Errorf("stopped after %d redirects", maxRedirects)
+	}
+	if linked, ok := req.Context().Value(linkedHeadersKey{}).(http.Header); ok && req.Response != nil {
+		for _, key := range []string{headerLinkedETag, headerLinkedSize} {
+			if value := req.Response.Header.Get(key); value != "" {
+				linked.Set(key, value)
+			}
+		}
+	}
+	return nil
+}
+
+// parseContentRange parses a "bytes start-end/total" header, where the
+// total may be "*" when unknown
+func parseContentRange(value string) (start, total uint64, ok bool) {
+	spec, found := strings.CutPrefix(value, "bytes ")
+	if !found {
+		return 0, 0, false
+	}
+	rng, size, found := strings.Cut(spec, "/")
+	if !found {
+		return 0, 0, false
+	}
+	first, _, found := strings.Cut(rng, "-")
+	if !found {
+		return 0, 0, false
+	}
+	start, err := strconv.ParseUint(first, 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	if size != "*" {
+		if total, err = strconv.ParseUint(size, 10, 64); err != nil {
+			return 0, 0, false
+		}
+	}
+	return start, total, true
+}
+
+// parseSHA256 returns a lowercase sha256 hex digest from an entity tag or
+// object id, or an empty string if the value is not a sha256 digest
+func parseSHA256(value string) string {
+	value = strings.ToLower(strings.Trim(strings.TrimPrefix(value, "W/"), `"`))
+	if len(value) != sha256.Size*2 {
+		return ""
+	} else if _, err := hex.DecodeString(value); err != nil {
+		return ""
+	}
+	return value
+}
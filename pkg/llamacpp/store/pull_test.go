@@ -0,0 +1,223 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	// Packages
+	llama "github.com/mutablelogic/go-llama"
+	assert "github.com/stretchr/testify/assert"
+	require "github.com/stretchr/testify/require"
+)
+
+// pullServer serves a model file with range requests. Requests to /redirect
+// are redirected to the file with X-Linked-* headers, as Hugging Face does
+// for LFS files.
+type pullServer struct {
+	sync.Mutex
+	data     []byte
+	etag     string
+	checksum string // Value of the X-Linked-Etag header
+	abort    int    // Number of GET responses to truncate
+	ranges   []string
+	served   int
+}
+
+func newPullServer(t *testing.T) (*pullServer, *httptest.Server) {
+	data, err := os.ReadFile(filepath.Join(testdataPath, "stories260K.gguf"))
+	require.NoError(t, err)
+	sum := sha256.Sum256(data)
+	s := &pullServer{data: data, etag: `"v1"`, checksum: `"` + hex.EncodeToString(sum[:]) + `"`}
+	server := httptest.NewServer(s)
+	t.Cleanup(server.Close)
+	return s, server
+}
+
+func (s *pullServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.Lock()
+	defer s.Unlock()
+
+	if r.URL.Path == "/redirect" {
+		w.Header().Set(headerLinkedETag, s.checksum)
+		w.Header().Set(headerLinkedSize, "1185376")
+		http.Redirect(w, r, "/model.gguf", http.StatusFound)
+		return
+	}
+
+	w.Header().Set(headerETag, s.etag)
+	if r.Method != http.MethodGet {
+		http.ServeContent(w, r, "model.gguf", time.Time{}, bytes.NewReader(s.data))
+		return
+	}
+	s.ranges = append(s.ranges, r.Header.Get(headerRange))
+
+	// Truncate the response, closing the connection half way through the file
+	if s.abort > 0 {
+		s.abort--
+		w.Header().Set("Content-Length", "1185376")
+		w.WriteHeader(http.StatusOK)
+		w.Write(s.data[:len(s.data)/2])
+		s.served += len(s.data) / 2
+		panic(http.ErrAbortHandler)
+	}
+
+	rec := httptest.NewRecorder()
+	rec.Header().Set(headerETag, s.etag)
+	http.ServeContent(rec, r, "model.gguf", time.Time{}, bytes.NewReader(s.data))
+	s.served += rec.Body.Len()
+	for key, values := range rec.Header() {
+		w.Header()[key] = values
+	}
+	w.WriteHeader(rec.Code)
+	w.Write(rec.Body.Bytes())
+}
+
+func newPullClient(t *testing.T) *Client {
+	client, err := NewClient()
+	require.NoError(t, err)
+	client.backoff = time.Millisecond
+	return client
+}
+
+func TestClient_PullModel_Writer(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	s, server := newPullServer(t)
+	client := newPullClient(t)
+
+	// The model is written to the writer, without resuming
+	var buf bytes.Buffer
+	var received uint64
+	destPath, err := client.PullModel(context.Background(), &buf, server.URL+"/model.gguf", func(_ string, n, _ uint64) error {
+		received = n
+		return nil
+	})
+	require.NoError(err)
+	assert.Equal("model.gguf", destPath)
+	assert.Equal(s.data, buf.Bytes())
+	assert.Equal(uint64(len(s.data)), received)
+}
+
+func TestClient_PullResume(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	s, server := newPullServer(t)
+	s.abort = 1
+	client := newPullClient(t)
+
+	// The truncated response is resumed with a range request
+	path := filepath.Join(t.TempDir(), "model.partial")
+	destPath, err := client.PullModelFile(context.Background(), path, server.URL+"/redirect", nil)
+	require.NoError(err)
+	assert.Equal("redirect", destPath)
+	assert.Equal([]string{"", "bytes=592688-"}, s.ranges)
+	assert.Equal(len(s.data), s.served)
+
+	// The file is complete and the sidecar state is removed
+	data, err := os.ReadFile(path)
+	require.NoError(err)
+	assert.True(bytes.Equal(s.data, data), "files differ")
+	assert.NoFileExists(path + pullStateExt)
+}
+
+func TestClient_PullPartial(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	s, server := newPullServer(t)
+	client := newPullClient(t)
+	path := filepath.Join(t.TempDir(), "model.partial")
+	state := pullState{URL: server.URL + "/redirect", Size: uint64(len(s.data)), ETag: s.etag, SHA256: s.checksum[1 : len(s.checksum)-1]}
+
+	// Existing partial file with matching state is resumed
+	require.NoError(os.WriteFile(path, s.data[:1000], 0644))
+	require.NoError(writeState(path+pullStateExt, state))
+	var first uint64
+	_, err := client.PullModelFile(context.Background(), path, state.URL, func(_ string, received, _ uint64) error {
+		if first == 0 {
+			first = received
+		}
+		return nil
+	})
+	require.NoError(err)
+	assert.Equal(uint64(1000), first)
+	assert.Equal([]string{"bytes=1000-"}, s.ranges)
+	assert.Equal(len(s.data)-1000, s.served)
+	data, err := os.ReadFile(path)
+	require.NoError(err)
+	assert.True(bytes.Equal(s.data, data), "files differ")
+
+	// Existing partial file for a different version of the file is discarded
+	s.ranges, s.served = nil, 0
+	state.ETag = `"v0"`
+	require.NoError(os.WriteFile(path, s.data[:1000], 0644))
+	require.NoError(writeState(path+pullStateExt, state))
+	_, err = client.PullModelFile(context.Background(), path, state.URL, nil)
+	require.NoError(err)
+	assert.Equal([]string{""}, s.ranges)
+	assert.Equal(len(s.data), s.served)
+}
+
+func TestClient_PullChecksum(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	s, server := newPullServer(t)
+	s.checksum = `"` + hex.EncodeToString(make([]byte, sha256.Size)) + `"`
+	client := newPullClient(t)
+
+	// A file which does not match the checksum is removed
+	path := filepath.Join(t.TempDir(), "model.partial")
+	_, err := client.PullModelFile(context.Background(), path, server.URL+"/redirect", nil)
+	require.ErrorIs(err, llama.ErrInvalidModel)
+	assert.Contains(err.Error(), "checksum")
+	assert.NoFileExists(path)
+	assert.NoFileExists(path + pullStateExt)
+}
+
+func TestStore_PullModel_Resume(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	s, server := newPullServer(t)
+	s.abort = 1
+	store, err := New(t.TempDir())
+	require.NoError(err)
+	store.client.retries = 0
+
+	// Without retries, the interrupted download is kept
+	url := server.URL + "/model.gguf"
+	_, err = store.PullModel(context.Background(), url, nil)
+	require.Error(err)
+	assert.FileExists(partialPath(store.Path(), url))
+	data, err := os.ReadFile(partialPath(store.Path(), url) + pullStateExt)
+	require.NoError(err)
+	var state pullState
+	require.NoError(json.Unmarshal(data, &state))
+	assert.Equal(url, state.URL)
+
+	// Partial files are not listed as models
+	models, err := store.ListModels(context.Background())
+	require.NoError(err)
+	assert.Empty(models)
+
+	// Pulling again resumes the download
+	model, err := store.PullModel(context.Background(), url, nil)
+	require.NoError(err)
+	assert.Equal("model.gguf", model.Path)
+	assert.Equal([]string{"", "bytes=592688-"}, s.ranges)
+	assert.NoFileExists(partialPath(store.Path(), url))
+	assert.NoFileExists(partialPath(store.Path(), url) + pullStateExt)
+}
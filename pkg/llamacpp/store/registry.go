@@ -0,0 +1,420 @@
+package store
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	// Packages
+	client "github.com/mutablelogic/go-client"
+	llama "github.com/mutablelogic/go-llama"
+	schema "github.com/mutablelogic/go-llama/pkg/llamacpp/schema"
+	gguf "github.com/mutablelogic/go-llama/sys/gguf"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// registryRef is a reference to a model in an Ollama or OCI registry, as
+// "ollama://[host/][namespace/]model[:tag]" or "oci://host/name[:tag|@digest]"
+type registryRef struct {
+	Host   string // Host and port of the registry
+	Name   string // Repository name, e.g. "library/llama3.2"
+	Tag    string // Tag, or empty when the digest is set
+	Digest string // Manifest digest, e.g. "sha256:..."
+}
+
+// registryManifest is an image manifest, in the Docker v2 or OCI format
+type registryManifest struct {
+	SchemaVersion int                  `json:"schemaVersion"`
+	MediaType     string               `json:"mediaType,omitempty"`
+	Config        registryDescriptor   `json:"config"`
+	Layers        []registryDescriptor `json:"layers"`
+}
+
+// registryDescriptor describes a blob in a registry
+type registryDescriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Size        uint64            `json:"size"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// registryModel is a model resolved from its manifest, with the URL and
+// digest of the model layer, and the preset from the other layers
+type registryModel struct {
+	URL      *url.URL            // URL of the model layer blob
+	SHA256   string              // Checksum of the model layer
+	DestPath string              // Path of the model in the store
+	Preset   *schema.ModelPreset // Chat template, system prompt and parameters, or nil
+	opts     []client.RequestOpt // Options for requests to the registry
+}
+
+// registryToken is the response from a registry token service
+type registryToken struct {
+	Token       string `json:"token"`
+	AccessToken string `json:"access_token"`
+}
+
+// jsonUnmarshaler decodes a JSON response of any content type, such as a
+// manifest, which is read up to a limit
+type jsonUnmarshaler struct {
+	v any
+}
+
+// blobUnmarshaler reads a small blob, up to a limit
+type blobUnmarshaler struct {
+	data []byte
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	schemeOCI          = "oci"
+	schemeOllama       = "ollama"
+	hostOllama         = "registry.ollama.ai"
+	ollamaNamespace    = "library"
+	defaultTag         = "latest"
+	headerAccept       = "Accept"
+	headerAuthenticate = "WWW-Authenticate"
+	registryBlobLimit  = 1 << 20 // Maximum size of a manifest, template or params blob
+)
+
+const (
+	mediaTypeDockerManifest = "application/vnd.docker.distribution.manifest.v2+json"
+	mediaTypeOCIManifest    = "application/vnd.oci.image.manifest.v1+json"
+	mediaTypeOllamaModel    = "application/vnd.ollama.image.model"
+	mediaTypeOllamaTemplate = "application/vnd.ollama.image.template"
+	mediaTypeOllamaSystem   = "application/vnd.ollama.image.system"
+	mediaTypeOllamaParams   = "application/vnd.ollama.image.params"
+	annotationTitle         = "org.opencontainers.image.title"
+)
+
+var (
+	reRegistryName   = regexp.MustCompile(`^[a-z0-9]+(?:[._-][a-z0-9]+)*(?:/[a-z0-9]+(?:[._-][a-z0-9]+)*)*$`)
+	reRegistryTag    = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_.-]{0,127}$`)
+	reRegistryParams = regexp.MustCompile(`(\w+)="([^"]*)"`)
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS - STORE
+
+// pullRegistryModel downloads the model layer of a model in an Ollama or OCI
+// registry into the store, and writes the source, checksum and preset to the
+// sidecar metadata file of the model
+func (s *Store) pullRegistryModel(ctx context.Context, rawURL string, callback ClientCallback, opts []Opt) (*schema.Model, error) {
+	// Resolve the manifest
+	remote, err := s.client.resolveRegistryModel(ctx, rawURL, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	// Download the model layer
+	model, sum, err := s.pullInto(remote.URL.String(), remote.DestPath, callback, func(path string, fn ClientCallback) (string, error) {
+		return s.client.pull(ctx, path, remote.URL, remote.SHA256, fn, remote.opts)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Record where the model came from, unless it was already in the store.
+	// The model is in the store, so failing to write the metadata is not an error
+	if sum != "" && !model.ReadOnly {
+		if meta, err := setProvenance(s.FilePath(model), rawURL, sum, remote.Preset); err == nil {
+			model.ModelMeta = meta
+		}
+	}
+
+	// Return the model
+	return model, nil
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS - CLIENT
+
+// resolveRegistryModel reads the manifest of a model in a registry, and
+// returns the model layer to download, and the chat template, system prompt
+// and parameters layers as a preset. Blobs are verified against their digests.
+func (c *Client) resolveRegistryModel(ctx context.Context, rawURL string, opts ...Opt) (*registryModel, error) {
+	o, err := applyOpts(opts...)
+	if err != nil {
+		return nil, err
+	}
+	ref, err := parseRegistryRef(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	// Authorize requests to the repository
+	reqOpts, err := c.registryAuth(ctx, ref, o)
+	if err != nil {
+		return nil, err
+	}
+
+	// Read the manifest
+	var manifest registryManifest
+	manifestOpts := append([]client.RequestOpt{
+		client.OptReqEndpoint(ref.url("manifests", ref.reference()).String()),
+		client.OptReqHeader(headerAccept, mediaTypeOCIManifest+", "+mediaTypeDockerManifest),
+	}, reqOpts...)
+	if err := c.DoWithContext(ctx, client.NewRequest(), &jsonUnmarshaler{v: &manifest}, manifestOpts...); err != nil {
+		return nil, err
+	} else if manifest.SchemaVersion != 2 {
+		return nil, llama.ErrInvalidModel.Withf("unsupported manifest for %q: schema version %d", rawURL, manifest.SchemaVersion)
+	}
+
+	// Find the model layer, and read the preset layers
+	result := &registryModel{DestPath: ref.destPath(), opts: reqOpts}
+	for _, layer := range manifest.Layers {
+		switch {
+		case result.URL == nil && layer.isModel():
+			sum, ok := strings.CutPrefix(layer.Digest, "sha256:")
+			if !ok || parseSHA256(sum) == "" {
+				return nil, llama.ErrInvalidModel.Withf("unsupported digest for model layer: %q", layer.Digest)
+			}
+			result.URL, result.SHA256 = ref.url("blobs", layer.Digest), sum
+		case layer.MediaType == mediaTypeOllamaTemplate, layer.MediaType == mediaTypeOllamaSystem, layer.MediaType == mediaTypeOllamaParams:
+			data, err := c.registryBlob(ctx, ref, layer, reqOpts)
+			if err != nil {
+				return nil, err
+			}
+			if result.Preset == nil {
+				result.Preset = new(schema.ModelPreset)
+			}
+			switch layer.MediaType {
+			case mediaTypeOllamaTemplate:
+				result.Preset.Template = string(data)
+			case mediaTypeOllamaSystem:
+				result.Preset.System = string(data)
+			case mediaTypeOllamaParams:
+				if err := json.Unmarshal(data, &result.Preset.Params); err != nil {
+					return nil, llama.ErrInvalidModel.Withf("invalid params layer: %v", err)
+				}
+			}
+		}
+	}
+	if result.URL == nil {
+		return nil, llama.ErrNotFound.Withf("no GGUF model layer in %q", rawURL)
+	}
+
+	// Return success
+	return result, nil
+}
+
+// registryAuth returns the options to authorize requests to the repository.
+// A registry which challenges anonymous requests for a bearer token is sent
+// to its token service, with any credentials for the registry host.
+func (c *Client) registryAuth(ctx context.Context, ref *registryRef, o *opt) ([]client.RequestOpt, error) {
+	endpoint := ref.url()
+	token := c.authorization(endpoint, o)
+
+	// Probe the manifest for a challenge
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, ref.url("manifests", ref.reference()).String(), nil)
+	if err != nil {
+		return nil, llama.ErrInvalidArgument.Withf("invalid registry URL: %v", err)
+	}
+	req.Header.Set(headerAccept, mediaTypeOCIManifest+", "+mediaTypeDockerManifest)
+	resp, err := c.Client.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	realm, params, ok := parseChallenge(resp.Header.Get(headerAuthenticate))
+	if resp.StatusCode != http.StatusUnauthorized || !ok {
+		if token.Value != "" {
+			return []client.RequestOpt{client.OptToken(token)}, nil
+		}
+		return nil, nil
+	}
+
+	// Request a token for the scope
+	realmURL, err := url.Parse(realm)
+	if err != nil || (realmURL.Scheme != schemeHTTP && realmURL.Scheme != schemeHTTPS) {
+		return nil, llama.ErrInvalidArgument.Withf("invalid token realm: %q", realm)
+	}
+	query := realmURL.Query()
+	for _, key := range []string{"service", "scope"} {
+		if value := params[key]; value != "" {
+			query.Set(key, value)
+		}
+	}
+	if query.Get("scope") == "" {
+		query.Set("scope", "repository:"+ref.Name+":pull")
+	}
+	realmURL.RawQuery = query.Encode()
+	tokenOpts := []client.RequestOpt{client.OptReqEndpoint(realmURL.String())}
+	if token.Value != "" {
+		tokenOpts = append(tokenOpts, client.OptToken(token))
+	}
+	var response registryToken
+	if err := c.DoWithContext(ctx, client.NewRequest(), &response, tokenOpts...); err != nil {
+		return nil, err
+	}
+	if response.Token == "" {
+		response.Token = response.AccessToken
+	}
+	if response.Token == "" {
+		return nil, llama.ErrInvalidArgument.Withf("no token from %s", realmURL.Host)
+	}
+	return []client.RequestOpt{client.OptToken(client.Token{Scheme: client.Bearer, Value: response.Token})}, nil
+}
+
+// registryBlob returns the contents of a small blob, verified against its digest
+func (c *Client) registryBlob(ctx context.Context, ref *registryRef, layer registryDescriptor, opts []client.RequestOpt) ([]byte, error) {
+	if layer.Size > registryBlobLimit {
+		return nil, llama.ErrTooLarge.Withf("layer %s is %d bytes", layer.Digest, layer.Size)
+	}
+	var blob blobUnmarshaler
+	blobOpts := append([]client.RequestOpt{client.OptReqEndpoint(ref.url("blobs", layer.Digest).String())}, opts...)
+	if err := c.DoWithContext(ctx, client.NewRequestEx(http.MethodGet, client.ContentTypeAny), &blob, blobOpts...); err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(blob.data)
+	if "sha256:"+hex.EncodeToString(sum[:]) != layer.Digest {
+		return nil, llama.ErrInvalidModel.Withf("digest mismatch for layer %s", layer.Digest)
+	}
+	return blob.data, nil
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS - REFERENCES
+
+// isRegistryURL returns true for an Ollama or OCI registry URL
+func isRegistryURL(rawURL string) bool {
+	return strings.HasPrefix(rawURL, schemeOllama+"://") || strings.HasPrefix(rawURL, schemeOCI+"://")
+}
+
+// parseRegistryRef parses an "ollama://" or "oci://" URL. For Ollama, the
+// host defaults to the Ollama registry and the namespace to "library". The
+// first part of the path is a host if it contains a dot or port, or is
+// "localhost". The tag defaults to "latest".
+func parseRegistryRef(rawURL string) (*registryRef, error) {
+	scheme, rest, _ := strings.Cut(rawURL, "://")
+	if scheme != schemeOllama && scheme != schemeOCI {
+		return nil, llama.ErrInvalidArgument.Withf("unsupported registry URL: %q", rawURL)
+	}
+	ref := new(registryRef)
+
+	// Host
+	if first, after, found := strings.Cut(rest, "/"); found && (strings.ContainsAny(first, ".:") || first == "localhost") {
+		ref.Host, rest = first, after
+	} else if scheme == schemeOCI {
+		return nil, llama.ErrInvalidArgument.Withf("invalid oci:// URL, expected oci://host/name[:tag]: %q", rawURL)
+	} else {
+		ref.Host = hostOllama
+	}
+
+	// Digest or tag
+	if name, digest, found := strings.Cut(rest, "@"); found {
+		rest, ref.Digest = name, digest
+		if sum, ok := strings.CutPrefix(digest, "sha256:"); !ok || parseSHA256(sum) == "" {
+			return nil, llama.ErrInvalidArgument.Withf("invalid digest in %q", rawURL)
+		}
+	} else if i := strings.LastIndex(rest, ":"); i > strings.LastIndex(rest, "/") {
+		rest, ref.Tag = rest[:i], rest[i+1:]
+	} else {
+		ref.Tag = defaultTag
+	}
+	if ref.Digest == "" && !reRegistryTag.MatchString(ref.Tag) {
+		return nil, llama.ErrInvalidArgument.Withf("invalid tag in %q", rawURL)
+	}
+
+	// Name
+	ref.Name = strings.Trim(rest, "/")
+	if scheme == schemeOllama && ref.Name != "" && !strings.Contains(ref.Name, "/") {
+		ref.Name = ollamaNamespace + "/" + ref.Name
+	}
+	if !reRegistryName.MatchString(ref.Name) {
+		return nil, llama.ErrInvalidArgument.Withf("invalid repository name in %q", rawURL)
+	}
+
+	// Return success
+	return ref, nil
+}
+
+// reference returns the tag or digest of the manifest
+func (r *registryRef) reference() string {
+	if r.Digest != "" {
+		return r.Digest
+	}
+	return r.Tag
+}
+
+// url returns the URL of the registry API for the repository. Registries on
+// loopback addresses are accessed over HTTP, and others over HTTPS.
+func (r *registryRef) url(elem ...string) *url.URL {
+	scheme := schemeHTTPS
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	if ip := net.ParseIP(host); host == "localhost" || (ip != nil && ip.IsLoopback()) {
+		scheme = schemeHTTP
+	}
+	u := &url.URL{Scheme: scheme, Host: r.Host, Path: "/v2/"}
+	if len(elem) > 0 {
+		u.Path = path.Join(append([]string{"/v2", r.Name}, elem...)...)
+	}
+	return u
+}
+
+// destPath returns the path of the model in the store, named from the
+// repository and the tag, or the start of the digest
+func (r *registryRef) destPath() string {
+	name := path.Base(r.Name)
+	tag := r.Tag
+	if tag == "" {
+		tag = strings.ReplaceAll(r.Digest, ":", "-")[:len("sha256-")+12]
+	}
+	return filepath.Join(name, name+"-"+tag+gguf.FileExtension)
+}
+
+// isModel returns true if the layer is a GGUF model
+func (d registryDescriptor) isModel() bool {
+	return d.MediaType == mediaTypeOllamaModel ||
+		strings.Contains(strings.ToLower(d.MediaType), "gguf") ||
+		strings.HasSuffix(d.Annotations[annotationTitle], gguf.FileExtension)
+}
+
+// parseChallenge parses a "Bearer realm=...,service=...,scope=..." header
+func parseChallenge(value string) (string, map[string]string, bool) {
+	scheme, rest, _ := strings.Cut(value, " ")
+	if !strings.EqualFold(scheme, client.Bearer) {
+		return "", nil, false
+	}
+	params := make(map[string]string)
+	for _, match := range reRegistryParams.FindAllStringSubmatch(rest, -1) {
+		params[strings.ToLower(match[1])] = match[2]
+	}
+	return params["realm"], params, params["realm"] != ""
+}
+
+// Unmarshal decodes the response as JSON
+func (j *jsonUnmarshaler) Unmarshal(_ http.Header, r io.Reader) error {
+	if err := json.NewDecoder(io.LimitReader(r, registryBlobLimit)).Decode(j.v); err != nil {
+		return llama.ErrInvalidModel.Withf("invalid manifest: %v", err)
+	}
+	return nil
+}
+
+// Unmarshal reads the response, up to the limit
+func (b *blobUnmarshaler) Unmarshal(_ http.Header, r io.Reader) error {
+	data, err := io.ReadAll(io.LimitReader(r, registryBlobLimit+1))
+	if err != nil {
+		return err
+	} else if len(data) > registryBlobLimit {
+		return llama.ErrTooLarge.Withf("blob is larger than %d bytes", registryBlobLimit)
+	}
+	b.data = data
+	return nil
+}
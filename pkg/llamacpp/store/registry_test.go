@@ -0,0 +1,203 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	// Packages
+	llama "github.com/mutablelogic/go-llama"
+	assert "github.com/stretchr/testify/assert"
+	require "github.com/stretchr/testify/require"
+)
+
+// registryServer is a registry stand-in, which serves a manifest for each
+// repository and tag, and blobs by digest. If token is set, requests must
+// have the bearer token, which is issued by the /token endpoint.
+type registryServer struct {
+	*httptest.Server
+	manifests map[string]registryManifest // Manifests by "name:tag"
+	blobs     map[string][]byte           // Blobs by digest
+	token     string
+}
+
+func newRegistryServer(t *testing.T) *registryServer {
+	s := &registryServer{
+		manifests: make(map[string]registryManifest),
+		blobs:     make(map[string][]byte),
+	}
+	s.Server = httptest.NewServer(s)
+	t.Cleanup(s.Close)
+	return s
+}
+
+// layer adds a blob and returns its descriptor
+func (s *registryServer) layer(mediaType string, data []byte) registryDescriptor {
+	sum := sha256.Sum256(data)
+	digest := "sha256:" + hex.EncodeToString(sum[:])
+	s.blobs[digest] = data
+	return registryDescriptor{MediaType: mediaType, Digest: digest, Size: uint64(len(data))}
+}
+
+// host returns the host and port of the registry
+func (s *registryServer) host() string {
+	return strings.TrimPrefix(s.URL, "http://")
+}
+
+func (s *registryServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/token" {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(registryToken{Token: s.token})
+		return
+	}
+	if s.token != "" && r.Header.Get("Authorization") != "Bearer "+s.token {
+		w.Header().Set(headerAuthenticate, `Bearer realm="`+s.URL+`/token",service="registry"`)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	name, ref, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/v2/"), "/manifests/")
+	if manifest, ok := s.manifests[name+":"+ref]; ok {
+		w.Header().Set("Content-Type", mediaTypeDockerManifest)
+		json.NewEncoder(w).Encode(manifest)
+		return
+	}
+	if i := strings.Index(r.URL.Path, "/blobs/"); i >= 0 {
+		if data, ok := s.blobs[r.URL.Path[i+len("/blobs/"):]]; ok {
+			http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(data))
+			return
+		}
+	}
+	http.NotFound(w, r)
+}
+
+func TestParseRegistryRef(t *testing.T) {
+	assert := assert.New(t)
+
+	tests := map[string]registryRef{
+		"ollama://llama3.2":                                            {Host: hostOllama, Name: "library/llama3.2", Tag: "latest"},
+		"ollama://llama3.2:1b":                                         {Host: hostOllama, Name: "library/llama3.2", Tag: "1b"},
+		"ollama://team/model:q4_K_M":                                   {Host: hostOllama, Name: "team/model", Tag: "q4_K_M"},
+		"ollama://localhost:5000/model":                                {Host: "localhost:5000", Name: "library/model", Tag: "latest"},
+		"oci://registry.example.com/ml/llama":                          {Host: "registry.example.com", Name: "ml/llama", Tag: "latest"},
+		"oci://localhost/ml/llama:v1":                                  {Host: "localhost", Name: "ml/llama", Tag: "v1"},
+		"oci://r.example.com/llama@sha256:" + strings.Repeat("ab", 32): {Host: "r.example.com", Name: "llama", Digest: "sha256:" + strings.Repeat("ab", 32)},
+	}
+	for rawURL, want := range tests {
+		ref, err := parseRegistryRef(rawURL)
+		if assert.NoError(err, rawURL) {
+			assert.Equal(want, *ref, rawURL)
+		}
+	}
+
+	for _, rawURL := range []string{"oci://llama", "ollama://", "ollama://Llama", "ollama://llama:", "oci://r.example.com/llama@sha256:1234", "https://example.com/llama"} {
+		_, err := parseRegistryRef(rawURL)
+		assert.ErrorIs(err, llama.ErrInvalidArgument, rawURL)
+	}
+
+	// Paths in the store, and API URLs
+	ref, err := parseRegistryRef("ollama://llama3.2:1b")
+	require.NoError(t, err)
+	assert.Equal(filepath.Join("llama3.2", "llama3.2-1b.gguf"), ref.destPath())
+	assert.Equal("https://registry.ollama.ai/v2/library/llama3.2/manifests/1b", ref.url("manifests", ref.reference()).String())
+	ref, err = parseRegistryRef("oci://127.0.0.1:5000/llama@sha256:" + strings.Repeat("ab", 32))
+	require.NoError(t, err)
+	assert.Equal(filepath.Join("llama", "llama-sha256-abababababab.gguf"), ref.destPath())
+	assert.Equal("http://127.0.0.1:5000/v2/", ref.url().String())
+}
+
+func TestStore_PullModel_Registry(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	data, err := os.ReadFile(filepath.Join(testdataPath, "stories260K.gguf"))
+	require.NoError(err)
+	registry := newRegistryServer(t)
+	registry.token = "secret"
+	registry.manifests["library/tiny:latest"] = registryManifest{
+		SchemaVersion: 2,
+		MediaType:     mediaTypeDockerManifest,
+		Layers: []registryDescriptor{
+			registry.layer(mediaTypeOllamaModel, data),
+			registry.layer(mediaTypeOllamaTemplate, []byte("{{ .Prompt }}")),
+			registry.layer(mediaTypeOllamaSystem, []byte("You are a storyteller.")),
+			registry.layer(mediaTypeOllamaParams, []byte(`{"stop": ["</s>"], "temperature": 0.7}`)),
+		},
+	}
+	store, err := New(t.TempDir())
+	require.NoError(err)
+
+	// The model layer is pulled with a token, and the other layers are kept as a preset
+	url := "ollama://" + registry.host() + "/tiny"
+	model, err := store.PullModel(context.Background(), url, nil)
+	require.NoError(err)
+	assert.Equal(filepath.Join("tiny", "tiny-latest.gguf"), model.Path)
+	assert.Equal(url, model.Source)
+	assert.Equal(testModelSum(t), model.SHA256)
+	require.NotNil(model.Preset)
+	assert.Equal("{{ .Prompt }}", model.Preset.Template)
+	assert.Equal("You are a storyteller.", model.Preset.System)
+	assert.Equal(0.7, model.Preset.Params["temperature"])
+	assert.Equal([]any{"</s>"}, model.Preset.Params["stop"])
+
+	// The preset is read from the sidecar file
+	other, err := New(store.Path())
+	require.NoError(err)
+	model, err = other.GetModel(context.Background(), model.Path)
+	require.NoError(err)
+	require.NotNil(model.Preset)
+	assert.Equal("You are a storyteller.", model.Preset.System)
+
+	// Pulling again finds the model in the store
+	model, err = store.PullModel(context.Background(), url, nil)
+	require.NoError(err)
+	assert.Equal(filepath.Join("tiny", "tiny-latest.gguf"), model.Path)
+
+	// Unknown tags are not found
+	_, err = store.PullModel(context.Background(), url+":missing", nil)
+	assert.Error(err)
+}
+
+func TestStore_PullModel_OCI(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	data, err := os.ReadFile(filepath.Join(testdataPath, "stories260K.gguf"))
+	require.NoError(err)
+	registry := newRegistryServer(t)
+
+	// A GGUF layer of an OCI artifact is pulled, without a preset
+	layer := registry.layer("application/vnd.docker.ai.gguf.v3", data)
+	registry.manifests["ml/stories:v1"] = registryManifest{SchemaVersion: 2, MediaType: mediaTypeOCIManifest, Layers: []registryDescriptor{layer}}
+	store, err := New(t.TempDir())
+	require.NoError(err)
+	model, err := store.PullModel(context.Background(), "oci://"+registry.host()+"/ml/stories:v1", nil)
+	require.NoError(err)
+	assert.Equal(filepath.Join("stories", "stories-v1.gguf"), model.Path)
+	assert.Nil(model.Preset)
+
+	// A manifest without a model layer is not pulled
+	registry.manifests["ml/empty:v1"] = registryManifest{SchemaVersion: 2, Layers: []registryDescriptor{registry.layer(mediaTypeOllamaTemplate, []byte("{{ .Prompt }}"))}}
+	_, err = store.PullModel(context.Background(), "oci://"+registry.host()+"/ml/empty:v1", nil)
+	assert.ErrorIs(err, llama.ErrNotFound)
+
+	// A blob which does not match its digest is removed
+	corrupt := append([]byte(nil), data...)
+	corrupt[len(corrupt)-1] ^= 0xff
+	registry.blobs[layer.Digest] = corrupt
+	registry.manifests["ml/corrupt:v1"] = registryManifest{SchemaVersion: 2, Layers: []registryDescriptor{layer}}
+	_, err = store.PullModel(context.Background(), "oci://"+registry.host()+"/ml/corrupt:v1", nil)
+	require.ErrorIs(err, llama.ErrInvalidModel)
+	assert.Contains(err.Error(), "checksum")
+	assert.NoFileExists(filepath.Join(store.Path(), "corrupt", "corrupt-v1.gguf"))
+	assert.Empty(tempFiles(t, store.Path()))
+}
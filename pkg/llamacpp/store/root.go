@@ -0,0 +1,179 @@
+package store
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	// Packages
+	llama "github.com/mutablelogic/go-llama"
+	schema "github.com/mutablelogic/go-llama/pkg/llamacpp/schema"
+	gguf "github.com/mutablelogic/go-llama/sys/gguf"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// root is a directory of models in the store, with an index of their
+// metadata. Models in a read-only root cannot be deleted or replaced.
+type root struct {
+	path     string
+	readonly bool
+	index    *index
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	// Format of the name of the index file for a read-only root, which is
+	// kept in the writable root and named from the read-only root path
+	readonlyIndexFormat = ".gguf-index-%s.json"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// LIFECYCLE
+
+// newRoot returns the root for the directory at path, which must exist. The
+// index is kept in the directory, or for a read-only root in the directory
+// indexDir.
+func newRoot(path string, readonly bool, indexDir string) (*root, error) {
+	if info, err := os.Stat(path); err != nil {
+		return nil, llama.ErrOpenFailed.Withf("%s: %v", path, err)
+	} else if !info.IsDir() {
+		return nil, llama.ErrInvalidArgument.Withf("not a directory: %s", path)
+	}
+	path = filepath.Clean(path)
+
+	// Determine the path of the index file
+	indexPath := filepath.Join(path, indexFilename)
+	if readonly {
+		sum := sha256.Sum256([]byte(path))
+		indexPath = filepath.Join(indexDir, fmt.Sprintf(readonlyIndexFormat, hex.EncodeToString(sum[:8])))
+	}
+
+	return &root{path: path, readonly: readonly, index: newIndex(indexPath)}, nil
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// models returns the valid models in the index, sorted by path
+func (r *root) models() []*schema.Model {
+	models := r.index.models()
+	for _, model := range models {
+		r.mark(model)
+	}
+	return models
+}
+
+// scan walks the root directory, updating the index for new or changed
+// files and removing files which no longer exist
+func (r *root) scan(ctx context.Context) ([]*schema.Model, error) {
+	// Walk the directory looking for .gguf files
+	var models []*schema.Model
+	seen := make(map[string]bool)
+	err := filepath.Walk(r.path, func(path string, info os.FileInfo, err error) error {
+		// Check for context cancellation, or other errors
+		if ctx.Err() != nil {
+			return ctx.Err()
+		} else if err != nil {
+			return err
+		}
+
+		// Skip hidden files and directories
+		if strings.HasPrefix(info.Name(), ".") {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		} else if info.IsDir() {
+			return nil
+		} else if filepath.Ext(path) != gguf.FileExtension {
+			return nil
+		}
+
+		// Try to load model metadata, skip if invalid
+		if relPath, err := filepath.Rel(r.path, path); err == nil {
+			seen[relPath] = true
+		}
+		if model, err := r.loadModel(path); err == nil && model != nil {
+			models = append(models, model)
+		}
+
+		// Return success
+		return nil
+	})
+
+	// Remove deleted files from the index, and persist any changes. The
+	// index is a cache, so failing to write it is not an error
+	if err == nil {
+		r.index.retain(seen)
+	}
+	_ = r.index.save()
+
+	// Sort models by path for predictable ordering
+	sort.Slice(models, func(i, j int) bool {
+		return models[i].Path < models[j].Path
+	})
+
+	// Return models and any error
+	return models, err
+}
+
+// loadModel returns the metadata for the model file at path, from the index
+// if the file is unchanged, or else by parsing the file and updating the index
+func (r *root) loadModel(path string) (*schema.Model, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, llama.ErrNotFound.Withf("%s: %v", filepath.Base(path), err)
+	}
+
+	// Compute relative path from the root
+	relPath, err := filepath.Rel(r.path, path)
+	if err != nil {
+		relPath = filepath.Base(path)
+	}
+
+	// Return the cached model if the file is unchanged
+	if model, exists := r.index.get(relPath, info); exists {
+		if model == nil {
+			return nil, llama.ErrInvalidModel.With(relPath)
+		}
+		return r.mark(model), nil
+	}
+
+	// Open the model GGUF file
+	ctx, err := gguf.Open(path)
+	if err != nil {
+		r.index.put(relPath, info, nil)
+		return nil, err
+	}
+	defer ctx.Close()
+
+	model, err := schema.NewModelFromGGUF(r.path, relPath, ctx)
+	if err != nil {
+		r.index.put(relPath, info, nil)
+		return nil, err
+	}
+	model.Size = uint64(info.Size())
+	r.index.put(relPath, info, model)
+	return r.mark(model), nil
+}
+
+// mark sets the root of the model, which is not cached in the index so
+// that the index remains valid if the directory is moved, and the metadata
+// from the sidecar file of the model
+func (r *root) mark(model *schema.Model) *schema.Model {
+	model.Root = r.path
+	model.ReadOnly = r.readonly
+	if meta, ok := readMeta(filepath.Join(r.path, model.Path)); ok {
+		model.ModelMeta = meta
+	}
+	return model
+}
@@ -0,0 +1,115 @@
+package store
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	// Packages
+	llama "github.com/mutablelogic/go-llama"
+	gguf "github.com/mutablelogic/go-llama/sys/gguf"
+	assert "github.com/stretchr/testify/assert"
+	require "github.com/stretchr/testify/require"
+)
+
+// newLayeredStore returns a store with a model in the store directory, a
+// model in a read-only directory, and a model with the same path in both
+func newLayeredStore(t *testing.T) (*Store, string) {
+	t.Helper()
+	local, shared := t.TempDir(), t.TempDir()
+	copyTestModel(t, local, "local.gguf")
+	copyTestModel(t, local, "both.gguf")
+	require.NoError(t, os.MkdirAll(filepath.Join(shared, "llama"), 0755))
+	copyTestModel(t, shared, filepath.Join("llama", "shared.gguf"))
+	copyTestModel(t, shared, "both.gguf")
+
+	store, err := New(local)
+	require.NoError(t, err)
+	require.NoError(t, store.AddReadOnlyRoot(shared))
+	return store, shared
+}
+
+func TestStore_AddReadOnlyRoot(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	store, shared := newLayeredStore(t)
+
+	// Directories must exist, and are only added once
+	assert.ErrorIs(store.AddReadOnlyRoot(shared), llama.ErrInvalidArgument)
+	assert.ErrorIs(store.AddReadOnlyRoot(store.Path()), llama.ErrInvalidArgument)
+	assert.ErrorIs(store.AddReadOnlyRoot(filepath.Join(shared, "missing")), llama.ErrOpenFailed)
+	assert.ErrorIs(store.AddReadOnlyRoot(filepath.Join(shared, "both.gguf")), llama.ErrInvalidArgument)
+
+	// Models are merged, and marked with their directory
+	models, err := store.ListModels(context.Background())
+	require.NoError(err)
+	require.Len(models, 3)
+	assert.Equal("both.gguf", models[0].Path)
+	assert.Equal(store.Path(), models[0].Root)
+	assert.False(models[0].ReadOnly)
+	assert.Equal(filepath.Join("llama", "shared.gguf"), models[1].Path)
+	assert.Equal(shared, models[1].Root)
+	assert.True(models[1].ReadOnly)
+	assert.Equal(filepath.Join(shared, "llama", "shared.gguf"), store.FilePath(models[1]))
+	assert.Equal("local.gguf", models[2].Path)
+
+	// The index for the read-only directory is kept in the store directory
+	assert.NoFileExists(filepath.Join(shared, indexFilename))
+	matches, err := filepath.Glob(filepath.Join(store.Path(), ".gguf-index-*.json"))
+	require.NoError(err)
+	assert.Len(matches, 1)
+}
+
+func TestStore_DeleteReadOnlyModel(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	store, shared := newLayeredStore(t)
+
+	// Models in the read-only directory cannot be deleted
+	err := store.DeleteModel(context.Background(), "shared")
+	assert.ErrorIs(err, llama.ErrReadOnly)
+	assert.FileExists(filepath.Join(shared, "llama", "shared.gguf"))
+
+	// Deleting a model in the store reveals the read-only model with the same path
+	require.NoError(store.DeleteModel(context.Background(), "both.gguf"))
+	model, err := store.GetModel(context.Background(), "both.gguf")
+	require.NoError(err)
+	assert.True(model.ReadOnly)
+	assert.ErrorIs(store.DeleteModel(context.Background(), "both.gguf"), llama.ErrReadOnly)
+}
+
+func TestStore_PatchReadOnlyModel(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	store, shared := newLayeredStore(t)
+
+	// The variant is written to the store directory
+	model, err := store.PatchModel(context.Background(), "shared", "", func(f *gguf.File) error {
+		return f.Set("general.name", "patched")
+	})
+	require.NoError(err)
+	assert.Equal(filepath.Join("llama", "shared-patched.gguf"), model.Path)
+	assert.False(model.ReadOnly)
+	assert.FileExists(filepath.Join(store.Path(), "llama", "shared-patched.gguf"))
+	assert.NoFileExists(filepath.Join(shared, "llama", "shared-patched.gguf"))
+}
+
+func TestStore_PullReadOnlyModel(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	_, server := newPullServer(t)
+	store, shared := newLayeredStore(t)
+	copyTestModel(t, shared, "model.gguf")
+
+	// A file which exists in a read-only directory is not downloaded again
+	model, err := store.PullModel(context.Background(), server.URL+"/model.gguf", nil)
+	require.NoError(err)
+	assert.Equal("model.gguf", model.Path)
+	assert.True(model.ReadOnly)
+	assert.NoFileExists(filepath.Join(store.Path(), "model.gguf"))
+}
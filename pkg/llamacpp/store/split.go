@@ -0,0 +1,148 @@
+package store
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+
+	// Packages
+	schema "github.com/mutablelogic/go-llama/pkg/llamacpp/schema"
+	gguf "github.com/mutablelogic/go-llama/sys/gguf"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	splitPathFormat = "%s-%05d-of-%05d" + gguf.FileExtension
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+var (
+	// reSplitPath matches the name of a file in a set of split files,
+	// as written by llama-gguf-split: "<prefix>-00001-of-00005.gguf"
+	reSplitPath = regexp.MustCompile(`^(.+)-(\d{5})-of-(\d{5})` + regexp.QuoteMeta(gguf.FileExtension) + `$`)
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// parseSplitPath returns the prefix, the one-based number of the file and
+// the number of files in the set, if path is the name of a split file
+func parseSplitPath(path string) (prefix string, no, count int, ok bool) {
+	match := reSplitPath.FindStringSubmatch(filepath.Base(path))
+	if match == nil {
+		return "", 0, 0, false
+	}
+	no, _ = strconv.Atoi(match[2])
+	count, _ = strconv.Atoi(match[3])
+	if no < 1 || count < 2 || no > count {
+		return "", 0, 0, false
+	}
+	return filepath.Join(filepath.Dir(path), match[1]), no, count, true
+}
+
+// splitPath returns the path of a file in a set of split files
+func splitPath(prefix string, no, count int) string {
+	return fmt.Sprintf(splitPathFormat, prefix, no, count)
+}
+
+// splitPaths returns the paths of all the files in the set of split files
+// which contains path, in order. If path is not a split file, it is returned
+func splitPaths(path string) []string {
+	prefix, _, count, ok := parseSplitPath(path)
+	if !ok {
+		return []string{path}
+	}
+	result := make([]string, 0, count)
+	for no := 1; no <= count; no++ {
+		result = append(result, splitPath(prefix, no, count))
+	}
+	return result
+}
+
+// splitName returns the name of a set of split files without the file
+// numbering, or an empty string if path is not a split file
+func splitName(path string) string {
+	if prefix, _, _, ok := parseSplitPath(path); ok {
+		return prefix + gguf.FileExtension
+	}
+	return ""
+}
+
+// splitURLs returns the URLs of all the files in the set of split files
+// which contains the file at rawURL. If the URL is not for a split file,
+// it is returned
+func splitURLs(rawURL string) []string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return []string{rawURL}
+	}
+	prefix, _, count, ok := parseSplitPath(path.Base(u.Path))
+	if !ok {
+		return []string{rawURL}
+	}
+	result := make([]string, 0, count)
+	for no := 1; no <= count; no++ {
+		u.Path = path.Join(path.Dir(u.Path), splitPath(prefix, no, count))
+		u.RawPath = ""
+		result = append(result, u.String())
+	}
+	return result
+}
+
+// mergeSplits returns the models with each complete set of split files
+// replaced by the model for the first file, with the combined size and the
+// number of files. The remaining files of a set, and incomplete sets which
+// cannot be loaded, are omitted. The result is sorted by path.
+func mergeSplits(models []*schema.Model) []*schema.Model {
+	type splitSet struct {
+		first *schema.Model
+		count int
+		n     int
+		size  uint64
+	}
+
+	result := make([]*schema.Model, 0, len(models))
+	sets := make(map[string]*splitSet)
+	for _, model := range models {
+		prefix, no, count, ok := parseSplitPath(model.Path)
+		if !ok {
+			result = append(result, model)
+			continue
+		}
+		key := splitPath(prefix, 1, count)
+		set, exists := sets[key]
+		if !exists {
+			set = &splitSet{count: count}
+			sets[key] = set
+		}
+		if no == 1 {
+			set.first = model
+		}
+		set.n++
+		set.size += model.Size
+	}
+
+	// Append complete sets
+	for _, set := range sets {
+		if set.first == nil || set.n != set.count {
+			continue
+		}
+		set.first.Size = set.size
+		set.first.Shards = set.count
+		result = append(result, set.first)
+	}
+
+	// Sort models by path for predictable ordering
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Path < result[j].Path
+	})
+	return result
+}
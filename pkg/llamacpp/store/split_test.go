@@ -0,0 +1,119 @@
+package store
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	// Packages
+	assert "github.com/stretchr/testify/assert"
+	require "github.com/stretchr/testify/require"
+)
+
+func TestParseSplitPath(t *testing.T) {
+	assert := assert.New(t)
+
+	prefix, no, count, ok := parseSplitPath("dir/model-Q4_K_M-00002-of-00005.gguf")
+	assert.True(ok)
+	assert.Equal(filepath.Join("dir", "model-Q4_K_M"), prefix)
+	assert.Equal(2, no)
+	assert.Equal(5, count)
+	assert.Equal(filepath.Join("dir", "model-Q4_K_M-00002-of-00005.gguf"), splitPath(prefix, no, count))
+	assert.Equal(filepath.Join("dir", "model-Q4_K_M.gguf"), splitName("dir/model-Q4_K_M-00002-of-00005.gguf"))
+
+	for _, path := range []string{"model.gguf", "model-00001-of-00001.gguf", "model-00003-of-00002.gguf", "model-00000-of-00002.gguf", "model-1-of-2.gguf", "model-00001-of-00002.bin"} {
+		_, _, _, ok := parseSplitPath(path)
+		assert.False(ok, path)
+	}
+}
+
+func TestSplitURLs(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal([]string{
+		"https://huggingface.co/org/repo/resolve/main/model-00001-of-00002.gguf?download=true",
+		"https://huggingface.co/org/repo/resolve/main/model-00002-of-00002.gguf?download=true",
+	}, splitURLs("https://huggingface.co/org/repo/resolve/main/model-00002-of-00002.gguf?download=true"))
+	assert.Equal([]string{
+		"hf://org/repo@dev/Q4/model-00001-of-00002.gguf",
+		"hf://org/repo@dev/Q4/model-00002-of-00002.gguf",
+	}, splitURLs("hf://org/repo@dev/Q4/model-00001-of-00002.gguf"))
+	assert.Equal([]string{"hf://org/repo/model.gguf"}, splitURLs("hf://org/repo/model.gguf"))
+}
+
+func TestStore_SplitModel(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	// Create a complete set of two files, and an incomplete set
+	dir := t.TempDir()
+	copyTestModel(t, dir, "big-00001-of-00002.gguf")
+	copyTestModel(t, dir, "big-00002-of-00002.gguf")
+	copyTestModel(t, dir, "partial-00001-of-00003.gguf")
+	copyTestModel(t, dir, "partial-00003-of-00003.gguf")
+	info, err := os.Stat(filepath.Join(dir, "big-00001-of-00002.gguf"))
+	require.NoError(err)
+
+	store, err := New(dir)
+	require.NoError(err)
+
+	// The complete set is listed as one model with the combined size
+	models, err := store.ListModels(context.Background())
+	require.NoError(err)
+	require.Len(models, 1)
+	assert.Equal("big-00001-of-00002.gguf", models[0].Path)
+	assert.Equal(2, models[0].Shards)
+	assert.Equal(uint64(info.Size())*2, models[0].Size)
+
+	// The set can be found by name without the file numbering
+	for _, name := range []string{"big", "big.gguf", "big-00001-of-00002.gguf"} {
+		model, err := store.GetModel(context.Background(), name)
+		if assert.NoError(err, name) {
+			assert.Equal("big-00001-of-00002.gguf", model.Path)
+		}
+	}
+
+	// The tensors of all the files are returned, with the number of each file
+	tensors, err := store.GetModelTensors(context.Background(), "big", true)
+	require.NoError(err)
+	single, err := readTensors(filepath.Join(dir, "big-00001-of-00002.gguf"), false)
+	require.NoError(err)
+	assert.Equal(single.Count*2, tensors.Count)
+	assert.Equal(single.Params*2, tensors.Params)
+	assert.Equal(single.Size*2, tensors.Size)
+	require.Len(tensors.Tensors, tensors.Count)
+	assert.Equal(1, tensors.Tensors[0].Shard)
+	assert.Equal(2, tensors.Tensors[tensors.Count-1].Shard)
+	var count int
+	for _, t := range tensors.Types {
+		count += t.Count
+	}
+	assert.Equal(tensors.Count, count)
+
+	// Deleting the model deletes all the files
+	require.NoError(store.DeleteModel(context.Background(), "big"))
+	assert.NoFileExists(filepath.Join(dir, "big-00001-of-00002.gguf"))
+	assert.NoFileExists(filepath.Join(dir, "big-00002-of-00002.gguf"))
+	assert.FileExists(filepath.Join(dir, "partial-00001-of-00003.gguf"))
+}
+
+func TestStore_PullSplitModel(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	s, server := newPullServer(t)
+	store, err := New(t.TempDir())
+	require.NoError(err)
+
+	// Pulling any file of a set pulls all the files
+	model, err := store.PullModel(context.Background(), server.URL+"/split/model-00002-of-00003.gguf", nil)
+	require.NoError(err)
+	assert.Equal("model-00001-of-00003.gguf", model.Path)
+	assert.Equal(3, model.Shards)
+	assert.Equal(uint64(len(s.data))*3, model.Size)
+	for _, name := range []string{"model-00001-of-00003.gguf", "model-00002-of-00003.gguf", "model-00003-of-00003.gguf"} {
+		assert.FileExists(filepath.Join(store.Path(), name))
+	}
+	assert.Len(s.ranges, 3)
+}
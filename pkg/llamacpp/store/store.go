@@ -2,11 +2,14 @@ package store
 
 import (
 	"context"
+	"errors"
 	"os"
 	"path/filepath"
 	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	// Packages
 	client "github.com/mutablelogic/go-client"
@@ -18,11 +21,18 @@ import (
 ///////////////////////////////////////////////////////////////////////////////
 // TYPES
 
-// Store manages a collection of GGUF models in a directory.
+// Store manages a collection of GGUF models in a directory. Models can also
+// be listed and loaded from read-only directories, which may be shared with
+// other stores, but are only pulled, imported or deleted in the store directory.
 type Store struct {
 	sync.RWMutex
-	path   string
-	client *Client
+	path     string
+	client   *Client
+	index    *index
+	roots    []*root // The store directory, then read-only directories in order of precedence
+	usage    *usage
+	quota    uint64
+	watching atomic.Bool
 }
 
 type PullCallbackFunc func(filename string, bytes_received uint64, total_bytes uint64)
@@ -34,12 +44,9 @@ type PullCallbackFunc func(filename string, bytes_received uint64, total_bytes u
 // The path must be an existing directory.
 func New(path string, opts ...client.ClientOpt) (*Store, error) {
 	// Check path exists and is a directory
-	if info, err := os.Stat(path); err != nil {
-		return nil, llama.ErrOpenFailed.Withf("%s: %v", path, err)
-	} else if !info.IsDir() {
-		return nil, llama.ErrInvalidArgument.Withf("not a directory: %s", path)
-	} else {
-		path = filepath.Clean(path)
+	r, err := newRoot(path, false, "")
+	if err != nil {
+		return nil, err
 	}
 
 	// Create client for downloading models
@@ -48,7 +55,7 @@ func New(path string, opts ...client.ClientOpt) (*Store, error) {
 		return nil, llama.ErrOpenFailed.Withf("failed to create client: %v", err)
 	}
 
-	return &Store{path: path, client: client}, nil
+	return &Store{path: r.path, client: client, index: r.index, roots: []*root{r}, usage: newUsage(r.path)}, nil
 }
 
 ///////////////////////////////////////////////////////////////////////////////
@@ -59,8 +66,48 @@ func (s *Store) Path() string {
 	return s.path
 }
 
+// FilePath returns the path of the file for a model returned by the store,
+// which may be in the store directory or a read-only directory. For a split
+// model, it is the path of the first file.
+func (s *Store) FilePath(model *schema.Model) string {
+	if model.Root == "" {
+		return filepath.Join(s.path, model.Path)
+	}
+	return filepath.Join(model.Root, model.Path)
+}
+
+// FilePaths returns the paths of the files for a model returned by the
+// store, which are numbered in order for a split model.
+func (s *Store) FilePaths(model *schema.Model) []string {
+	return splitPaths(s.FilePath(model))
+}
+
+// AddReadOnlyRoot adds a directory of models which are listed and loaded,
+// but not deleted or replaced. A model in the store directory takes
+// precedence over a model with the same path in a read-only directory, and
+// read-only directories take precedence in the order they are added. The
+// index for the directory is kept in the store directory.
+func (s *Store) AddReadOnlyRoot(path string) error {
+	readonly, err := newRoot(path, true, s.path)
+	if err != nil {
+		return err
+	}
+
+	s.Lock()
+	defer s.Unlock()
+	for _, r := range s.roots {
+		if r.path == readonly.path {
+			return llama.ErrInvalidArgument.Withf("directory already in store: %s", path)
+		}
+	}
+	s.roots = append(s.roots, readonly)
+	return nil
+}
+
 // ListModels scans the store directory for GGUF models and returns their metadata.
 // Hidden files and directories are skipped. Invalid GGUF files are silently skipped.
+// Metadata is cached in an index, so only new or changed files are parsed. While
+// the store is being watched, the index is returned without scanning.
 func (s *Store) ListModels(ctx context.Context) ([]*schema.Model, error) {
 	s.RLock()
 	defer s.RUnlock()
@@ -75,8 +122,41 @@ func (s *Store) GetModel(ctx context.Context, name string) (*schema.Model, error
 	return s.getModel(ctx, name)
 }
 
+// GetModelTensors returns the tensor layout of a model by name, read from
+// the GGUF file, or from all the files of a split model. If detail is true,
+// individual tensors are included as well as the per-type breakdown. Returns
+// ErrNotFound if the model doesn't exist.
+func (s *Store) GetModelTensors(ctx context.Context, name string, detail bool) (*schema.ModelTensors, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	// Find the model
+	model, err := s.getModel(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	// Read the tensor information of each file, which is numbered for a
+	// split model
+	result := &schema.ModelTensors{Types: []schema.ModelTensorType{}}
+	paths := s.FilePaths(model)
+	for i, path := range paths {
+		tensors, err := readTensors(path, detail)
+		if err != nil {
+			return nil, err
+		}
+		shard := 0
+		if len(paths) > 1 {
+			shard = i + 1
+		}
+		result.Merge(tensors, shard)
+	}
+	return result, nil
+}
+
 // DeleteModel deletes a model from the store by name. It matches against the full relative path,
-// filename, or model name. Returns ErrNotFound if the model doesn't exist.
+// filename, or model name. All the files of a split model are deleted. Returns ErrNotFound if
+// the model doesn't exist, or ErrReadOnly if the model is in a read-only directory.
 func (s *Store) DeleteModel(ctx context.Context, name string) error {
 	s.Lock()
 	defer s.Unlock()
@@ -85,39 +165,213 @@ func (s *Store) DeleteModel(ctx context.Context, name string) error {
 	model, err := s.getModel(ctx, name)
 	if err != nil {
 		return err
+	} else if model.ReadOnly {
+		return llama.ErrReadOnly.Withf("model %q is in read-only directory %s", model.Path, model.Root)
+	}
+
+	// Delete the files
+	return s.deleteModel(model)
+}
+
+// PatchModel creates a patched variant of a model next to the original, with
+// metadata modified by edit. Tensor data is copied unchanged. The variant is
+// named dest, or "<name>-patched.gguf" if dest is empty, and must not already
+// exist. A variant of a model in a read-only directory is written to the same
+// relative directory in the store. Returns the metadata of the new model.
+func (s *Store) PatchModel(ctx context.Context, name, dest string, edit func(*gguf.File) error) (*schema.Model, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	// Find the model
+	model, err := s.getModel(ctx, name)
+	if err != nil {
+		return nil, err
+	} else if model.Shards > 0 {
+		return nil, llama.ErrInvalidArgument.Withf("cannot patch split model %q", model.Path)
+	}
+
+	// Determine the destination, which is in the same directory as the model
+	if dest == "" {
+		dest = strings.TrimSuffix(filepath.Base(model.Path), gguf.FileExtension) + "-patched"
+	} else if dest != filepath.Base(dest) || strings.HasPrefix(dest, ".") {
+		return nil, llama.ErrInvalidArgument.Withf("invalid model filename: %q", dest)
+	}
+	if filepath.Ext(dest) != gguf.FileExtension {
+		dest += gguf.FileExtension
+	}
+	srcPath := s.FilePath(model)
+	destPath := filepath.Join(s.path, filepath.Dir(model.Path), dest)
+	if _, err := os.Stat(destPath); err == nil {
+		return nil, llama.ErrInvalidArgument.Withf("model already exists at %s", dest)
+	} else if err := os.MkdirAll(filepath.Dir(destPath), 0755); err != nil {
+		return nil, llama.ErrOpenFailed.Withf("failed to create directory: %v", err)
 	}
 
-	// Delete the file
-	if err := os.Remove(filepath.Join(s.path, model.Path)); err != nil {
-		return llama.ErrOpenFailed.Withf("failed to delete model: %v", err)
+	// Write the patched file
+	if err := gguf.Rewrite(srcPath, destPath, edit); err != nil {
+		return nil, err
 	}
 
-	// Return success
-	return nil
+	// Load the model and return it
+	patched, err := s.loadModel(destPath)
+	if err != nil {
+		os.Remove(destPath)
+		return nil, err
+	}
+	_ = s.index.save()
+
+	return patched, nil
 }
 
 // PullModel downloads a model from the given URL into the store and returns the loaded model.
+// A file which already exists with the same size, in the store or a read-only directory, is
+// not downloaded again.
 // Supports HuggingFace URLs with hf:// scheme and regular HTTP(S) URLs.
-// The callback receives progress updates during download.
-func (s *Store) PullModel(ctx context.Context, url string, callback ClientCallback) (*schema.Model, error) {
+// The callback receives progress updates during download. An interrupted download
+// is kept in the store directory, and is resumed when the same URL is pulled again.
+// If the URL is for one file of a split model, all the files of the model are downloaded.
+// A Hugging Face repository URL "hf://org/repo[:quant]" is resolved to a model in the repository.
+// A model in an Ollama or OCI registry, "ollama://[host/]model[:tag]" or "oci://host/name[:tag]",
+// is resolved from its manifest, and its chat template and parameters are kept as a preset.
+// The URL, the time of the pull and the checksum of a single file model are written to the
+// sidecar metadata file of the model.
+func (s *Store) PullModel(ctx context.Context, url string, callback ClientCallback, opts ...Opt) (*schema.Model, error) {
+	// Pull a model from a registry
+	if isRegistryURL(url) {
+		return s.pullRegistryModel(ctx, url, callback, opts)
+	}
+
+	// Resolve a repository to a model file
+	url, err := s.client.ResolveModel(ctx, url, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	// Download each file in turn
+	urls := splitURLs(url)
+	models := make([]*schema.Model, 0, len(urls))
+	var sum string
+	var downloaded bool
+	for _, url := range urls {
+		model, fileSum, err := s.pullFile(ctx, url, callback, opts)
+		if err != nil {
+			return nil, err
+		}
+		models = append(models, model)
+		if fileSum != "" {
+			sum, downloaded = fileSum, true
+		}
+	}
+
+	// Combine the files of a split model
+	if models = mergeSplits(models); len(models) != 1 {
+		return nil, llama.ErrInvalidModel.Withf("incomplete split model: %s", url)
+	}
+	model := models[0]
+
+	// Record where the model came from, unless it was already in the store.
+	// The model is in the store, so failing to write the metadata is not an error
+	if downloaded && !model.ReadOnly {
+		if len(urls) > 1 {
+			sum = ""
+		}
+		if meta, err := setProvenance(s.FilePath(model), url, sum, nil); err == nil {
+			model.ModelMeta = meta
+		}
+	}
+
+	// Return the model
+	return model, nil
+}
+
+// ListRemoteModels returns the GGUF models in a Hugging Face repository,
+// given as "org/repo" with an optional "@branch".
+func (s *Store) ListRemoteModels(ctx context.Context, repo string, opts ...Opt) ([]*schema.RemoteModel, error) {
+	return s.client.ListRemoteModels(ctx, repo, opts...)
+}
+
+// SetCredentials sets the credentials for downloads from hosts other than
+// Hugging Face, replacing any existing credentials. The first credential
+// which matches a host is used. It should be called before pulling models.
+func (s *Store) SetCredentials(credentials ...Credential) {
+	s.Lock()
+	defer s.Unlock()
+	s.client.credentials = append([]Credential(nil), credentials...)
+}
+
+// Watch keeps the model index up to date by polling the store directory at
+// the given interval, until the context is cancelled. Only file sizes and
+// modification times are checked, and new or changed files are parsed. While
+// watching, model lookups are served from the index without a scan, so files
+// added outside the store appear after the next poll.
+func (s *Store) Watch(ctx context.Context, interval time.Duration) error {
+	if interval <= 0 {
+		return llama.ErrInvalidArgument.Withf("invalid watch interval: %v", interval)
+	}
+
+	// Initial scan
+	if err := s.refresh(ctx); err != nil {
+		return err
+	}
+	s.watching.Store(true)
+	defer s.watching.Store(false)
+
+	// Poll until cancelled
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := s.refresh(ctx); err != nil && ctx.Err() == nil {
+				return err
+			}
+		}
+	}
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// pullFile downloads a single file into the store and returns the loaded model,
+// and the checksum of the file or an empty string if it was already in the store
+func (s *Store) pullFile(ctx context.Context, url string, callback ClientCallback, opts []Opt) (*schema.Model, string, error) {
 	// Get the suggested destination path first
 	destPath, err := s.client.GetDestPath(url)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
-	// Determine final file path in the store
-	finalPath := filepath.Join(s.path, destPath)
+	// Download the file
+	return s.pullInto(url, destPath, callback, func(path string, fn ClientCallback) (string, error) {
+		_, sum, err := s.client.pullModel(ctx, path, url, fn, opts...)
+		return sum, err
+	})
+}
+
+// pullInto downloads a file with the download function into the store at
+// destPath, and returns the loaded model, and the checksum of the file or an
+// empty string if it was already in the store. The download is into a partial
+// file named from url, and is skipped when a file exists at destPath in any
+// of the store directories with the same size.
+func (s *Store) pullInto(url, destPath string, callback ClientCallback, download func(path string, fn ClientCallback) (string, error)) (*schema.Model, string, error) {
+	s.RLock()
+	roots := s.roots
+	s.RUnlock()
 
 	// Create a wrapper callback that checks if file exists with matching size
-	var skipDownload bool
+	// in any of the store directories
+	var skipRoot *root
+	var started bool
 	wrappedCallback := func(filename string, bytesReceived, totalBytes uint64) error {
-		// On first call (bytesReceived == 0), check if we can skip download
-		if bytesReceived == 0 {
-			if info, err := os.Stat(finalPath); err == nil {
-				if uint64(info.Size()) == totalBytes {
+		// On first call, check if we can skip download
+		if !started {
+			started = true
+			for _, r := range roots {
+				if info, err := os.Stat(filepath.Join(r.path, destPath)); err == nil && uint64(info.Size()) == totalBytes {
 					// File exists with matching size, skip download
-					skipDownload = true
+					skipRoot = r
 					return llama.ErrInvalidArgument.With("file already exists with correct size")
 				}
 			}
@@ -129,54 +383,51 @@ func (s *Store) PullModel(ctx context.Context, url string, callback ClientCallba
 		return nil
 	}
 
-	// Create temporary file in the store directory for the download
-	tempFile, err := os.CreateTemp(s.path, ".gguf-*.tmp")
-	if err != nil {
-		return nil, llama.ErrOpenFailed.Withf("failed to create temporary file: %v", err)
-	}
-	tempPath := tempFile.Name()
-	defer func() {
-		tempFile.Close()
-		os.Remove(tempPath)
-	}()
+	// The partial file in the store directory is named from the URL, so that
+	// the download can be resumed. It is hidden, so it is not scanned as a model.
+	tempPath := partialPath(s.path, url)
 
-	// Download the model and get the suggested destination path
-	destPath, err = s.client.PullModel(ctx, tempFile, url, wrappedCallback)
-	if err != nil && skipDownload {
+	// Download the model
+	sum, err := download(tempPath, wrappedCallback)
+	if err != nil && skipRoot != nil {
 		// Download was skipped because file exists, load and return it
-		return s.loadModel(finalPath)
+		removePartial(tempPath)
+		model, err := skipRoot.loadModel(filepath.Join(skipRoot.path, destPath))
+		if err == nil {
+			_ = skipRoot.index.save()
+		}
+		return model, "", err
 	} else if err != nil {
-		return nil, err // Return the original error from PullModel
+		return nil, "", err // Return the original error from PullModel
 	}
 
-	// Recalculate final path (should be same as before)
-	finalPath = filepath.Join(s.path, destPath)
-
 	// Ensure the directory exists
+	finalPath := filepath.Join(s.path, destPath)
 	if err := os.MkdirAll(filepath.Dir(finalPath), 0755); err != nil {
-		return nil, llama.ErrOpenFailed.Withf("failed to create directory: %v", err)
+		return nil, "", llama.ErrOpenFailed.Withf("failed to create directory: %v", err)
 	}
 
 	// Check if file already exists
 	if _, err := os.Stat(finalPath); err == nil {
-		return nil, llama.ErrInvalidArgument.Withf("model already exists at %s", destPath)
+		return nil, "", llama.ErrInvalidArgument.Withf("model already exists at %s", destPath)
 	}
 
 	// Validate download
 	if info, err := os.Stat(tempPath); err != nil {
-		return nil, llama.ErrNotFound.Withf("temporary file not found after download: %v", err)
+		return nil, "", llama.ErrNotFound.Withf("temporary file not found after download: %v", err)
 	} else if info.Size() == 0 {
-		return nil, llama.ErrInvalidModel.With("downloaded file is empty")
+		os.Remove(tempPath)
+		return nil, "", llama.ErrInvalidModel.With("downloaded file is empty")
 	}
 
 	// Move temporary file to final location (synchronized)
 	s.Lock()
 	defer s.Unlock()
 	if _, err := os.Stat(finalPath); err == nil {
-		return nil, llama.ErrInvalidArgument.Withf("model already exists at %s", destPath)
+		return nil, "", llama.ErrInvalidArgument.Withf("model already exists at %s", destPath)
 	}
 	if err := os.Rename(tempPath, finalPath); err != nil {
-		return nil, llama.ErrOpenFailed.Withf("failed to move file to final location: %v", err)
+		return nil, "", llama.ErrOpenFailed.Withf("failed to move file to final location: %v", err)
 	}
 
 	// Load the model and return it
@@ -184,55 +435,92 @@ func (s *Store) PullModel(ctx context.Context, url string, callback ClientCallba
 	if err != nil {
 		// If loadModel fails, clean up the downloaded file
 		os.Remove(finalPath)
-		return nil, err
+		return nil, "", err
 	}
+	_ = s.index.save()
 
-	return model, nil
+	return model, sum, nil
 }
 
-///////////////////////////////////////////////////////////////////////////////
-// PRIVATE METHODS
-
-// listModels is the internal unlocked version of ListModels
-func (s *Store) listModels(ctx context.Context) ([]*schema.Model, error) {
-	// Walk the directory looking for .gguf files
-	var models []*schema.Model
-	err := filepath.Walk(s.path, func(path string, info os.FileInfo, err error) error {
-		// Check for context cancellation, or other errors
-		if ctx.Err() != nil {
-			return ctx.Err()
-		} else if err != nil {
+// refresh scans the store directories and updates their indexes
+func (s *Store) refresh(ctx context.Context) error {
+	s.RLock()
+	defer s.RUnlock()
+	for _, r := range s.roots {
+		if _, err := r.scan(ctx); err != nil {
 			return err
 		}
+	}
+	return nil
+}
 
-		// Skip hidden files and directories
-		if strings.HasPrefix(info.Name(), ".") {
-			if info.IsDir() {
-				return filepath.SkipDir
-			}
-			return nil
-		} else if info.IsDir() {
-			return nil
-		} else if filepath.Ext(path) != gguf.FileExtension {
-			return nil
+// listModels is the internal unlocked version of ListModels. Each set of
+// split files is returned as a single model. A model with the same path as
+// one in a directory with higher precedence is not returned.
+func (s *Store) listModels(ctx context.Context) ([]*schema.Model, error) {
+	var result []*schema.Model
+	seen := make(map[string]bool)
+	for _, r := range s.roots {
+		var models []*schema.Model
+		if s.watching.Load() {
+			models = r.models()
+		} else if scanned, err := r.scan(ctx); err != nil {
+			return result, err
+		} else {
+			models = scanned
 		}
-
-		// Try to load model metadata, skip if invalid
-		if model, err := s.loadModel(path); err == nil && model != nil {
-			models = append(models, model)
+		for _, model := range mergeSplits(models) {
+			if seen[model.Path] {
+				continue
+			} else if !r.readonly {
+				s.usage.mark(model)
+			}
+			seen[model.Path] = true
+			result = append(result, model)
 		}
-
-		// Return success
-		return nil
-	})
+	}
 
 	// Sort models by path for predictable ordering
-	sort.Slice(models, func(i, j int) bool {
-		return models[i].Path < models[j].Path
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Path < result[j].Path
 	})
+	return result, nil
+}
+
+// readTensors returns the tensor layout of a GGUF file
+func readTensors(path string, detail bool) (*schema.ModelTensors, error) {
+	gctx, err := gguf.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer gctx.Close()
+	return schema.NewModelTensorsFromGGUF(gctx, detail)
+}
 
-	// Return models and any error
-	return models, err
+// deleteModel deletes the files of a model in the store directory, and their
+// sidecar metadata files. The caller must hold the lock.
+func (s *Store) deleteModel(model *schema.Model) error {
+	var result error
+	for _, path := range splitPaths(model.Path) {
+		if err := os.Remove(filepath.Join(s.path, path)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			result = errors.Join(result, llama.ErrOpenFailed.Withf("failed to delete model: %v", err))
+		} else {
+			_ = os.Remove(filepath.Join(s.path, path) + metaExt)
+			s.index.remove(path)
+		}
+	}
+	_ = s.index.save()
+	if result == nil {
+		s.usage.remove(model.Path)
+		_ = s.usage.save()
+	}
+	return result
+}
+
+// loadModel returns the metadata for the model file at path in the store
+// directory, from the index if the file is unchanged
+func (s *Store) loadModel(path string) (*schema.Model, error) {
+	return s.roots[0].loadModel(path)
 }
 
 // getModel is the internal unlocked version of GetModel
@@ -257,21 +545,14 @@ func (s *Store) getModel(ctx context.Context, name string) (*schema.Model, error
 		}
 	}
 
-	return nil, llama.ErrNotFound.Withf("%s", name)
-}
-
-func (s *Store) loadModel(path string) (*schema.Model, error) {
-	// Open the model GGUF file
-	ctx, err := gguf.Open(path)
-	if err != nil {
-		return nil, err
+	// Match split models by name without the file numbering
+	for _, m := range models {
+		if split := splitName(m.Path); split != "" {
+			if split == name || filepath.Base(split) == name || split == name+gguf.FileExtension || filepath.Base(split) == name+gguf.FileExtension {
+				return m, nil
+			}
+		}
 	}
-	defer ctx.Close()
 
-	// Compute relative path from store root
-	relPath, err := filepath.Rel(s.path, path)
-	if err != nil {
-		relPath = filepath.Base(path)
-	}
-	return schema.NewModelFromGGUF(s.path, relPath, ctx)
+	return nil, llama.ErrNotFound.Withf("%s", name)
 }
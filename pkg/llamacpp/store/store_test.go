@@ -10,6 +10,8 @@ import (
 	"testing"
 	"time"
 
+	llama "github.com/mutablelogic/go-llama"
+	gguf "github.com/mutablelogic/go-llama/sys/gguf"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -150,3 +152,44 @@ func TestStore_DeleteModel(t *testing.T) {
 	_, err = os.Stat(dstPath)
 	assert.Error(err)
 }
+
+func TestStore_PatchModel(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	// Create a temporary directory for this test
+	tempDir := t.TempDir()
+
+	store, err := New(tempDir)
+	require.NoError(err)
+
+	// Copy a test GGUF file into the temp store
+	data, err := os.ReadFile(filepath.Join(testdataPath, "stories260K.gguf"))
+	require.NoError(err)
+	require.NoError(os.WriteFile(filepath.Join(tempDir, "stories260K.gguf"), data, 0644))
+
+	// Patch the chat template
+	template := "{% for m in messages %}{{ m.content }}{% endfor %}"
+	model, err := store.PatchModel(context.Background(), "stories260K.gguf", "", func(f *gguf.File) error {
+		return f.Set("tokenizer.chat_template", template)
+	})
+	require.NoError(err)
+	assert.Equal("stories260K-patched.gguf", model.Path)
+	assert.Equal(template, model.ChatTemplate)
+
+	// Both models are listed, and the original is unchanged
+	models, err := store.ListModels(context.Background())
+	require.NoError(err)
+	assert.Len(models, 2)
+	original, err := store.GetModel(context.Background(), "stories260K.gguf")
+	require.NoError(err)
+	assert.Empty(original.ChatTemplate)
+
+	// The variant cannot be overwritten, or written outside the model directory
+	_, err = store.PatchModel(context.Background(), "stories260K.gguf", "", nil)
+	assert.ErrorIs(err, llama.ErrInvalidArgument)
+	_, err = store.PatchModel(context.Background(), "stories260K.gguf", "../escape.gguf", nil)
+	assert.ErrorIs(err, llama.ErrInvalidArgument)
+	_, err = store.PatchModel(context.Background(), "nonexistent", "", nil)
+	assert.ErrorIs(err, llama.ErrNotFound)
+}
@@ -0,0 +1,131 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	// Packages
+	schema "github.com/mutablelogic/go-llama/pkg/llamacpp/schema"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// usage records when each model in the store was last loaded, and which
+// models are pinned, keyed by the path of the model relative to the store
+// root. Unlike the index, it is not a cache, so entries are kept when a
+// model file changes.
+type usage struct {
+	sync.Mutex
+	path    string
+	entries map[string]*usageEntry
+}
+
+// usageEntry is the usage of a single model
+type usageEntry struct {
+	LastLoaded time.Time `json:"last_loaded,omitzero"`
+	Pinned     bool      `json:"pinned,omitempty"`
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	// Name of the usage file in the store root. It is hidden, so it is not
+	// scanned as a model.
+	usageFilename = ".gguf-usage.json"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// LIFECYCLE
+
+// newUsage returns the usage for the store at root, loading any existing
+// usage file. A missing or unreadable usage file is ignored.
+func newUsage(root string) *usage {
+	u := &usage{
+		path:    filepath.Join(root, usageFilename),
+		entries: make(map[string]*usageEntry),
+	}
+	if data, err := os.ReadFile(u.path); err == nil {
+		var entries map[string]*usageEntry
+		if err := json.Unmarshal(data, &entries); err == nil && entries != nil {
+			u.entries = entries
+		}
+	}
+	return u
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// mark sets the usage of the model
+func (u *usage) mark(model *schema.Model) *schema.Model {
+	u.Lock()
+	defer u.Unlock()
+	if entry, exists := u.entries[model.Path]; exists {
+		model.LastLoaded = entry.LastLoaded
+		model.Pinned = entry.Pinned
+	}
+	return model
+}
+
+// loaded records that the model at relPath was loaded at time t
+func (u *usage) loaded(relPath string, t time.Time) {
+	u.Lock()
+	defer u.Unlock()
+	u.entry(relPath).LastLoaded = t
+}
+
+// pin pins or unpins the model at relPath
+func (u *usage) pin(relPath string, pinned bool) {
+	u.Lock()
+	defer u.Unlock()
+	u.entry(relPath).Pinned = pinned
+}
+
+// remove removes the usage of the model at relPath
+func (u *usage) remove(relPath string) {
+	u.Lock()
+	defer u.Unlock()
+	delete(u.entries, relPath)
+}
+
+// entry returns the usage of the model at relPath, creating it if it does
+// not exist. The caller must hold the lock.
+func (u *usage) entry(relPath string) *usageEntry {
+	entry, exists := u.entries[relPath]
+	if !exists {
+		entry = new(usageEntry)
+		u.entries[relPath] = entry
+	}
+	return entry
+}
+
+// save writes the usage file. The file is written to a temporary file and
+// renamed, so a partially written file is never read.
+func (u *usage) save() error {
+	u.Lock()
+	defer u.Unlock()
+
+	data, err := json.Marshal(u.entries)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(u.path), ".gguf-usage-*.tmp")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	if err = errors.Join(err, tmp.Close()); err == nil {
+		err = os.Rename(tmp.Name(), u.path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
@@ -110,6 +110,9 @@ func (l *Llama) WithContext(ctx context.Context, req schema.ContextRequest, fn T
 	if req.FlashAttn != nil {
 		params.FlashAttn = llamacpp.FlashAttnType(*req.FlashAttn)
 	}
+	if req.PoolingType != nil {
+		params.PoolingType = llamacpp.PoolingType(*req.PoolingType)
+	}
 	if req.Embeddings != nil {
 		params.Embeddings = *req.Embeddings
 	}
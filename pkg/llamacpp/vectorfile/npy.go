@@ -0,0 +1,112 @@
+package vectorfile
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+
+	// Packages
+	llama "github.com/mutablelogic/go-llama"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// NPY writes vectors as a two-dimensional NumPy array of float32, with one
+// row per vector. The header is rewritten with the number of rows when the
+// writer is closed.
+type NPY struct {
+	w         io.WriteSeeker
+	ids       io.Writer
+	rows      int
+	dimension int
+	buf       []byte
+}
+
+var _ Writer = (*NPY)(nil)
+
+///////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+const (
+	// npyMagic starts a file, followed by the version 1.0
+	npyMagic = "\x93NUMPY\x01\x00"
+
+	// npyHeaderSize is the size of the header, including the magic and
+	// padding, which is large enough for any shape
+	npyHeaderSize = 128
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// LIFECYCLE
+
+// NewNPY returns a writer of vectors to w. The identifier of each vector is
+// written to ids as a JSON string on each line, unless ids is nil.
+func NewNPY(w io.WriteSeeker, ids io.Writer) (*NPY, error) {
+	npy := &NPY{w: w, ids: ids}
+	if err := npy.writeHeader(); err != nil {
+		return nil, err
+	}
+	return npy, nil
+}
+
+// Close rewrites the header with the number of rows, and leaves the
+// position at the end of the file.
+func (npy *NPY) Close() error {
+	if _, err := npy.w.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if err := npy.writeHeader(); err != nil {
+		return err
+	}
+	_, err := npy.w.Seek(0, io.SeekEnd)
+	return err
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Write appends a vector, which has the same dimension as the first vector.
+func (npy *NPY) Write(id string, vector []float32) error {
+	if npy.rows == 0 {
+		npy.dimension = len(vector)
+	} else if len(vector) != npy.dimension {
+		return llama.ErrInvalidArgument.Withf("vector %q has %d dimensions, expected %d", id, len(vector), npy.dimension)
+	}
+
+	npy.buf = npy.buf[:0]
+	for _, v := range vector {
+		npy.buf = binary.LittleEndian.AppendUint32(npy.buf, math.Float32bits(v))
+	}
+	if _, err := npy.w.Write(npy.buf); err != nil {
+		return err
+	}
+	if npy.ids != nil {
+		if err := json.NewEncoder(npy.ids).Encode(id); err != nil {
+			return err
+		}
+	}
+	npy.rows++
+	return nil
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// writeHeader writes the magic, the length of the header and the header,
+// which is padded with spaces to a fixed size and ends with a newline
+func (npy *NPY) writeHeader() error {
+	header := fmt.Sprintf("{'descr': '<f4', 'fortran_order': False, 'shape': (%d, %d), }", npy.rows, npy.dimension)
+	size := npyHeaderSize - len(npyMagic) - 2
+	header += strings.Repeat(" ", size-len(header)-1) + "\n"
+
+	buf := make([]byte, 0, npyHeaderSize)
+	buf = append(buf, npyMagic...)
+	buf = binary.LittleEndian.AppendUint16(buf, uint16(size))
+	buf = append(buf, header...)
+	_, err := npy.w.Write(buf)
+	return err
+}
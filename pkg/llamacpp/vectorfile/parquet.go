@@ -0,0 +1,307 @@
+package vectorfile
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+
+	// Packages
+	llama "github.com/mutablelogic/go-llama"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// Parquet writes vectors as a Parquet table with a string "id" column and
+// an "embedding" column, which is a list of floats. Rows are written in
+// uncompressed row groups, and the metadata is written when the writer is
+// closed.
+type Parquet struct {
+	w         io.Writer
+	offset    int64
+	rows      int64
+	dimension int
+	ids       []string
+	vectors   [][]float32
+	groups    []parquetRowGroup
+}
+
+// parquetRowGroup is the position of a row group in the file
+type parquetRowGroup struct {
+	rows    int64
+	columns []parquetColumn
+}
+
+// parquetColumn is the position of a column chunk in the file
+type parquetColumn struct {
+	path   []string
+	typ    int32
+	values int64
+	offset int64
+	size   int64
+}
+
+// parquetLevels are the repetition or definition levels of a column, as
+// runs of the same level
+type parquetLevels struct {
+	runs []parquetRun
+}
+
+// parquetRun is a number of repeated levels
+type parquetRun struct {
+	level byte
+	count int
+}
+
+var _ Writer = (*Parquet)(nil)
+
+///////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+const (
+	// parquetMagic starts and ends a file
+	parquetMagic = "PAR1"
+
+	// parquetGroupRows is the most rows in a row group
+	parquetGroupRows = 1024
+)
+
+// Parquet physical types, repetitions, converted types, encodings and page types
+const (
+	parquetFloat     = 4
+	parquetByteArray = 6
+	parquetRequired  = 0
+	parquetRepeated  = 2
+	parquetUTF8      = 0
+	parquetList      = 3
+	parquetPlain     = 0
+	parquetRLE       = 3
+	parquetDataPage  = 0
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// LIFECYCLE
+
+// NewParquet returns a writer of vectors to w.
+func NewParquet(w io.Writer) (*Parquet, error) {
+	p := &Parquet{w: w}
+	if err := p.write([]byte(parquetMagic)); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Close writes the remaining rows and the metadata of the file.
+func (p *Parquet) Close() error {
+	if err := p.flush(); err != nil {
+		return err
+	}
+	metadata := p.metadata()
+	footer := binary.LittleEndian.AppendUint32(metadata, uint32(len(metadata)))
+	return p.write(append(footer, parquetMagic...))
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Write appends a vector, which has the same dimension as the first vector.
+func (p *Parquet) Write(id string, vector []float32) error {
+	if p.rows == 0 && len(p.ids) == 0 {
+		p.dimension = len(vector)
+	} else if len(vector) != p.dimension {
+		return llama.ErrInvalidArgument.Withf("vector %q has %d dimensions, expected %d", id, len(vector), p.dimension)
+	}
+	p.ids = append(p.ids, id)
+	p.vectors = append(p.vectors, vector)
+	if len(p.ids) >= parquetGroupRows {
+		return p.flush()
+	}
+	return nil
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// write writes data to the file, and advances the offset
+func (p *Parquet) write(data []byte) error {
+	n, err := p.w.Write(data)
+	p.offset += int64(n)
+	return err
+}
+
+// flush writes the pending rows as a row group, with one data page for each
+// column
+func (p *Parquet) flush() error {
+	if len(p.ids) == 0 {
+		return nil
+	}
+	group := parquetRowGroup{rows: int64(len(p.ids))}
+
+	// The id column has no levels, and each value has its length first
+	var data []byte
+	for _, id := range p.ids {
+		data = binary.LittleEndian.AppendUint32(data, uint32(len(id)))
+		data = append(data, id...)
+	}
+	column, err := p.writePage([]string{"id"}, parquetByteArray, int64(len(p.ids)), data)
+	if err != nil {
+		return err
+	}
+	group.columns = append(group.columns, column)
+
+	// The elements of each list have a repetition level of zero for the first
+	// element and one for the others, and a definition level of one. An empty
+	// list is a single level of zero with no value.
+	var rep, def parquetLevels
+	data = data[:0]
+	var values int64
+	for _, vector := range p.vectors {
+		if len(vector) == 0 {
+			rep.append(0, 1)
+			def.append(0, 1)
+			values++
+			continue
+		}
+		rep.append(0, 1)
+		rep.append(1, len(vector)-1)
+		def.append(1, len(vector))
+		values += int64(len(vector))
+		for _, v := range vector {
+			data = binary.LittleEndian.AppendUint32(data, math.Float32bits(v))
+		}
+	}
+	data = append(def.encode(), data...)
+	data = append(rep.encode(), data...)
+	column, err = p.writePage([]string{"embedding", "list", "element"}, parquetFloat, values, data)
+	if err != nil {
+		return err
+	}
+	group.columns = append(group.columns, column)
+
+	p.groups = append(p.groups, group)
+	p.rows += group.rows
+	p.ids, p.vectors = p.ids[:0], p.vectors[:0]
+	return nil
+}
+
+// writePage writes a column chunk with a single uncompressed data page
+func (p *Parquet) writePage(path []string, typ int32, values int64, data []byte) (parquetColumn, error) {
+	var header thrift
+	header.i32(1, parquetDataPage)
+	header.i32(2, int32(len(data)))
+	header.i32(3, int32(len(data)))
+	header.begin(5)
+	header.i32(1, int32(values))
+	header.i32(2, parquetPlain)
+	header.i32(3, parquetRLE)
+	header.i32(4, parquetRLE)
+	header.end()
+	header.end()
+
+	column := parquetColumn{
+		path:   path,
+		typ:    typ,
+		values: values,
+		offset: p.offset,
+		size:   int64(len(header.buf) + len(data)),
+	}
+	if err := p.write(header.buf); err != nil {
+		return column, err
+	}
+	return column, p.write(data)
+}
+
+// metadata returns the file metadata, with the schema and the position of
+// each row group
+func (p *Parquet) metadata() []byte {
+	var t thrift
+	t.i32(1, 1)
+
+	// The schema has a root, the id, and a list of floats in three levels
+	t.list(2, thriftStruct, 5)
+	t.element()
+	t.binary(4, "schema")
+	t.i32(5, 2)
+	t.end()
+	t.element()
+	t.i32(1, parquetByteArray)
+	t.i32(3, parquetRequired)
+	t.binary(4, "id")
+	t.i32(6, parquetUTF8)
+	t.end()
+	t.element()
+	t.i32(3, parquetRequired)
+	t.binary(4, "embedding")
+	t.i32(5, 1)
+	t.i32(6, parquetList)
+	t.end()
+	t.element()
+	t.i32(3, parquetRepeated)
+	t.binary(4, "list")
+	t.i32(5, 1)
+	t.end()
+	t.element()
+	t.i32(1, parquetFloat)
+	t.i32(3, parquetRequired)
+	t.binary(4, "element")
+	t.end()
+
+	t.i64(3, p.rows)
+	t.list(4, thriftStruct, len(p.groups))
+	for _, group := range p.groups {
+		var size int64
+		t.element()
+		t.list(1, thriftStruct, len(group.columns))
+		for _, column := range group.columns {
+			t.element()
+			t.i64(2, column.offset)
+			t.begin(3)
+			t.i32(1, column.typ)
+			t.list(2, thriftI32, 2)
+			t.varint(parquetPlain)
+			t.varint(parquetRLE)
+			t.list(3, thriftBinary, len(column.path))
+			for _, name := range column.path {
+				t.bytes(name)
+			}
+			t.i32(4, 0)
+			t.i64(5, column.values)
+			t.i64(6, column.size)
+			t.i64(7, column.size)
+			t.i64(9, column.offset)
+			t.end()
+			t.end()
+			size += column.size
+		}
+		t.i64(2, size)
+		t.i64(3, group.rows)
+		t.end()
+	}
+	t.binary(6, "go-llama")
+	t.end()
+	return t.buf
+}
+
+// append adds n levels, extending the last run when it has the same level
+func (l *parquetLevels) append(level byte, n int) {
+	if n <= 0 {
+		return
+	}
+	if last := len(l.runs) - 1; last >= 0 && l.runs[last].level == level {
+		l.runs[last].count += n
+	} else {
+		l.runs = append(l.runs, parquetRun{level: level, count: n})
+	}
+}
+
+// encode returns the levels with the RLE encoding for a bit width of one,
+// with the length first
+func (l *parquetLevels) encode() []byte {
+	data := make([]byte, 4)
+	for _, run := range l.runs {
+		data = binary.AppendUvarint(data, uint64(run.count)<<1)
+		data = append(data, run.level)
+	}
+	binary.LittleEndian.PutUint32(data, uint32(len(data)-4))
+	return data
+}
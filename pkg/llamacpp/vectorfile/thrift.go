@@ -0,0 +1,103 @@
+package vectorfile
+
+import (
+	"encoding/binary"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// thrift encodes structures with the Thrift compact protocol, which is used
+// for Parquet metadata
+type thrift struct {
+	buf   []byte
+	field int16   // Last field of the current structure
+	stack []int16 // Last fields of the enclosing structures
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+// Thrift compact types
+const (
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// i32 writes a 32-bit integer field
+func (t *thrift) i32(id int16, v int32) {
+	t.header(id, thriftI32)
+	t.varint(v)
+}
+
+// i64 writes a 64-bit integer field
+func (t *thrift) i64(id int16, v int64) {
+	t.header(id, thriftI64)
+	t.buf = binary.AppendUvarint(t.buf, uint64((v<<1)^(v>>63)))
+}
+
+// binary writes a string field
+func (t *thrift) binary(id int16, v string) {
+	t.header(id, thriftBinary)
+	t.bytes(v)
+}
+
+// list writes the header of a list field, which is followed by n elements
+func (t *thrift) list(id int16, typ byte, n int) {
+	t.header(id, thriftList)
+	if n < 15 {
+		t.buf = append(t.buf, byte(n<<4)|typ)
+	} else {
+		t.buf = append(t.buf, 0xF0|typ)
+		t.buf = binary.AppendUvarint(t.buf, uint64(n))
+	}
+}
+
+// begin writes the header of a structure field, which is ended with end
+func (t *thrift) begin(id int16) {
+	t.header(id, thriftStruct)
+	t.element()
+}
+
+// element begins a structure which is an element of a list
+func (t *thrift) element() {
+	t.stack = append(t.stack, t.field)
+	t.field = 0
+}
+
+// end ends the current structure
+func (t *thrift) end() {
+	t.buf = append(t.buf, 0)
+	if n := len(t.stack); n > 0 {
+		t.field, t.stack = t.stack[n-1], t.stack[:n-1]
+	}
+}
+
+// varint writes a 32-bit integer, which is a field value or list element
+func (t *thrift) varint(v int32) {
+	t.buf = binary.AppendUvarint(t.buf, uint64(uint32((v<<1)^(v>>31))))
+}
+
+// bytes writes a string, which is a field value or list element
+func (t *thrift) bytes(v string) {
+	t.buf = binary.AppendUvarint(t.buf, uint64(len(v)))
+	t.buf = append(t.buf, v...)
+}
+
+// header writes the type and identifier of a field, with the identifier as
+// a difference from the last field when it is small
+func (t *thrift) header(id int16, typ byte) {
+	if delta := id - t.field; delta > 0 && delta <= 15 {
+		t.buf = append(t.buf, byte(delta<<4)|typ)
+	} else {
+		t.buf = append(t.buf, typ)
+		t.buf = binary.AppendUvarint(t.buf, uint64(uint16((id<<1)^(id>>15))))
+	}
+	t.field = id
+}
@@ -0,0 +1,16 @@
+// Package vectorfile writes embeddings to files which can be read by data
+// tools, as NumPy arrays or Parquet tables.
+package vectorfile
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// Writer appends vectors and their identifiers to a file. The file is
+// complete once the writer is closed.
+type Writer interface {
+	// Write appends a vector and its identifier
+	Write(id string, vector []float32) error
+
+	// Close completes the file, but does not close the underlying writer
+	Close() error
+}
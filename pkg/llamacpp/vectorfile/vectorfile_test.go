@@ -0,0 +1,347 @@
+package vectorfile
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	llama "github.com/mutablelogic/go-llama"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNPY_Write(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	f, err := os.Create(filepath.Join(t.TempDir(), "vectors.npy"))
+	require.NoError(err)
+	defer f.Close()
+
+	var ids bytes.Buffer
+	npy, err := NewNPY(f, &ids)
+	require.NoError(err)
+	require.NoError(npy.Write("a", []float32{1, 2, 3}))
+	require.NoError(npy.Write("b", []float32{4, 5, 6}))
+	assert.ErrorIs(npy.Write("c", []float32{1}), llama.ErrInvalidArgument)
+	require.NoError(npy.Close())
+
+	data, err := os.ReadFile(f.Name())
+	require.NoError(err)
+	require.Len(data, npyHeaderSize+6*4)
+
+	// The header has the shape, and is padded to the fixed size
+	assert.Equal(npyMagic, string(data[:8]))
+	size := int(binary.LittleEndian.Uint16(data[8:10]))
+	assert.Equal(npyHeaderSize, 10+size)
+	header := string(data[10:npyHeaderSize])
+	assert.True(strings.HasPrefix(header, "{'descr': '<f4', 'fortran_order': False, 'shape': (2, 3), }"))
+	assert.True(strings.HasSuffix(header, " \n"))
+
+	// The vectors follow, one row after another
+	for i, want := range []float32{1, 2, 3, 4, 5, 6} {
+		got := math.Float32frombits(binary.LittleEndian.Uint32(data[npyHeaderSize+i*4:]))
+		assert.Equal(want, got)
+	}
+	assert.Equal("\"a\"\n\"b\"\n", ids.String())
+}
+
+func TestNPY_Empty(t *testing.T) {
+	require := require.New(t)
+
+	f, err := os.Create(filepath.Join(t.TempDir(), "vectors.npy"))
+	require.NoError(err)
+	defer f.Close()
+
+	npy, err := NewNPY(f, nil)
+	require.NoError(err)
+	require.NoError(npy.Close())
+
+	data, err := os.ReadFile(f.Name())
+	require.NoError(err)
+	require.Len(data, npyHeaderSize)
+	require.Contains(string(data), "'shape': (0, 0)")
+}
+
+func TestParquet_Write(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	// Write more rows than fit in one row group
+	var buf bytes.Buffer
+	w, err := NewParquet(&buf)
+	require.NoError(err)
+	rows := parquetGroupRows + 10
+	for i := range rows {
+		require.NoError(w.Write(strings.Repeat("x", i%5), []float32{float32(i), 0.5}))
+	}
+	assert.ErrorIs(w.Write("short", []float32{1}), llama.ErrInvalidArgument)
+	require.NoError(w.Close())
+
+	// The file starts and ends with the magic, with the metadata before the end
+	data := buf.Bytes()
+	require.Greater(len(data), 12)
+	assert.Equal(parquetMagic, string(data[:4]))
+	assert.Equal(parquetMagic, string(data[len(data)-4:]))
+	size := int(binary.LittleEndian.Uint32(data[len(data)-8:]))
+	require.Less(size, len(data)-12)
+	metadata, n := readThriftStruct(t, data[len(data)-8-size:len(data)-8])
+	assert.Equal(size, n)
+
+	// Check the version, the schema and the number of rows
+	assert.Equal(int64(1), metadata[1])
+	assert.Equal(int64(rows), metadata[3])
+	assert.Equal("go-llama", metadata[6])
+	var names []string
+	for _, element := range metadata[2].([]any) {
+		names = append(names, element.(map[int16]any)[4].(string))
+	}
+	assert.Equal([]string{"schema", "id", "embedding", "list", "element"}, names)
+
+	// Check each row group, and the page of each column
+	groups := metadata[4].([]any)
+	require.Len(groups, 2)
+	total := int64(0)
+	for _, group := range groups {
+		group := group.(map[int16]any)
+		total += group[3].(int64)
+		for _, column := range group[1].([]any) {
+			meta := column.(map[int16]any)[3].(map[int16]any)
+			offset := meta[9].(int64)
+			page, n := readThriftStruct(t, data[offset:])
+			assert.Equal(int64(0), page[1])
+			assert.Equal(meta[6], int64(n)+page[2].(int64))
+			assert.Equal(meta[5], page[5].(map[int16]any)[1])
+		}
+	}
+	assert.Equal(int64(rows), total)
+}
+
+func TestParquet_RoundTrip(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	// Write more rows than fit in one row group
+	var buf bytes.Buffer
+	w, err := NewParquet(&buf)
+	require.NoError(err)
+	var ids []string
+	var vectors [][]float32
+	for i := range parquetGroupRows + 10 {
+		ids = append(ids, strings.Repeat("x", i%5))
+		vectors = append(vectors, []float32{float32(i), -0.5, float32(math.Inf(1))})
+		require.NoError(w.Write(ids[i], vectors[i]))
+	}
+	require.NoError(w.Close())
+
+	// The rows are read back from the pages of each row group
+	readIDs, readVectors := readParquetRows(t, buf.Bytes())
+	assert.Equal(ids, readIDs)
+	assert.Equal(vectors, readVectors)
+}
+
+func TestParquet_PyArrow(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	// Read the file with Apache Arrow, when it is installed
+	if err := exec.Command("python3", "-c", "import pyarrow.parquet").Run(); err != nil {
+		t.Skip("Skipping test: pyarrow is not installed")
+	}
+	path := filepath.Join(t.TempDir(), "vectors.parquet")
+	f, err := os.Create(path)
+	require.NoError(err)
+	w, err := NewParquet(f)
+	require.NoError(err)
+	for i := range parquetGroupRows + 10 {
+		require.NoError(w.Write(strconv.Itoa(i), []float32{float32(i), 0.5}))
+	}
+	require.NoError(w.Close())
+	require.NoError(f.Close())
+
+	script := `import json, sys, pyarrow.parquet as pq
+table = pq.read_table(sys.argv[1])
+print(json.dumps({"schema": str(table.schema), "rows": table.to_pylist()}))`
+	out, err := exec.Command("python3", "-c", script, path).Output()
+	require.NoError(err)
+	var result struct {
+		Schema string `json:"schema"`
+		Rows   []struct {
+			ID        string    `json:"id"`
+			Embedding []float32 `json:"embedding"`
+		} `json:"rows"`
+	}
+	require.NoError(json.Unmarshal(out, &result))
+	assert.Contains(result.Schema, "id: string not null")
+	require.Len(result.Rows, parquetGroupRows+10)
+	for i, row := range result.Rows {
+		assert.Equal(strconv.Itoa(i), row.ID)
+		assert.Equal([]float32{float32(i), 0.5}, row.Embedding)
+	}
+}
+
+func TestParquetLevels_Encode(t *testing.T) {
+	assert := assert.New(t)
+
+	var levels parquetLevels
+	levels.append(0, 1)
+	levels.append(1, 2)
+	levels.append(1, 0)
+	levels.append(0, 1)
+	levels.append(1, 200)
+	assert.Equal([]byte{
+		9, 0, 0, 0, // Length
+		2, 0, // One zero
+		4, 1, // Two ones
+		2, 0, // One zero
+		0x90, 0x03, 1, // 200 ones
+	}, levels.encode())
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// readThriftStruct decodes a structure with the Thrift compact protocol,
+// and returns its fields by identifier and the number of bytes read
+func readThriftStruct(t *testing.T, data []byte) (map[int16]any, int) {
+	t.Helper()
+	fields := make(map[int16]any)
+	pos := 0
+	var id int16
+	for {
+		b := data[pos]
+		pos++
+		if b == 0 {
+			return fields, pos
+		}
+		typ := b & 0x0F
+		if delta := int16(b >> 4); delta != 0 {
+			id += delta
+		} else {
+			v, n := binary.Uvarint(data[pos:])
+			pos += n
+			id = int16(v>>1) ^ -int16(v&1)
+		}
+		var n int
+		fields[id], n = readThriftValue(t, typ, data[pos:])
+		pos += n
+	}
+}
+
+// readThriftValue decodes a value of a type, and returns the number of bytes
+// read
+func readThriftValue(t *testing.T, typ byte, data []byte) (any, int) {
+	t.Helper()
+	switch typ {
+	case thriftI32, thriftI64:
+		v, n := binary.Uvarint(data)
+		return int64(v>>1) ^ -int64(v&1), n
+	case thriftBinary:
+		size, n := binary.Uvarint(data)
+		return string(data[n : n+int(size)]), n + int(size)
+	case thriftList:
+		size, elem, pos := int(data[0]>>4), data[0]&0x0F, 1
+		if size == 15 {
+			v, n := binary.Uvarint(data[1:])
+			size, pos = int(v), pos+n
+		}
+		list := make([]any, size)
+		for i := range list {
+			var n int
+			list[i], n = readThriftValue(t, elem, data[pos:])
+			pos += n
+		}
+		return list, pos
+	case thriftStruct:
+		return readThriftStruct(t, data)
+	default:
+		t.Fatalf("unexpected thrift type %d", typ)
+		return nil, 0
+	}
+}
+
+// readParquetRows returns the identifiers and vectors of a file, decoding
+// the levels and values of the page of each column in each row group
+func readParquetRows(t *testing.T, data []byte) ([]string, [][]float32) {
+	t.Helper()
+	size := int(binary.LittleEndian.Uint32(data[len(data)-8:]))
+	metadata, _ := readThriftStruct(t, data[len(data)-8-size:len(data)-8])
+
+	var ids []string
+	var vectors [][]float32
+	for _, group := range metadata[4].([]any) {
+		for _, column := range group.(map[int16]any)[1].([]any) {
+			meta := column.(map[int16]any)[3].(map[int16]any)
+			offset := meta[9].(int64)
+			page, n := readThriftStruct(t, data[offset:])
+			values := int(page[5].(map[int16]any)[1].(int64))
+			body := data[offset+int64(n) : offset+int64(n)+page[3].(int64)]
+			switch path := meta[3].([]any); path[0] {
+			case "id":
+				// Plain byte arrays, each with its length first
+				for range values {
+					length := int(binary.LittleEndian.Uint32(body))
+					ids = append(ids, string(body[4:4+length]))
+					body = body[4+length:]
+				}
+			case "embedding":
+				// Repetition levels, then definition levels, then the values
+				// of the defined elements
+				rep, n := readParquetLevels(t, body, values)
+				body = body[n:]
+				def, n := readParquetLevels(t, body, values)
+				body = body[n:]
+				for i := range values {
+					if rep[i] == 0 {
+						vectors = append(vectors, []float32{})
+					}
+					if def[i] == 1 {
+						last := len(vectors) - 1
+						vectors[last] = append(vectors[last], math.Float32frombits(binary.LittleEndian.Uint32(body)))
+						body = body[4:]
+					}
+				}
+			default:
+				t.Fatalf("unexpected column %v", path)
+			}
+			assert.Empty(t, body, "unread values in column")
+		}
+	}
+	return ids, vectors
+}
+
+// readParquetLevels decodes levels with a bit width of one, which have
+// their length first and are runs or bit-packed groups of eight, and
+// returns the levels and the number of bytes read
+func readParquetLevels(t *testing.T, data []byte, count int) ([]byte, int) {
+	t.Helper()
+	length := int(binary.LittleEndian.Uint32(data))
+	buf := data[4 : 4+length]
+	var levels []byte
+	for len(buf) > 0 {
+		header, n := binary.Uvarint(buf)
+		buf = buf[n:]
+		if header&1 == 0 {
+			for range header >> 1 {
+				levels = append(levels, buf[0])
+			}
+			buf = buf[1:]
+		} else {
+			for _, b := range buf[:header>>1] {
+				for bit := range 8 {
+					levels = append(levels, (b>>bit)&1)
+				}
+			}
+			buf = buf[header>>1:]
+		}
+	}
+	require.GreaterOrEqual(t, len(levels), count)
+	return levels[:count], 4 + length
+}
@@ -0,0 +1,52 @@
+package gguf
+
+///////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	// FileExtension is the file extension for GGUF model files
+	FileExtension = ".gguf"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS - COMMON ACCESSORS
+
+// Name returns the model name from metadata, or empty string if not found
+func (c *Context) Name() string {
+	if v, err := c.MetaValue("general.name"); err == nil {
+		if s, ok := v.(string); ok {
+			return s
+		}
+	}
+	return ""
+}
+
+// Architecture returns the model architecture, or empty string if not found
+func (c *Context) Architecture() string {
+	if v, err := c.MetaValue("general.architecture"); err == nil {
+		if s, ok := v.(string); ok {
+			return s
+		}
+	}
+	return ""
+}
+
+// Description returns the model description, or empty string if not found
+func (c *Context) Description() string {
+	if v, err := c.MetaValue("general.description"); err == nil {
+		if s, ok := v.(string); ok {
+			return s
+		}
+	}
+	return ""
+}
+
+// ChatTemplate returns the chat template, or empty string if not found
+func (c *Context) ChatTemplate() string {
+	if v, err := c.MetaValue("tokenizer.chat_template"); err == nil {
+		if s, ok := v.(string); ok {
+			return s
+		}
+	}
+	return ""
+}
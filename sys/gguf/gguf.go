@@ -1,3 +1,5 @@
+//go:build !client
+
 package gguf
 
 ///////////////////////////////////////////////////////////////////////////////
@@ -8,6 +10,7 @@ package gguf
 #cgo linux pkg-config: libllama-linux
 #cgo darwin pkg-config: libllama-darwin
 #cgo windows pkg-config: libllama-windows
+#include <ggml.h>
 #include <gguf.h>
 #include <stdlib.h>
 */
@@ -17,20 +20,13 @@ import (
 	"unsafe"
 )
 
-///////////////////////////////////////////////////////////////////////////////
-// CONSTANTS
-
-const (
-	// FileExtension is the file extension for GGUF model files
-	FileExtension = ".gguf"
-)
-
 ///////////////////////////////////////////////////////////////////////////////
 // TYPES
 
 // Context represents a GGUF file context for reading metadata
 type Context struct {
-	ctx *C.struct_gguf_context
+	ctx  *C.struct_gguf_context
+	meta *C.struct_ggml_context // Tensor metadata (no data allocated)
 }
 
 ///////////////////////////////////////////////////////////////////////////////
@@ -42,21 +38,28 @@ func Open(path string) (*Context, error) {
 	cPath := C.CString(path)
 	defer C.free(unsafe.Pointer(cPath))
 
+	// Tensor shapes are only available through the ggml context, which is
+	// created without allocating any tensor data
+	c := new(Context)
 	params := C.struct_gguf_init_params{
 		no_alloc: C.bool(true),
-		ctx:      nil,
+		ctx:      &c.meta,
 	}
 
-	ctx := C.gguf_init_from_file(cPath, params)
-	if ctx == nil {
+	c.ctx = C.gguf_init_from_file(cPath, params)
+	if c.ctx == nil {
 		return nil, ErrOpenFailed
 	}
 
-	return &Context{ctx: ctx}, nil
+	return c, nil
 }
 
 // Close releases the GGUF context resources
 func (c *Context) Close() error {
+	if c.meta != nil {
+		C.ggml_free(c.meta)
+		c.meta = nil
+	}
 	if c.ctx != nil {
 		C.gguf_free(c.ctx)
 		c.ctx = nil
@@ -102,11 +105,19 @@ func (c *Context) MetaValue(key string) (any, error) {
 		return nil, ErrKeyNotFound
 	}
 
-	return c.valueAtIndex(int(idx))
+	return c.valueAtIndex(int(idx), 0)
 }
 
-// AllMetadata returns all key-value pairs as a map
+// AllMetadata returns all key-value pairs as a map. Array values are
+// decoded into typed slices, for example []string or []int32.
 func (c *Context) AllMetadata() (map[string]any, error) {
+	return c.AllMetadataWithLimit(0)
+}
+
+// AllMetadataWithLimit returns all key-value pairs as a map. Arrays with more
+// than maxArrayLen elements (such as tokenizer.ggml.tokens) are returned as an
+// ArraySummary rather than being decoded. A maxArrayLen of zero decodes all arrays.
+func (c *Context) AllMetadataWithLimit(maxArrayLen int) (map[string]any, error) {
 	if c.ctx == nil {
 		return nil, ErrInvalidContext
 	}
@@ -119,7 +130,7 @@ func (c *Context) AllMetadata() (map[string]any, error) {
 		if err != nil {
 			return nil, err
 		}
-		value, err := c.valueAtIndex(i)
+		value, err := c.valueAtIndex(i, maxArrayLen)
 		if err != nil {
 			return nil, err
 		}
@@ -130,52 +141,70 @@ func (c *Context) AllMetadata() (map[string]any, error) {
 }
 
 ///////////////////////////////////////////////////////////////////////////////
-// PUBLIC METHODS - COMMON ACCESSORS
+// PUBLIC METHODS - TENSOR ACCESS
 
-// Name returns the model name from metadata, or empty string if not found
-func (c *Context) Name() string {
-	if v, err := c.MetaValue("general.name"); err == nil {
-		if s, ok := v.(string); ok {
-			return s
-		}
+// TensorCount returns the number of tensors in the file
+func (c *Context) TensorCount() int {
+	if c.ctx == nil {
+		return 0
 	}
-	return ""
+	return int(C.gguf_get_n_tensors(c.ctx))
 }
 
-// Architecture returns the model architecture, or empty string if not found
-func (c *Context) Architecture() string {
-	if v, err := c.MetaValue("general.architecture"); err == nil {
-		if s, ok := v.(string); ok {
-			return s
-		}
+// Tensor returns information about the tensor at the given index
+func (c *Context) Tensor(index int) (TensorInfo, error) {
+	if c.ctx == nil {
+		return TensorInfo{}, ErrInvalidContext
+	}
+	n := int(C.gguf_get_n_tensors(c.ctx))
+	if index < 0 || index >= n {
+		return TensorInfo{}, ErrIndexOutOfRange
 	}
-	return ""
-}
 
-// Description returns the model description, or empty string if not found
-func (c *Context) Description() string {
-	if v, err := c.MetaValue("general.description"); err == nil {
-		if s, ok := v.(string); ok {
-			return s
+	idx := C.int64_t(index)
+	name := C.gguf_get_tensor_name(c.ctx, idx)
+	info := TensorInfo{
+		Name:   C.GoString(name),
+		Type:   TensorType(C.gguf_get_tensor_type(c.ctx, idx)),
+		Offset: uint64(C.gguf_get_tensor_offset(c.ctx, idx)),
+		Size:   uint64(C.gguf_get_tensor_size(c.ctx, idx)),
+	}
+
+	// Shape is read from the ggml tensor metadata
+	if c.meta != nil {
+		if t := C.ggml_get_tensor(c.meta, name); t != nil {
+			dims := int(C.ggml_n_dims(t))
+			info.Shape = make([]int64, dims)
+			for i := 0; i < dims; i++ {
+				info.Shape[i] = int64(t.ne[i])
+			}
 		}
 	}
-	return ""
+
+	return info, nil
 }
 
-// ChatTemplate returns the chat template, or empty string if not found
-func (c *Context) ChatTemplate() string {
-	if v, err := c.MetaValue("tokenizer.chat_template"); err == nil {
-		if s, ok := v.(string); ok {
-			return s
+// Tensors returns information about all tensors in the file, in file order
+func (c *Context) Tensors() ([]TensorInfo, error) {
+	if c.ctx == nil {
+		return nil, ErrInvalidContext
+	}
+	n := c.TensorCount()
+	result := make([]TensorInfo, 0, n)
+	for i := 0; i < n; i++ {
+		info, err := c.Tensor(i)
+		if err != nil {
+			return nil, err
 		}
+		result = append(result, info)
 	}
-	return ""
+	return result, nil
 }
 
 ///////////////////////////////////////////////////////////////////////////////
 // PRIVATE METHODS
 
-func (c *Context) valueAtIndex(idx int) (any, error) {
+func (c *Context) valueAtIndex(idx int, maxArrayLen int) (any, error) {
 	valType := C.gguf_get_kv_type(c.ctx, C.int64_t(idx))
 
 	switch valType {
@@ -204,10 +233,69 @@ func (c *Context) valueAtIndex(idx int) (any, error) {
 	case C.GGUF_TYPE_STRING:
 		return C.GoString(C.gguf_get_val_str(c.ctx, C.int64_t(idx))), nil
 	case C.GGUF_TYPE_ARRAY:
-		// Arrays are complex - return nil for now
-		// Could be extended to handle typed arrays
-		return nil, nil
+		return c.arrayAtIndex(idx, maxArrayLen)
 	default:
 		return nil, ErrTypeMismatch
 	}
 }
+
+func (c *Context) arrayAtIndex(idx int, maxArrayLen int) (any, error) {
+	elemType := C.gguf_get_arr_type(c.ctx, C.int64_t(idx))
+	n := int(C.gguf_get_arr_n(c.ctx, C.int64_t(idx)))
+
+	// Summarise large arrays
+	if maxArrayLen > 0 && n > maxArrayLen {
+		return ArraySummary{Type: Type(elemType).String(), Len: n}, nil
+	}
+
+	// Strings are read one at a time
+	if elemType == C.GGUF_TYPE_STRING {
+		result := make([]string, n)
+		for i := range result {
+			result[i] = C.GoString(C.gguf_get_arr_str(c.ctx, C.int64_t(idx), C.size_t(i)))
+		}
+		return result, nil
+	}
+
+	// Other types are copied from the raw array data
+	var data unsafe.Pointer
+	if n > 0 {
+		data = unsafe.Pointer(C.gguf_get_arr_data(c.ctx, C.int64_t(idx)))
+	}
+	switch elemType {
+	case C.GGUF_TYPE_UINT8:
+		return copyArray[uint8](data, n), nil
+	case C.GGUF_TYPE_INT8:
+		return copyArray[int8](data, n), nil
+	case C.GGUF_TYPE_UINT16:
+		return copyArray[uint16](data, n), nil
+	case C.GGUF_TYPE_INT16:
+		return copyArray[int16](data, n), nil
+	case C.GGUF_TYPE_UINT32:
+		return copyArray[uint32](data, n), nil
+	case C.GGUF_TYPE_INT32:
+		return copyArray[int32](data, n), nil
+	case C.GGUF_TYPE_UINT64:
+		return copyArray[uint64](data, n), nil
+	case C.GGUF_TYPE_INT64:
+		return copyArray[int64](data, n), nil
+	case C.GGUF_TYPE_FLOAT32:
+		return copyArray[float32](data, n), nil
+	case C.GGUF_TYPE_FLOAT64:
+		return copyArray[float64](data, n), nil
+	case C.GGUF_TYPE_BOOL:
+		return copyArray[bool](data, n), nil
+	default:
+		// Nested arrays are not supported
+		return nil, ErrTypeMismatch
+	}
+}
+
+// copyArray copies n elements of type T from C memory into a Go slice
+func copyArray[T any](data unsafe.Pointer, n int) []T {
+	result := make([]T, n)
+	if n > 0 && data != nil {
+		copy(result, unsafe.Slice((*T)(data), n))
+	}
+	return result
+}
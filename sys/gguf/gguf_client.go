@@ -0,0 +1,121 @@
+//go:build client
+
+package gguf
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// Context represents a GGUF file context for reading metadata. In client
+// builds the file is decoded in pure Go, without linking llama.cpp.
+type Context struct {
+	file *File
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// LIFECYCLE
+
+// Open opens a GGUF file and returns a Context for reading metadata.
+// The caller must call Close() when done.
+func Open(path string) (*Context, error) {
+	file, err := ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return &Context{file: file}, nil
+}
+
+// Close releases the GGUF context resources
+func (c *Context) Close() error {
+	c.file = nil
+	return nil
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS - METADATA ACCESS
+
+// MetaCount returns the number of key-value pairs in the GGUF metadata
+func (c *Context) MetaCount() int {
+	if c.file == nil {
+		return 0
+	}
+	return len(c.file.KV)
+}
+
+// MetaKey returns the key at the given index
+func (c *Context) MetaKey(index int) (string, error) {
+	if c.file == nil {
+		return "", ErrInvalidContext
+	}
+	if index < 0 || index >= len(c.file.KV) {
+		return "", ErrIndexOutOfRange
+	}
+	return c.file.KV[index].Key, nil
+}
+
+// MetaValue returns the value for a given key as an interface{}
+func (c *Context) MetaValue(key string) (any, error) {
+	if c.file == nil {
+		return nil, ErrInvalidContext
+	}
+	idx := c.file.Find(key)
+	if idx < 0 {
+		return nil, ErrKeyNotFound
+	}
+	return c.file.KV[idx].Value, nil
+}
+
+// AllMetadata returns all key-value pairs as a map. Array values are
+// decoded into typed slices, for example []string or []int32.
+func (c *Context) AllMetadata() (map[string]any, error) {
+	return c.AllMetadataWithLimit(0)
+}
+
+// AllMetadataWithLimit returns all key-value pairs as a map. Arrays with more
+// than maxArrayLen elements (such as tokenizer.ggml.tokens) are returned as an
+// ArraySummary rather than being decoded. A maxArrayLen of zero decodes all arrays.
+func (c *Context) AllMetadataWithLimit(maxArrayLen int) (map[string]any, error) {
+	if c.file == nil {
+		return nil, ErrInvalidContext
+	}
+
+	result := make(map[string]any, len(c.file.KV))
+	for _, kv := range c.file.KV {
+		if n := kv.Len(); kv.Type == TypeArray && maxArrayLen > 0 && n > maxArrayLen {
+			result[kv.Key] = ArraySummary{Type: kv.ElemType.String(), Len: n}
+		} else {
+			result[kv.Key] = kv.Value
+		}
+	}
+
+	return result, nil
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS - TENSOR ACCESS
+
+// TensorCount returns the number of tensors in the file
+func (c *Context) TensorCount() int {
+	if c.file == nil {
+		return 0
+	}
+	return len(c.file.Tensors)
+}
+
+// Tensor returns information about the tensor at the given index
+func (c *Context) Tensor(index int) (TensorInfo, error) {
+	if c.file == nil {
+		return TensorInfo{}, ErrInvalidContext
+	}
+	if index < 0 || index >= len(c.file.Tensors) {
+		return TensorInfo{}, ErrIndexOutOfRange
+	}
+	return c.file.Tensors[index], nil
+}
+
+// Tensors returns information about all tensors in the file, in file order
+func (c *Context) Tensors() ([]TensorInfo, error) {
+	if c.file == nil {
+		return nil, ErrInvalidContext
+	}
+	return append([]TensorInfo(nil), c.file.Tensors...), nil
+}
@@ -188,3 +188,72 @@ func TestNilContext(t *testing.T) {
 	_, err = ctx.AllMetadata()
 	assert.ErrorIs(err, gguf.ErrInvalidContext)
 }
+
+func TestMetaValue_Array(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	path := filepath.Join(testdataDir, "stories260K.gguf")
+	ctx, err := gguf.Open(path)
+	require.NoError(err)
+	defer ctx.Close()
+
+	// Tokens are decoded as a string slice
+	value, err := ctx.MetaValue("tokenizer.ggml.tokens")
+	require.NoError(err)
+	tokens, ok := value.([]string)
+	require.True(ok, "expected []string, got %T", value)
+	assert.NotEmpty(tokens)
+
+	// Scores are decoded as a float32 slice of the same length
+	value, err = ctx.MetaValue("tokenizer.ggml.scores")
+	require.NoError(err)
+	scores, ok := value.([]float32)
+	require.True(ok, "expected []float32, got %T", value)
+	assert.Len(scores, len(tokens))
+}
+
+func TestAllMetadataWithLimit(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	path := filepath.Join(testdataDir, "stories260K.gguf")
+	ctx, err := gguf.Open(path)
+	require.NoError(err)
+	defer ctx.Close()
+
+	meta, err := ctx.AllMetadataWithLimit(16)
+	require.NoError(err)
+	summary, ok := meta["tokenizer.ggml.tokens"].(gguf.ArraySummary)
+	require.True(ok, "expected ArraySummary, got %T", meta["tokenizer.ggml.tokens"])
+	assert.Equal("str", summary.Type)
+	assert.Greater(summary.Len, 16)
+
+	// Scalars are unaffected
+	assert.Equal(ctx.Architecture(), meta["general.architecture"])
+}
+
+func TestTensors(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	path := filepath.Join(testdataDir, "stories260K.gguf")
+	ctx, err := gguf.Open(path)
+	require.NoError(err)
+	defer ctx.Close()
+
+	tensors, err := ctx.Tensors()
+	require.NoError(err)
+	require.Len(tensors, ctx.TensorCount())
+	require.NotEmpty(tensors)
+
+	for _, tensor := range tensors {
+		assert.NotEmpty(tensor.Name)
+		assert.NotEmpty(tensor.Shape, tensor.Name)
+		assert.Greater(tensor.Size, uint64(0), tensor.Name)
+		assert.Greater(tensor.Elements(), uint64(0), tensor.Name)
+	}
+
+	_, err = ctx.Tensor(len(tensors))
+	assert.ErrorIs(err, gguf.ErrIndexOutOfRange)
+}
@@ -0,0 +1,469 @@
+package gguf
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"os"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// File is a GGUF file header decoded without cgo. It contains the metadata
+// key-value pairs and tensor information, but not the tensor data itself.
+type File struct {
+	Version    uint32       // GGUF version (2 or 3)
+	Alignment  uint64       // Alignment of the tensor data section
+	DataOffset uint64       // Offset of the tensor data section from the start of the file
+	KV         []KV         // Metadata key-value pairs, in file order
+	Tensors    []TensorInfo // Tensor information, in file order
+}
+
+// KV is a metadata key-value pair
+type KV struct {
+	Key      string
+	Type     Type // Value type
+	ElemType Type // Element type, when Type is TypeArray
+	Value    any  // Decoded value, arrays are typed slices such as []string
+}
+
+// decoder reads little-endian GGUF values, tracking the file offset and
+// the number of bytes remaining so that corrupt lengths are rejected
+// before they are allocated
+type decoder struct {
+	r         *bufio.Reader
+	offset    uint64
+	remaining uint64
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	// Magic is the four byte magic number at the start of a GGUF file
+	Magic = "GGUF"
+
+	// DefaultAlignment is the data alignment when general.alignment is not set
+	DefaultAlignment = 32
+
+	// KeyAlignment is the metadata key which sets the data alignment
+	KeyAlignment = "general.alignment"
+
+	// Maximum number of tensor dimensions
+	maxDims = 4
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// LIFECYCLE
+
+// ReadFile decodes the header of a GGUF file at path
+func ReadFile(path string) (*File, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, ErrOpenFailed.With(err.Error())
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, ErrOpenFailed.With(err.Error())
+	}
+
+	return Decode(f, uint64(info.Size()))
+}
+
+// Decode decodes a GGUF header from r, which should contain size bytes.
+// A size of zero disables the length checks, for example when reading
+// from a stream of unknown length.
+func Decode(r io.Reader, size uint64) (*File, error) {
+	if size == 0 {
+		size = math.MaxUint64
+	}
+	d := &decoder{r: bufio.NewReaderSize(r, 1<<16), remaining: size}
+	file := new(File)
+
+	// Magic and version
+	var magic [4]byte
+	if err := d.read(magic[:]); err != nil {
+		return nil, err
+	} else if string(magic[:]) != Magic {
+		return nil, ErrOpenFailed.With("not a GGUF file")
+	}
+	version, err := d.u32()
+	if err != nil {
+		return nil, err
+	} else if version != 2 && version != 3 {
+		return nil, ErrOpenFailed.Withf("unsupported GGUF version %d", version)
+	}
+	file.Version = version
+
+	// Counts
+	nTensors, err := d.u64()
+	if err != nil {
+		return nil, err
+	}
+	nKV, err := d.u64()
+	if err != nil {
+		return nil, err
+	}
+	if err := d.check(nKV, 12); err != nil {
+		return nil, err
+	} else if err := d.check(nTensors, 24); err != nil {
+		return nil, err
+	}
+
+	// Metadata
+	file.KV = make([]KV, 0, nKV)
+	for i := uint64(0); i < nKV; i++ {
+		kv, err := d.kv()
+		if err != nil {
+			return nil, err
+		}
+		file.KV = append(file.KV, kv)
+	}
+
+	// Alignment
+	file.Alignment = DefaultAlignment
+	if kv := file.find(KeyAlignment); kv != nil {
+		if v, ok := kv.Value.(uint32); ok && v > 0 && v&(v-1) == 0 {
+			file.Alignment = uint64(v)
+		} else {
+			return nil, ErrOpenFailed.With("invalid " + KeyAlignment)
+		}
+	}
+
+	// Tensor information
+	file.Tensors = make([]TensorInfo, 0, nTensors)
+	for i := uint64(0); i < nTensors; i++ {
+		info, err := d.tensorInfo()
+		if err != nil {
+			return nil, err
+		}
+		if info.Offset%file.Alignment != 0 {
+			return nil, ErrOpenFailed.Withf("tensor %q is not aligned", info.Name)
+		}
+		file.Tensors = append(file.Tensors, info)
+	}
+
+	// The data section starts at the next aligned offset
+	file.DataOffset = align(d.offset, file.Alignment)
+
+	return file, nil
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Find returns the index of the metadata key, or -1 if it does not exist
+func (f *File) Find(key string) int {
+	for i := range f.KV {
+		if f.KV[i].Key == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// Len returns the number of elements of an array value, or zero for
+// a scalar value
+func (kv KV) Len() int {
+	switch v := kv.Value.(type) {
+	case []uint8:
+		return len(v)
+	case []int8:
+		return len(v)
+	case []uint16:
+		return len(v)
+	case []int16:
+		return len(v)
+	case []uint32:
+		return len(v)
+	case []int32:
+		return len(v)
+	case []uint64:
+		return len(v)
+	case []int64:
+		return len(v)
+	case []float32:
+		return len(v)
+	case []float64:
+		return len(v)
+	case []bool:
+		return len(v)
+	case []string:
+		return len(v)
+	case []any:
+		return len(v)
+	default:
+		return 0
+	}
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS - FILE
+
+func (f *File) find(key string) *KV {
+	if i := f.Find(key); i >= 0 {
+		return &f.KV[i]
+	}
+	return nil
+}
+
+// align rounds offset up to the next multiple of alignment
+func align(offset, alignment uint64) uint64 {
+	return (offset + alignment - 1) / alignment * alignment
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS - DECODER
+
+// check returns an error if n elements of at least size bytes each cannot
+// fit in the remainder of the file
+func (d *decoder) check(n, size uint64) error {
+	if n > d.remaining/size {
+		return ErrOpenFailed.With("truncated or corrupt GGUF file")
+	}
+	return nil
+}
+
+func (d *decoder) read(buf []byte) error {
+	if uint64(len(buf)) > d.remaining {
+		return ErrOpenFailed.With("truncated GGUF file")
+	}
+	if _, err := io.ReadFull(d.r, buf); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return ErrOpenFailed.With("truncated GGUF file")
+		}
+		return err
+	}
+	d.offset += uint64(len(buf))
+	d.remaining -= uint64(len(buf))
+	return nil
+}
+
+func (d *decoder) u8() (uint8, error) {
+	var buf [1]byte
+	if err := d.read(buf[:]); err != nil {
+		return 0, err
+	}
+	return buf[0], nil
+}
+
+func (d *decoder) u16() (uint16, error) {
+	var buf [2]byte
+	if err := d.read(buf[:]); err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint16(buf[:]), nil
+}
+
+func (d *decoder) u32() (uint32, error) {
+	var buf [4]byte
+	if err := d.read(buf[:]); err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint32(buf[:]), nil
+}
+
+func (d *decoder) u64() (uint64, error) {
+	var buf [8]byte
+	if err := d.read(buf[:]); err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint64(buf[:]), nil
+}
+
+func (d *decoder) str() (string, error) {
+	n, err := d.u64()
+	if err != nil {
+		return "", err
+	}
+	if err := d.check(n, 1); err != nil {
+		return "", err
+	}
+	buf := make([]byte, n)
+	if err := d.read(buf); err != nil {
+		return "", err
+	}
+	return string(buf), nil
+}
+
+func (d *decoder) kv() (KV, error) {
+	var kv KV
+	var err error
+	if kv.Key, err = d.str(); err != nil {
+		return kv, err
+	}
+	t, err := d.u32()
+	if err != nil {
+		return kv, err
+	}
+	kv.Type = Type(t)
+	if kv.Type == TypeArray {
+		kv.Value, kv.ElemType, err = d.array()
+	} else {
+		kv.Value, err = d.scalar(kv.Type)
+	}
+	if err != nil {
+		return kv, err
+	}
+	return kv, nil
+}
+
+func (d *decoder) scalar(t Type) (any, error) {
+	switch t {
+	case TypeUint8:
+		return d.u8()
+	case TypeInt8:
+		v, err := d.u8()
+		return int8(v), err
+	case TypeUint16:
+		return d.u16()
+	case TypeInt16:
+		v, err := d.u16()
+		return int16(v), err
+	case TypeUint32:
+		return d.u32()
+	case TypeInt32:
+		v, err := d.u32()
+		return int32(v), err
+	case TypeUint64:
+		return d.u64()
+	case TypeInt64:
+		v, err := d.u64()
+		return int64(v), err
+	case TypeFloat32:
+		v, err := d.u32()
+		return math.Float32frombits(v), err
+	case TypeFloat64:
+		v, err := d.u64()
+		return math.Float64frombits(v), err
+	case TypeBool:
+		v, err := d.u8()
+		return v != 0, err
+	case TypeString:
+		return d.str()
+	default:
+		return nil, ErrTypeMismatch.Withf("unknown metadata type %d", uint32(t))
+	}
+}
+
+func (d *decoder) array() (any, Type, error) {
+	t, err := d.u32()
+	if err != nil {
+		return nil, 0, err
+	}
+	elemType := Type(t)
+	n, err := d.u64()
+	if err != nil {
+		return nil, elemType, err
+	}
+
+	// Every element takes at least one byte, which bounds the allocation
+	if err := d.check(n, 1); err != nil {
+		return nil, elemType, err
+	}
+
+	var value any
+	switch elemType {
+	case TypeUint8:
+		value, err = decodeArray(n, d.u8)
+	case TypeInt8:
+		value, err = decodeArray(n, func() (int8, error) { v, err := d.u8(); return int8(v), err })
+	case TypeUint16:
+		value, err = decodeArray(n, d.u16)
+	case TypeInt16:
+		value, err = decodeArray(n, func() (int16, error) { v, err := d.u16(); return int16(v), err })
+	case TypeUint32:
+		value, err = decodeArray(n, d.u32)
+	case TypeInt32:
+		value, err = decodeArray(n, func() (int32, error) { v, err := d.u32(); return int32(v), err })
+	case TypeUint64:
+		value, err = decodeArray(n, d.u64)
+	case TypeInt64:
+		value, err = decodeArray(n, func() (int64, error) { v, err := d.u64(); return int64(v), err })
+	case TypeFloat32:
+		value, err = decodeArray(n, func() (float32, error) { v, err := d.u32(); return math.Float32frombits(v), err })
+	case TypeFloat64:
+		value, err = decodeArray(n, func() (float64, error) { v, err := d.u64(); return math.Float64frombits(v), err })
+	case TypeBool:
+		value, err = decodeArray(n, func() (bool, error) { v, err := d.u8(); return v != 0, err })
+	case TypeString:
+		value, err = decodeArray(n, d.str)
+	case TypeArray:
+		// Nested arrays are decoded as a slice of typed slices
+		value, err = decodeArray(n, func() (any, error) { v, _, err := d.array(); return v, err })
+	default:
+		err = ErrTypeMismatch.Withf("unknown array element type %d", t)
+	}
+	return value, elemType, err
+}
+
+func (d *decoder) tensorInfo() (TensorInfo, error) {
+	var info TensorInfo
+	var err error
+	if info.Name, err = d.str(); err != nil {
+		return info, err
+	}
+	dims, err := d.u32()
+	if err != nil {
+		return info, err
+	} else if dims == 0 || dims > maxDims {
+		return info, ErrOpenFailed.Withf("tensor %q has %d dimensions", info.Name, dims)
+	}
+	ne := make([]int64, dims)
+	for i := range ne {
+		v, err := d.u64()
+		if err != nil {
+			return info, err
+		} else if int64(v) < 0 {
+			return info, ErrOpenFailed.Withf("tensor %q has an invalid shape", info.Name)
+		}
+		ne[i] = int64(v)
+	}
+	t, err := d.u32()
+	if err != nil {
+		return info, err
+	}
+	info.Type = TensorType(t)
+	if info.Offset, err = d.u64(); err != nil {
+		return info, err
+	}
+
+	// Size of the tensor data, which requires a known type
+	blck, size := info.Type.BlockSize(), info.Type.TypeSize()
+	if blck == 0 {
+		return info, ErrOpenFailed.Withf("tensor %q has unknown type %d", info.Name, t)
+	} else if ne[0]%int64(blck) != 0 {
+		return info, ErrOpenFailed.Withf("tensor %q is not a multiple of the %s block size", info.Name, info.Type)
+	}
+	info.Size = uint64(ne[0]) / blck * size
+	for _, n := range ne[1:] {
+		info.Size *= uint64(n)
+	}
+
+	// Trailing dimensions of one are dropped, as ggml_n_dims does
+	for len(ne) > 1 && ne[len(ne)-1] == 1 {
+		ne = ne[:len(ne)-1]
+	}
+	info.Shape = ne
+
+	return info, nil
+}
+
+// decodeArray reads n elements using fn. The slice grows as elements are
+// read, so a corrupt length cannot cause a large up-front allocation
+func decodeArray[T any](n uint64, fn func() (T, error)) ([]T, error) {
+	result := make([]T, 0, min(n, 1<<16))
+	for i := uint64(0); i < n; i++ {
+		v, err := fn()
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, v)
+	}
+	return result, nil
+}
@@ -0,0 +1,87 @@
+package gguf_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mutablelogic/go-llama/sys/gguf"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadFile_InvalidPath(t *testing.T) {
+	assert := assert.New(t)
+
+	file, err := gguf.ReadFile("/nonexistent/path/model.gguf")
+	assert.Nil(file)
+	assert.ErrorIs(err, gguf.ErrOpenFailed)
+}
+
+func TestReadFile_Stories(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	path := filepath.Join(testdataDir, "stories260K.gguf")
+	file, err := gguf.ReadFile(path)
+	require.NoError(err)
+	assert.Contains([]uint32{2, 3}, file.Version)
+	assert.NotEmpty(file.KV)
+	assert.NotEmpty(file.Tensors)
+	assert.Zero(file.DataOffset % file.Alignment)
+
+	// All tensor data lies within the file
+	info, err := os.Stat(path)
+	require.NoError(err)
+	for _, tensor := range file.Tensors {
+		assert.LessOrEqual(file.DataOffset+tensor.Offset+tensor.Size, uint64(info.Size()), tensor.Name)
+	}
+}
+
+func TestReadFile_AgreesWithOpen(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	path := filepath.Join(testdataDir, "stories260K.gguf")
+	file, err := gguf.ReadFile(path)
+	require.NoError(err)
+	ctx, err := gguf.Open(path)
+	require.NoError(err)
+	defer ctx.Close()
+
+	// Metadata keys are in the same order with the same values
+	require.Equal(ctx.MetaCount(), len(file.KV))
+	for i, kv := range file.KV {
+		key, err := ctx.MetaKey(i)
+		require.NoError(err)
+		assert.Equal(key, kv.Key)
+		value, err := ctx.MetaValue(key)
+		require.NoError(err)
+		assert.Equal(value, kv.Value, key)
+	}
+
+	// Tensors are identical
+	tensors, err := ctx.Tensors()
+	require.NoError(err)
+	assert.Equal(tensors, file.Tensors)
+}
+
+func TestDecode_Invalid(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	// Not a GGUF file
+	_, err := gguf.Decode(bytes.NewReader([]byte("GGML\x03\x00\x00\x00")), 0)
+	assert.ErrorIs(err, gguf.ErrOpenFailed)
+
+	// Unsupported version
+	_, err = gguf.Decode(bytes.NewReader([]byte("GGUF\x01\x00\x00\x00")), 0)
+	assert.ErrorIs(err, gguf.ErrOpenFailed)
+
+	// Truncated file
+	data, err := os.ReadFile(filepath.Join(testdataDir, "stories260K.gguf"))
+	require.NoError(err)
+	_, err = gguf.Decode(bytes.NewReader(data[:1024]), 1024)
+	assert.ErrorIs(err, gguf.ErrOpenFailed)
+}
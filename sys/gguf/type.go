@@ -0,0 +1,215 @@
+package gguf
+
+import "fmt"
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// Type is the type of a metadata value
+type Type uint32
+
+// TensorType is the ggml data type of a tensor
+type TensorType uint32
+
+// TensorInfo describes a tensor stored in a GGUF file
+type TensorInfo struct {
+	Name   string     // Tensor name
+	Type   TensorType // Data type of the tensor
+	Shape  []int64    // Number of elements in each dimension
+	Offset uint64     // Offset of the tensor data, relative to the start of the data section
+	Size   uint64     // Size of the tensor data in bytes
+}
+
+// ArraySummary stands in for an array value which is too large to decode
+type ArraySummary struct {
+	Type string `json:"type"`   // Element type
+	Len  int    `json:"length"` // Number of elements
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	TypeUint8 Type = iota
+	TypeInt8
+	TypeUint16
+	TypeInt16
+	TypeUint32
+	TypeInt32
+	TypeFloat32
+	TypeBool
+	TypeString
+	TypeArray
+	TypeUint64
+	TypeInt64
+	TypeFloat64
+)
+
+const (
+	TensorTypeF32     TensorType = 0
+	TensorTypeF16     TensorType = 1
+	TensorTypeQ4_0    TensorType = 2
+	TensorTypeQ4_1    TensorType = 3
+	TensorTypeQ5_0    TensorType = 6
+	TensorTypeQ5_1    TensorType = 7
+	TensorTypeQ8_0    TensorType = 8
+	TensorTypeQ8_1    TensorType = 9
+	TensorTypeQ2_K    TensorType = 10
+	TensorTypeQ3_K    TensorType = 11
+	TensorTypeQ4_K    TensorType = 12
+	TensorTypeQ5_K    TensorType = 13
+	TensorTypeQ6_K    TensorType = 14
+	TensorTypeQ8_K    TensorType = 15
+	TensorTypeIQ2_XXS TensorType = 16
+	TensorTypeIQ2_XS  TensorType = 17
+	TensorTypeIQ3_XXS TensorType = 18
+	TensorTypeIQ1_S   TensorType = 19
+	TensorTypeIQ4_NL  TensorType = 20
+	TensorTypeIQ3_S   TensorType = 21
+	TensorTypeIQ2_S   TensorType = 22
+	TensorTypeIQ4_XS  TensorType = 23
+	TensorTypeI8      TensorType = 24
+	TensorTypeI16     TensorType = 25
+	TensorTypeI32     TensorType = 26
+	TensorTypeI64     TensorType = 27
+	TensorTypeF64     TensorType = 28
+	TensorTypeIQ1_M   TensorType = 29
+	TensorTypeBF16    TensorType = 30
+	TensorTypeTQ1_0   TensorType = 34
+	TensorTypeTQ2_0   TensorType = 35
+	TensorTypeMXFP4   TensorType = 39
+)
+
+var (
+	typeNames = map[Type]string{
+		TypeUint8:   "u8",
+		TypeInt8:    "i8",
+		TypeUint16:  "u16",
+		TypeInt16:   "i16",
+		TypeUint32:  "u32",
+		TypeInt32:   "i32",
+		TypeFloat32: "f32",
+		TypeBool:    "bool",
+		TypeString:  "str",
+		TypeArray:   "arr",
+		TypeUint64:  "u64",
+		TypeInt64:   "i64",
+		TypeFloat64: "f64",
+	}
+	tensorTypeNames = map[TensorType]string{
+		TensorTypeF32:     "f32",
+		TensorTypeF16:     "f16",
+		TensorTypeQ4_0:    "q4_0",
+		TensorTypeQ4_1:    "q4_1",
+		TensorTypeQ5_0:    "q5_0",
+		TensorTypeQ5_1:    "q5_1",
+		TensorTypeQ8_0:    "q8_0",
+		TensorTypeQ8_1:    "q8_1",
+		TensorTypeQ2_K:    "q2_K",
+		TensorTypeQ3_K:    "q3_K",
+		TensorTypeQ4_K:    "q4_K",
+		TensorTypeQ5_K:    "q5_K",
+		TensorTypeQ6_K:    "q6_K",
+		TensorTypeQ8_K:    "q8_K",
+		TensorTypeIQ2_XXS: "iq2_xxs",
+		TensorTypeIQ2_XS:  "iq2_xs",
+		TensorTypeIQ3_XXS: "iq3_xxs",
+		TensorTypeIQ1_S:   "iq1_s",
+		TensorTypeIQ4_NL:  "iq4_nl",
+		TensorTypeIQ3_S:   "iq3_s",
+		TensorTypeIQ2_S:   "iq2_s",
+		TensorTypeIQ4_XS:  "iq4_xs",
+		TensorTypeI8:      "i8",
+		TensorTypeI16:     "i16",
+		TensorTypeI32:     "i32",
+		TensorTypeI64:     "i64",
+		TensorTypeF64:     "f64",
+		TensorTypeIQ1_M:   "iq1_m",
+		TensorTypeBF16:    "bf16",
+		TensorTypeTQ1_0:   "tq1_0",
+		TensorTypeTQ2_0:   "tq2_0",
+		TensorTypeMXFP4:   "mxfp4",
+	}
+)
+
+// Block and type sizes, in elements and bytes, of each tensor type
+var tensorTypeSizes = map[TensorType][2]uint64{
+	TensorTypeF32:     {1, 4},
+	TensorTypeF16:     {1, 2},
+	TensorTypeQ4_0:    {32, 18},
+	TensorTypeQ4_1:    {32, 20},
+	TensorTypeQ5_0:    {32, 22},
+	TensorTypeQ5_1:    {32, 24},
+	TensorTypeQ8_0:    {32, 34},
+	TensorTypeQ8_1:    {32, 36},
+	TensorTypeQ2_K:    {256, 84},
+	TensorTypeQ3_K:    {256, 110},
+	TensorTypeQ4_K:    {256, 144},
+	TensorTypeQ5_K:    {256, 176},
+	TensorTypeQ6_K:    {256, 210},
+	TensorTypeQ8_K:    {256, 292},
+	TensorTypeIQ2_XXS: {256, 66},
+	TensorTypeIQ2_XS:  {256, 74},
+	TensorTypeIQ3_XXS: {256, 98},
+	TensorTypeIQ1_S:   {256, 50},
+	TensorTypeIQ4_NL:  {32, 18},
+	TensorTypeIQ3_S:   {256, 110},
+	TensorTypeIQ2_S:   {256, 82},
+	TensorTypeIQ4_XS:  {256, 136},
+	TensorTypeI8:      {1, 1},
+	TensorTypeI16:     {1, 2},
+	TensorTypeI32:     {1, 4},
+	TensorTypeI64:     {1, 8},
+	TensorTypeF64:     {1, 8},
+	TensorTypeIQ1_M:   {256, 56},
+	TensorTypeBF16:    {1, 2},
+	TensorTypeTQ1_0:   {256, 54},
+	TensorTypeTQ2_0:   {256, 66},
+	TensorTypeMXFP4:   {32, 17},
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+// String returns the name of the metadata value type
+func (t Type) String() string {
+	if name, ok := typeNames[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("type(%d)", uint32(t))
+}
+
+// String returns the ggml name of the tensor type, e.g. "q4_K"
+func (t TensorType) String() string {
+	if name, ok := tensorTypeNames[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("type(%d)", uint32(t))
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// BlockSize returns the number of elements in each block of the tensor
+// type, or zero if the type is unknown
+func (t TensorType) BlockSize() uint64 {
+	return tensorTypeSizes[t][0]
+}
+
+// TypeSize returns the size in bytes of each block of the tensor type,
+// or zero if the type is unknown
+func (t TensorType) TypeSize() uint64 {
+	return tensorTypeSizes[t][1]
+}
+
+// Elements returns the total number of elements in the tensor
+func (t TensorInfo) Elements() uint64 {
+	if len(t.Shape) == 0 {
+		return 0
+	}
+	n := uint64(1)
+	for _, d := range t.Shape {
+		n *= uint64(d)
+	}
+	return n
+}
@@ -0,0 +1,525 @@
+package gguf
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// encoder writes little-endian GGUF values, tracking the file offset
+type encoder struct {
+	w      *bufio.Writer
+	offset uint64
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS - EDIT
+
+// Set sets the value of a metadata key, adding the key if it does not exist.
+// The type is inferred from the Go type of value, which should be one of the
+// types returned by the reader, for example uint32, string or []int32.
+func (f *File) Set(key string, value any) error {
+	t, elemType, err := typeOf(value)
+	if err != nil {
+		return err
+	}
+	return f.SetKV(KV{Key: key, Type: t, ElemType: elemType, Value: value})
+}
+
+// SetKV sets a metadata key-value pair, replacing any existing value for the
+// key. New keys are appended after the existing keys.
+func (f *File) SetKV(kv KV) error {
+	if kv.Key == "" {
+		return ErrInvalidArgument.With("empty metadata key")
+	}
+	if err := kv.validate(); err != nil {
+		return err
+	}
+	if i := f.Find(kv.Key); i >= 0 {
+		f.KV[i] = kv
+	} else {
+		f.KV = append(f.KV, kv)
+	}
+	return nil
+}
+
+// Remove removes a metadata key, and returns false if the key did not exist
+func (f *File) Remove(key string) bool {
+	i := f.Find(key)
+	if i < 0 {
+		return false
+	}
+	f.KV = append(f.KV[:i], f.KV[i+1:]...)
+	return true
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS - WRITE
+
+// Encode writes the GGUF header, including metadata, tensor information and
+// the padding before the data section, to w. The tensor data is not written.
+func (f *File) Encode(w io.Writer) error {
+	e := &encoder{w: bufio.NewWriterSize(w, 1<<16)}
+
+	// Magic, version and counts
+	e.write([]byte(Magic))
+	e.u32(f.Version)
+	e.u64(uint64(len(f.Tensors)))
+	e.u64(uint64(len(f.KV)))
+
+	// Metadata
+	for _, kv := range f.KV {
+		if err := kv.validate(); err != nil {
+			return err
+		}
+		e.str(kv.Key)
+		e.u32(uint32(kv.Type))
+		if err := e.value(kv.Type, kv.Value); err != nil {
+			return ErrTypeMismatch.Withf("%q: %v", kv.Key, err)
+		}
+	}
+
+	// Tensor information. Trailing dimensions of one are implied, so the
+	// shape is written as it was read
+	for _, t := range f.Tensors {
+		shape := t.Shape
+		if len(shape) == 0 {
+			shape = []int64{1}
+		}
+		e.str(t.Name)
+		e.u32(uint32(len(shape)))
+		for _, n := range shape {
+			e.u64(uint64(n))
+		}
+		e.u32(uint32(t.Type))
+		e.u64(t.Offset)
+	}
+
+	// Pad to the start of the data section
+	alignment := f.Alignment
+	if alignment == 0 {
+		alignment = DefaultAlignment
+	}
+	e.write(make([]byte, align(e.offset, alignment)-e.offset))
+
+	return e.w.Flush()
+}
+
+// Rewrite copies the GGUF file at src to dst, applying edit to the metadata.
+// Tensor data is streamed across unchanged. The destination is written to a
+// temporary file and renamed, so dst may be the same path as src.
+func Rewrite(src, dst string, edit func(*File) error) error {
+	file, err := ReadFile(src)
+	if err != nil {
+		return err
+	}
+	alignment, dataOffset := file.Alignment, file.DataOffset
+
+	// Apply edits. Tensor offsets are relative to the data section, so the
+	// alignment cannot be changed without moving the tensor data
+	if edit != nil {
+		if err := edit(file); err != nil {
+			return err
+		}
+	}
+	if kv := file.find(KeyAlignment); kv != nil {
+		if v, ok := kv.Value.(uint32); !ok || uint64(v) != alignment {
+			return ErrInvalidArgument.With(KeyAlignment + " cannot be changed")
+		}
+	} else if alignment != DefaultAlignment {
+		return ErrInvalidArgument.With(KeyAlignment + " cannot be removed")
+	}
+	file.Alignment = alignment
+
+	// Open the source at the start of the data section
+	r, err := os.Open(src)
+	if err != nil {
+		return ErrOpenFailed.With(err.Error())
+	}
+	defer r.Close()
+	if _, err := r.Seek(int64(dataOffset), io.SeekStart); err != nil {
+		return err
+	}
+
+	// Write the header and tensor data to a temporary file
+	w, err := os.CreateTemp(filepath.Dir(dst), ".gguf-*.tmp")
+	if err != nil {
+		return err
+	}
+	tmp := w.Name()
+	defer os.Remove(tmp)
+	if err := file.Encode(w); err != nil {
+		w.Close()
+		return err
+	}
+	if _, err := io.Copy(w, r); err != nil {
+		w.Close()
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	// Keep the permissions of the source file
+	if info, err := r.Stat(); err == nil {
+		_ = os.Chmod(tmp, info.Mode().Perm())
+	}
+
+	return os.Rename(tmp, dst)
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS - PARSE
+
+// ParseType parses a metadata type name, such as "u32" or "str", or an array
+// type such as "arr[i32]", and returns the type and array element type
+func ParseType(s string) (Type, Type, error) {
+	if elem, ok := strings.CutPrefix(s, TypeArray.String()+"["); ok {
+		elem, ok = strings.CutSuffix(elem, "]")
+		if !ok {
+			return 0, 0, ErrInvalidArgument.Withf("invalid type %q", s)
+		}
+		elemType, _, err := ParseType(elem)
+		if err != nil {
+			return 0, 0, err
+		} else if elemType == TypeArray {
+			return 0, 0, ErrInvalidArgument.Withf("nested array type %q is not supported", s)
+		}
+		return TypeArray, elemType, nil
+	}
+	for t, name := range typeNames {
+		if name == s && t != TypeArray {
+			return t, 0, nil
+		}
+	}
+	return 0, 0, ErrInvalidArgument.Withf("invalid type %q", s)
+}
+
+// ParseValue parses the string form of a metadata value of the given type.
+// Array values are parsed as a JSON array, for example [1,2,3] or ["a","b"].
+func ParseValue(t, elemType Type, s string) (any, error) {
+	if t == TypeArray {
+		var values []json.RawMessage
+		if err := json.Unmarshal([]byte(s), &values); err != nil {
+			return nil, ErrInvalidArgument.Withf("invalid array %q: %v", s, err)
+		}
+		return parseArray(elemType, values)
+	}
+	return parseScalar(t, s)
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS - TYPES
+
+// typeOf returns the metadata type for a Go value
+func typeOf(value any) (Type, Type, error) {
+	switch value.(type) {
+	case uint8:
+		return TypeUint8, 0, nil
+	case int8:
+		return TypeInt8, 0, nil
+	case uint16:
+		return TypeUint16, 0, nil
+	case int16:
+		return TypeInt16, 0, nil
+	case uint32:
+		return TypeUint32, 0, nil
+	case int32:
+		return TypeInt32, 0, nil
+	case uint64:
+		return TypeUint64, 0, nil
+	case int64:
+		return TypeInt64, 0, nil
+	case float32:
+		return TypeFloat32, 0, nil
+	case float64:
+		return TypeFloat64, 0, nil
+	case bool:
+		return TypeBool, 0, nil
+	case string:
+		return TypeString, 0, nil
+	case []uint8:
+		return TypeArray, TypeUint8, nil
+	case []int8:
+		return TypeArray, TypeInt8, nil
+	case []uint16:
+		return TypeArray, TypeUint16, nil
+	case []int16:
+		return TypeArray, TypeInt16, nil
+	case []uint32:
+		return TypeArray, TypeUint32, nil
+	case []int32:
+		return TypeArray, TypeInt32, nil
+	case []uint64:
+		return TypeArray, TypeUint64, nil
+	case []int64:
+		return TypeArray, TypeInt64, nil
+	case []float32:
+		return TypeArray, TypeFloat32, nil
+	case []float64:
+		return TypeArray, TypeFloat64, nil
+	case []bool:
+		return TypeArray, TypeBool, nil
+	case []string:
+		return TypeArray, TypeString, nil
+	case []any:
+		return TypeArray, TypeArray, nil
+	default:
+		return 0, 0, ErrTypeMismatch.Withf("unsupported metadata value of type %T", value)
+	}
+}
+
+// validate checks that the Go type of the value matches the metadata type
+func (kv KV) validate() error {
+	if t, elemType, err := typeOf(kv.Value); err != nil {
+		return ErrTypeMismatch.Withf("%q: %v", kv.Key, err)
+	} else if t != kv.Type || (t == TypeArray && elemType != kv.ElemType) {
+		return ErrTypeMismatch.Withf("%q: value of type %T is not %s", kv.Key, kv.Value, formatType(kv.Type, kv.ElemType))
+	}
+	return nil
+}
+
+func formatType(t, elemType Type) string {
+	if t == TypeArray {
+		return fmt.Sprintf("%s[%s]", t, elemType)
+	}
+	return t.String()
+}
+
+func parseScalar(t Type, s string) (any, error) {
+	var v any
+	var err error
+	switch t {
+	case TypeUint8:
+		v, err = parseUint[uint8](s, 8)
+	case TypeInt8:
+		v, err = parseInt[int8](s, 8)
+	case TypeUint16:
+		v, err = parseUint[uint16](s, 16)
+	case TypeInt16:
+		v, err = parseInt[int16](s, 16)
+	case TypeUint32:
+		v, err = parseUint[uint32](s, 32)
+	case TypeInt32:
+		v, err = parseInt[int32](s, 32)
+	case TypeUint64:
+		v, err = parseUint[uint64](s, 64)
+	case TypeInt64:
+		v, err = parseInt[int64](s, 64)
+	case TypeFloat32:
+		var f float64
+		f, err = strconv.ParseFloat(s, 32)
+		v = float32(f)
+	case TypeFloat64:
+		v, err = strconv.ParseFloat(s, 64)
+	case TypeBool:
+		v, err = strconv.ParseBool(s)
+	case TypeString:
+		v = s
+	default:
+		return nil, ErrInvalidArgument.Withf("cannot parse value of type %s", t)
+	}
+	if err != nil {
+		return nil, ErrInvalidArgument.Withf("invalid %s value %q", t, s)
+	}
+	return v, nil
+}
+
+func parseArray(elemType Type, values []json.RawMessage) (any, error) {
+	switch elemType {
+	case TypeUint8:
+		return parseElements[uint8](elemType, values)
+	case TypeInt8:
+		return parseElements[int8](elemType, values)
+	case TypeUint16:
+		return parseElements[uint16](elemType, values)
+	case TypeInt16:
+		return parseElements[int16](elemType, values)
+	case TypeUint32:
+		return parseElements[uint32](elemType, values)
+	case TypeInt32:
+		return parseElements[int32](elemType, values)
+	case TypeUint64:
+		return parseElements[uint64](elemType, values)
+	case TypeInt64:
+		return parseElements[int64](elemType, values)
+	case TypeFloat32:
+		return parseElements[float32](elemType, values)
+	case TypeFloat64:
+		return parseElements[float64](elemType, values)
+	case TypeBool:
+		return parseElements[bool](elemType, values)
+	case TypeString:
+		return parseElements[string](elemType, values)
+	default:
+		return nil, ErrInvalidArgument.Withf("cannot parse array of type %s", elemType)
+	}
+}
+
+// parseElements parses each JSON element as a scalar of type elemType.
+// String elements are JSON strings, other elements are JSON literals.
+func parseElements[T any](elemType Type, values []json.RawMessage) ([]T, error) {
+	result := make([]T, 0, len(values))
+	for _, raw := range values {
+		s := string(raw)
+		if elemType == TypeString {
+			if err := json.Unmarshal(raw, &s); err != nil {
+				return nil, ErrInvalidArgument.Withf("invalid string %s", raw)
+			}
+		}
+		v, err := parseScalar(elemType, s)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, v.(T))
+	}
+	return result, nil
+}
+
+func parseUint[T uint8 | uint16 | uint32 | uint64](s string, bits int) (T, error) {
+	v, err := strconv.ParseUint(s, 0, bits)
+	return T(v), err
+}
+
+func parseInt[T int8 | int16 | int32 | int64](s string, bits int) (T, error) {
+	v, err := strconv.ParseInt(s, 0, bits)
+	return T(v), err
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS - ENCODER
+
+// write errors are reported when the buffer is flushed
+func (e *encoder) write(buf []byte) {
+	n, _ := e.w.Write(buf)
+	e.offset += uint64(n)
+}
+
+func (e *encoder) u8(v uint8) {
+	e.write([]byte{v})
+}
+
+func (e *encoder) u16(v uint16) {
+	e.write(binary.LittleEndian.AppendUint16(nil, v))
+}
+
+func (e *encoder) u32(v uint32) {
+	e.write(binary.LittleEndian.AppendUint32(nil, v))
+}
+
+func (e *encoder) u64(v uint64) {
+	e.write(binary.LittleEndian.AppendUint64(nil, v))
+}
+
+func (e *encoder) str(v string) {
+	e.u64(uint64(len(v)))
+	e.write([]byte(v))
+}
+
+func (e *encoder) bool(v bool) {
+	if v {
+		e.u8(1)
+	} else {
+		e.u8(0)
+	}
+}
+
+func (e *encoder) value(t Type, value any) error {
+	if t == TypeArray {
+		return e.array(value)
+	}
+	switch v := value.(type) {
+	case uint8:
+		e.u8(v)
+	case int8:
+		e.u8(uint8(v))
+	case uint16:
+		e.u16(v)
+	case int16:
+		e.u16(uint16(v))
+	case uint32:
+		e.u32(v)
+	case int32:
+		e.u32(uint32(v))
+	case uint64:
+		e.u64(v)
+	case int64:
+		e.u64(uint64(v))
+	case float32:
+		e.u32(math.Float32bits(v))
+	case float64:
+		e.u64(math.Float64bits(v))
+	case bool:
+		e.bool(v)
+	case string:
+		e.str(v)
+	default:
+		return fmt.Errorf("unsupported value of type %T", value)
+	}
+	return nil
+}
+
+func (e *encoder) array(value any) error {
+	_, elemType, err := typeOf(value)
+	if err != nil {
+		return err
+	}
+
+	// Nested arrays are written element by element
+	if v, ok := value.([]any); ok {
+		e.u32(uint32(TypeArray))
+		e.u64(uint64(len(v)))
+		for _, elem := range v {
+			if err := e.array(elem); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	e.u32(uint32(elemType))
+	switch v := value.(type) {
+	case []uint8:
+		encodeArray(e, v, e.u8)
+	case []int8:
+		encodeArray(e, v, func(v int8) { e.u8(uint8(v)) })
+	case []uint16:
+		encodeArray(e, v, e.u16)
+	case []int16:
+		encodeArray(e, v, func(v int16) { e.u16(uint16(v)) })
+	case []uint32:
+		encodeArray(e, v, e.u32)
+	case []int32:
+		encodeArray(e, v, func(v int32) { e.u32(uint32(v)) })
+	case []uint64:
+		encodeArray(e, v, e.u64)
+	case []int64:
+		encodeArray(e, v, func(v int64) { e.u64(uint64(v)) })
+	case []float32:
+		encodeArray(e, v, func(v float32) { e.u32(math.Float32bits(v)) })
+	case []float64:
+		encodeArray(e, v, func(v float64) { e.u64(math.Float64bits(v)) })
+	case []bool:
+		encodeArray(e, v, e.bool)
+	case []string:
+		encodeArray(e, v, e.str)
+	}
+	return nil
+}
+
+func encodeArray[T any](e *encoder, values []T, fn func(T)) {
+	e.u64(uint64(len(values)))
+	for _, v := range values {
+		fn(v)
+	}
+}
@@ -0,0 +1,140 @@
+package gguf_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mutablelogic/go-llama/sys/gguf"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRewrite_Unchanged(t *testing.T) {
+	require := require.New(t)
+
+	src := filepath.Join(testdataDir, "stories260K.gguf")
+	dst := filepath.Join(t.TempDir(), "copy.gguf")
+	require.NoError(gguf.Rewrite(src, dst, nil))
+
+	// Without edits, the copy is identical to the source
+	a, err := os.ReadFile(src)
+	require.NoError(err)
+	b, err := os.ReadFile(dst)
+	require.NoError(err)
+	require.True(bytes.Equal(a, b), "files differ")
+}
+
+func TestRewrite_Edit(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	src := filepath.Join(testdataDir, "stories260K.gguf")
+	dst := filepath.Join(t.TempDir(), "patched.gguf")
+	err := gguf.Rewrite(src, dst, func(f *gguf.File) error {
+		if err := f.Set("tokenizer.chat_template", "{{ messages }}"); err != nil {
+			return err
+		}
+		if err := f.Set("tokenizer.ggml.eog_token_ids", []int32{2, 3}); err != nil {
+			return err
+		}
+		if err := f.Set("general.name", "patched"); err != nil {
+			return err
+		}
+		assert.True(f.Remove("tokenizer.ggml.padding_token_id"))
+		assert.False(f.Remove("nonexistent"))
+		return nil
+	})
+	require.NoError(err)
+
+	before, err := gguf.ReadFile(src)
+	require.NoError(err)
+	after, err := gguf.ReadFile(dst)
+	require.NoError(err)
+
+	// Metadata is updated
+	assert.Equal(len(before.KV)+1, len(after.KV))
+	assert.Equal("patched", after.KV[after.Find("general.name")].Value)
+	assert.Equal("{{ messages }}", after.KV[after.Find("tokenizer.chat_template")].Value)
+	assert.Equal([]int32{2, 3}, after.KV[after.Find("tokenizer.ggml.eog_token_ids")].Value)
+	assert.Equal(-1, after.Find("tokenizer.ggml.padding_token_id"))
+	assert.Equal(before.KV[before.Find("tokenizer.ggml.tokens")], after.KV[after.Find("tokenizer.ggml.tokens")])
+
+	// Tensor information and data are unchanged
+	assert.Equal(before.Tensors, after.Tensors)
+	a, err := os.ReadFile(src)
+	require.NoError(err)
+	b, err := os.ReadFile(dst)
+	require.NoError(err)
+	assert.True(bytes.Equal(a[before.DataOffset:], b[after.DataOffset:]), "tensor data differs")
+
+	// The patched file can be opened
+	ctx, err := gguf.Open(dst)
+	require.NoError(err)
+	defer ctx.Close()
+	assert.Equal("{{ messages }}", ctx.ChatTemplate())
+}
+
+func TestRewrite_Alignment(t *testing.T) {
+	assert := assert.New(t)
+
+	src := filepath.Join(testdataDir, "stories260K.gguf")
+	dst := filepath.Join(t.TempDir(), "patched.gguf")
+	err := gguf.Rewrite(src, dst, func(f *gguf.File) error {
+		return f.Set(gguf.KeyAlignment, uint32(64))
+	})
+	assert.ErrorIs(err, gguf.ErrInvalidArgument)
+	assert.NoFileExists(dst)
+}
+
+func TestSetKV_TypeMismatch(t *testing.T) {
+	assert := assert.New(t)
+
+	f := new(gguf.File)
+	assert.ErrorIs(f.SetKV(gguf.KV{Key: "a", Type: gguf.TypeUint32, Value: int32(1)}), gguf.ErrTypeMismatch)
+	assert.ErrorIs(f.SetKV(gguf.KV{Key: "a", Type: gguf.TypeArray, ElemType: gguf.TypeString, Value: []int32{1}}), gguf.ErrTypeMismatch)
+	assert.ErrorIs(f.Set("a", 1), gguf.ErrTypeMismatch)
+	assert.ErrorIs(f.Set("", uint32(1)), gguf.ErrInvalidArgument)
+	assert.NoError(f.Set("a", uint32(1)))
+	assert.Len(f.KV, 1)
+}
+
+func TestParseValue(t *testing.T) {
+	tests := []struct {
+		typ   string
+		value string
+		want  any
+	}{
+		{"u8", "255", uint8(255)},
+		{"i8", "-1", int8(-1)},
+		{"u32", "0x10", uint32(16)},
+		{"i64", "-42", int64(-42)},
+		{"f32", "0.5", float32(0.5)},
+		{"f64", "1e-5", float64(1e-5)},
+		{"bool", "true", true},
+		{"str", "hello, world", "hello, world"},
+		{"arr[i32]", "[1, 2, 3]", []int32{1, 2, 3}},
+		{"arr[str]", `["a", "b,c"]`, []string{"a", "b,c"}},
+		{"arr[bool]", "[]", []bool{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.typ, func(t *testing.T) {
+			typ, elemType, err := gguf.ParseType(tt.typ)
+			require.NoError(t, err)
+			value, err := gguf.ParseValue(typ, elemType, tt.value)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, value)
+		})
+	}
+
+	// Errors
+	_, _, err := gguf.ParseType("arr[arr[u8]]")
+	assert.ErrorIs(t, err, gguf.ErrInvalidArgument)
+	_, _, err = gguf.ParseType("int")
+	assert.ErrorIs(t, err, gguf.ErrInvalidArgument)
+	_, err = gguf.ParseValue(gguf.TypeUint8, 0, "256")
+	assert.ErrorIs(t, err, gguf.ErrInvalidArgument)
+	_, err = gguf.ParseValue(gguf.TypeArray, gguf.TypeString, "[1]")
+	assert.ErrorIs(t, err, gguf.ErrInvalidArgument)
+}
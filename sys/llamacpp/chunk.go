@@ -0,0 +1,211 @@
+package llamacpp
+
+import "C"
+
+///////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// ChunkOptions configures how texts longer than a sequence are embedded.
+type ChunkOptions struct {
+	// Size is the most tokens in a chunk, including special tokens
+	// (0 = the most tokens the context can embed in one sequence)
+	Size int
+	// Overlap is the number of tokens repeated at the start of each chunk
+	// from the end of the previous chunk
+	Overlap int
+	// MaxChunks is the most chunks for each text, and the rest of the text
+	// is not embedded (0 = unlimited, 1 = truncate)
+	MaxChunks int
+}
+
+// EmbeddingChunk is the embedding of a span of one text.
+type EmbeddingChunk struct {
+	Input     int       // Index of the text
+	Start     int       // Start byte offset of the span in the text
+	End       int       // End byte offset of the span in the text
+	Tokens    int       // Number of tokens embedded, including special tokens
+	Embedding []float32 // Pooled embedding of the span
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// MaxEmbeddingTokens returns the most tokens which can be embedded in one
+// sequence. A pooled sequence must fit in a single physical batch.
+func (ctx *Context) MaxEmbeddingTokens() int {
+	return int(min(ctx.ContextSize(), ctx.BatchSize(), ctx.UBatchSize()))
+}
+
+// ComputeChunkEmbeddings splits each text into chunks on token boundaries,
+// which fit in one sequence, and computes the pooled embedding of each chunk.
+// Special tokens the tokenizer adds at the start and end of a text are added
+// to every chunk. The context must have pooling enabled. Returns the chunks
+// of all texts, in order.
+func (ctx *Context) ComputeChunkEmbeddings(model *Model, texts []string, opts EmbeddingOptions, chunkOpts ChunkOptions) ([]EmbeddingChunk, error) {
+	if ctx.handle == nil {
+		return nil, ErrInvalidContext
+	}
+	if model == nil || model.handle == nil {
+		return nil, ErrInvalidModel
+	}
+
+	// Enable embeddings mode, which needs pooling
+	ctx.SetEmbeddings(true)
+	if ctx.PoolingType() == PoolingNone {
+		return nil, ErrInvalidContext
+	}
+	nEmbd := int(ctx.NEmbd())
+
+	// Determine the chunk size
+	size := ctx.MaxEmbeddingTokens()
+	if chunkOpts.Size > 0 {
+		size = min(size, chunkOpts.Size)
+	}
+	if chunkOpts.Overlap < 0 || chunkOpts.MaxChunks < 0 {
+		return nil, ErrInvalidArgument
+	}
+
+	// Tokenize all texts and split them into chunks
+	tokOpts := DefaultTokenizeOptions()
+	tokOpts.AddSpecial = opts.AddBOS
+	tokOpts.ParseSpecial = false
+
+	var chunks []EmbeddingChunk
+	var allTokens [][]Token
+	for i, text := range texts {
+		tokens, err := model.Tokenize(text, tokOpts)
+		if err != nil {
+			return nil, err
+		}
+		if opts.AddEOS {
+			if eos := model.EOS(); eos != -1 {
+				tokens = append(tokens, eos)
+			}
+		}
+		offsets, err := model.tokenOffsets(text, tokens)
+		if err != nil {
+			return nil, err
+		}
+
+		// Separate the special tokens at the start and end from the content
+		first, last := 0, len(tokens)
+		for first < last && model.IsControl(tokens[first]) {
+			first++
+		}
+		for last > first && model.IsControl(tokens[last-1]) {
+			last--
+		}
+		prefix, content, suffix := tokens[:first], tokens[first:last], tokens[last:]
+
+		// Determine the content tokens in each chunk
+		step := size - len(prefix) - len(suffix)
+		if step <= chunkOpts.Overlap {
+			return nil, ErrInvalidArgument.Withf("chunk size %d is too small for %d special tokens and an overlap of %d", size, len(prefix)+len(suffix), chunkOpts.Overlap)
+		}
+
+		// An empty text is a single chunk of special tokens
+		if len(content) == 0 {
+			chunks = append(chunks, EmbeddingChunk{Input: i, Tokens: len(tokens)})
+			allTokens = append(allTokens, tokens)
+			continue
+		}
+		for start, n := 0, 0; start < len(content); n++ {
+			if chunkOpts.MaxChunks > 0 && n >= chunkOpts.MaxChunks {
+				break
+			}
+			end := min(start+step, len(content))
+			chunk := make([]Token, 0, len(prefix)+end-start+len(suffix))
+			chunk = append(chunk, prefix...)
+			chunk = append(chunk, content[start:end]...)
+			chunk = append(chunk, suffix...)
+			chunks = append(chunks, EmbeddingChunk{
+				Input:  i,
+				Start:  offsets[first+start][0],
+				End:    offsets[first+end-1][1],
+				Tokens: len(chunk),
+			})
+			allTokens = append(allTokens, chunk)
+			if end == len(content) {
+				break
+			}
+			start = end - chunkOpts.Overlap
+		}
+	}
+
+	// Embed the chunks
+	embeddings, err := ctx.embedSequences(allTokens, nEmbd, opts.Normalize)
+	if err != nil {
+		return nil, err
+	}
+	for i := range chunks {
+		chunks[i].Embedding = embeddings[i]
+	}
+	return chunks, nil
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// embedSequences computes the pooled embedding of each sequence, decoding as
+// many sequences in each batch as the context allows. The sequences of a
+// batch are decoded together, so they must fit in one physical batch.
+func (ctx *Context) embedSequences(allTokens [][]Token, nEmbd int, normalize bool) ([][]float32, error) {
+	result := make([][]float32, len(allTokens))
+	maxTokens := ctx.MaxEmbeddingTokens()
+	maxSeqs := max(int(ctx.SeqMax()), 1)
+
+	batch, err := NewBatch(int32(maxTokens), int32(maxSeqs))
+	if err != nil {
+		return nil, err
+	}
+	defer batch.Close()
+
+	for start := 0; start < len(allTokens); {
+		// An empty sequence has a zero embedding
+		if len(allTokens[start]) == 0 {
+			result[start] = make([]float32, nEmbd)
+			start++
+			continue
+		}
+
+		// Fill the batch with whole sequences
+		end, nTokens := start, 0
+		for end < len(allTokens) && end-start < maxSeqs && len(allTokens[end]) > 0 && nTokens+len(allTokens[end]) <= maxTokens {
+			nTokens += len(allTokens[end])
+			end++
+		}
+		if end == start {
+			return nil, ErrTooLarge.Withf("sequence of %d tokens is larger than the batch size %d", len(allTokens[start]), maxTokens)
+		}
+
+		batch.Clear()
+		if err := ctx.MemoryClear(true); err != nil {
+			return nil, err
+		}
+		for seqID, tokens := range allTokens[start:end] {
+			for j, tok := range tokens {
+				if err := batch.Add(tok, int32(j), int32(seqID), j == len(tokens)-1); err != nil {
+					return nil, err
+				}
+			}
+		}
+		if err := batch.Decode(ctx); err != nil {
+			return nil, err
+		}
+
+		// Copy the embedding of each sequence
+		for seqID := range end - start {
+			embd, err := ctx.GetEmbeddingsBySeq(int32(seqID))
+			if err != nil {
+				return nil, err
+			}
+			result[start+seqID] = make([]float32, nEmbd)
+			copy(result[start+seqID], embd)
+			if normalize {
+				NormalizeEmbeddings(result[start+seqID])
+			}
+		}
+		start = end
+	}
+	return result, nil
+}
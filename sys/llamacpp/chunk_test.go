@@ -0,0 +1,70 @@
+package llamacpp_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mutablelogic/go-llama/sys/llamacpp"
+)
+
+func TestComputeChunkEmbeddings(t *testing.T) {
+	llamacpp.Init()
+	defer llamacpp.Cleanup()
+
+	modelParams := llamacpp.DefaultModelParams()
+	model, err := llamacpp.LoadModel("../../testdata/all-MiniLM-L6-v2-Q4_K_M.gguf", modelParams)
+	if err != nil {
+		t.Fatalf("failed to load model: %v", err)
+	}
+	defer model.Close()
+
+	ctxParams := llamacpp.DefaultContextParams()
+	ctxParams.Embeddings = true
+	ctx, err := llamacpp.NewContext(model, ctxParams)
+	if err != nil {
+		t.Fatalf("failed to create context: %v", err)
+	}
+	defer ctx.Close()
+
+	texts := []string{
+		"Hello world",
+		strings.Repeat("The cat sat on the mat. ", 20),
+	}
+
+	// Long texts are split into chunks of at most eight tokens
+	opts := llamacpp.DefaultEmbeddingOptions()
+	chunks, err := ctx.ComputeChunkEmbeddings(model, texts, opts, llamacpp.ChunkOptions{Size: 8, Overlap: 2})
+	if err != nil {
+		t.Fatalf("failed to compute chunk embeddings: %v", err)
+	}
+	counts := make([]int, len(texts))
+	for _, chunk := range chunks {
+		counts[chunk.Input]++
+		if chunk.Tokens > 8 {
+			t.Errorf("chunk has %d tokens, more than 8", chunk.Tokens)
+		}
+		if chunk.Start > chunk.End || chunk.End > len(texts[chunk.Input]) {
+			t.Errorf("invalid chunk span %d-%d", chunk.Start, chunk.End)
+		}
+		if len(chunk.Embedding) != int(ctx.NEmbd()) {
+			t.Errorf("expected dimension %d, got %d", ctx.NEmbd(), len(chunk.Embedding))
+		}
+	}
+	if counts[0] != 1 || counts[1] < 2 {
+		t.Errorf("expected one chunk and several chunks, got %v", counts)
+	}
+
+	// Truncation embeds one chunk of each text
+	chunks, err = ctx.ComputeChunkEmbeddings(model, texts, opts, llamacpp.ChunkOptions{Size: 8, MaxChunks: 1})
+	if err != nil {
+		t.Fatalf("failed to compute chunk embeddings: %v", err)
+	}
+	if len(chunks) != len(texts) {
+		t.Errorf("expected %d chunks, got %d", len(texts), len(chunks))
+	}
+
+	// The overlap must be smaller than a chunk
+	if _, err := ctx.ComputeChunkEmbeddings(model, texts, opts, llamacpp.ChunkOptions{Size: 8, Overlap: 8}); err == nil {
+		t.Error("expected an error with too much overlap")
+	}
+}
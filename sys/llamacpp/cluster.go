@@ -0,0 +1,177 @@
+package llamacpp
+
+import (
+	"math"
+	"math/rand/v2"
+	"slices"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// CLUSTERING
+
+// kMeansIterations is the most iterations of k-means
+const kMeansIterations = 100
+
+// KMeans groups the embeddings into at most k clusters by cosine similarity,
+// with centroids seeded by k-means++. The result is deterministic. Returns
+// the cluster of each embedding, numbered in order of first appearance.
+// Identical embeddings are always in the same cluster, so there may be fewer
+// than k clusters.
+func (be *BatchEmbeddings) KMeans(k int) []int {
+	n := len(be.Embeddings)
+	if n == 0 || k <= 0 {
+		return nil
+	}
+	k = min(k, n)
+
+	// Compare normalized copies of the embeddings
+	vectors := make([][]float32, n)
+	for i, embd := range be.Embeddings {
+		vectors[i] = slices.Clone(embd)
+		NormalizeEmbeddings(vectors[i])
+	}
+
+	// Choose the first centroid at random, and each other centroid with a
+	// probability of its squared distance from the nearest centroid
+	r := rand.New(rand.NewPCG(1, 2))
+	centroids := [][]float32{slices.Clone(vectors[r.IntN(n)])}
+	distances := make([]float64, n)
+	for len(centroids) < k {
+		var total float64
+		for i, v := range vectors {
+			best := math.Inf(-1)
+			for _, c := range centroids {
+				best = max(best, DotProduct(v, c))
+			}
+			distances[i] = (1 - best) * (1 - best)
+			total += distances[i]
+		}
+		if total <= 0 {
+			break
+		}
+		target := r.Float64() * total
+		next := n - 1
+		for i, d := range distances {
+			if target -= d; target < 0 {
+				next = i
+				break
+			}
+		}
+		centroids = append(centroids, slices.Clone(vectors[next]))
+	}
+
+	// Assign each embedding to the most similar centroid, and move each
+	// centroid to the mean of its embeddings, until nothing changes
+	labels := make([]int, n)
+	for iteration := 0; iteration < kMeansIterations; iteration++ {
+		changed := iteration == 0
+		for i, v := range vectors {
+			best, bestScore := 0, math.Inf(-1)
+			for j, c := range centroids {
+				if score := DotProduct(v, c); score > bestScore {
+					best, bestScore = j, score
+				}
+			}
+			if labels[i] != best {
+				labels[i], changed = best, true
+			}
+		}
+		if !changed {
+			break
+		}
+		for j, c := range centroids {
+			sum := make([]float32, len(c))
+			count := 0
+			for i, v := range vectors {
+				if labels[i] == j {
+					for d := range sum {
+						sum[d] += v[d]
+					}
+					count++
+				}
+			}
+			// An empty cluster keeps its centroid
+			if count > 0 {
+				NormalizeEmbeddings(sum)
+				centroids[j] = sum
+			}
+		}
+	}
+
+	return relabel(labels)
+}
+
+// Agglomerative groups the embeddings by repeatedly merging the two clusters
+// with the highest average cosine similarity between their embeddings. It
+// stops when there are k clusters, or when no two clusters have an average
+// similarity of at least threshold. Returns the cluster of each embedding,
+// numbered in order of first appearance.
+func (be *BatchEmbeddings) Agglomerative(k int, threshold float64) []int {
+	n := len(be.Embeddings)
+	if n == 0 {
+		return nil
+	}
+	k = max(k, 1)
+
+	// Start with each embedding in its own cluster
+	similarity := be.SimilarityMatrix()
+	sizes := make([]int, n)
+	labels := make([]int, n)
+	active := make([]int, n)
+	for i := range n {
+		sizes[i] = 1
+		labels[i] = i
+		active[i] = i
+	}
+
+	for len(active) > k {
+		// Find the most similar pair of clusters
+		a, b, best := -1, -1, math.Inf(-1)
+		for x, i := range active {
+			for _, j := range active[x+1:] {
+				if similarity[i][j] > best {
+					a, b, best = i, j, similarity[i][j]
+				}
+			}
+		}
+		if best < threshold {
+			break
+		}
+
+		// Merge the second cluster into the first, where the similarity to
+		// another cluster is the mean weighted by cluster size
+		for _, c := range active {
+			if c != a && c != b {
+				s := (float64(sizes[a])*similarity[a][c] + float64(sizes[b])*similarity[b][c]) / float64(sizes[a]+sizes[b])
+				similarity[a][c], similarity[c][a] = s, s
+			}
+		}
+		sizes[a] += sizes[b]
+		for i, label := range labels {
+			if label == b {
+				labels[i] = a
+			}
+		}
+		active = slices.DeleteFunc(active, func(c int) bool { return c == b })
+	}
+
+	return relabel(labels)
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// relabel numbers clusters from zero in order of first appearance
+func relabel(labels []int) []int {
+	numbers := make(map[int]int)
+	result := make([]int, len(labels))
+	for i, label := range labels {
+		number, exists := numbers[label]
+		if !exists {
+			number = len(numbers)
+			numbers[label] = number
+		}
+		result[i] = number
+	}
+	return result
+}
@@ -0,0 +1,84 @@
+package llamacpp_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/mutablelogic/go-llama/sys/llamacpp"
+)
+
+func clusterBatch() *llamacpp.BatchEmbeddings {
+	return &llamacpp.BatchEmbeddings{
+		Embeddings: [][]float32{
+			{1, 0, 0},
+			{0, 1, 0},
+			{0.9, 0.1, 0},
+			{0, 0.95, 0.05},
+			{0, 0, 1},
+			{1, 0.05, 0},
+		},
+		Dimension: 3,
+	}
+}
+
+func TestKMeans(t *testing.T) {
+	batch := clusterBatch()
+
+	labels := batch.KMeans(3)
+	if want := []int{0, 1, 0, 1, 2, 0}; !slices.Equal(labels, want) {
+		t.Errorf("expected %v, got %v", want, labels)
+	}
+
+	// The result is the same every time
+	if again := batch.KMeans(3); !slices.Equal(labels, again) {
+		t.Errorf("expected %v, got %v", labels, again)
+	}
+
+	// There are no more clusters than embeddings
+	labels = batch.KMeans(10)
+	if n := slices.Max(labels) + 1; n > len(batch.Embeddings) {
+		t.Errorf("expected at most %d clusters, got %d", len(batch.Embeddings), n)
+	}
+}
+
+func TestKMeansIdentical(t *testing.T) {
+	batch := &llamacpp.BatchEmbeddings{
+		Embeddings: [][]float32{{1, 0}, {1, 0}, {2, 0}},
+		Dimension:  2,
+	}
+	if labels := batch.KMeans(2); !slices.Equal(labels, []int{0, 0, 0}) {
+		t.Errorf("expected one cluster, got %v", labels)
+	}
+}
+
+func TestAgglomerative(t *testing.T) {
+	batch := clusterBatch()
+
+	// Merge until there are three clusters
+	if labels := batch.Agglomerative(3, -1); !slices.Equal(labels, []int{0, 1, 0, 1, 2, 0}) {
+		t.Errorf("expected three clusters, got %v", labels)
+	}
+
+	// Merge only clusters which are very similar
+	if labels := batch.Agglomerative(0, 0.998); !slices.Equal(labels, []int{0, 1, 2, 1, 3, 0}) {
+		t.Errorf("expected four clusters, got %v", labels)
+	}
+
+	// Merge everything
+	if labels := batch.Agglomerative(1, -1); !slices.Equal(labels, []int{0, 0, 0, 0, 0, 0}) {
+		t.Errorf("expected one cluster, got %v", labels)
+	}
+}
+
+func TestClusterEmpty(t *testing.T) {
+	batch := &llamacpp.BatchEmbeddings{Dimension: 3}
+	if labels := batch.KMeans(2); labels != nil {
+		t.Error("expected nil labels for empty batch")
+	}
+	if labels := batch.Agglomerative(2, 0); labels != nil {
+		t.Error("expected nil labels for empty batch")
+	}
+	if labels := clusterBatch().KMeans(0); labels != nil {
+		t.Error("expected nil labels for k=0")
+	}
+}
@@ -25,6 +25,7 @@ extern "C" llama_go_context_params llama_go_context_default_params(void) {
   params.type_v = -1; // -1 means use default (F16)
   params.attention_type = static_cast<int32_t>(defaults.attention_type);
   params.flash_attn = static_cast<int32_t>(defaults.flash_attn_type);
+  params.pooling_type = static_cast<int32_t>(defaults.pooling_type);
   params.embeddings = defaults.embeddings;
   params.offload_kqv = defaults.offload_kqv;
   params.kv_unified = defaults.kv_unified;
@@ -64,6 +65,8 @@ extern "C" void *llama_go_context_new(void *model_handle,
       static_cast<enum llama_attention_type>(params.attention_type);
   ctx_params.flash_attn_type =
       static_cast<enum llama_flash_attn_type>(params.flash_attn);
+  ctx_params.pooling_type =
+      static_cast<enum llama_pooling_type>(params.pooling_type);
   ctx_params.embeddings = params.embeddings;
   ctx_params.offload_kqv = params.offload_kqv;
   ctx_params.kv_unified = params.kv_unified;
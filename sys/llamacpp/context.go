@@ -80,6 +80,7 @@ type ContextParams struct {
 	TypeV         GGMLType      // KV cache V type (-1 = default F16)
 	AttentionType AttentionType // Attention type for embeddings (-1 = auto)
 	FlashAttn     FlashAttnType // Flash attention mode (-1 = auto, 0 = disabled, 1 = enabled)
+	PoolingType   PoolingType   // Pooling type for embeddings (-1 = from model)
 	Embeddings    bool          // Extract embeddings
 	OffloadKQV    bool          // Offload KQV ops to GPU
 	KVUnified     bool          // Use unified KV cache (required for encoder/BERT models)
@@ -105,6 +106,7 @@ func DefaultContextParams() ContextParams {
 		TypeV:         GGMLType(cParams.type_v),
 		AttentionType: AttentionType(cParams.attention_type),
 		FlashAttn:     FlashAttnType(cParams.flash_attn),
+		PoolingType:   PoolingType(cParams.pooling_type),
 		Embeddings:    bool(cParams.embeddings),
 		OffloadKQV:    bool(cParams.offload_kqv),
 		KVUnified:     bool(cParams.kv_unified),
@@ -137,6 +139,7 @@ func NewContext(model *Model, params ContextParams) (*Context, error) {
 		type_v:          C.int32_t(params.TypeV),
 		attention_type:  C.int32_t(params.AttentionType),
 		flash_attn:      C.int32_t(params.FlashAttn),
+		pooling_type:    C.int32_t(params.PoolingType),
 		embeddings:      C.bool(params.Embeddings),
 		offload_kqv:     C.bool(params.OffloadKQV),
 		kv_unified:      C.bool(params.KVUnified),
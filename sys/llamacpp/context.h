@@ -52,6 +52,8 @@ typedef struct {
                           // = causal, 1 = non-causal)
   int32_t
       flash_attn; // flash attention type (-1 = auto, 0 = disabled, 1 = enabled)
+  int32_t pooling_type; // pooling type for embeddings (-1 = from model, 0 =
+                        // none, 1 = mean, 2 = cls, 3 = last, 4 = rank)
   bool embeddings;  // if true, extract embeddings
   bool offload_kqv; // offload KQV ops to GPU
   bool kv_unified;  // use unified KV cache (required for encoder/BERT models)
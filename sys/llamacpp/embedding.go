@@ -6,7 +6,7 @@ package llamacpp
 */
 import "C"
 import (
-	"math"
+	"strings"
 	"unsafe"
 )
 
@@ -110,118 +110,6 @@ func (ctx *Context) GetEmbeddingsBySeq(seqID int32) ([]float32, error) {
 	return unsafe.Slice((*float32)(unsafe.Pointer(embd)), nEmbd), nil
 }
 
-///////////////////////////////////////////////////////////////////////////////
-// EMBEDDING UTILITIES
-
-// NormalizeEmbeddings performs L2 normalization on an embedding vector in-place.
-// This is commonly needed before computing cosine similarity.
-func NormalizeEmbeddings(embd []float32) {
-	if len(embd) == 0 {
-		return
-	}
-
-	// Compute L2 norm
-	var sum float64
-	for _, v := range embd {
-		sum += float64(v) * float64(v)
-	}
-
-	if sum > 0 {
-		norm := math.Sqrt(sum)
-		for i := range embd {
-			embd[i] = float32(float64(embd[i]) / norm)
-		}
-	}
-}
-
-// CosineSimilarity computes the cosine similarity between two embedding vectors.
-// Both vectors should be normalized for best results.
-// Returns a value between -1 and 1.
-func CosineSimilarity(a, b []float32) float64 {
-	if len(a) != len(b) || len(a) == 0 {
-		return 0
-	}
-
-	var dot, normA, normB float64
-	for i := range a {
-		dot += float64(a[i]) * float64(b[i])
-		normA += float64(a[i]) * float64(a[i])
-		normB += float64(b[i]) * float64(b[i])
-	}
-
-	if normA == 0 || normB == 0 {
-		return 0
-	}
-
-	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
-}
-
-// EuclideanDistance computes the Euclidean distance between two embedding vectors.
-func EuclideanDistance(a, b []float32) float64 {
-	if len(a) != len(b) || len(a) == 0 {
-		return 0
-	}
-
-	var sum float64
-	for i := range a {
-		diff := float64(a[i]) - float64(b[i])
-		sum += diff * diff
-	}
-
-	return math.Sqrt(sum)
-}
-
-// DotProduct computes the dot product between two embedding vectors.
-func DotProduct(a, b []float32) float64 {
-	if len(a) != len(b) || len(a) == 0 {
-		return 0
-	}
-
-	var dot float64
-	for i := range a {
-		dot += float64(a[i]) * float64(b[i])
-	}
-
-	return dot
-}
-
-// BatchEmbeddings holds multiple embeddings with their sequence information.
-type BatchEmbeddings struct {
-	Embeddings [][]float32 // One embedding vector per sequence/token
-	Dimension  int         // Embedding dimension
-}
-
-// ExtractBatchEmbeddings extracts individual embeddings from a flattened buffer.
-// nOutputs is the number of embeddings, nEmbd is the dimension.
-func ExtractBatchEmbeddings(flat []float32, nOutputs, nEmbd int) BatchEmbeddings {
-	if len(flat) != nOutputs*nEmbd {
-		return BatchEmbeddings{}
-	}
-
-	result := BatchEmbeddings{
-		Embeddings: make([][]float32, nOutputs),
-		Dimension:  nEmbd,
-	}
-
-	for i := 0; i < nOutputs; i++ {
-		start := i * nEmbd
-		end := start + nEmbd
-		// Make a copy to avoid referencing the original buffer
-		embd := make([]float32, nEmbd)
-		copy(embd, flat[start:end])
-		result.Embeddings[i] = embd
-	}
-
-	return result
-}
-
-// Normalize normalizes all embeddings in the batch.
-func (be *BatchEmbeddings) Normalize() {
-	for i := range be.Embeddings {
-		NormalizeEmbeddings(be.Embeddings[i])
-	}
-}
-
 ///////////////////////////////////////////////////////////////////////////////
 // BATCH EMBEDDING COMPUTATION
 
@@ -408,83 +296,148 @@ func (ctx *Context) computeEmbeddingsWithPooling(batch *Batch, allTokens [][]Tok
 	return result, nil
 }
 
-// ComputeEmbedding computes embedding for a single text.
-// Convenience wrapper around ComputeEmbeddings.
-func (ctx *Context) ComputeEmbedding(model *Model, text string, opts EmbeddingOptions) ([]float32, error) {
-	batch, err := ctx.ComputeEmbeddings(model, []string{text}, opts)
-	if err != nil {
-		return nil, err
+// TokenEmbeddings holds the embedding of each token of a text, for
+// late-interaction retrieval.
+type TokenEmbeddings struct {
+	Tokens     []Token     // Token IDs
+	Offsets    [][2]int    // Start and end byte offsets of each token in the text
+	Embeddings [][]float32 // One embedding vector per token
+	Dimension  int         // Embedding dimension
+}
+
+// ComputeTokenEmbeddings computes an embedding for every token of each text,
+// rather than pooling them into one vector. The context must have pooling
+// disabled. Returns one TokenEmbeddings per input text.
+func (ctx *Context) ComputeTokenEmbeddings(model *Model, texts []string, opts EmbeddingOptions) ([]TokenEmbeddings, error) {
+	if ctx.handle == nil {
+		return nil, ErrInvalidContext
 	}
-	if len(batch.Embeddings) == 0 {
+	if model == nil || model.handle == nil {
+		return nil, ErrInvalidModel
+	}
+
+	// Enable embeddings mode, which needs per-token outputs
+	ctx.SetEmbeddings(true)
+	if ctx.PoolingType() != PoolingNone {
 		return nil, ErrInvalidContext
 	}
-	return batch.Embeddings[0], nil
-}
+	nEmbd := int(ctx.NEmbd())
+
+	// Tokenize all texts
+	tokOpts := DefaultTokenizeOptions()
+	tokOpts.AddSpecial = opts.AddBOS
+	tokOpts.ParseSpecial = false
 
-// SimilarityMatrix computes pairwise cosine similarity between all embeddings.
-// Returns an NxN matrix where result[i][j] is the similarity between embeddings i and j.
-func (be *BatchEmbeddings) SimilarityMatrix() [][]float64 {
-	n := len(be.Embeddings)
-	if n == 0 {
-		return nil
-	}
-
-	matrix := make([][]float64, n)
-	for i := 0; i < n; i++ {
-		matrix[i] = make([]float64, n)
-		for j := 0; j < n; j++ {
-			if i == j {
-				matrix[i][j] = 1.0 // Self-similarity
-			} else if j < i {
-				matrix[i][j] = matrix[j][i] // Symmetric
-			} else {
-				matrix[i][j] = CosineSimilarity(be.Embeddings[i], be.Embeddings[j])
+	allTokens := make([][]Token, len(texts))
+	maxTokens := int32(1)
+	for i, text := range texts {
+		tokens, err := model.Tokenize(text, tokOpts)
+		if err != nil {
+			return nil, err
+		}
+		if opts.AddEOS {
+			if eos := model.EOS(); eos != -1 {
+				tokens = append(tokens, eos)
 			}
 		}
+		allTokens[i] = tokens
+		maxTokens = max(maxTokens, int32(len(tokens)))
 	}
 
-	return matrix
-}
-
-// MostSimilar returns indices of the k most similar embeddings to the given query index.
-// Excludes the query itself from results.
-func (be *BatchEmbeddings) MostSimilar(queryIdx int, k int) []int {
-	n := len(be.Embeddings)
-	if queryIdx < 0 || queryIdx >= n || k <= 0 {
-		return nil
+	// Create batch large enough for the longest text
+	batch, err := NewBatch(maxTokens, 1)
+	if err != nil {
+		return nil, err
 	}
+	defer batch.Close()
 
-	// Compute similarities
-	type scoredIdx struct {
-		idx   int
-		score float64
-	}
+	// Process each text separately, with an output for every token
+	result := make([]TokenEmbeddings, len(texts))
+	for i, tokens := range allTokens {
+		offsets, err := model.tokenOffsets(texts[i], tokens)
+		if err != nil {
+			return nil, err
+		}
+		result[i] = TokenEmbeddings{
+			Tokens:     tokens,
+			Offsets:    offsets,
+			Embeddings: make([][]float32, len(tokens)),
+			Dimension:  nEmbd,
+		}
+		if len(tokens) == 0 {
+			continue
+		}
 
-	scores := make([]scoredIdx, 0, n-1)
-	query := be.Embeddings[queryIdx]
-	for i, embd := range be.Embeddings {
-		if i != queryIdx {
-			scores = append(scores, scoredIdx{i, CosineSimilarity(query, embd)})
+		batch.Clear()
+		if err := ctx.MemoryClear(true); err != nil {
+			return nil, err
+		}
+		for j, tok := range tokens {
+			if err := batch.Add(tok, int32(j), 0, true); err != nil {
+				return nil, err
+			}
+		}
+		if err := batch.Decode(ctx); err != nil {
+			return nil, err
 		}
-	}
 
-	// Sort by score descending
-	for i := 0; i < len(scores)-1; i++ {
-		for j := i + 1; j < len(scores); j++ {
-			if scores[j].score > scores[i].score {
-				scores[i], scores[j] = scores[j], scores[i]
+		// Copy the embedding of each token
+		for j := range tokens {
+			embd, err := ctx.GetEmbeddings(int32(j))
+			if err != nil {
+				return nil, err
+			}
+			result[i].Embeddings[j] = make([]float32, nEmbd)
+			copy(result[i].Embeddings[j], embd)
+			if opts.Normalize {
+				NormalizeEmbeddings(result[i].Embeddings[j])
 			}
 		}
 	}
 
-	// Return top-k indices
-	if k > len(scores) {
-		k = len(scores)
-	}
-	result := make([]int, k)
-	for i := 0; i < k; i++ {
-		result[i] = scores[i].idx
+	return result, nil
+}
+
+// tokenOffsets returns the start and end byte offsets of each token in the
+// text. Control tokens, and pieces which cannot be found in the text, have
+// an empty span at the current position.
+func (m *Model) tokenOffsets(text string, tokens []Token) ([][2]int, error) {
+	offsets := make([][2]int, len(tokens))
+	pos := 0
+	for i, tok := range tokens {
+		offsets[i] = [2]int{pos, pos}
+		if m.IsControl(tok) {
+			continue
+		}
+		piece, err := m.TokenToString(tok)
+		if err != nil {
+			return nil, err
+		}
+
+		// Tokenizers may add a leading space which is not in the text
+		idx := strings.Index(text[pos:], piece)
+		if idx < 0 {
+			piece = strings.TrimLeft(piece, " ")
+			idx = strings.Index(text[pos:], piece)
+		}
+		if idx < 0 || piece == "" {
+			continue
+		}
+		offsets[i] = [2]int{pos + idx, pos + idx + len(piece)}
+		pos += idx + len(piece)
 	}
+	return offsets, nil
+}
 
-	return result
+// ComputeEmbedding computes embedding for a single text.
+// Convenience wrapper around ComputeEmbeddings.
+func (ctx *Context) ComputeEmbedding(model *Model, text string, opts EmbeddingOptions) ([]float32, error) {
+	batch, err := ctx.ComputeEmbeddings(model, []string{text}, opts)
+	if err != nil {
+		return nil, err
+	}
+	if len(batch.Embeddings) == 0 {
+		return nil, ErrInvalidContext
+	}
+	return batch.Embeddings[0], nil
 }
@@ -87,6 +87,63 @@ func TestComputeEmbeddings(t *testing.T) {
 	t.Logf("Computed %d embeddings of dimension %d", len(batch.Embeddings), batch.Dimension)
 }
 
+func TestComputeTokenEmbeddings(t *testing.T) {
+	llamacpp.Init()
+	defer llamacpp.Cleanup()
+
+	modelParams := llamacpp.DefaultModelParams()
+	model, err := llamacpp.LoadModel("../../testdata/all-MiniLM-L6-v2-Q4_K_M.gguf", modelParams)
+	if err != nil {
+		t.Fatalf("failed to load model: %v", err)
+	}
+	defer model.Close()
+
+	ctxParams := llamacpp.DefaultContextParams()
+	ctxParams.Embeddings = true
+	ctxParams.PoolingType = llamacpp.PoolingNone
+	ctx, err := llamacpp.NewContext(model, ctxParams)
+	if err != nil {
+		t.Fatalf("failed to create context: %v", err)
+	}
+	defer ctx.Close()
+
+	texts := []string{"Hello world", "The cat sat on the mat"}
+	result, err := ctx.ComputeTokenEmbeddings(model, texts, llamacpp.DefaultEmbeddingOptions())
+	if err != nil {
+		t.Fatalf("failed to compute token embeddings: %v", err)
+	}
+	if len(result) != len(texts) {
+		t.Fatalf("expected %d results, got %d", len(texts), len(result))
+	}
+
+	for i, r := range result {
+		if len(r.Embeddings) != len(r.Tokens) || len(r.Offsets) != len(r.Tokens) {
+			t.Fatalf("text %d: %d tokens, %d offsets and %d embeddings", i, len(r.Tokens), len(r.Offsets), len(r.Embeddings))
+		}
+		for j, embd := range r.Embeddings {
+			if len(embd) != r.Dimension {
+				t.Errorf("text %d token %d: expected dimension %d, got %d", i, j, r.Dimension, len(embd))
+			}
+		}
+		for _, offset := range r.Offsets {
+			if offset[0] > offset[1] || offset[1] > len(texts[i]) {
+				t.Errorf("text %d: invalid offset %v", i, offset)
+			}
+		}
+	}
+
+	// A context with pooling cannot return token embeddings
+	ctxParams.PoolingType = llamacpp.PoolingMean
+	pooled, err := llamacpp.NewContext(model, ctxParams)
+	if err != nil {
+		t.Fatalf("failed to create context: %v", err)
+	}
+	defer pooled.Close()
+	if _, err := pooled.ComputeTokenEmbeddings(model, texts, llamacpp.DefaultEmbeddingOptions()); err == nil {
+		t.Error("expected an error with pooling enabled")
+	}
+}
+
 func TestComputeEmbeddingsEmpty(t *testing.T) {
 	llamacpp.Init()
 	defer llamacpp.Cleanup()
@@ -23,6 +23,7 @@ var (
 	ErrKeyNotFound     = llama.ErrKeyNotFound
 	ErrIndexOutOfRange = llama.ErrIndexOutOfRange
 	ErrInvalidToken    = llama.ErrInvalidToken
+	ErrTooLarge        = llama.ErrTooLarge
 )
 
 ///////////////////////////////////////////////////////////////////////////////
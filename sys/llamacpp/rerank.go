@@ -0,0 +1,74 @@
+package llamacpp
+
+import "C"
+
+///////////////////////////////////////////////////////////////////////////////
+// RERANKING
+
+// RerankTokens returns the input tokens for scoring a query and document pair
+// with a cross-encoder model. The input is [BOS] query [EOS] [SEP] document [EOS],
+// where special tokens the model does not define are left out.
+func (m *Model) RerankTokens(query, document string) ([]Token, error) {
+	if m.handle == nil {
+		return nil, ErrInvalidModel
+	}
+
+	// Tokenize the query and document without special tokens
+	tokOpts := TokenizeOptions{AddSpecial: false, ParseSpecial: false}
+	queryTokens, err := m.Tokenize(query, tokOpts)
+	if err != nil {
+		return nil, err
+	}
+	docTokens, err := m.Tokenize(document, tokOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	tokens := make([]Token, 0, len(queryTokens)+len(docTokens)+4)
+	tokens = appendToken(tokens, m.BOS())
+	tokens = append(tokens, queryTokens...)
+	tokens = appendToken(tokens, m.EOS())
+	tokens = appendToken(tokens, m.SEP())
+	tokens = append(tokens, docTokens...)
+	tokens = appendToken(tokens, m.EOS())
+	return tokens, nil
+}
+
+// ComputeRankScores scores each query and document pair, which is the
+// input returned by RerankTokens, and returns one relevance score per pair.
+// The context must use rank pooling. Pairs are decoded in batches of as many
+// whole pairs as the context allows, and a pair which does not fit in one
+// batch is an error.
+func (ctx *Context) ComputeRankScores(pairs [][]Token) ([]float32, error) {
+	if ctx.handle == nil {
+		return nil, ErrInvalidContext
+	}
+	if len(pairs) == 0 {
+		return []float32{}, nil
+	}
+
+	// Enable embeddings mode, which is required for pooled scores
+	ctx.SetEmbeddings(true)
+	if ctx.PoolingType() != PoolingRank {
+		return nil, ErrInvalidContext
+	}
+
+	// With rank pooling, the first value of each sequence is the score
+	embeddings, err := ctx.embedSequences(pairs, 1, false)
+	if err != nil {
+		return nil, err
+	}
+	scores := make([]float32, len(pairs))
+	for i, embedding := range embeddings {
+		scores[i] = embedding[0]
+	}
+	return scores, nil
+}
+
+// appendToken appends a special token, unless the model does not define it
+func appendToken(tokens []Token, token Token) []Token {
+	if token < 0 {
+		return tokens
+	}
+	return append(tokens, token)
+}
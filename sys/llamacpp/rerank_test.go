@@ -0,0 +1,112 @@
+package llamacpp_test
+
+import (
+	"errors"
+	"math"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/mutablelogic/go-llama/sys/llamacpp"
+)
+
+const testModelRerank = "../../testdata/bge-reranker-v2-m3-Q4_K_M.gguf"
+
+func loadRerankModel(t *testing.T) *llamacpp.Model {
+	t.Helper()
+	if _, err := os.Stat(testModelRerank); os.IsNotExist(err) {
+		t.Skipf("Skipping test: model not found at %s", testModelRerank)
+	}
+	model, err := llamacpp.LoadModel(testModelRerank, llamacpp.DefaultModelParams())
+	if err != nil {
+		t.Fatalf("failed to load model: %v", err)
+	}
+	return model
+}
+
+func rerankPairs(t *testing.T, model *llamacpp.Model, query string, documents []string) [][]llamacpp.Token {
+	t.Helper()
+	pairs := make([][]llamacpp.Token, len(documents))
+	for i, document := range documents {
+		tokens, err := model.RerankTokens(query, document)
+		if err != nil {
+			t.Fatalf("failed to tokenize pair %d: %v", i, err)
+		}
+		pairs[i] = tokens
+	}
+	return pairs
+}
+
+func TestComputeRankScoresMoreThanSeqMax(t *testing.T) {
+	llamacpp.Init()
+	defer llamacpp.Cleanup()
+
+	model := loadRerankModel(t)
+	defer model.Close()
+
+	ctxParams := llamacpp.DefaultContextParams()
+	ctxParams.Embeddings = true
+	ctxParams.PoolingType = llamacpp.PoolingRank
+	ctxParams.NSeqMax = 2
+	ctx, err := llamacpp.NewContext(model, ctxParams)
+	if err != nil {
+		t.Fatalf("failed to create context: %v", err)
+	}
+	defer ctx.Close()
+
+	query := "What is the capital of France?"
+	documents := []string{
+		"Paris is the capital of France.",
+		"The cat sat on the mat.",
+		"France is a country in Europe.",
+		"Berlin is the capital of Germany.",
+		"The capital of France is known for the Eiffel Tower.",
+	}
+	pairs := rerankPairs(t, model, query, documents)
+
+	scores, err := ctx.ComputeRankScores(pairs)
+	if err != nil {
+		t.Fatalf("failed to compute scores: %v", err)
+	}
+	if len(scores) != len(documents) {
+		t.Fatalf("expected %d scores, got %d", len(documents), len(scores))
+	}
+
+	// Each score is the same as the score of the pair on its own
+	for i, pair := range pairs {
+		score, err := ctx.ComputeRankScores([][]llamacpp.Token{pair})
+		if err != nil {
+			t.Fatalf("failed to compute score %d: %v", i, err)
+		}
+		if math.Abs(float64(score[0]-scores[i])) > 1e-3 {
+			t.Errorf("score %d: expected %f, got %f", i, score[0], scores[i])
+		}
+	}
+	if scores[0] <= scores[1] {
+		t.Errorf("expected relevant document to score higher: %f <= %f", scores[0], scores[1])
+	}
+}
+
+func TestComputeRankScoresTooLarge(t *testing.T) {
+	llamacpp.Init()
+	defer llamacpp.Cleanup()
+
+	model := loadRerankModel(t)
+	defer model.Close()
+
+	ctxParams := llamacpp.DefaultContextParams()
+	ctxParams.Embeddings = true
+	ctxParams.PoolingType = llamacpp.PoolingRank
+	ctxParams.NBatch = 32
+	ctxParams.NUBatch = 32
+	ctx, err := llamacpp.NewContext(model, ctxParams)
+	if err != nil {
+		t.Fatalf("failed to create context: %v", err)
+	}
+	defer ctx.Close()
+
+	pairs := rerankPairs(t, model, "query", []string{strings.Repeat("word ", 100)})
+	if _, err := ctx.ComputeRankScores(pairs); !errors.Is(err, llamacpp.ErrTooLarge) {
+		t.Errorf("expected ErrTooLarge, got %v", err)
+	}
+}
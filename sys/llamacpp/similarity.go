@@ -0,0 +1,183 @@
+package llamacpp
+
+import (
+	"cmp"
+	"math"
+	"slices"
+)
+
+///////////////////////////////////////////////////////////////////////////////
+// EMBEDDING UTILITIES
+
+// NormalizeEmbeddings performs L2 normalization on an embedding vector in-place.
+// This is commonly needed before computing cosine similarity.
+func NormalizeEmbeddings(embd []float32) {
+	if len(embd) == 0 {
+		return
+	}
+
+	// Compute L2 norm
+	var sum float64
+	for _, v := range embd {
+		sum += float64(v) * float64(v)
+	}
+
+	if sum > 0 {
+		norm := math.Sqrt(sum)
+		for i := range embd {
+			embd[i] = float32(float64(embd[i]) / norm)
+		}
+	}
+}
+
+// CosineSimilarity computes the cosine similarity between two embedding vectors.
+// Both vectors should be normalized for best results.
+// Returns a value between -1 and 1.
+func CosineSimilarity(a, b []float32) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
+
+// EuclideanDistance computes the Euclidean distance between two embedding vectors.
+func EuclideanDistance(a, b []float32) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+
+	var sum float64
+	for i := range a {
+		diff := float64(a[i]) - float64(b[i])
+		sum += diff * diff
+	}
+
+	return math.Sqrt(sum)
+}
+
+// DotProduct computes the dot product between two embedding vectors.
+func DotProduct(a, b []float32) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+
+	var dot float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+	}
+
+	return dot
+}
+
+// BatchEmbeddings holds multiple embeddings with their sequence information.
+type BatchEmbeddings struct {
+	Embeddings [][]float32 // One embedding vector per sequence/token
+	Dimension  int         // Embedding dimension
+}
+
+// ExtractBatchEmbeddings extracts individual embeddings from a flattened buffer.
+// nOutputs is the number of embeddings, nEmbd is the dimension.
+func ExtractBatchEmbeddings(flat []float32, nOutputs, nEmbd int) BatchEmbeddings {
+	if len(flat) != nOutputs*nEmbd {
+		return BatchEmbeddings{}
+	}
+
+	result := BatchEmbeddings{
+		Embeddings: make([][]float32, nOutputs),
+		Dimension:  nEmbd,
+	}
+
+	for i := 0; i < nOutputs; i++ {
+		start := i * nEmbd
+		end := start + nEmbd
+		// Make a copy to avoid referencing the original buffer
+		embd := make([]float32, nEmbd)
+		copy(embd, flat[start:end])
+		result.Embeddings[i] = embd
+	}
+
+	return result
+}
+
+// Normalize normalizes all embeddings in the batch.
+func (be *BatchEmbeddings) Normalize() {
+	for i := range be.Embeddings {
+		NormalizeEmbeddings(be.Embeddings[i])
+	}
+}
+
+// SimilarityMatrix computes pairwise cosine similarity between all embeddings.
+// Returns an NxN matrix where result[i][j] is the similarity between embeddings i and j.
+func (be *BatchEmbeddings) SimilarityMatrix() [][]float64 {
+	n := len(be.Embeddings)
+	if n == 0 {
+		return nil
+	}
+
+	matrix := make([][]float64, n)
+	for i := 0; i < n; i++ {
+		matrix[i] = make([]float64, n)
+		for j := 0; j < n; j++ {
+			if i == j {
+				matrix[i][j] = 1.0 // Self-similarity
+			} else if j < i {
+				matrix[i][j] = matrix[j][i] // Symmetric
+			} else {
+				matrix[i][j] = CosineSimilarity(be.Embeddings[i], be.Embeddings[j])
+			}
+		}
+	}
+
+	return matrix
+}
+
+// MostSimilar returns indices of the k most similar embeddings to the given query index.
+// Excludes the query itself from results.
+func (be *BatchEmbeddings) MostSimilar(queryIdx int, k int) []int {
+	n := len(be.Embeddings)
+	if queryIdx < 0 || queryIdx >= n || k <= 0 {
+		return nil
+	}
+
+	// Compute similarities
+	type scoredIdx struct {
+		idx   int
+		score float64
+	}
+
+	scores := make([]scoredIdx, 0, n-1)
+	query := be.Embeddings[queryIdx]
+	for i, embd := range be.Embeddings {
+		if i != queryIdx {
+			scores = append(scores, scoredIdx{i, CosineSimilarity(query, embd)})
+		}
+	}
+
+	// Sort by score descending, keeping the order of equal scores
+	slices.SortStableFunc(scores, func(a, b scoredIdx) int {
+		return cmp.Compare(b.score, a.score)
+	})
+
+	// Return top-k indices
+	if k > len(scores) {
+		k = len(scores)
+	}
+	result := make([]int, k)
+	for i := 0; i < k; i++ {
+		result[i] = scores[i].idx
+	}
+
+	return result
+}
@@ -160,6 +160,18 @@ int32_t llama_go_token_pad(void *model) {
   return llama_vocab_pad(vocab);
 }
 
+int32_t llama_go_token_sep(void *model) {
+  if (!model)
+    return -1;
+  struct llama_model *lmodel = llama_go_model_get_llama_model(model);
+  if (!lmodel)
+    return -1;
+  const struct llama_vocab *vocab = llama_model_get_vocab(lmodel);
+  if (!vocab)
+    return -1;
+  return llama_vocab_sep(vocab);
+}
+
 bool llama_go_token_is_eog(void *model, int32_t token) {
   if (!model)
     return false;
@@ -58,6 +58,14 @@ func (m *Model) PAD() Token {
 	return Token(C.llama_go_token_pad(m.handle))
 }
 
+// SEP returns the separator token
+func (m *Model) SEP() Token {
+	if m.handle == nil {
+		return -1
+	}
+	return Token(C.llama_go_token_sep(m.handle))
+}
+
 ///////////////////////////////////////////////////////////////////////////////
 // TOKEN CHECKING
 
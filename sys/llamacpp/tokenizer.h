@@ -49,6 +49,7 @@ int32_t llama_go_token_eos(void* model);
 int32_t llama_go_token_eot(void* model);
 int32_t llama_go_token_nl(void* model);
 int32_t llama_go_token_pad(void* model);
+int32_t llama_go_token_sep(void* model);
 
 // Check if token is special
 bool llama_go_token_is_eog(void* model, int32_t token);